@@ -4,11 +4,16 @@ package getit
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
-	"net/http"
 	"net/url"
-	"os/exec"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 )
 
 // Mapper maps one form of a source to another.
@@ -30,6 +35,8 @@ type Resolver interface {
 type Source struct {
 	URL    *url.URL
 	SubDir string
+	// Archive is the archive type forced by the "archive" query parameter, eg. "tar.gz", "zip" or "none", if any.
+	Archive string
 }
 
 // Fetcher retrieves archives from a pluggable source.
@@ -38,35 +45,156 @@ type Source struct {
 //
 //	git+ssh://host/path/to/repo.git//path/to/subdir
 //	https://host/path/to/archive.tgz//path/to/subdir
+//
+// Resolvers are usually selected by the extension of the URL path. Where the path has no useful extension, eg. a
+// signed CDN URL, the "archive" query parameter selects the resolver as if the path had that extension instead. The
+// parameter is removed from the URL before fetching, and "none" downloads the source without unpacking it:
+//
+//	https://cdn.example.com/a1b2c3?signature=xyz&archive=tar.gz
 type Fetcher struct {
+	// mu guards mappers and resolvers, which are replaced rather than modified when added to.
+	mu        sync.RWMutex
 	mappers   []Mapper
 	resolvers []Resolver
+	// addedMappers and addedResolvers count those added after construction, which precede the rest.
+	addedMappers   int
+	addedResolvers int
+	config         config
 }
 
-func New(resolvers []Resolver, mappers []Mapper) *Fetcher {
-	return &Fetcher{
+func New(resolvers []Resolver, mappers []Mapper, options ...Option) *Fetcher {
+	f := &Fetcher{
 		mappers:   mappers,
 		resolvers: resolvers,
+		config:    defaultConfig(),
 	}
+	for _, option := range options {
+		option(f)
+	}
+	f.config.finalise()
+	return f
+}
+
+// AddResolver adds resolvers to f ahead of those it was created with, and after any added earlier, so that they take
+// precedence over eg. the built-in resolvers of [Default].
+//
+// It is safe to call concurrently with fetches and other calls to AddResolver, eg. from the init functions of plugin
+// packages.
+func (f *Fetcher) AddResolver(resolvers ...Resolver) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.resolvers = slices.Concat(f.resolvers[:f.addedResolvers], resolvers, f.resolvers[f.addedResolvers:])
+	f.addedResolvers += len(resolvers)
+}
+
+// AddMapper adds mappers to f ahead of those it was created with, and after any added earlier, so that they take
+// precedence over eg. the built-in mappers of [Default].
+//
+// It is safe to call concurrently with fetches and other calls to AddMapper.
+func (f *Fetcher) AddMapper(mappers ...Mapper) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.mappers = slices.Concat(f.mappers[:f.addedMappers], mappers, f.mappers[f.addedMappers:])
+	f.addedMappers += len(mappers)
+}
+
+// registered returns the current resolvers and mappers of f, which must not be modified.
+func (f *Fetcher) registered() ([]Resolver, []Mapper) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.resolvers, f.mappers
+}
+
+// withConfig returns a copy of f, with its configuration modified by configure.
+func (f *Fetcher) withConfig(configure func(cfg *config)) *Fetcher {
+	resolvers, mappers := f.registered()
+	clone := &Fetcher{mappers: mappers, resolvers: resolvers, config: f.config}
+	configure(&clone.config)
+	return clone
 }
 
 // Resolve a source string to a Source and URL.
 func (f *Fetcher) Resolve(source string) (Resolver, Source, error) {
-	for _, mapper := range f.mappers {
+	expanded, err := f.config.expandEnv(source)
+	if err != nil {
+		return nil, Source{}, err
+	}
+	_, mappers := f.registered()
+	if f.config.allowedSchemes != nil && !f.config.allowedSchemes["file"] {
+		// Local paths will be rejected, so there is no need to check whether they exist.
+		mappers = syntacticMappers(mappers)
+	}
+	resolver, src, err := f.resolveMapped(mapSource(mappers, expanded))
+	if err != nil {
+		return nil, Source{}, err
+	}
+	f.config.logger.Debug("resolve", "source", redactSource(source), "url", RedactURL(src.URL), "subdir", src.SubDir, "resolver", fmt.Sprintf("%T", resolver))
+	return resolver, src, nil
+}
+
+// Detect reports the name of the resolver that would handle source, eg. "Git" or "TAR", or false if it would not be
+// handled. It is intended for validating input, eg. in a UI.
+//
+// Unlike [Fetcher.Resolve], Detect never touches the filesystem: the [FilePath] mapper is replaced by
+// [SyntacticFilePath], so paths are detected whether or not they exist. Other mappers are expected to be pure
+// functions of the source.
+func (f *Fetcher) Detect(source string) (string, bool) {
+	source, err := f.config.expandEnv(source)
+	if err != nil {
+		return "", false
+	}
+	_, mappers := f.registered()
+	resolver, _, err := f.resolveMapped(mapSource(syntacticMappers(mappers), source))
+	if err != nil {
+		return "", false
+	}
+	return resolverName(resolver), true
+}
+
+// syntacticMappers returns mappers with [FilePath] replaced by [SyntacticFilePath].
+func syntacticMappers(mappers []Mapper) []Mapper {
+	syntactic := make([]Mapper, len(mappers))
+	for i, mapper := range mappers {
+		// Funcs can't be compared directly, but their code pointers can.
+		if reflect.ValueOf(mapper).Pointer() == reflect.ValueOf(FilePath).Pointer() {
+			mapper = SyntacticFilePath
+		}
+		syntactic[i] = mapper
+	}
+	return syntactic
+}
+
+// mapSource applies the first of mappers that matches source.
+func mapSource(mappers []Mapper, source string) string {
+	for _, mapper := range mappers {
 		if mapped, ok := mapper(source); ok {
-			source = mapped
-			if _, err := url.Parse(source); err != nil {
-				panic("mapper did not produce a valid URL: " + source)
+			if _, err := url.Parse(mapped); err != nil {
+				panic("mapper did not produce a valid URL: " + redactSource(mapped))
 			}
-			break
+			return mapped
 		}
 	}
+	return source
+}
+
+// resolveMapped resolves a source that mappers have already been applied to.
+func (f *Fetcher) resolveMapped(source string) (Resolver, Source, error) {
 	u, err := url.Parse(source)
 	if err != nil {
-		return nil, Source{}, fmt.Errorf("invalid source %q", source)
+		return nil, Source{}, fmt.Errorf("invalid source %q", redactSource(source))
+	}
+	if err := f.config.checkScheme(u); err != nil {
+		return nil, Source{}, err
+	}
+	archive := u.Query().Get("archive")
+	if archive != "" {
+		nu := *u
+		nu.RawQuery = removeQueryParam(u.RawQuery, "archive")
+		u = &nu
 	}
-	for _, resolver := range f.resolvers {
-		if !resolver.Match(u) {
+	resolvers, _ := f.registered()
+	for _, resolver := range resolvers {
+		if !matchSource(resolver, u, archive) {
 			continue
 		}
 		base, subdir, ok := strings.Cut(u.Path, "//")
@@ -77,46 +205,373 @@ func (f *Fetcher) Resolve(source string) (Resolver, Source, error) {
 			u = &nu
 		}
 		return resolver, Source{
-			URL:    u,
-			SubDir: subdir,
+			URL:     u,
+			SubDir:  subdir,
+			Archive: archive,
 		}, nil
 	}
-	return nil, Source{}, fmt.Errorf("unsupported source: %s", u)
+	if archive != "" {
+		return nil, Source{}, fmt.Errorf("unsupported archive type %q for source: %s", archive, RedactURL(u))
+	}
+	return nil, Source{}, fmt.Errorf("unsupported source: %s", RedactURL(u))
+}
+
+// matchSource reports whether resolver handles u, or if archive is set, the archive type it names. Archive types are
+// matched as if they were the extension of the URL path, skipping resolvers that would match without it.
+func matchSource(resolver Resolver, u *url.URL, archive string) bool {
+	if archive == "" {
+		return resolver.Match(u)
+	}
+	probe := *u
+	probe.Path = "/archive"
+	probe.RawPath = ""
+	matchesBare := resolver.Match(&probe)
+	if archive == "none" {
+		return matchesBare
+	}
+	probe.Path += "." + archive
+	return !matchesBare && resolver.Match(&probe)
+}
+
+// removeQueryParam removes a parameter from a raw query string, leaving the rest of it untouched so that eg. signed
+// URLs remain valid.
+func removeQueryParam(rawQuery, key string) string {
+	params := strings.Split(rawQuery, "&")
+	kept := params[:0]
+	for _, param := range params {
+		name, _, _ := strings.Cut(param, "=")
+		if unescaped, err := url.QueryUnescape(name); err == nil && unescaped == key {
+			continue
+		}
+		kept = append(kept, param)
+	}
+	return strings.Join(kept, "&")
+}
+
+// FetchOptions control an individual fetch.
+type FetchOptions struct {
+	// PreserveTimes applies source modification times to fetched files where the source records them.
+	PreserveTimes bool
+	// Deterministic guarantees byte-identical destination trees for identical inputs. Modification times are set to
+	// a fixed epoch (1980-01-01 UTC), entries are extracted in sorted order, permissions are normalised as with
+	// [Permissions.Normalize], ownership is not preserved, and .git metadata is omitted.
+	Deterministic bool
+	// ManifestPath, if set, is where a JSON [Manifest] of the destination is written after a successful fetch.
+	ManifestPath string
+	// DryRun resolves the source, applies policy hooks and checks the source exists via [Stater] where supported,
+	// but writes nothing. The returned [FetchResult] reports what would have been fetched.
+	DryRun bool
+	// PostFetch, if set, is run after a successful fetch, eg. to mark binaries executable or apply patches. See
+	// [PostFetchCommand].
+	//
+	// The source is fetched into a staging directory alongside dest, which is passed to PostFetch. Only if PostFetch
+	// succeeds is the fetched tree moved into dest, so a failing hook leaves dest untouched.
+	PostFetch func(ctx context.Context, dir string) error
+	// ArchivePassword decrypts password-protected zip archives, encrypted with either traditional PKWARE (ZipCrypto)
+	// or WinZip AES encryption. Without it, fetching an encrypted archive fails with [ErrArchivePassword].
+	ArchivePassword string
+	// ProvenancePath, if set, is where an in-toto provenance statement for the fetch is written after it succeeds.
+	// Its subjects are the files of the destination, and it records the source URL, its revision and version where
+	// known, the tree hash of the destination, and when the fetch started and finished. Revisions are found with
+	// [Stater] before fetching.
+	ProvenancePath string
+	// UpdateChecksums accepts changed content from a source, recording its new digest in the checksum database rather
+	// than failing. See [WithChecksumDB].
+	UpdateChecksums bool
+	// Delta updates an existing destination in place, rewriting only the files whose content, permissions or link
+	// target changed. The source is fetched into a staging directory alongside dest, whose files are compared by
+	// digest with those in dest, so re-fetching a large tree that barely changed doesn't rewrite all of it. Files in
+	// dest that are missing from the source are left in place, unless Delete is set.
+	Delta bool
+	// Delete removes files and directories from dest that are not in the source, like rsync --delete, so that
+	// updating a destination to a new version of a source doesn't leave behind files the old version had. The source
+	// is fetched into a staging directory alongside dest, and nothing is removed unless the fetch succeeds.
+	Delete bool
 }
 
 // Fetch fetches an archive from a source and unpacks it to a destination.
+//
+// Cancellation is handled as for [Fetcher.FetchWithOptions].
 func (f *Fetcher) Fetch(ctx context.Context, source, dest string) error {
-	src, u, err := f.Resolve(source)
+	_, err := f.FetchWithOptions(ctx, source, dest, FetchOptions{})
+	return err
+}
+
+// FetchWithOptions fetches an archive from a source and unpacks it to a destination, controlled by options.
+//
+// If ctx is cancelled part way through a fetch, FetchWithOptions kills any processes it started, such as git or an
+// external decompressor, removes its temporary files and returns promptly with an error wrapping the cause. Without a
+// controlling terminal, processes they started in turn, such as ssh, are killed too; in a terminal they are left in
+// the foreground process group, so that they can prompt for credentials and receive Ctrl-C. A
+// destination that was missing or empty beforehand is left as it was; one that already had content may be left
+// partially updated, unless the fetch is staged, eg. with [FetchOptions.PostFetch], [WithQuarantine] or a
+// [Transaction].
+func (f *Fetcher) FetchWithOptions(ctx context.Context, source, dest string, options FetchOptions) (result *FetchResult, err error) {
+	fetcher := f.withContextOptions(ctx)
+	if fetcher != f {
+		defer fetcher.config.client.CloseIdleConnections()
+	}
+	cfg := fetcher.config
+	cfg.options = options
+	cfg.usage = &usageCounter{}
+	if options.Deterministic {
+		cfg.permissions = Permissions{Normalize: true}
+	}
+	display := redactSource(source)
+	ctx = contextWithConfig(ctx, &cfg)
+	ctx, span := startSpan(ctx, "getit.Fetch", map[string]string{"source": display, "dest": dest})
+	defer func() { span.End(err) }()
+
+	_, resolveSpan := startSpan(ctx, "getit.Resolve", map[string]string{"source": display})
+	src, u, err := fetcher.Resolve(source)
+	resolveSpan.End(err)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if err := cfg.hooks.resolve(source, u); err != nil {
+		return nil, fmt.Errorf("fetching %s: %w", display, err)
+	}
+	cfg.resolver = resolverName(src)
+	result = &FetchResult{URL: RedactURL(u.URL), Resolver: cfg.resolver, Dest: dest, DryRun: options.DryRun}
+	if options.DryRun {
+		if result.Info, err = dryRun(ctx, src, u); err != nil {
+			return nil, fmt.Errorf("fetching %s: %w", display, err)
+		}
+		result.Version = cfg.version
+		cfg.logger.InfoContext(ctx, "dry run", "source", display, "dest", dest, "size", result.Info.Size, "revision", result.Info.Revision)
+		return result, nil
+	}
+	labels := MetricLabels{Resolver: cfg.resolver, Host: u.URL.Host}
+	cfg.metrics.FetchStarted(labels)
+	start := time.Now()
+	defer func() { cfg.metrics.FetchFinished(labels, time.Since(start), err) }()
+
+	logger := cfg.logger
+	logger.InfoContext(ctx, "fetch", "source", display, "dest", dest)
+	size := int64(-1)
+	if options.ProvenancePath != "" || cfg.audit != nil || cfg.diskSpace != nil {
+		// Record the revision being fetched, and its size to check there is space for it.
+		if stater, ok := src.(Stater); ok {
+			var statErr error
+			if result.Info, statErr = stater.Stat(ctx, u); statErr != nil {
+				logger.WarnContext(ctx, "stat failed", "source", display, "error", statErr)
+			} else {
+				size = result.Info.Size
+			}
+		}
+	}
+	if cfg.diskSpace != nil {
+		err = cfg.diskSpace.check(ctx, src, u, dest, size)
 	}
-	if err := src.Fetch(ctx, u, dest); err != nil {
-		return fmt.Errorf("fetching %s: %w", source, err)
+	if err == nil {
+		_, statErr := os.Stat(dest)
+		missing, clean := errors.Is(statErr, os.ErrNotExist), isEmptyOrMissing(dest)
+		err = fetchResolved(ctx, src, u, dest)
+		if err != nil && clean && ctx.Err() != nil {
+			if cleanupErr := removePartial(dest, missing); cleanupErr != nil {
+				err = errors.Join(err, cleanupErr)
+			}
+		}
+	}
+	result.Version = cfg.version
+	result.Usage = cfg.usage.usage()
+	if err == nil && options.ProvenancePath != "" {
+		err = writeProvenance(ctx, options.ProvenancePath, display, result, start, time.Now())
+	}
+	if cfg.audit != nil {
+		cfg.audit.record(ctx, display, result, start, err)
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "fetch failed", "source", display, "error", err)
+		err = fmt.Errorf("fetching %s: %w", display, err)
+		cfg.hooks.error(display, err)
+		return nil, err
+	}
+	logger.InfoContext(ctx, "fetched", "source", display, "dest", dest)
+	cfg.hooks.complete(display, dest)
+	return result, nil
+}
+
+// dryRun checks a source exists without fetching it.
+func dryRun(ctx context.Context, resolver Resolver, source Source) (SourceInfo, error) {
+	stater, ok := resolver.(Stater)
+	if !ok {
+		return SourceInfo{Size: -1}, nil
+	}
+	info, err := stater.Stat(ctx, source)
+	if err != nil {
+		return info, fmt.Errorf("stat: %w", err)
+	}
+	return info, nil
+}
+
+// fetchResolved fetches a resolved source, then runs any post-fetch steps requested by the fetch options.
+func fetchResolved(ctx context.Context, resolver Resolver, source Source, dest string) error {
+	cfg := configFromContext(ctx)
+	options := cfg.options
+	// Fetch into a staging directory if the fetched tree may yet be rejected, or must be told apart from existing
+	// files in dest, eg. so that only fetched files are moved into the store or normalised.
+	staged := options.PostFetch != nil || cfg.checksums != nil || cfg.quarantine != nil || cfg.store != "" ||
+		options.Delta || options.Delete || options.Deterministic
+	target := dest
+	if staged {
+		var staging string
+		var err error
+		if cfg.quarantine != nil {
+			staging, err = cfg.quarantine.newStaging(dest)
+		} else {
+			staging, err = newStaging(dest)
+		}
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(staging)
+		target = staging
+	}
+	if err := resolver.Fetch(ctx, source, target); err != nil {
+		return err //nolint:wrapcheck // wrapped by the caller
+	}
+	if cfg.checksums != nil {
+		if err := cfg.checksums.verify(ctx, source, target, options.UpdateChecksums); err != nil {
+			return err
+		}
+	}
+	if cfg.quarantine != nil {
+		if err := cfg.quarantine.check(ctx, target, source); err != nil {
+			return err
+		}
+	}
+	if options.PostFetch != nil {
+		if err := options.PostFetch(ctx, target); err != nil {
+			return fmt.Errorf("post-fetch hook: %w", err)
+		}
+	}
+	if cfg.store != "" {
+		if err := storeTree(ctx, cfg.store, target, source.URL.Host); err != nil {
+			return err
+		}
+	}
+	if options.Deterministic {
+		// Done last, as each of the steps above may write to directories.
+		if err := normalizeDirs(ctx, target); err != nil {
+			return err
+		}
+	}
+	apply := promote
+	switch {
+	case options.Delta:
+		apply = func(ctx context.Context, staging, dest string) error {
+			return syncTree(ctx, staging, dest, options.Delete)
+		}
+	case options.Delete:
+		apply = promoteDelete
+	}
+	if cfg.quarantine != nil {
+		if err := cfg.quarantine.promote(ctx, target, dest, apply); err != nil {
+			return err
+		}
+	} else if staged {
+		if err := apply(ctx, target, dest); err != nil {
+			return err
+		}
+	}
+	if options.ManifestPath != "" {
+		manifest, err := BuildManifest(ctx, dest)
+		if err != nil {
+			return err
+		}
+		if err := manifest.WriteFile(options.ManifestPath); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
+// FetchAny fetches the first source that succeeds from an ordered list of sources and unpacks it to a destination.
+//
+// This is intended for mirrors: each source is tried in turn until one succeeds. If the destination did not exist
+// or was empty before the fetch, any partial content left behind by a failed attempt is removed before the next
+// source is tried.
+func (f *Fetcher) FetchAny(ctx context.Context, sources []string, dest string) error {
+	if len(sources) == 0 {
+		return errors.New("no sources to fetch")
+	}
+	clean := isEmptyOrMissing(dest)
+	errs := make([]error, 0, len(sources))
+	for _, source := range sources {
+		err := f.Fetch(ctx, source, dest)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, err)
+		f.config.logger.WarnContext(ctx, "retry", "failed_source", redactSource(source), "error", err)
+		if clean {
+			if err := resetDest(dest); err != nil {
+				return errors.Join(append(errs, err)...)
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // FetchIntoPipe retrieves the given URL using Go's HTTP library then pipes it into the input of the given command.
 func FetchIntoPipe(ctx context.Context, u *url.URL, cmd string, args ...string) error {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
-	if err != nil {
-		return fmt.Errorf("creating request: %w", err)
-	}
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := httpGet(ctx, u)
 	if err != nil {
-		return fmt.Errorf("fetching %s: %w", u, err)
+		return err
 	}
 	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("fetching %s: %s", u, resp.Status)
-	}
 
+	configFromContext(ctx).logger.DebugContext(ctx, "extract", "url", RedactURL(u), "cmd", cmd, "args", args)
+	ctx, span := startSpan(ctx, "getit.extract", map[string]string{"url": RedactURL(u), "cmd": cmd})
 	stderr := &bytes.Buffer{}
-	c := exec.CommandContext(ctx, cmd, args...)
-	c.Stdin = resp.Body
+	c := command(ctx, cmd, args...)
+	c.Stdin = newCountingReader(ctx, resp.Body, u.Host)
 	c.Stderr = stderr
 	if err := c.Run(); err != nil {
-		return fmt.Errorf("%s failed: %w: %s", cmd, err, stderr.String())
+		err = fmt.Errorf("%s failed: %w: %s", cmd, err, stderr.String())
+		span.End(err)
+		return err
+	}
+	span.End(nil)
+	return nil
+}
+
+func isEmptyOrMissing(dir string) bool {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return true
+	}
+	return err == nil && len(entries) == 0
+}
+
+// resetDest removes everything inside dir, leaving dir itself in place if it exists.
+func resetDest(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("reading %s: %w", dir, err)
+	}
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+			return fmt.Errorf("removing partial content: %w", err)
+		}
+	}
+	return nil
+}
+
+// removePartial removes the content left in dest by a cancelled fetch, including dest itself if it was missing
+// beforehand.
+func removePartial(dest string, missing bool) error {
+	if !missing {
+		return resetDest(dest)
+	}
+	if err := os.RemoveAll(dest); err != nil {
+		return fmt.Errorf("removing partial content: %w", err)
 	}
 	return nil
 }
@@ -1,11 +1,18 @@
 package getit_test
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/alecthomas/assert/v2"
 
@@ -119,3 +126,246 @@ func TestFetchIntoPipeInvalidURL(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "fetching")
 }
+
+func TestFetchAny(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "archive.tar.gz"))
+	assert.NoError(t, err)
+
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(data)
+	}))
+	defer mirror.Close()
+
+	fetcher := getit.New([]getit.Resolver{getit.NewTAR()}, nil)
+	dest := t.TempDir()
+	err = fetcher.FetchAny(context.Background(), []string{
+		down.URL + "/archive.tar.gz",
+		mirror.URL + "/archive.tar.gz",
+	}, dest)
+	assert.NoError(t, err)
+
+	content, err := os.ReadFile(filepath.Join(dest, "file.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "hello from test\n", string(content))
+}
+
+func TestFetchAnyAllFail(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+
+	fetcher := getit.New([]getit.Resolver{getit.NewTAR()}, nil)
+	err := fetcher.FetchAny(context.Background(), []string{
+		down.URL + "/primary.tar.gz",
+		down.URL + "/mirror.tar.gz",
+	}, t.TempDir())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "primary.tar.gz")
+	assert.Contains(t, err.Error(), "mirror.tar.gz")
+}
+
+func TestWithLogger(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "archive.tar.gz"))
+	assert.NoError(t, err)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+
+	logs := &bytes.Buffer{}
+	logger := slog.New(slog.NewJSONHandler(logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	fetcher := getit.New([]getit.Resolver{getit.NewTAR()}, nil, getit.WithLogger(logger),
+		getit.WithChecksumDB(filepath.Join(t.TempDir(), "getit.sum")))
+	err = fetcher.Fetch(context.Background(), server.URL+"/archive.tar.gz", t.TempDir())
+	assert.NoError(t, err)
+
+	for _, event := range []string{"resolve", "fetch", "request", "response", "extract", "verify", "fetched"} {
+		assert.Contains(t, logs.String(), `"msg":"`+event+`"`)
+	}
+}
+
+func TestResolveArchiveOverride(t *testing.T) {
+	fetcher := getit.New([]getit.Resolver{getit.NewTAR(), getit.NewZIP(), getit.NewHTTP()}, nil)
+	tests := []struct {
+		name     string
+		source   string
+		resolver string
+		query    string
+		archive  string
+		err      string
+	}{
+		{name: "TarGz", source: "https://cdn.example.com/a1b2?sig=a%2Bb&archive=tar.gz&expires=1", resolver: "*getit.TAR", query: "sig=a%2Bb&expires=1", archive: "tar.gz"},
+		{name: "Zip", source: "https://cdn.example.com/a1b2?archive=zip", resolver: "*getit.ZIP", archive: "zip"},
+		{name: "OverridesExtension", source: "https://cdn.example.com/file.zip?archive=none", resolver: "*getit.HTTP", archive: "none"},
+		{name: "NoOverride", source: "https://cdn.example.com/a1b2?sig=abc", resolver: "*getit.HTTP", query: "sig=abc"},
+		{name: "Unsupported", source: "https://cdn.example.com/a1b2?archive=rar", err: `unsupported archive type "rar"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver, source, err := fetcher.Resolve(tt.source)
+			if tt.err != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.resolver, fmt.Sprintf("%T", resolver))
+			assert.Equal(t, tt.query, source.URL.RawQuery)
+			assert.Equal(t, tt.archive, source.Archive)
+		})
+	}
+}
+
+func TestFetchArchiveOverride(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "archive.tar.bz2"))
+	assert.NoError(t, err)
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+
+	fetcher := getit.New([]getit.Resolver{getit.NewTAR(), getit.NewHTTP()}, nil)
+	dest := t.TempDir()
+	err = fetcher.Fetch(context.Background(), server.URL+"/download/12345?token=abc&archive=tar.bz2", dest)
+	assert.NoError(t, err)
+	assert.Equal(t, "token=abc", query)
+	content, err := os.ReadFile(filepath.Join(dest, "file.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "hello from test\n", string(content))
+}
+
+func TestDetect(t *testing.T) {
+	fetcher := getit.New(
+		[]getit.Resolver{getit.NewFile(), getit.NewGit(), getit.NewTAR(), getit.NewZIP()},
+		[]getit.Mapper{getit.GitHub, getit.GitHubOrgRepo, getit.FilePath},
+	)
+	tests := []struct {
+		source   string
+		resolver string
+	}{
+		{source: "user/repo?ref=main", resolver: "Git"},
+		{source: "github.com/user/repo", resolver: "Git"},
+		{source: "https://example.com/archive.tar.gz//subdir", resolver: "TAR"},
+		{source: "https://cdn.example.com/a1b2?archive=zip", resolver: "ZIP"},
+		{source: "/does/not/exist", resolver: "File"},
+		{source: "./not/yet/created", resolver: "File"},
+		{source: "~/later", resolver: "File"},
+		{source: "https://example.com/page.html"},
+		{source: "ftp://example.com/%zz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			resolver, ok := fetcher.Detect(tt.source)
+			assert.Equal(t, tt.resolver != "", ok)
+			assert.Equal(t, tt.resolver, resolver)
+		})
+	}
+}
+
+func TestFetchCancelled(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		// data is the archive served, of which only the first half is sent before the server stalls.
+		data string
+	}{
+		{name: "TAR", source: "/archive.tar", data: "archive.tar"},
+		{name: "Decompressor", source: "/archive.tar.Z", data: "archive.tar.Z"},
+		{name: "ZIP", source: "/archive.zip", data: "archive.zip"},
+		{name: "HTTP", source: "/file.bin", data: "archive.tar"},
+		{name: "Git", source: "/repo.git"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var data []byte
+			if tt.data != "" {
+				var err error
+				data, err = os.ReadFile(filepath.Join("testdata", tt.data))
+				assert.NoError(t, err)
+			}
+			started := make(chan struct{}, 1)
+			disconnected := make(chan struct{}, 1)
+			newServer := httptest.NewServer
+			if data == nil {
+				newServer = httptest.NewTLSServer
+				t.Setenv("GIT_SSL_NO_VERIFY", "1")
+			}
+			server := newServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if data != nil {
+					w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+					_, _ = w.Write(data[:len(data)/2])
+					w.(http.Flusher).Flush() //nolint:forcetypeassert
+				}
+				started <- struct{}{}
+				select {
+				case <-r.Context().Done():
+					disconnected <- struct{}{}
+				case <-time.After(10 * time.Second):
+				}
+			}))
+			defer server.Close()
+
+			source := server.URL + tt.source
+			if tt.data == "" {
+				source = "git+" + source
+			}
+			tmp := t.TempDir()
+			fetcher := getit.New([]getit.Resolver{getit.NewGit(), getit.NewTAR(), getit.NewZIP(), getit.NewHTTP()}, nil,
+				getit.WithTempDir(tmp))
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go func() {
+				<-started
+				cancel()
+			}()
+			dest := filepath.Join(t.TempDir(), "dest")
+			start := time.Now()
+			err := fetcher.Fetch(ctx, source, dest)
+			assert.True(t, errors.Is(err, context.Canceled), "%v", err)
+			assert.True(t, time.Since(start) < 5*time.Second, "fetch should return promptly")
+
+			select {
+			case <-disconnected:
+			case <-time.After(5 * time.Second):
+				t.Fatal("request should be abandoned")
+			}
+			_, err = os.Stat(dest)
+			assert.True(t, os.IsNotExist(err), "partial destination should be removed")
+			entries, err := os.ReadDir(tmp)
+			assert.NoError(t, err)
+			assert.Equal(t, 0, len(entries), "temporary files should be removed")
+		})
+	}
+}
+
+func TestFetchCancelledKeepsExistingDest(t *testing.T) {
+	started := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	dest := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dest, "keep.txt"), []byte("keep"), 0600))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-started
+		cancel()
+	}()
+	err := getit.New([]getit.Resolver{getit.NewTAR()}, nil).Fetch(ctx, server.URL+"/archive.tar", dest)
+	assert.True(t, errors.Is(err, context.Canceled), "%v", err)
+	content, err := os.ReadFile(filepath.Join(dest, "keep.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "keep", string(content))
+}
@@ -0,0 +1,176 @@
+package getit
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// archiveIndexMaxTail bounds the size of the zip central directories kept by an archive index cache.
+const archiveIndexMaxTail = 16 << 20
+
+// WithArchiveIndexCache keeps the indexes of up to size remote archives in memory, so that extracting subdirectories
+// of an archive repeatedly, eg. a different one each time, doesn't re-read the archive to find their entries.
+//
+// Indexes are kept for zip archives read with range requests, whose central directory is cached, and for zstd
+// tarballs in the seekable format, whose tar entries are cached so that only the frames holding the entries of a
+// subdirectory are downloaded and decompressed. Archives are identified by their URL together with their size and
+// strong ETag or Last-Modified time, so indexes of archives that have changed are not used, and archives served
+// without either are not indexed.
+func WithArchiveIndexCache(size int) Option {
+	return func(f *Fetcher) {
+		f.config.archiveIndexes = &archiveIndexCache{size: size, indexes: map[string]*archiveIndex{}}
+	}
+}
+
+// archiveIndexCache holds the most recently used archive indexes. A nil cache holds nothing.
+type archiveIndexCache struct {
+	size int
+
+	lock    sync.Mutex
+	indexes map[string]*archiveIndex
+	// recent lists cached keys, most recently used last.
+	recent []string
+}
+
+// archiveIndex locates the entries of an archive.
+type archiveIndex struct {
+	// tail holds the end of a zip archive, starting at tailStart, covering its central directory.
+	tail      []byte
+	tailStart int64
+	// entries of a tarball, in order.
+	entries []tarIndexEntry
+}
+
+// tarIndexEntry locates the data of a tar entry in the decompressed stream of a tarball.
+type tarIndexEntry struct {
+	header *tar.Header
+	offset int64
+}
+
+// key identifies the remote archive read by ra, or is empty if the archive can't be identified reliably.
+func (c *archiveIndexCache) key(u *url.URL, ra *rangeReader) string {
+	if c == nil || ra.ifRange == "" {
+		return ""
+	}
+	return u.String() + "\x00" + ra.ifRange + "\x00" + strconv.FormatInt(ra.size, 10)
+}
+
+func (c *archiveIndexCache) get(key string) (*archiveIndex, bool) {
+	if key == "" {
+		return nil, false
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	index, ok := c.indexes[key]
+	if ok {
+		c.recent = append(slices.DeleteFunc(c.recent, func(k string) bool { return k == key }), key)
+	}
+	return index, ok
+}
+
+func (c *archiveIndexCache) put(key string, index *archiveIndex) {
+	if key == "" || c.size <= 0 {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if _, ok := c.indexes[key]; !ok && len(c.recent) >= c.size {
+		delete(c.indexes, c.recent[0])
+		c.recent = c.recent[1:]
+	}
+	c.indexes[key] = index
+	c.recent = append(slices.DeleteFunc(c.recent, func(k string) bool { return k == key }), key)
+}
+
+// lowestReaderAt records the lowest offset read from an [io.ReaderAt].
+type lowestReaderAt struct {
+	r      io.ReaderAt
+	lowest int64
+}
+
+func (l *lowestReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	l.lowest = min(l.lowest, off)
+	return l.r.ReadAt(p, off) //nolint:wrapcheck // io.ReaderAt errors such as io.EOF must not be wrapped
+}
+
+// tarIndexer indexes the entries of a tar stream read through it, as reported to add.
+type tarIndexer struct {
+	r       io.ReadSeeker
+	pos     int64
+	entries []tarIndexEntry
+	// complete is false once an entry that can't be indexed has been read.
+	complete bool
+}
+
+var _ io.ReadSeeker = (*tarIndexer)(nil)
+
+func newTarIndexer(r io.ReadSeeker) *tarIndexer { return &tarIndexer{r: r, complete: true} }
+
+func (t *tarIndexer) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	t.pos += int64(n)
+	return n, err //nolint:wrapcheck // io.Reader errors such as io.EOF must not be wrapped
+}
+
+func (t *tarIndexer) Seek(offset int64, whence int) (int64, error) {
+	pos, err := t.r.Seek(offset, whence)
+	if err == nil {
+		t.pos = pos
+	}
+	return pos, err //nolint:wrapcheck // passed through to archive/tar
+}
+
+// add records an entry whose header has just been read, so its data starts at the current position.
+func (t *tarIndexer) add(hdr *tar.Header) {
+	if isSparse(hdr) {
+		// The data of sparse entries is not stored contiguously.
+		t.complete = false
+		return
+	}
+	header := *hdr
+	if tarHeaderOnly(header.Typeflag) {
+		header.Size = 0
+	}
+	t.entries = append(t.entries, tarIndexEntry{header: &header, offset: t.pos})
+}
+
+// tarIndexStream returns a tar stream holding the entries of an indexed tarball beneath subdir, along with any global
+// headers, reading their data from r.
+func tarIndexStream(entries []tarIndexEntry, r io.ReaderAt, subdir string) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		tw := tar.NewWriter(pw)
+		for _, entry := range entries {
+			if _, ok := withinSubDir(entry.header.Name, subdir); subdir != "" && !ok && entry.header.Typeflag != tar.TypeXGlobalHeader {
+				continue
+			}
+			if err := tw.WriteHeader(entry.header); err != nil {
+				pw.CloseWithError(fmt.Errorf("writing indexed tar entry %s: %w", entry.header.Name, err))
+				return
+			}
+			if tarHeaderOnly(entry.header.Typeflag) {
+				continue
+			}
+			if _, err := io.Copy(tw, io.NewSectionReader(r, entry.offset, entry.header.Size)); err != nil {
+				pw.CloseWithError(fmt.Errorf("reading indexed tar entry %s: %w", entry.header.Name, err))
+				return
+			}
+		}
+		pw.CloseWithError(tw.Close())
+	}()
+	return pr
+}
+
+// tarHeaderOnly reports whether entries of a tar type have no data, whatever their recorded size.
+func tarHeaderOnly(typeflag byte) bool {
+	switch typeflag {
+	case tar.TypeLink, tar.TypeSymlink, tar.TypeChar, tar.TypeBlock, tar.TypeDir, tar.TypeFifo, tar.TypeXGlobalHeader:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,149 @@
+package getit //nolint:testpackage
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+// archiveServer serves data with range requests and a strong ETag, counting the requests made and bytes served.
+func archiveServer(t *testing.T, data []byte) (*httptest.Server, *atomic.Int64, *atomic.Int64) {
+	t.Helper()
+	var requests, served atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(&countingResponseWriter{ResponseWriter: w, n: &served}, r, "archive", time.Time{}, bytes.NewReader(data))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests, &served
+}
+
+func TestArchiveIndexCacheTAR(t *testing.T) {
+	if _, err := exec.LookPath("zstd"); err != nil {
+		t.Skip("zstd not installed")
+	}
+	// Entries smaller than a frame, so that finding the entries of a subdirectory without an index reads every frame.
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	content := make([]byte, 128<<10)
+	for dir := range 8 {
+		for file := range 8 {
+			_, _ = rand.Read(content)
+			name := fmt.Sprintf("d%d/f%d.bin", dir, file)
+			assert.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len(content))}))
+			_, err := tw.Write(content)
+			assert.NoError(t, err)
+		}
+	}
+	assert.NoError(t, tw.Close())
+	archive := seekableZstdArchive(t, buf.Bytes(), 256<<10)
+	server, _, served := archiveServer(t, archive)
+	source := server.URL + "/archive.tar.zst//"
+
+	fetch := func(fetcher *Fetcher, subdir string) int64 {
+		t.Helper()
+		served.Store(0)
+		dest := t.TempDir()
+		assert.NoError(t, fetcher.Fetch(context.Background(), source+subdir, dest))
+		entries, err := os.ReadDir(dest)
+		assert.NoError(t, err)
+		assert.Equal(t, 8, len(entries))
+		return served.Load()
+	}
+	total := int64(len(archive))
+
+	fetcher := New([]Resolver{NewTAR()}, nil, WithArchiveIndexCache(1))
+	assert.True(t, fetch(fetcher, "d0") > total*3/4, "without an index every frame is read")
+	assert.True(t, fetch(fetcher, "d5") < total/2, "with an index only the frames of the subdirectory are read")
+	assert.True(t, fetch(fetcher, "d2") < total/2, "with an index only the frames of the subdirectory are read")
+
+	uncached := New([]Resolver{NewTAR()}, nil)
+	assert.True(t, fetch(uncached, "d0") > total*3/4)
+	assert.True(t, fetch(uncached, "d5") > total*3/4)
+}
+
+func TestArchiveIndexCacheZIP(t *testing.T) {
+	// A central directory larger than the initial request for the end of the archive, following a large entry.
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: "big/data.bin", Method: zip.Store})
+	assert.NoError(t, err)
+	big := make([]byte, 3_000_000)
+	_, _ = rand.Read(big)
+	_, err = w.Write(big)
+	assert.NoError(t, err)
+	for i := range 3000 {
+		w, err := zw.Create(fmt.Sprintf("small/a-file-with-a-rather-long-name-%04d.txt", i))
+		assert.NoError(t, err)
+		_, err = w.Write([]byte("x"))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, zw.Close())
+	server, requests, _ := archiveServer(t, buf.Bytes())
+
+	fetch := func(fetcher *Fetcher) int64 {
+		t.Helper()
+		requests.Store(0)
+		dest := t.TempDir()
+		assert.NoError(t, fetcher.Fetch(context.Background(), server.URL+"/archive.zip//big", dest))
+		content, err := os.ReadFile(filepath.Join(dest, "data.bin"))
+		assert.NoError(t, err)
+		assert.True(t, bytes.Equal(big, content))
+		return requests.Load()
+	}
+	metrics := &cacheMetrics{}
+	fetcher := New([]Resolver{NewZIP()}, nil, WithArchiveIndexCache(1), WithMetrics(metrics))
+	first := fetch(fetcher)
+	second := fetch(fetcher)
+	assert.True(t, second < first, "the cached central directory is not re-read: %d then %d requests", first, second)
+	assert.Equal(t, []bool{false, true}, metrics.hits["archive-index"])
+}
+
+// cacheMetrics records cache lookups.
+type cacheMetrics struct {
+	noopMetrics
+	lock sync.Mutex
+	hits map[string][]bool
+}
+
+func (c *cacheMetrics) CacheLookup(_ MetricLabels, cache string, hit bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.hits == nil {
+		c.hits = map[string][]bool{}
+	}
+	c.hits[cache] = append(c.hits[cache], hit)
+}
+
+func TestArchiveIndexCacheEviction(t *testing.T) {
+	cache := &archiveIndexCache{size: 2, indexes: map[string]*archiveIndex{}}
+	for _, key := range []string{"a", "b", "a", "c"} {
+		cache.put(key, &archiveIndex{})
+	}
+	_, ok := cache.get("b")
+	assert.False(t, ok, "least recently used")
+	_, ok = cache.get("a")
+	assert.True(t, ok)
+	_, ok = cache.get("c")
+	assert.True(t, ok)
+
+	var disabled *archiveIndexCache
+	assert.Equal(t, "", disabled.key(nil, nil))
+	_, ok = disabled.get("")
+	assert.False(t, ok)
+}
@@ -0,0 +1,139 @@
+package getit
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// The Artifact [Resolver] fetches the artifacts of CI runs from GitHub Actions and GitLab CI/CD using their APIs,
+// unzipping them into the destination.
+//
+// The URL formats supported are:
+//
+//	gh-artifact://owner/repo/<run-id>/<artifact-name>
+//	gl-artifact://group/project/<job-id>
+//	gl-artifact://group/project/<job-name>?ref=<ref>
+//
+// The last GitLab form fetches the artifacts of the latest successful pipeline for ref. Both APIs require
+// authentication to download artifacts, eg. with [WithTokenProvider] for "api.github.com" or "gitlab.com".
+type Artifact struct{}
+
+var (
+	_ Resolver           = (*Artifact)(nil)
+	_ CapabilityReporter = (*Artifact)(nil)
+)
+
+func NewArtifact() *Artifact { return &Artifact{} }
+
+func (a *Artifact) Match(source *url.URL) bool {
+	return source.Scheme == "gh-artifact" || source.Scheme == "gl-artifact"
+}
+
+func (a *Artifact) Capabilities() Capabilities {
+	return Capabilities{Refs: true}
+}
+
+// Fetch downloads the artifact archive to a temporary file and extracts it into dest.
+func (a *Artifact) Fetch(ctx context.Context, source Source, dest string) error {
+	var u *url.URL
+	var err error
+	if source.URL.Scheme == "gh-artifact" {
+		u, err = gitHubArtifactURL(ctx, source.URL)
+	} else {
+		u, err = gitLabArtifactURL(source.URL)
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dest, 0750); err != nil {
+		return fmt.Errorf("creating destination directory: %w", err)
+	}
+	resp, err := httpGet(ctx, u)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	tmp, err := downloadZip(ctx, newCountingReader(ctx, resp.Body, u.Host))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	cfg := configFromContext(ctx)
+	cfg.logger.DebugContext(ctx, "extract", "url", RedactURL(source.URL), "dest", dest)
+	ctx, span := startSpan(ctx, "getit.extract", map[string]string{"url": RedactURL(source.URL)})
+	ctx, cancel := withPhaseTimeout(ctx, "extract", cfg.timeouts.Extract)
+	defer cancel()
+	err = extractZipFile(ctx, u, tmp, dest, source.SubDir, 1)
+	span.End(err)
+	return err
+}
+
+// gitHubArtifactURL finds the download URL of a gh-artifact:// source by listing the artifacts of its run.
+func gitHubArtifactURL(ctx context.Context, source *url.URL) (*url.URL, error) {
+	parts := strings.Split(strings.Trim(source.Path, "/"), "/")
+	if source.Host == "" || len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+		return nil, fmt.Errorf("invalid GitHub artifact %s: expected gh-artifact://owner/repo/<run-id>/<artifact-name>", RedactURL(source))
+	}
+	repo, run, name := source.Host+"/"+parts[0], parts[1], parts[2]
+	if _, err := strconv.ParseUint(run, 10, 64); err != nil {
+		return nil, fmt.Errorf("invalid GitHub artifact %s: run ID %q is not a number", RedactURL(source), run)
+	}
+	forge := forges["github.com"]
+	var list struct {
+		Artifacts []struct {
+			Name        string `json:"name"`
+			Expired     bool   `json:"expired"`
+			DownloadURL string `json:"archive_download_url"`
+		} `json:"artifacts"`
+	}
+	found, err := forge.get(ctx, forge.api+"/repos/"+repo+"/actions/runs/"+run+"/artifacts?name="+url.QueryEscape(name), &list)
+	if err != nil {
+		return nil, err
+	} else if !found {
+		return nil, fmt.Errorf("run %s of %s not found", run, repo)
+	}
+	for _, artifact := range list.Artifacts {
+		if artifact.Name != name {
+			continue
+		}
+		if artifact.Expired {
+			return nil, fmt.Errorf("artifact %q of run %s of %s has expired", name, run, repo)
+		}
+		u, err := url.Parse(artifact.DownloadURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid download URL for artifact %q: %w", name, err)
+		}
+		return u, nil
+	}
+	return nil, fmt.Errorf("artifact %q not found in run %s of %s", name, run, repo)
+}
+
+// gitLabArtifactURL returns the download URL of a gl-artifact:// source.
+func gitLabArtifactURL(source *url.URL) (*url.URL, error) {
+	path := strings.Trim(source.Path, "/")
+	i := strings.LastIndex(path, "/")
+	if source.Host == "" || i <= 0 || i == len(path)-1 {
+		return nil, fmt.Errorf("invalid GitLab artifact %s: expected gl-artifact://group/project/<job-id>", RedactURL(source))
+	}
+	project, job := source.Host+"/"+path[:i], path[i+1:]
+	forge := forges["gitlab.com"]
+	endpoint := forge.api + "/projects/" + url.PathEscape(project)
+	if _, err := strconv.ParseUint(job, 10, 64); err == nil {
+		endpoint += "/jobs/" + job + "/artifacts"
+	} else if ref := source.Query().Get("ref"); ref != "" {
+		endpoint += "/jobs/artifacts/" + url.PathEscape(ref) + "/download?job=" + url.QueryEscape(job)
+	} else {
+		return nil, fmt.Errorf("invalid GitLab artifact %s: job name %q requires a ref query parameter", RedactURL(source), job)
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid gitlab API URL: %w", err)
+	}
+	return u, nil
+}
@@ -0,0 +1,93 @@
+package getit //nolint:testpackage
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestArtifactFetch(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "archive.zip"))
+	assert.NoError(t, err)
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/blob/1" {
+			// Signed storage URLs are not authenticated.
+			_, _ = w.Write(data)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.RequestURI() {
+		case "/api/v3/repos/owner/repo/actions/runs/42/artifacts?name=dist":
+			_, _ = w.Write([]byte(`{"artifacts": [{"name": "dist", "archive_download_url": "` + server.URL + `/api/v3/artifacts/1/zip"}]}`))
+		case "/api/v3/repos/owner/repo/actions/runs/42/artifacts?name=old":
+			_, _ = w.Write([]byte(`{"artifacts": [{"name": "old", "expired": true}]}`))
+		case "/api/v3/repos/owner/repo/actions/runs/42/artifacts?name=missing":
+			_, _ = w.Write([]byte(`{"artifacts": []}`))
+		case "/api/v3/artifacts/1/zip":
+			http.Redirect(w, r, "/blob/1", http.StatusFound)
+		case "/api/v4/projects/group%2Fsub%2Fproject/jobs/7/artifacts",
+			"/api/v4/projects/group%2Fproject/jobs/artifacts/release%2F1.0/download?job=build+linux":
+			_, _ = w.Write(data)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	saved := forges
+	t.Cleanup(func() { forges = saved })
+	forges = map[string]forge{
+		"github.com": {kind: "github", api: server.URL + "/api/v3"},
+		"gitlab.com": {kind: "gitlab", api: server.URL + "/api/v4"},
+	}
+	host := strings.TrimPrefix(server.URL, "http://")
+	token := WithTokenProvider(host, TokenProviderFunc(func(context.Context) (string, error) { return "secret", nil }))
+
+	tests := []struct {
+		name    string
+		source  string
+		options []Option
+		err     string
+	}{
+		{name: "GitHub", source: "gh-artifact://owner/repo/42/dist", options: []Option{token}},
+		{name: "GitHubUnauthenticated", source: "gh-artifact://owner/repo/42/dist", err: "401 Unauthorized"},
+		{name: "GitHubExpired", source: "gh-artifact://owner/repo/42/old", options: []Option{token}, err: `artifact "old" of run 42 of owner/repo has expired`},
+		{name: "GitHubMissing", source: "gh-artifact://owner/repo/42/missing", options: []Option{token}, err: `artifact "missing" not found in run 42 of owner/repo`},
+		{name: "GitHubMissingRun", source: "gh-artifact://owner/repo/43/dist", options: []Option{token}, err: "run 43 of owner/repo not found"},
+		{name: "GitHubInvalidRun", source: "gh-artifact://owner/repo/latest/dist", err: `run ID "latest" is not a number`},
+		{name: "GitHubInvalid", source: "gh-artifact://owner/repo/42", err: "expected gh-artifact://owner/repo/<run-id>/<artifact-name>"},
+		{name: "GitLabJobID", source: "gl-artifact://group/sub/project/7", options: []Option{token}},
+		{name: "GitLabJobName", source: "gl-artifact://group/project/build%20linux?ref=release/1.0", options: []Option{token}},
+		{name: "GitLabJobNameWithoutRef", source: "gl-artifact://group/project/build", err: `job name "build" requires a ref query parameter`},
+		{name: "GitLabInvalid", source: "gl-artifact://project/", err: "expected gl-artifact://group/project/<job-id>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmp := t.TempDir()
+			fetcher := New([]Resolver{NewArtifact()}, nil, append(tt.options, WithTempDir(tmp))...)
+			dest := t.TempDir()
+			err := fetcher.Fetch(context.Background(), tt.source, dest)
+			if tt.err != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			content, err := os.ReadFile(filepath.Join(dest, "file.txt"))
+			assert.NoError(t, err)
+			assert.NotZero(t, len(content))
+			entries, err := os.ReadDir(tmp)
+			assert.NoError(t, err)
+			assert.Equal(t, 0, len(entries), "the downloaded archive should be removed")
+		})
+	}
+}
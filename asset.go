@@ -0,0 +1,90 @@
+package getit
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// Names used for each GOOS and GOARCH in release asset names, besides the Go name itself.
+var (
+	assetOSAliases = map[string][]string{
+		"darwin":  {"macos", "mac", "osx", "apple"},
+		"windows": {"win", "win32", "win64"},
+	}
+	assetArchAliases = map[string][]string{
+		"amd64": {"x86_64", "x64"},
+		"arm64": {"aarch64", "armv8"},
+		"386":   {"i386", "i686", "x86", "32bit"},
+		"arm":   {"armv7", "armv6", "armhf", "armel"},
+		// Listed so that assets for them aren't mistaken for assets naming no architecture.
+		"ppc64le": nil,
+		"riscv64": nil,
+		"s390x":   nil,
+		"loong64": nil,
+	}
+	// assetUniversal marks assets that run on any architecture of their OS, eg. macOS universal binaries.
+	assetUniversal = []string{"universal", "all"}
+	// assetSkipExts are the extensions of assets that are not the release itself, or are packages getit can't unpack.
+	assetSkipExts = []string{
+		".sha256", ".sha256sum", ".sha512", ".sha512sum", ".md5", ".asc", ".sig", ".pem", ".sbom", ".json", ".txt",
+		".deb", ".rpm", ".apk", ".msi", ".pkg", ".dmg",
+	}
+)
+
+// SelectAsset picks the asset for platform from the names of a release's assets, eg. "tool_Darwin_x86_64.tar.gz"
+// for darwin/amd64. Names are matched case-insensitively against the common variants of each OS and architecture,
+// such as "macos" and "osx" for darwin or "x86_64" and "x64" for amd64. Checksums, signatures and OS packages are
+// ignored.
+//
+// An asset naming the architecture is preferred over a universal asset, or one naming no architecture at all. If
+// several assets match equally well the first is picked, and if none match an error listing the assets is returned.
+func SelectAsset(names []string, platform Platform) (string, error) {
+	best, bestScore := "", 0
+	for _, name := range names {
+		if score := assetScore(name, platform); score > bestScore {
+			best, bestScore = name, score
+		}
+	}
+	if bestScore == 0 {
+		return "", fmt.Errorf("no asset for %s in %s", platform, strings.Join(names, ", "))
+	}
+	return best, nil
+}
+
+// assetScore rates how well an asset name matches platform, 0 if it doesn't.
+func assetScore(name string, platform Platform) int {
+	lower := strings.ToLower(path.Base(name))
+	if strings.Contains(lower, "checksums") || slices.ContainsFunc(assetSkipExts, func(ext string) bool {
+		return strings.HasSuffix(lower, ext)
+	}) {
+		return 0
+	}
+	// Normalise the variants containing separators before splitting into words.
+	lower = strings.NewReplacer("x86_64", "amd64", "x86-64", "amd64").Replace(lower)
+	words := strings.FieldsFunc(lower, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+	})
+	names := func(goName string, aliases map[string][]string) []string {
+		return append([]string{goName}, aliases[goName]...)
+	}
+	mentions := func(candidates []string) bool {
+		return slices.ContainsFunc(words, func(word string) bool { return slices.Contains(candidates, word) })
+	}
+	if !mentions(names(platform.OS, assetOSAliases)) {
+		return 0
+	}
+	switch {
+	case mentions(names(platform.Arch, assetArchAliases)):
+		return 3
+	case mentions(assetUniversal):
+		return 2
+	}
+	for arch := range assetArchAliases {
+		if mentions(names(arch, assetArchAliases)) {
+			return 0
+		}
+	}
+	return 1
+}
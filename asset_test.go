@@ -0,0 +1,77 @@
+package getit_test
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/block/getit"
+)
+
+func TestSelectAsset(t *testing.T) {
+	goreleaser := []string{
+		"checksums.txt",
+		"tool_Darwin_all.tar.gz",
+		"tool_Linux_arm64.tar.gz",
+		"tool_Linux_x86_64.tar.gz",
+		"tool_Linux_x86_64.tar.gz.sig",
+		"tool_Linux_i386.tar.gz",
+		"tool_Windows_x86_64.zip",
+		"tool_1.0.0_amd64.deb",
+	}
+	tests := []struct {
+		name     string
+		assets   []string
+		platform getit.Platform
+		expected string
+		err      string
+	}{
+		{name: "X86_64", assets: goreleaser, platform: getit.Platform{OS: "linux", Arch: "amd64"}, expected: "tool_Linux_x86_64.tar.gz"},
+		{name: "ARM64", assets: goreleaser, platform: getit.Platform{OS: "linux", Arch: "arm64"}, expected: "tool_Linux_arm64.tar.gz"},
+		{name: "I386", assets: goreleaser, platform: getit.Platform{OS: "linux", Arch: "386"}, expected: "tool_Linux_i386.tar.gz"},
+		{name: "Universal", assets: goreleaser, platform: getit.Platform{OS: "darwin", Arch: "arm64"}, expected: "tool_Darwin_all.tar.gz"},
+		{name: "Windows", assets: goreleaser, platform: getit.Platform{OS: "windows", Arch: "amd64"}, expected: "tool_Windows_x86_64.zip"},
+		{
+			name:     "Aliases",
+			assets:   []string{"tool-macos-x64.tar.gz", "tool-macos-aarch64.tar.gz", "tool-linux-x64.tar.gz"},
+			platform: getit.Platform{OS: "darwin", Arch: "arm64"},
+			expected: "tool-macos-aarch64.tar.gz",
+		},
+		{
+			name:     "ArchPreferredOverNoArch",
+			assets:   []string{"tool-osx.zip", "tool-osx-x86-64.zip"},
+			platform: getit.Platform{OS: "darwin", Arch: "amd64"},
+			expected: "tool-osx-x86-64.zip",
+		},
+		{
+			name:     "NoArch",
+			assets:   []string{"tool-linux.tar.gz", "tool-windows.zip"},
+			platform: getit.Platform{OS: "linux", Arch: "riscv64"},
+			expected: "tool-linux.tar.gz",
+		},
+		{
+			name:     "OtherArchOnly",
+			assets:   []string{"tool-linux-riscv64.tar.gz"},
+			platform: getit.Platform{OS: "linux", Arch: "amd64"},
+			err:      "no asset for linux/amd64 in tool-linux-riscv64.tar.gz",
+		},
+		{
+			name:     "NoMatch",
+			assets:   goreleaser,
+			platform: getit.Platform{OS: "freebsd", Arch: "amd64"},
+			err:      "no asset for freebsd/amd64",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			asset, err := getit.SelectAsset(test.assets, test.platform)
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), test.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, asset)
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package getit
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"os/user"
+	"sync"
+	"time"
+)
+
+// AuditRecord is written as a JSON line by [WithAuditLog] for each fetch.
+type AuditRecord struct {
+	// Time the fetch started.
+	Time time.Time `json:"time"`
+	// Duration of the fetch.
+	Duration time.Duration `json:"duration"`
+	// User running the fetch, and the host it ran on.
+	User string `json:"user"`
+	Host string `json:"host"`
+	// Source as passed to the fetch, with credentials redacted.
+	Source string `json:"source"`
+	// URL of the resolved source, with credentials redacted.
+	URL      string `json:"url"`
+	Resolver string `json:"resolver"`
+	Dest     string `json:"dest"`
+	// Result is "success" or "failure".
+	Result string `json:"result"`
+	// Error of a failed fetch.
+	Error string `json:"error,omitempty"`
+	// Revision and Version of the source, where known.
+	Revision string `json:"revision,omitempty"`
+	Version  string `json:"version,omitempty"`
+	// Digest is the tree hash of the destination after a successful fetch, as in [Manifest.TreeHash].
+	Digest string `json:"digest,omitempty"`
+}
+
+// WithAuditLog appends an [AuditRecord] to w as a line of JSON for each fetch, successful or not, for environments
+// that must account for every external download. Dry runs, and sources that fail to resolve or are rejected by
+// [Hooks.Resolve], are not recorded as nothing is downloaded.
+//
+// Writes to w are serialised. Failures to write the log are reported to the logger, and do not fail the fetch.
+func WithAuditLog(w io.Writer) Option {
+	return func(f *Fetcher) { f.config.audit = &auditLog{w: w} }
+}
+
+type auditLog struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// auditIdentity returns the user and host running the process.
+var auditIdentity = sync.OnceValues(func() (string, string) {
+	username := os.Getenv("USER")
+	if u, err := user.Current(); err == nil {
+		username = u.Username
+	}
+	host, _ := os.Hostname() //nolint:errcheck // left empty if unknown
+	return username, host
+})
+
+// record writes the record of a fetch of source that started at start, and finished with err.
+func (a *auditLog) record(ctx context.Context, source string, result *FetchResult, start time.Time, err error) {
+	cfg := configFromContext(ctx)
+	record := AuditRecord{
+		Time:     start.UTC(),
+		Duration: time.Since(start),
+		Source:   source,
+		URL:      result.URL,
+		Resolver: result.Resolver,
+		Dest:     result.Dest,
+		Result:   "success",
+		Revision: result.Info.Revision,
+		Version:  result.Version,
+	}
+	record.User, record.Host = auditIdentity()
+	if err != nil {
+		record.Result, record.Error = "failure", err.Error()
+	} else if manifest, err := BuildManifest(ctx, result.Dest); err == nil {
+		record.Digest = manifest.TreeHash
+	} else {
+		cfg.logger.WarnContext(ctx, "audit digest failed", "dest", result.Dest, "error", err)
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		cfg.logger.ErrorContext(ctx, "audit log failed", "error", err)
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, err := a.w.Write(append(data, '\n')); err != nil {
+		cfg.logger.ErrorContext(ctx, "audit log failed", "error", err)
+	}
+}
@@ -0,0 +1,54 @@
+package getit_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/block/getit"
+)
+
+func TestAuditLog(t *testing.T) {
+	mem := getit.NewMemResolver(map[string]string{"repo/a.txt": "a\n"})
+	log := &bytes.Buffer{}
+	fetcher := getit.New([]getit.Resolver{mem}, nil, getit.WithAuditLog(log))
+	ctx := context.Background()
+	dest := t.TempDir()
+	assert.NoError(t, fetcher.Fetch(ctx, "mem://repo?token=secret", dest))
+	assert.Error(t, fetcher.Fetch(ctx, "mem://missing", t.TempDir()))
+	_, err := fetcher.FetchWithOptions(ctx, "mem://repo", t.TempDir(), getit.FetchOptions{DryRun: true})
+	assert.NoError(t, err)
+	assert.Error(t, fetcher.Fetch(ctx, "https://example.com/unsupported", t.TempDir()))
+
+	assert.NotContains(t, log.String(), "secret")
+	var records []getit.AuditRecord
+	scanner := bufio.NewScanner(log)
+	for scanner.Scan() {
+		var record getit.AuditRecord
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		records = append(records, record)
+	}
+	assert.Equal(t, 2, len(records))
+
+	manifest, err := getit.BuildManifest(ctx, dest)
+	assert.NoError(t, err)
+	success := records[0]
+	assert.Equal(t, "mem://repo?token=xxxxx", success.Source)
+	assert.Equal(t, "MemResolver", success.Resolver)
+	assert.Equal(t, dest, success.Dest)
+	assert.Equal(t, "success", success.Result)
+	assert.Equal(t, manifest.TreeHash, success.Digest)
+	assert.True(t, strings.HasPrefix(success.Revision, "sha256:"))
+	assert.False(t, success.Time.IsZero())
+
+	failure := records[1]
+	assert.Equal(t, "mem://missing", failure.Source)
+	assert.Equal(t, "failure", failure.Result)
+	assert.Contains(t, failure.Error, "file does not exist")
+	assert.Equal(t, "", failure.Digest)
+}
@@ -0,0 +1,132 @@
+package getit
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+)
+
+// ErrDrained is returned by [Fetcher.FetchAll] when it stops launching fetches because [BatchOptions.Drain] was
+// signalled.
+var ErrDrained = errors.New("batch drained")
+
+// BatchFetch is a single fetch made by [Fetcher.FetchAll].
+type BatchFetch struct {
+	// Source may reference the template variables {os}, {arch} and {version}, see [ExpandTemplate].
+	Source string
+	Dest   string
+	// Version substituted for {version} in Source.
+	Version string
+	Options FetchOptions
+}
+
+// BatchOptions control [Fetcher.FetchAll].
+type BatchOptions struct {
+	// Concurrency is the maximum number of fetches made concurrently. Defaults to GOMAXPROCS.
+	Concurrency int
+	// Drain, once closed, stops new fetches from being launched while letting those in flight finish, eg. on SIGTERM:
+	//
+	//	drain, stop := signal.NotifyContext(ctx, syscall.SIGTERM)
+	//	defer stop()
+	//	report, err := fetcher.FetchAll(ctx, fetches, getit.BatchOptions{Drain: drain.Done()})
+	Drain <-chan struct{}
+	// Platform substituted for {os} and {arch} in sources. Defaults to [CurrentPlatform].
+	Platform Platform
+}
+
+// BatchReport is returned by [Fetcher.FetchAll].
+type BatchReport struct {
+	// Results correspond to the fetches of the batch, in order.
+	Results []BatchResult
+	// Drained is true if fetches were skipped because [BatchOptions.Drain] was signalled.
+	Drained bool
+}
+
+// BatchResult is the outcome of a single fetch of a batch.
+type BatchResult struct {
+	// Source with credentials redacted.
+	Source string
+	Dest   string
+	// Result of the fetch, if it succeeded.
+	Result *FetchResult
+	// Err is the error the fetch failed with, if any.
+	Err error
+	// Skipped is true if the fetch was never launched, because the batch was drained or its context cancelled.
+	Skipped bool
+}
+
+// FetchAll makes a batch of fetches concurrently, continuing past failures. The returned report records the outcome
+// of every fetch, and the error joins those of the failed fetches.
+//
+// Cancelling ctx cancels the fetches in flight. To shut down gracefully instead, signal [BatchOptions.Drain]: no
+// further fetches are launched, those in flight finish, and the partial report is returned with an error wrapping
+// [ErrDrained].
+func (f *Fetcher) FetchAll(ctx context.Context, fetches []BatchFetch, options BatchOptions) (*BatchReport, error) {
+	concurrency := options.Concurrency
+	if concurrency <= 0 {
+		concurrency = runtime.GOMAXPROCS(0)
+	}
+	report := &BatchReport{Results: make([]BatchResult, len(fetches))}
+	for i, fetch := range fetches {
+		report.Results[i] = BatchResult{Source: redactSource(fetch.Source), Dest: fetch.Dest}
+	}
+	platform := options.Platform
+	if platform == (Platform{}) {
+		platform = CurrentPlatform()
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	launched := 0
+	for ; launched < len(fetches); launched++ {
+		if report.Drained = drained(options.Drain); report.Drained {
+			break
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-options.Drain:
+			report.Drained = true
+		case <-ctx.Done():
+		}
+		if report.Drained || ctx.Err() != nil {
+			break
+		}
+		result, fetch := &report.Results[launched], fetches[launched]
+		wg.Go(func() {
+			defer func() { <-sem }()
+			source, err := ExpandTemplate(fetch.Source, platform, fetch.Version)
+			if err != nil {
+				result.Err = err
+				return
+			}
+			result.Result, result.Err = f.FetchWithOptions(ctx, source, fetch.Dest, fetch.Options)
+		})
+	}
+	wg.Wait()
+
+	var errs []error
+	for i := range report.Results {
+		result := &report.Results[i]
+		if i >= launched {
+			result.Skipped = true
+		} else if result.Err != nil {
+			errs = append(errs, result.Err)
+		}
+	}
+	if report.Drained {
+		f.config.logger.InfoContext(ctx, "batch drained", "fetched", launched, "skipped", len(fetches)-launched)
+		errs = append(errs, ErrDrained)
+	} else if launched < len(fetches) {
+		errs = append(errs, contextError(ctx))
+	}
+	return report, errors.Join(errs...)
+}
+
+func drained(drain <-chan struct{}) bool {
+	select {
+	case <-drain:
+		return true
+	default:
+		return false
+	}
+}
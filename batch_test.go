@@ -0,0 +1,84 @@
+package getit_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/block/getit"
+)
+
+func TestFetchAll(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "archive.tar.gz"))
+	assert.NoError(t, err)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing.tar.gz" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+
+	root := t.TempDir()
+	fetches := []getit.BatchFetch{
+		{Source: server.URL + "/a.tar.gz", Dest: filepath.Join(root, "a")},
+		{Source: server.URL + "/missing.tar.gz", Dest: filepath.Join(root, "missing")},
+		{Source: server.URL + "/b.tar.gz", Dest: filepath.Join(root, "b")},
+	}
+	fetcher := getit.New([]getit.Resolver{getit.NewTAR()}, nil)
+	report, err := fetcher.FetchAll(context.Background(), fetches, getit.BatchOptions{Concurrency: 2})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "404")
+	assert.False(t, report.Drained)
+	assert.Equal(t, 3, len(report.Results))
+	for i, result := range report.Results {
+		assert.Equal(t, fetches[i].Dest, result.Dest)
+		assert.False(t, result.Skipped)
+		assert.Equal(t, i == 1, result.Err != nil, "%v", result.Err)
+	}
+	_, err = os.Stat(filepath.Join(root, "b"))
+	assert.NoError(t, err)
+}
+
+func TestFetchAllDrain(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "archive.tar.gz"))
+	assert.NoError(t, err)
+	drain := make(chan struct{})
+	started := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		// Drain while the first fetch is in flight.
+		close(started)
+		<-drain
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+	go func() {
+		<-started
+		close(drain)
+	}()
+
+	root := t.TempDir()
+	fetches := []getit.BatchFetch{
+		{Source: server.URL + "/a.tar.gz", Dest: filepath.Join(root, "a")},
+		{Source: server.URL + "/b.tar.gz", Dest: filepath.Join(root, "b")},
+		{Source: server.URL + "/c.tar.gz", Dest: filepath.Join(root, "c")},
+	}
+	fetcher := getit.New([]getit.Resolver{getit.NewTAR()}, nil)
+	report, err := fetcher.FetchAll(context.Background(), fetches, getit.BatchOptions{Concurrency: 1, Drain: drain})
+	assert.True(t, errors.Is(err, getit.ErrDrained), "%v", err)
+	assert.True(t, report.Drained)
+	assert.NoError(t, report.Results[0].Err)
+	assert.NotZero(t, report.Results[0].Result)
+	assert.Equal(t, []bool{false, true, true}, []bool{report.Results[0].Skipped, report.Results[1].Skipped, report.Results[2].Skipped})
+	_, err = os.Stat(filepath.Join(root, "a", "file.txt"))
+	assert.NoError(t, err)
+	_, err = os.Stat(filepath.Join(root, "b"))
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,112 @@
+package getit
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/kballard/go-shellquote"
+)
+
+// The Bundle [Resolver] clones a working tree from a git bundle, as created by "git bundle create", for air-gapped
+// environments where repositories are transported as files.
+//
+// Bundles are recognised by a .bundle extension, and may be fetched over HTTP or from a local file:// path:
+//
+//	https://example.com/repo.bundle
+//	file:///path/to/repo.bundle
+//
+// The ref query parameter selects a branch or tag recorded in the bundle, defaulting to the bundle's HEAD. The
+// clone has no remote, as the bundle it was cloned from is removed.
+type Bundle struct{}
+
+var (
+	_ Resolver           = (*Bundle)(nil)
+	_ Stater             = (*Bundle)(nil)
+	_ CapabilityReporter = (*Bundle)(nil)
+)
+
+func NewBundle() *Bundle { return &Bundle{} }
+
+// Match returns true for http, https and file URLs with a .bundle extension, ignoring case and any query or fragment.
+func (b *Bundle) Match(source *url.URL) bool {
+	switch source.Scheme {
+	case "http", "https", "file":
+		return strings.HasSuffix(strings.ToLower(archivePath(source)), ".bundle")
+	default:
+		return false
+	}
+}
+
+func (b *Bundle) Capabilities() Capabilities {
+	return Capabilities{Refs: true, Binaries: []string{"git"}}
+}
+
+func (b *Bundle) Stat(ctx context.Context, source Source) (SourceInfo, error) {
+	return statArchive(ctx, source.URL)
+}
+
+// Fetch clones the bundle into dest. Remote bundles are downloaded to a temporary file first.
+func (b *Bundle) Fetch(ctx context.Context, source Source, dest string) error {
+	path := localPath(source.URL)
+	if source.URL.Scheme != "file" {
+		tmp, err := downloadBundle(ctx, source.URL)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(tmp)
+		path = tmp
+	}
+	args := []string{"clone"}
+	if ref := source.URL.Query().Get("ref"); ref != "" {
+		args = append(args, "--branch", ref)
+	}
+	args = append(args, path, dest)
+
+	display := RedactURL(source.URL)
+	configFromContext(ctx).logger.DebugContext(ctx, "unbundle", "url", display, "dest", dest)
+	ctx, span := startSpan(ctx, "getit.unbundle", map[string]string{"url": display})
+	ctx, cancel := withPhaseTimeout(ctx, "extract", configFromContext(ctx).timeouts.Extract)
+	defer cancel()
+	if output, err := gitCommand(ctx, args...).CombinedOutput(); err != nil {
+		if cause := contextError(ctx); cause != nil {
+			err = cause
+		}
+		err = fmt.Errorf("git clone failed: git %s: %w: %s", shellquote.Join(args...), err, output)
+		span.End(err)
+		return err
+	}
+	if output, err := gitCommand(ctx, "-C", dest, "remote", "remove", "origin").CombinedOutput(); err != nil {
+		err = fmt.Errorf("removing bundle remote: %w: %s", err, output)
+		span.End(err)
+		return err
+	}
+	span.End(nil)
+	return finishClone(ctx, dest)
+}
+
+// downloadBundle downloads the bundle at u to a temporary file, returning its path. The caller must remove it.
+func downloadBundle(ctx context.Context, u *url.URL) (string, error) {
+	resp, err := httpGet(ctx, u)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	tmp, err := configFromContext(ctx).createTemp("bundle-*.bundle")
+	if err != nil {
+		return "", err
+	}
+	defer tmp.Close()
+	if _, err := io.Copy(tmp, newCountingReader(ctx, resp.Body, u.Host)); err != nil {
+		_ = os.Remove(tmp.Name())
+		return "", fmt.Errorf("downloading %s: %w", RedactURL(u), err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return "", fmt.Errorf("downloading %s: %w", RedactURL(u), err)
+	}
+	return tmp.Name(), nil
+}
@@ -0,0 +1,89 @@
+package getit //nolint:testpackage
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestBundleFetch(t *testing.T) {
+	repoDir, runGit := createTestRepo(t)
+	runGit("checkout", "-b", "feature")
+	assert.NoError(t, os.WriteFile(filepath.Join(repoDir, "file.txt"), []byte("feature content\n"), 0o644))
+	runGit("commit", "-am", "Feature commit")
+	runGit("checkout", "master")
+	bundle := filepath.Join(t.TempDir(), "repo.bundle")
+	runGit("bundle", "create", bundle, "--all")
+	data, err := os.ReadFile(bundle)
+	assert.NoError(t, err)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+		err      string
+	}{
+		{name: "HTTP", source: server.URL + "/repo.bundle", expected: "hello from test\n"},
+		{name: "Ref", source: server.URL + "/repo.bundle?ref=feature", expected: "feature content\n"},
+		{name: "ArchiveParam", source: server.URL + "/download?archive=bundle", expected: "hello from test\n"},
+		{name: "File", source: "file://" + filepath.ToSlash(bundle), expected: "hello from test\n"},
+		{name: "MissingRef", source: server.URL + "/repo.bundle?ref=missing", err: "git clone failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmp := t.TempDir()
+			fetcher := New([]Resolver{NewFile(), NewBundle(), NewHTTP()}, nil, WithTempDir(tmp))
+			dest := filepath.Join(t.TempDir(), "dest")
+			err := fetcher.Fetch(context.Background(), tt.source, dest)
+			if tt.err != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			content, err := os.ReadFile(filepath.Join(dest, "file.txt"))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, string(content))
+
+			cmd := exec.Command("git", "remote")
+			cmd.Dir = dest
+			output, err := cmd.Output()
+			assert.NoError(t, err)
+			assert.Equal(t, "", string(output), "the clone should have no remote")
+			entries, err := os.ReadDir(tmp)
+			assert.NoError(t, err)
+			assert.Equal(t, 0, len(entries), "the downloaded bundle should be removed")
+		})
+	}
+}
+
+func TestBundleMatch(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected bool
+	}{
+		{source: "https://example.com/repo.bundle", expected: true},
+		{source: "http://example.com/repo.BUNDLE?token=x", expected: true},
+		{source: "file:///tmp/repo.bundle", expected: true},
+		{source: "git+https://example.com/repo.bundle"},
+		{source: "https://example.com/repo.tar.gz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			u, err := url.Parse(tt.source)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, NewBundle().Match(u))
+		})
+	}
+}
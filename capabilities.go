@@ -0,0 +1,49 @@
+package getit
+
+import "fmt"
+
+// Capabilities describe what a [Resolver] supports, so that frontends can adapt their validation and UX per source.
+type Capabilities struct {
+	// Refs is true if a version of the source may be selected, eg. with the ref or version query parameters.
+	Refs bool
+	// SubDir is true if the resolver honours [Source.SubDir].
+	SubDir bool
+	// Stat is true if the resolver implements [Stater].
+	Stat bool
+	// Versions is true if the resolver implements [Versioner].
+	Versions bool
+	// Push is true if the resolver implements [Pusher].
+	Push bool
+	// Binaries lists external programs the resolver may run. Some are only needed for certain sources, eg. xz for
+	// .tar.xz archives.
+	Binaries []string
+}
+
+// CapabilityReporter is an optional interface implemented by a [Resolver] to describe its capabilities.
+type CapabilityReporter interface {
+	// Capabilities of the resolver. Stat, Versions and Push are derived from the interfaces the resolver implements, so
+	// need not be set.
+	Capabilities() Capabilities
+}
+
+// ResolverCapabilities returns the capabilities of a resolver. Resolvers that don't implement [CapabilityReporter]
+// are assumed to support only the optional interfaces they implement.
+func ResolverCapabilities(resolver Resolver) Capabilities {
+	var capabilities Capabilities
+	if reporter, ok := resolver.(CapabilityReporter); ok {
+		capabilities = reporter.Capabilities()
+	}
+	_, capabilities.Stat = resolver.(Stater)
+	_, capabilities.Versions = resolver.(Versioner)
+	_, capabilities.Push = resolver.(Pusher)
+	return capabilities
+}
+
+// Capabilities returns the capabilities of the resolver that would handle source.
+func (f *Fetcher) Capabilities(source string) (Capabilities, error) {
+	resolver, _, err := f.Resolve(source)
+	if err != nil {
+		return Capabilities{}, fmt.Errorf("capabilities: %w", err)
+	}
+	return ResolverCapabilities(resolver), nil
+}
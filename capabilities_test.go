@@ -0,0 +1,33 @@
+package getit_test
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/block/getit"
+)
+
+func TestCapabilities(t *testing.T) {
+	fetcher := getit.New([]getit.Resolver{getit.NewGit(), getit.NewTAR(), getit.NewZIP(), unstatableResolver{}}, nil)
+	tests := []struct {
+		name     string
+		source   string
+		expected getit.Capabilities
+	}{
+		{name: "Git", source: "git+https://example.com/repo.git", expected: getit.Capabilities{Refs: true, Stat: true, Versions: true, Binaries: []string{"git"}}},
+		{name: "TAR", source: "https://example.com/archive.tar.xz", expected: getit.Capabilities{SubDir: true, Stat: true, Push: true, Binaries: []string{"xz", "zstd", "lzip", "brotli", "gzip"}}},
+		{name: "ZIP", source: "https://example.com/archive.zip", expected: getit.Capabilities{SubDir: true, Stat: true, Push: true}},
+		{name: "Minimal", source: "https://example.com/file", expected: getit.Capabilities{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			capabilities, err := fetcher.Capabilities(tt.source)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, capabilities)
+		})
+	}
+
+	_, err := getit.New(nil, nil).Capabilities("https://example.com/archive.zip")
+	assert.Error(t, err)
+}
@@ -0,0 +1,146 @@
+package getit
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ErrChecksumMismatch is returned when fetched content differs from the digest recorded in the checksum database.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// WithChecksumDB enables a trust-on-first-use checksum database at path, similar to go.sum.
+//
+// The first fetch of each source records a tree hash of its fetched content, similar to [Manifest.TreeHash]. Later
+// fetches of the same source fail with [ErrChecksumMismatch] if their content differs, leaving the destination
+// untouched, unless [FetchOptions.UpdateChecksums] is set to accept the change. Sources are keyed by their resolved
+// URL with credentials redacted, and the digest is taken before any [FetchOptions.PostFetch] hook runs.
+//
+// Signed URLs, such as S3 or GCS presigned URLs, are keyed without their query string, as their signature and expiry
+// parameters change each time they are issued. Permissions are recorded only as whether each file is executable, so
+// that the digest doesn't depend on the umask of the process that fetched it.
+//
+// The database is a text file of "<source> <digest>" lines, sorted by source, suitable for checking in.
+func WithChecksumDB(path string) Option {
+	return func(f *Fetcher) { f.config.checksums = &checksumDB{path: path} }
+}
+
+// checksumDB is a trust-on-first-use database of source digests.
+type checksumDB struct {
+	path string
+	mu   sync.Mutex
+}
+
+// checksumKey returns the key of a source in the checksum database.
+func checksumKey(source Source) string {
+	u := redactURL(source.URL)
+	if isSignedURL(u) {
+		u.RawQuery = ""
+	}
+	key := u.String()
+	if source.SubDir != "" {
+		key += "//" + source.SubDir
+	}
+	return key
+}
+
+// isSignedURL reports whether u carries a signature in its query string, as presigned URLs do.
+func isSignedURL(u *url.URL) bool {
+	for key := range u.Query() {
+		switch strings.ToLower(key) {
+		case "signature", "sig", "x-amz-signature", "x-goog-signature":
+			return true
+		}
+	}
+	return false
+}
+
+// checksumDigest returns the digest of manifest recorded in the checksum database. It is the tree hash of the
+// manifest with permissions normalised to 0755 for directories and executable files and 0644 for other files, so
+// that it is the same whatever umask the tree was fetched under.
+func checksumDigest(manifest *Manifest) string {
+	entries := slices.Clone(manifest.Entries)
+	for i, entry := range entries {
+		switch {
+		case entry.Type == "dir", entry.Type == "file" && entry.Mode&0o111 != 0:
+			entries[i].Mode = 0o755
+		case entry.Type == "file":
+			entries[i].Mode = 0o644
+		}
+	}
+	return treeHash(entries)
+}
+
+// verify checks the tree hash of dir against the digest recorded for source, recording it if there is none or if
+// update is set.
+func (db *checksumDB) verify(ctx context.Context, source Source, dir string, update bool) error {
+	manifest, err := BuildManifest(ctx, dir)
+	if err != nil {
+		return err
+	}
+	key := checksumKey(source)
+	digest := checksumDigest(manifest)
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	sums, err := db.read()
+	if err != nil {
+		return err
+	}
+	recorded, ok := sums[key]
+	cfg := configFromContext(ctx)
+	cfg.logger.DebugContext(ctx, "verify", "resolver", cfg.resolver, "source", key, "digest", digest, "recorded", recorded)
+	switch {
+	case ok && recorded == digest:
+		return nil
+	case ok && !update:
+		return fmt.Errorf("%w for %s: recorded %s, fetched %s", ErrChecksumMismatch, key, recorded, digest)
+	}
+	cfg.logger.DebugContext(ctx, "recording checksum", "source", key, "digest", digest, "previous", recorded)
+	sums[key] = digest
+	return db.write(sums)
+}
+
+func (db *checksumDB) read() (map[string]string, error) {
+	sums := map[string]string{}
+	f, err := os.Open(db.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return sums, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("reading checksum database: %w", err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		i := strings.LastIndexByte(text, ' ')
+		if i < 0 {
+			return nil, fmt.Errorf("%s:%d: malformed checksum line", db.path, line)
+		}
+		sums[strings.TrimSpace(text[:i])] = text[i+1:]
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading checksum database: %w", err)
+	}
+	return sums, nil
+}
+
+func (db *checksumDB) write(sums map[string]string) error {
+	var b strings.Builder
+	for _, key := range slices.Sorted(maps.Keys(sums)) {
+		fmt.Fprintf(&b, "%s %s\n", key, sums[key])
+	}
+	if err := writeFileAtomic(db.path, strings.NewReader(b.String())); err != nil {
+		return fmt.Errorf("writing checksum database: %w", err)
+	}
+	return nil
+}
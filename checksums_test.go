@@ -0,0 +1,111 @@
+package getit_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/block/getit"
+)
+
+func TestChecksumDB(t *testing.T) {
+	mem := getit.NewMemResolver(map[string]string{"repo/file.txt": "v1\n", "other/file.txt": "other\n"})
+	db := filepath.Join(t.TempDir(), "getit.sum")
+	fetcher := getit.New([]getit.Resolver{mem}, nil, getit.WithChecksumDB(db))
+	ctx := context.Background()
+	dest := filepath.Join(t.TempDir(), "dest")
+
+	// The first fetch of each source is trusted.
+	assert.NoError(t, fetcher.Fetch(ctx, "mem://repo", dest))
+	assert.NoError(t, fetcher.Fetch(ctx, "mem://other", t.TempDir()))
+	data, err := os.ReadFile(db)
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	assert.Equal(t, 2, len(lines))
+	assert.True(t, strings.HasPrefix(lines[0], "mem://other sha256:"), lines[0])
+	assert.True(t, strings.HasPrefix(lines[1], "mem://repo sha256:"), lines[1])
+
+	// Unchanged content is accepted.
+	assert.NoError(t, fetcher.Fetch(ctx, "mem://repo", dest))
+
+	// Changed content is rejected, leaving the destination untouched.
+	mem.SetFile("repo/file.txt", "v2\n")
+	err = fetcher.Fetch(ctx, "mem://repo", dest)
+	assert.IsError(t, err, getit.ErrChecksumMismatch)
+	content, err := os.ReadFile(filepath.Join(dest, "file.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "v1\n", string(content))
+	after, err := os.ReadFile(db)
+	assert.NoError(t, err)
+	assert.Equal(t, string(data), string(after))
+
+	// Until the change is accepted.
+	_, err = fetcher.FetchWithOptions(ctx, "mem://repo", dest, getit.FetchOptions{UpdateChecksums: true})
+	assert.NoError(t, err)
+	content, err = os.ReadFile(filepath.Join(dest, "file.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "v2\n", string(content))
+	assert.NoError(t, fetcher.Fetch(ctx, "mem://repo", dest))
+	after, err = os.ReadFile(db)
+	assert.NoError(t, err)
+	assert.NotEqual(t, string(data), string(after))
+}
+
+func TestChecksumDBMalformed(t *testing.T) {
+	db := filepath.Join(t.TempDir(), "getit.sum")
+	assert.NoError(t, os.WriteFile(db, []byte("garbage\n"), 0o600))
+	mem := getit.NewMemResolver(map[string]string{"repo/file.txt": "v1\n"})
+	fetcher := getit.New([]getit.Resolver{mem}, nil, getit.WithChecksumDB(db))
+	err := fetcher.Fetch(context.Background(), "mem://repo", t.TempDir())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "getit.sum:1: malformed checksum line")
+}
+
+func TestChecksumDBIgnoresUmask(t *testing.T) {
+	write := func(dirMode, fileMode, toolMode os.FileMode) string {
+		dir := t.TempDir()
+		assert.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), dirMode))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "file.txt"), []byte("file\n"), fileMode))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "tool"), []byte("#!/bin/sh\n"), toolMode))
+		for name, mode := range map[string]os.FileMode{"sub": dirMode, "sub/file.txt": fileMode, "tool": toolMode} {
+			assert.NoError(t, os.Chmod(filepath.Join(dir, name), mode))
+		}
+		hash, err := getit.HashTree(context.Background(), dir)
+		assert.NoError(t, err)
+		return hash
+	}
+	umask022 := write(0o755, 0o644, 0o755)
+	assert.Equal(t, umask022, write(0o700, 0o600, 0o700), "umask 077")
+	if runtime.GOOS != "windows" {
+		assert.NotEqual(t, umask022, write(0o755, 0o644, 0o644), "executable bit")
+	}
+}
+
+func TestChecksumDBSignedURL(t *testing.T) {
+	content := "v1\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(content))
+	}))
+	defer server.Close()
+	db := filepath.Join(t.TempDir(), "getit.sum")
+	fetcher := getit.New([]getit.Resolver{getit.NewHTTP()}, nil, getit.WithChecksumDB(db))
+	ctx := context.Background()
+
+	assert.NoError(t, fetcher.Fetch(ctx, server.URL+"/file.txt?X-Amz-Date=1&X-Amz-Signature=a", t.TempDir()))
+	data, err := os.ReadFile(db)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(data), server.URL+"/file.txt sha256:"), string(data))
+
+	// A freshly signed URL for the same object is checked against the recorded digest.
+	assert.NoError(t, fetcher.Fetch(ctx, server.URL+"/file.txt?X-Amz-Date=2&X-Amz-Signature=b", t.TempDir()))
+	content = "v2\n"
+	err = fetcher.Fetch(ctx, server.URL+"/file.txt?X-Amz-Date=3&X-Amz-Signature=c", t.TempDir())
+	assert.IsError(t, err, getit.ErrChecksumMismatch)
+}
@@ -0,0 +1,23 @@
+package getit
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// ficlone is the FICLONE ioctl request, which shares the extents of one file with another.
+const ficlone = 0x40049409
+
+// cloneFile makes dest a copy-on-write clone of src, returning [errors.ErrUnsupported] if the filesystem can't.
+func cloneFile(dest, src *os.File) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, dest.Fd(), ficlone, src.Fd())
+	switch errno {
+	case 0:
+		return nil
+	case syscall.EOPNOTSUPP, syscall.ENOTTY, syscall.EXDEV, syscall.EINVAL, syscall.ENOSYS:
+		return errors.ErrUnsupported
+	default:
+		return errno
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package getit
+
+import (
+	"errors"
+	"os"
+)
+
+// cloneFile is not supported on this platform.
+func cloneFile(_, _ *os.File) error {
+	return errors.ErrUnsupported
+}
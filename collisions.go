@@ -0,0 +1,110 @@
+package getit
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// CaseCollisionPolicy controls how archive entries whose paths differ only by case are extracted.
+//
+// Such entries overwrite each other on case-insensitive filesystems, as used by default on macOS and Windows.
+type CaseCollisionPolicy int
+
+const (
+	// CaseCollisionIgnore extracts entries as named, so that colliding entries overwrite each other on
+	// case-insensitive filesystems. This is the default.
+	CaseCollisionIgnore CaseCollisionPolicy = iota
+	// CaseCollisionFail fails extraction with a *[CaseCollisionError].
+	CaseCollisionFail
+	// CaseCollisionRename extracts each colliding path under a new name, with a "~N" suffix before any extension.
+	// Later entries beneath a renamed directory are extracted into the renamed directory.
+	CaseCollisionRename
+)
+
+// WithCaseCollisionPolicy sets how a [Fetcher] extracts archive entries whose paths differ only by case.
+func WithCaseCollisionPolicy(policy CaseCollisionPolicy) Option {
+	return func(f *Fetcher) { f.config.caseCollisions = policy }
+}
+
+// CaseCollisionError is returned when extracting an archive entry whose path differs only by case from an earlier
+// entry, and the policy is [CaseCollisionFail].
+type CaseCollisionError struct {
+	// Path of the entry being extracted.
+	Path string
+	// Existing is the path of the earlier entry it collides with.
+	Existing string
+}
+
+func (c *CaseCollisionError) Error() string {
+	return fmt.Sprintf("%s: collides with %s on case-insensitive filesystems", c.Path, c.Existing)
+}
+
+// caseTracker detects case collisions between the entries of a single archive, resolving each entry to the path it
+// should be extracted to.
+type caseTracker struct {
+	policy CaseCollisionPolicy
+	// folded maps the case-folded form of each resolved path to the resolved path.
+	folded map[string]string
+	// resolved maps entry paths, and their parent directories, to their resolved paths.
+	resolved map[string]string
+}
+
+func newCaseTracker(policy CaseCollisionPolicy) *caseTracker {
+	return &caseTracker{policy: policy, folded: map[string]string{}, resolved: map[string]string{}}
+}
+
+// resolve returns the slash-separated path that the archive entry name should be extracted to.
+func (c *caseTracker) resolve(name string) (string, error) {
+	if c.policy == CaseCollisionIgnore {
+		return name, nil
+	}
+	clean := path.Clean("/" + name)[1:]
+	if clean == "" {
+		return name, nil
+	}
+	original, resolved := "", ""
+	for component := range strings.SplitSeq(clean, "/") {
+		original = path.Join(original, component)
+		if r, ok := c.resolved[original]; ok {
+			resolved = r
+			continue
+		}
+		candidate := path.Join(resolved, component)
+		if existing, ok := c.folded[strings.ToLower(candidate)]; ok && existing != candidate {
+			if c.policy == CaseCollisionFail {
+				return "", &CaseCollisionError{Path: name, Existing: existing}
+			}
+			for n := 1; ; n++ {
+				candidate = path.Join(resolved, renameComponent(component, n))
+				if _, taken := c.folded[strings.ToLower(candidate)]; !taken {
+					break
+				}
+			}
+		}
+		c.folded[strings.ToLower(candidate)] = candidate
+		c.resolved[original] = candidate
+		resolved = candidate
+	}
+	if strings.HasSuffix(name, "/") {
+		resolved += "/"
+	}
+	return resolved, nil
+}
+
+// lookup returns the resolved path of an entry that has already been extracted, eg. the target of a hard link.
+func (c *caseTracker) lookup(name string) string {
+	if resolved, ok := c.resolved[strings.TrimSuffix(path.Clean("/" + name)[1:], "/")]; ok {
+		return resolved
+	}
+	return name
+}
+
+// renameComponent inserts "~n" before the extension of a path component.
+func renameComponent(component string, n int) string {
+	ext := path.Ext(component)
+	if ext == component {
+		ext = ""
+	}
+	return fmt.Sprintf("%s~%d%s", strings.TrimSuffix(component, ext), n, ext)
+}
@@ -0,0 +1,114 @@
+package getit_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/block/getit"
+)
+
+var collidingEntries = []string{"README.md", "readme.md", "Dir/a.txt", "dir/b.txt", "DIR/c.txt"}
+
+func collidingTar(t *testing.T) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	for _, name := range collidingEntries {
+		assert.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(name)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(name))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, tw.Close())
+	return buf.Bytes()
+}
+
+func collidingZip(t *testing.T) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	for _, name := range collidingEntries {
+		w, err := zw.Create(name)
+		assert.NoError(t, err)
+		_, err = w.Write([]byte(name))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestWithCaseCollisionPolicy(t *testing.T) {
+	archives := map[string][]byte{
+		"archive.tar": collidingTar(t),
+		"archive.zip": collidingZip(t),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(archives[filepath.Base(r.URL.Path)])
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		policy   getit.CaseCollisionPolicy
+		expected map[string]string
+		err      *getit.CaseCollisionError
+	}{
+		{
+			name:   "Ignore",
+			policy: getit.CaseCollisionIgnore,
+			expected: map[string]string{
+				"README.md": "README.md",
+				"readme.md": "readme.md",
+				"Dir/a.txt": "Dir/a.txt",
+				"dir/b.txt": "dir/b.txt",
+				"DIR/c.txt": "DIR/c.txt",
+			},
+		},
+		{
+			name:   "Fail",
+			policy: getit.CaseCollisionFail,
+			err:    &getit.CaseCollisionError{Path: "readme.md", Existing: "README.md"},
+		},
+		{
+			name:   "Rename",
+			policy: getit.CaseCollisionRename,
+			expected: map[string]string{
+				"README.md":   "README.md",
+				"readme~1.md": "readme.md",
+				"Dir/a.txt":   "Dir/a.txt",
+				"dir~1/b.txt": "dir/b.txt",
+				"DIR~2/c.txt": "DIR/c.txt",
+			},
+		},
+	}
+	for _, tt := range tests {
+		for archive := range archives {
+			t.Run(tt.name+"/"+archive, func(t *testing.T) {
+				fetcher := getit.New([]getit.Resolver{getit.NewTAR(), getit.NewZIP()}, nil, getit.WithCaseCollisionPolicy(tt.policy))
+				dest := t.TempDir()
+				err := fetcher.Fetch(context.Background(), server.URL+"/"+archive, dest)
+				if tt.err != nil {
+					var collision *getit.CaseCollisionError
+					assert.True(t, errors.As(err, &collision), "expected a CaseCollisionError, got %v", err)
+					assert.Equal(t, tt.err, collision)
+					return
+				}
+				assert.NoError(t, err)
+				for path, content := range tt.expected {
+					data, err := os.ReadFile(filepath.Join(dest, filepath.FromSlash(path)))
+					assert.NoError(t, err)
+					assert.Equal(t, content, string(data))
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package getit
+
+import (
+	"context"
+	"os/exec"
+	"time"
+)
+
+// commandWaitDelay bounds how long a cancelled command may hold its output pipes open, eg. via a grandchild process
+// that escaped being killed, before Wait gives up on them.
+const commandWaitDelay = 5 * time.Second
+
+// command returns a command running name with args, which is killed along with any processes it started when ctx is
+// done.
+func command(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	killProcessGroup(cmd)
+	cmd.WaitDelay = commandWaitDelay
+	return cmd
+}
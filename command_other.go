@@ -0,0 +1,9 @@
+//go:build !unix
+
+package getit
+
+import "os/exec"
+
+// killProcessGroup leaves cmd to be killed alone when its context is done, as process groups are not supported on
+// this platform.
+func killProcessGroup(*exec.Cmd) {}
@@ -0,0 +1,38 @@
+//go:build unix
+
+package getit
+
+import (
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+)
+
+// killProcessGroup runs cmd in its own process group, and kills the whole group when its context is done, so that
+// eg. the git-remote-https and ssh processes started by git don't outlive it.
+//
+// This is only done when getit has no controlling terminal, eg. in CI or a service. A process outside the terminal's
+// foreground process group is stopped by SIGTTIN when it reads the terminal, as ssh and git do to prompt for
+// passphrases, host keys and credentials, and doesn't receive the SIGINT of a Ctrl-C. So in a terminal cmd stays in
+// getit's process group: Ctrl-C reaches it and any processes it started, but on cancellation only cmd itself is
+// killed, and [commandWaitDelay] bounds how long its descendants can delay Wait.
+func killProcessGroup(cmd *exec.Cmd) {
+	if hasControllingTerminal() {
+		return
+	}
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL) //nolint:wrapcheck // reported by Wait
+	}
+}
+
+// hasControllingTerminal reports whether the process has a controlling terminal, which its children may prompt on.
+var hasControllingTerminal = sync.OnceValue(func() bool {
+	tty, err := os.Open("/dev/tty")
+	if err != nil {
+		return false
+	}
+	_ = tty.Close()
+	return true
+})
@@ -0,0 +1,106 @@
+package getit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ConflictPolicy controls how [Fetcher.Compose] handles a file from one source at a path already present in the
+// destination, eg. from an earlier source. Directories are always merged.
+type ConflictPolicy int
+
+const (
+	// ConflictOverwrite replaces the existing file, so that later sources take precedence. This is the default.
+	ConflictOverwrite ConflictPolicy = iota
+	// ConflictKeepFirst keeps the existing file, so that earlier sources take precedence.
+	ConflictKeepFirst
+	// ConflictFail fails with a *[ConflictError] before anything from the conflicting source is written.
+	ConflictFail
+)
+
+// WithConflictPolicy sets how [Fetcher.Compose] handles files from different sources at the same path.
+func WithConflictPolicy(policy ConflictPolicy) Option {
+	return func(f *Fetcher) { f.config.conflicts = policy }
+}
+
+// ConflictError is returned by [Fetcher.Compose] when a source contains a path already present in the destination,
+// and the policy is [ConflictFail].
+type ConflictError struct {
+	// Path of the conflicting file, relative to the destination.
+	Path string
+	// Source whose file conflicts, with credentials redacted.
+	Source string
+}
+
+func (c *ConflictError) Error() string {
+	return fmt.Sprintf("%s: %s already exists in the destination", c.Source, c.Path)
+}
+
+// Compose fetches sources in order into the same destination, eg. to assemble a workspace from several repositories
+// and archives. Each source is fetched into a staging directory alongside dest and then merged into it: directories
+// are merged, and files at the same path are handled by the [ConflictPolicy] set with [WithConflictPolicy]. Files
+// already in dest before Compose is called are treated as if from an earlier source.
+//
+// If a source fails, dest is left with the sources before it merged.
+func (f *Fetcher) Compose(ctx context.Context, sources []string, dest string) error {
+	if len(sources) == 0 {
+		return errors.New("no sources to compose")
+	}
+	if err := os.MkdirAll(dest, 0750); err != nil {
+		return fmt.Errorf("creating destination directory: %w", err)
+	}
+	policy := f.config.conflicts
+	for _, source := range sources {
+		err := f.fetchMerged(ctx, source, dest, func(ctx context.Context, dir string) error {
+			if policy == ConflictFail {
+				if path, ok, err := findConflict(dir, dest); err != nil {
+					return err
+				} else if ok {
+					return &ConflictError{Path: path, Source: redactSource(source)}
+				}
+			}
+			return mergeTree(ctx, dir, dest, mergeOptions{keep: policy == ConflictKeepFirst})
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findConflict returns the slash-separated path of the first entry of the tree in dir, other than a directory merged
+// with a directory, that is already present in dest.
+func findConflict(dir, dest string) (string, bool, error) {
+	var conflict string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || path == dir {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err //nolint:wrapcheck // wrapped below
+		}
+		info, err := os.Lstat(filepath.Join(dest, rel))
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		case err != nil:
+			return err //nolint:wrapcheck // wrapped below
+		case d.IsDir() && info.IsDir():
+			return nil
+		}
+		conflict = filepath.ToSlash(rel)
+		return filepath.SkipAll
+	})
+	if err != nil {
+		return "", false, fmt.Errorf("checking for conflicts in %s: %w", dest, err)
+	}
+	return conflict, conflict != "", nil
+}
@@ -0,0 +1,82 @@
+package getit_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/block/getit"
+)
+
+func TestCompose(t *testing.T) {
+	sources := map[string][]byte{
+		"/tools.tar": layerTar(t,
+			[2]string{"bin/", ""},
+			[2]string{"bin/lint", "lint v1"},
+			[2]string{"README", "tools"},
+		),
+		"/app.tar": layerTar(t,
+			[2]string{"bin/", ""},
+			[2]string{"bin/app", "app"},
+			[2]string{"bin/lint", "lint v2"},
+			[2]string{"README", "app"},
+		),
+		"/docs.tar": layerTar(t,
+			[2]string{"docs/index.md", "docs"},
+		),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(sources[r.URL.Path])
+	}))
+	defer server.Close()
+	urls := []string{server.URL + "/tools.tar", server.URL + "/docs.tar", server.URL + "/app.tar"}
+
+	tests := []struct {
+		name     string
+		policy   getit.ConflictPolicy
+		expected []string
+		conflict string
+	}{
+		{
+			name:   "Overwrite",
+			policy: getit.ConflictOverwrite,
+			expected: []string{
+				"README=app", "bin/", "bin/app=app", "bin/lint=lint v2", "docs/", "docs/index.md=docs",
+			},
+		},
+		{
+			name:   "KeepFirst",
+			policy: getit.ConflictKeepFirst,
+			expected: []string{
+				"README=tools", "bin/", "bin/app=app", "bin/lint=lint v1", "docs/", "docs/index.md=docs",
+			},
+		},
+		{
+			name:     "Fail",
+			policy:   getit.ConflictFail,
+			expected: []string{"README=tools", "bin/", "bin/lint=lint v1", "docs/", "docs/index.md=docs"},
+			conflict: "README",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetcher := getit.New([]getit.Resolver{getit.NewTAR()}, nil, getit.WithConflictPolicy(tt.policy))
+			dest := filepath.Join(t.TempDir(), "workspace")
+			err := fetcher.Compose(context.Background(), urls, dest)
+			if tt.conflict != "" {
+				var conflictErr *getit.ConflictError
+				assert.True(t, errors.As(err, &conflictErr), "%v", err)
+				assert.Equal(t, tt.conflict, conflictErr.Path)
+				assert.Equal(t, server.URL+"/app.tar", conflictErr.Source)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expected, listTree(t, dest))
+		})
+	}
+}
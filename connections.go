@@ -0,0 +1,166 @@
+package getit
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Connections tunes the pooling of HTTP connections, eg. so that a batch of fetches from the same artifact host reuses
+// connections rather than churning through them.
+//
+// A zero value for any field keeps the default of [http.DefaultTransport].
+type Connections struct {
+	// MaxIdle limits idle connections kept open across all hosts. Defaults to 100.
+	MaxIdle int
+	// MaxIdlePerHost limits idle connections kept open to each host. Defaults to 2, which is low for concurrent fetches
+	// or parallel [Downloads] from a single host.
+	MaxIdlePerHost int
+	// MaxPerHost limits connections to each host, including those in use. Requests beyond the limit wait for a
+	// connection. Defaults to no limit.
+	MaxPerHost int
+	// IdleTimeout closes connections that have been idle this long. Defaults to 90s.
+	IdleTimeout time.Duration
+	// KeepAlive is the interval between TCP keep-alive probes. Defaults to 30s, and negative values disable them.
+	KeepAlive time.Duration
+	// DisableHTTP2 restricts connections to HTTP/1.1, eg. for servers or proxies with broken HTTP/2 support.
+	DisableHTTP2 bool
+	// IP selects the address families connected to, eg. [PreferIPv4] in environments with broken IPv6 routes, where
+	// connections would otherwise stall until they time out. Defaults to the order of the system resolver.
+	IP IPPreference
+	// FallbackDelay is how long a connection attempt over the preferred address family may take before one over the
+	// other family is raced against it ("Happy Eyeballs"). Attempts fall back immediately if the preferred family
+	// fails. Defaults to 300ms, and negative values disable racing so the other family is only tried once all
+	// preferred addresses have failed.
+	FallbackDelay time.Duration
+}
+
+// IPPreference selects the address families used for connections.
+type IPPreference int
+
+const (
+	// IPDefault connects to addresses in the order of the system resolver, usually IPv6 first.
+	IPDefault IPPreference = iota
+	// PreferIPv4 connects to IPv4 addresses first, falling back to IPv6.
+	PreferIPv4
+	// PreferIPv6 connects to IPv6 addresses first, falling back to IPv4.
+	PreferIPv6
+	// IPv4Only connects only to IPv4 addresses.
+	IPv4Only
+	// IPv6Only connects only to IPv6 addresses.
+	IPv6Only
+)
+
+// WithConnections tunes the HTTP connections made by a [Fetcher].
+func WithConnections(connections Connections) Option {
+	return func(f *Fetcher) { f.config.connections = connections }
+}
+
+// apply configures transport and its dialer, which is used by dialContext.
+func (c Connections) apply(transport *http.Transport, dialer *net.Dialer) {
+	if c.MaxIdle > 0 {
+		transport.MaxIdleConns = c.MaxIdle
+	}
+	if c.MaxIdlePerHost > 0 {
+		transport.MaxIdleConnsPerHost = c.MaxIdlePerHost
+	}
+	if c.MaxPerHost > 0 {
+		transport.MaxConnsPerHost = c.MaxPerHost
+	}
+	if c.IdleTimeout > 0 {
+		transport.IdleConnTimeout = c.IdleTimeout
+	}
+	if c.KeepAlive != 0 {
+		dialer.KeepAlive = c.KeepAlive
+	}
+	if c.FallbackDelay != 0 {
+		dialer.FallbackDelay = c.FallbackDelay
+	}
+	if c.DisableHTTP2 {
+		transport.Protocols = new(http.Protocols)
+		transport.Protocols.SetHTTP1(true)
+	}
+}
+
+// dialContext returns a function dialing with dialer, for [http.Transport.DialContext].
+func (c Connections) dialContext(dialer *net.Dialer) func(ctx context.Context, network, address string) (net.Conn, error) {
+	var primary, fallback string
+	switch c.IP {
+	case IPDefault:
+		return dialer.DialContext
+	case PreferIPv4, IPv4Only:
+		primary, fallback = "4", "6"
+	case PreferIPv6, IPv6Only:
+		primary, fallback = "6", "4"
+	}
+	only := c.IP == IPv4Only || c.IP == IPv6Only
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		if network != "tcp" {
+			return dialer.DialContext(ctx, network, address) //nolint:wrapcheck // as returned by net.Dialer
+		}
+		if only {
+			return dialer.DialContext(ctx, network+primary, address) //nolint:wrapcheck // as returned by net.Dialer
+		}
+		return dialPreferring(ctx, dialer, network+primary, network+fallback, address)
+	}
+}
+
+// dialPreferring dials address over the primary network, racing a dial over the fallback network if the primary
+// fails or takes longer than the fallback delay of dialer.
+func dialPreferring(ctx context.Context, dialer *net.Dialer, primary, fallback, address string) (net.Conn, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	type result struct {
+		conn    net.Conn
+		err     error
+		primary bool
+	}
+	results := make(chan result, 2)
+	dial := func(network string) {
+		conn, err := dialer.DialContext(ctx, network, address)
+		results <- result{conn: conn, err: err, primary: network == primary}
+	}
+	go dial(primary)
+	pending := 1
+	var delay <-chan time.Time
+	switch {
+	case dialer.FallbackDelay == 0:
+		delay = time.After(300 * time.Millisecond)
+	case dialer.FallbackDelay > 0:
+		delay = time.After(dialer.FallbackDelay)
+	}
+	startFallback := func() {
+		if pending < 2 {
+			go dial(fallback)
+			pending, delay = 2, nil
+		}
+	}
+	var primaryErr error
+	for received := 0; ; {
+		select {
+		case <-delay:
+			startFallback()
+		case r := <-results:
+			received++
+			if r.err == nil {
+				if received < pending {
+					// Close the connection of the losing dial, should it succeed.
+					go func() {
+						if r := <-results; r.conn != nil {
+							_ = r.conn.Close()
+						}
+					}()
+				}
+				return r.conn, nil
+			}
+			if r.primary {
+				primaryErr = r.err
+				startFallback()
+			}
+			if received == pending {
+				return nil, primaryErr
+			}
+		}
+	}
+}
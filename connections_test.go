@@ -0,0 +1,114 @@
+package getit_test
+
+import (
+	"context"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/block/getit"
+)
+
+func TestConnections(t *testing.T) {
+	body := tarball(t, "file.txt", "hello\n")
+	var mu sync.Mutex
+	var protos []string
+	var conns int
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		protos = append(protos, r.Proto)
+		mu.Unlock()
+		_, _ = w.Write(body)
+	}))
+	server.EnableHTTP2 = true
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			mu.Lock()
+			conns++
+			mu.Unlock()
+		}
+	}
+	server.StartTLS()
+	defer server.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+
+	tests := []struct {
+		name        string
+		connections getit.Connections
+		proto       string
+	}{
+		{name: "Default", proto: "HTTP/2.0"},
+		{name: "DisableHTTP2", connections: getit.Connections{DisableHTTP2: true, MaxIdlePerHost: 4, KeepAlive: -1}, proto: "HTTP/1.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Connections from earlier subtests are closed, so that none are reused or counted here.
+			server.CloseClientConnections()
+			mu.Lock()
+			protos, conns = nil, 0
+			mu.Unlock()
+			fetcher := getit.New([]getit.Resolver{getit.NewTAR()}, nil,
+				getit.WithTLS(getit.TLS{RootCAs: roots}),
+				getit.WithConnections(tt.connections),
+			)
+			for range 3 {
+				assert.NoError(t, fetcher.Fetch(context.Background(), server.URL+"/archive.tar.gz", t.TempDir()))
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Equal(t, []string{tt.proto, tt.proto, tt.proto}, protos)
+			// Connections are reused between fetches.
+			assert.Equal(t, 1, conns)
+		})
+	}
+}
+
+func TestConnectionsIP(t *testing.T) {
+	body := tarball(t, "file.txt", "hello\n")
+	// Listens on IPv4 only, so IPv6 connections to localhost fail.
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	assert.NoError(t, err)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(body)
+	}))
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+	_, port, err := net.SplitHostPort(listener.Addr().String())
+	assert.NoError(t, err)
+	source := "http://localhost:" + port + "/archive.tar.gz"
+
+	tests := []struct {
+		name        string
+		connections getit.Connections
+		fail        bool
+	}{
+		{name: "Default"},
+		{name: "PreferIPv4", connections: getit.Connections{IP: getit.PreferIPv4}},
+		// The fallback is immediate when IPv6 fails, rather than waiting for the delay.
+		{name: "PreferIPv6", connections: getit.Connections{IP: getit.PreferIPv6, FallbackDelay: time.Hour}},
+		{name: "PreferIPv6NoRace", connections: getit.Connections{IP: getit.PreferIPv6, FallbackDelay: -1}},
+		{name: "IPv4Only", connections: getit.Connections{IP: getit.IPv4Only}},
+		{name: "IPv6Only", connections: getit.Connections{IP: getit.IPv6Only}, fail: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			fetcher := getit.New([]getit.Resolver{getit.NewTAR()}, nil, getit.WithConnections(tt.connections))
+			err := fetcher.Fetch(ctx, source, t.TempDir())
+			if tt.fail {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
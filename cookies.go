@@ -0,0 +1,62 @@
+package getit
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+)
+
+// WithCookieJar enables cookies for the HTTP requests made by a [Fetcher], for servers that gate downloads behind
+// session cookies. Cookies set by responses, including redirects, are stored in jar and sent with later requests by
+// all fetches of the Fetcher.
+//
+// If jar is nil an in-memory jar is used, which accepts cookies for any domain.
+func WithCookieJar(jar http.CookieJar) Option {
+	return func(f *Fetcher) {
+		if f.config.cookies == nil {
+			f.config.cookies = &cookiesConfig{}
+		}
+		f.config.cookies.jar = jar
+	}
+}
+
+// WithCookies seeds the cookie jar with cookies for a host, eg. "artifacts.example.com", as if set by a response
+// from https://host/. Cookies without a Domain are sent only to host, and those without a Path to any path. Cookies
+// are enabled as if by [WithCookieJar] with a nil jar unless a jar is configured.
+func WithCookies(host string, cookies ...*http.Cookie) Option {
+	return func(f *Fetcher) {
+		if f.config.cookies == nil {
+			f.config.cookies = &cookiesConfig{}
+		}
+		if f.config.cookies.seeds == nil {
+			f.config.cookies.seeds = map[string][]*http.Cookie{}
+		}
+		f.config.cookies.seeds[host] = append(f.config.cookies.seeds[host], cookies...)
+	}
+}
+
+// cookiesConfig is the cookie configuration of a Fetcher.
+type cookiesConfig struct {
+	jar   http.CookieJar
+	seeds map[string][]*http.Cookie
+}
+
+// newJar returns the configured jar, seeded with cookies.
+func (c *cookiesConfig) newJar() http.CookieJar {
+	jar := c.jar
+	if jar == nil {
+		jar, _ = cookiejar.New(nil) //nolint:errcheck // never fails without options
+	}
+	for host, cookies := range c.seeds {
+		seeded := make([]*http.Cookie, len(cookies))
+		for i, cookie := range cookies {
+			cookie := *cookie
+			if cookie.Path == "" {
+				cookie.Path = "/"
+			}
+			seeded[i] = &cookie
+		}
+		jar.SetCookies(&url.URL{Scheme: "https", Host: host, Path: "/"}, seeded)
+	}
+	return jar
+}
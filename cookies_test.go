@@ -0,0 +1,65 @@
+package getit_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/block/getit"
+)
+
+func TestCookies(t *testing.T) {
+	body := tarball(t, "file.txt", "hello\n")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "granted", Path: "/"})
+			http.Redirect(w, r, "/archive.tar.gz", http.StatusFound)
+		case "/archive.tar.gz":
+			if cookie, err := r.Cookie("session"); err != nil || cookie.Value != "granted" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			_, _ = w.Write(body)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	host := server.Listener.Addr().String()
+	ctx := context.Background()
+
+	t.Run("Disabled", func(t *testing.T) {
+		fetcher := getit.New([]getit.Resolver{getit.NewTAR()}, nil)
+		err := fetcher.Fetch(ctx, server.URL+"/login?archive=tar.gz", t.TempDir())
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "403 Forbidden")
+	})
+
+	t.Run("Redirect", func(t *testing.T) {
+		jar, err := cookiejar.New(nil)
+		assert.NoError(t, err)
+		fetcher := getit.New([]getit.Resolver{getit.NewTAR()}, nil, getit.WithCookieJar(jar))
+		assert.NoError(t, fetcher.Fetch(ctx, server.URL+"/login?archive=tar.gz", t.TempDir()))
+		// The session persists for later fetches.
+		assert.NoError(t, fetcher.Fetch(ctx, server.URL+"/archive.tar.gz", t.TempDir()))
+		u, err := url.Parse(server.URL)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(jar.Cookies(u)))
+	})
+
+	t.Run("Seeded", func(t *testing.T) {
+		fetcher := getit.New([]getit.Resolver{getit.NewTAR()}, nil,
+			getit.WithCookies(host, &http.Cookie{Name: "session", Value: "granted"}))
+		assert.NoError(t, fetcher.Fetch(ctx, server.URL+"/archive.tar.gz", t.TempDir()))
+
+		fetcher = getit.New([]getit.Resolver{getit.NewTAR()}, nil,
+			getit.WithCookies("other.example.com", &http.Cookie{Name: "session", Value: "granted"}))
+		assert.Error(t, fetcher.Fetch(ctx, server.URL+"/archive.tar.gz", t.TempDir()))
+	})
+}
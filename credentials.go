@@ -0,0 +1,259 @@
+package getit
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// maxCredentialAttempts bounds how many times credentials are prompted for during a single request before its
+// authentication failure is returned.
+const maxCredentialAttempts = 3
+
+// Credential authenticates requests to a host.
+type Credential struct {
+	// Username and Password are sent with HTTP Basic authentication.
+	Username string
+	Password string
+	// Token, if set, is sent as a bearer token in place of Username and Password.
+	Token string
+}
+
+// authorization returns the value of the Authorization header for c.
+func (c Credential) authorization() string {
+	if c.Token != "" {
+		return "Bearer " + c.Token
+	}
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(c.Username+":"+c.Password))
+}
+
+// CredentialRequest describes a request that was denied for lack of credentials.
+type CredentialRequest struct {
+	// Host that denied the request, eg. "github.com" or "example.com:8443".
+	Host string
+	// URL of the request, with credentials redacted.
+	URL string
+	// Attempt is 1 for the first request for credentials, and increments each time those supplied are rejected.
+	Attempt int
+}
+
+// CredentialPrompter supplies credentials for a host when a request is denied, eg. by prompting the user for a token
+// or running an OAuth device flow.
+type CredentialPrompter interface {
+	// PromptCredential returns credentials with which the denied request is retried. Returning an error fails the
+	// fetch with it.
+	PromptCredential(ctx context.Context, req CredentialRequest) (Credential, error)
+}
+
+// CredentialPrompterFunc adapts a function to a [CredentialPrompter].
+type CredentialPrompterFunc func(ctx context.Context, req CredentialRequest) (Credential, error)
+
+// PromptCredential calls f(ctx, req).
+func (f CredentialPrompterFunc) PromptCredential(ctx context.Context, req CredentialRequest) (Credential, error) {
+	return f(ctx, req)
+}
+
+// WithCredentialPrompter calls prompter when an HTTP request is denied with 401 Unauthorized, or git fails to
+// authenticate with an https:// remote, and transparently retries with the credentials it returns. Credentials are
+// remembered per host for later requests by all fetches of the [Fetcher], and prompts are serialised so that
+// concurrent fetches prompt once per host.
+//
+// Requests carrying an Authorization header, eg. from [WithHeaders], are left alone.
+func WithCredentialPrompter(prompter CredentialPrompter) Option {
+	return func(f *Fetcher) { f.config.ensureCredentials().prompter = prompter }
+}
+
+// credentials obtains and remembers the credentials for each host.
+type credentials struct {
+	prompter CredentialPrompter
+	tokens   map[string]TokenProvider
+	stores   []SecretStore
+
+	mu    sync.Mutex
+	hosts map[string]Credential
+	// nextStore is the index of the next store to look up credentials for each host in.
+	nextStore map[string]int
+}
+
+func (c *config) ensureCredentials() *credentials {
+	if c.credentials == nil {
+		c.credentials = &credentials{
+			tokens:    map[string]TokenProvider{},
+			hosts:     map[string]Credential{},
+			nextStore: map[string]int{},
+		}
+	}
+	return c.credentials
+}
+
+// maxAttempts is the number of attempts made with renewed credentials before a denied request fails.
+func (c *credentials) maxAttempts() int {
+	return len(c.stores) + maxCredentialAttempts
+}
+
+// lookup returns the remembered credentials for host.
+func (c *credentials) lookup(host string) (Credential, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	credential, ok := c.hosts[host]
+	return credential, ok
+}
+
+// renew returns new credentials for a request that was denied, having been sent with rejected if ok. If another
+// request has already renewed the credentials for the host they are returned without prompting again. Otherwise
+// the secret stores not yet tried for the host are looked up in turn, then the prompter is called. renewed is false
+// if there are no more credentials to try.
+func (c *credentials) renew(ctx context.Context, req CredentialRequest, rejected Credential, ok bool) (credential Credential, renewed bool, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if current, found := c.hosts[req.Host]; found && (!ok || current != rejected) {
+		return current, true, nil
+	}
+	for c.nextStore[req.Host] < len(c.stores) {
+		store := c.stores[c.nextStore[req.Host]]
+		c.nextStore[req.Host]++
+		credential, found, err := store.Lookup(ctx, req)
+		if err != nil {
+			return Credential{}, false, fmt.Errorf("credentials for %s: %w", req.Host, err)
+		}
+		if found {
+			c.hosts[req.Host] = credential
+			return credential, true, nil
+		}
+	}
+	if c.prompter == nil {
+		return Credential{}, false, nil
+	}
+	credential, err = c.prompter.PromptCredential(ctx, req)
+	if err != nil {
+		return Credential{}, false, fmt.Errorf("credentials for %s: %w", req.Host, err)
+	}
+	c.hosts[req.Host] = credential
+	return credential, true, nil
+}
+
+// authTransport adds credentials to requests, renewing them when a request is denied.
+type authTransport struct {
+	credentials *credentials
+	next        http.RoundTripper
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("Authorization") != "" {
+		return t.next.RoundTrip(req) //nolint:wrapcheck // transport errors are passed through
+	}
+	if provider, ok := t.credentials.tokenProvider(req.URL); ok {
+		token, err := provider.Token(req.Context())
+		if err != nil {
+			return nil, fmt.Errorf("token for %s: %w", req.URL.Host, err)
+		}
+		req = req.Clone(req.Context())
+		req.Header.Set("Authorization", Credential{Token: token}.authorization())
+		return t.next.RoundTrip(req) //nolint:wrapcheck // transport errors are passed through
+	}
+	host := req.URL.Host
+	credential, ok := t.credentials.lookup(host)
+	for attempt := 1; ; attempt++ {
+		attemptReq := req
+		if ok {
+			attemptReq = req.Clone(req.Context())
+			if attempt > 1 && req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, fmt.Errorf("rewinding request body: %w", err)
+				}
+				attemptReq.Body = body
+			}
+			attemptReq.Header.Set("Authorization", credential.authorization())
+		}
+		resp, err := t.next.RoundTrip(attemptReq)
+		if err != nil || resp.StatusCode != http.StatusUnauthorized || attempt > t.credentials.maxAttempts() {
+			return resp, err //nolint:wrapcheck // transport errors are passed through
+		}
+		if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+			// The body has been consumed, so the request can't be retried.
+			return resp, nil
+		}
+		var renewed bool
+		credential, renewed, err = t.credentials.renew(req.Context(), CredentialRequest{Host: host, URL: RedactURL(req.URL), Attempt: attempt}, credential, ok)
+		if !renewed && err == nil {
+			return resp, nil
+		}
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		ok = true
+	}
+}
+
+// gitAuthenticated runs git against repoURL with run, retrying with renewed credentials if an https:// remote denies
+// it. Credentials are passed to git in its environment rather than its arguments, scoped to the remote's host.
+func gitAuthenticated(ctx context.Context, repoURL string, run func(ctx context.Context) ([]byte, error)) ([]byte, error) {
+	cfg := configFromContext(ctx)
+	headers := gitHeaderConfig(cfg, repoURL)
+	u, err := url.Parse(repoURL)
+	if cfg.credentials == nil || err != nil || u.Scheme != "https" {
+		if len(headers) == 0 {
+			return run(ctx)
+		}
+		runCfg := *cfg
+		runCfg.gitConfig = slices.Concat(cfg.gitConfig, headers)
+		return run(contextWithConfig(ctx, &runCfg))
+	}
+	host := u.Host
+	credential, ok := cfg.credentials.lookup(host)
+	provider, hasToken := cfg.credentials.tokenProvider(u)
+	for attempt := 1; ; attempt++ {
+		if hasToken {
+			token, err := provider.Token(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("token for %s: %w", host, err)
+			}
+			credential, ok = Credential{Token: token}, true
+		}
+		runCfg := *cfg
+		runCfg.gitEnv = slices.Concat(cfg.gitEnv, []string{"GIT_TERMINAL_PROMPT=0"})
+		runCfg.gitConfig = slices.Concat(cfg.gitConfig, headers)
+		if ok {
+			runCfg.gitConfig = slices.Concat(runCfg.gitConfig, []gitConfigEntry{{
+				key:   "http.https://" + host + "/.extraHeader",
+				value: "Authorization: " + credential.authorization(),
+			}})
+		}
+		output, err := run(contextWithConfig(ctx, &runCfg))
+		if err == nil || hasToken || attempt > cfg.credentials.maxAttempts() || !gitAuthFailed(output, err) {
+			return output, err
+		}
+		var renewed bool
+		var renewErr error
+		credential, renewed, renewErr = cfg.credentials.renew(ctx, CredentialRequest{Host: host, URL: redactSource(repoURL), Attempt: attempt}, credential, ok)
+		if renewErr != nil {
+			return nil, renewErr
+		} else if !renewed {
+			return output, err
+		}
+		ok = true
+	}
+}
+
+// gitAuthFailed reports whether git failed to authenticate with an https:// remote, given its output.
+func gitAuthFailed(output []byte, err error) bool {
+	text := string(output)
+	if exitErr := (*exec.ExitError)(nil); errors.As(err, &exitErr) {
+		text += string(exitErr.Stderr)
+	}
+	return strings.Contains(text, "Authentication failed") ||
+		strings.Contains(text, "could not read Username") ||
+		strings.Contains(text, "could not read Password") ||
+		strings.Contains(text, "The requested URL returned error: 401")
+}
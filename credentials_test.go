@@ -0,0 +1,144 @@
+package getit //nolint:testpackage
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/cgi"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+// authServer serves testdata/archive.tar.gz to requests with the given Authorization header.
+func authServer(t *testing.T, authorization string) *httptest.Server {
+	t.Helper()
+	archive, err := os.ReadFile(filepath.Join("testdata", "archive.tar.gz"))
+	assert.NoError(t, err)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != authorization {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write(archive)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// gitHTTPServer serves the repository in repoDir over smart HTTPS at /repo, to requests with the given Authorization
+// header.
+func gitHTTPServer(t *testing.T, repoDir string, authorization string) *httptest.Server {
+	t.Helper()
+	execPath, err := exec.Command("git", "--exec-path").Output()
+	assert.NoError(t, err)
+	root := t.TempDir()
+	assert.NoError(t, os.Symlink(repoDir, filepath.Join(root, "repo")))
+	backend := &cgi.Handler{
+		Path: filepath.Join(strings.TrimSpace(string(execPath)), "git-http-backend"),
+		Env:  []string{"GIT_PROJECT_ROOT=" + root, "GIT_HTTP_EXPORT_ALL=1"},
+	}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != authorization {
+			w.Header().Set("WWW-Authenticate", `Basic realm="git"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		backend.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("GIT_SSL_NO_VERIFY", "1")
+	return server
+}
+
+// prompter returns credentials in turn, recording the requests it is called with.
+type prompter struct {
+	mu          sync.Mutex
+	credentials []Credential
+	requests    []CredentialRequest
+}
+
+func (p *prompter) PromptCredential(_ context.Context, req CredentialRequest) (Credential, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.requests = append(p.requests, req)
+	if len(p.credentials) == 0 {
+		return Credential{}, errors.New("cancelled")
+	}
+	credential := p.credentials[0]
+	p.credentials = p.credentials[1:]
+	return credential, nil
+}
+
+func TestCredentialPrompterHTTP(t *testing.T) {
+	server := authServer(t, "Basic dXNlcjpwYXNz")
+	host := strings.TrimPrefix(server.URL, "http://")
+	source := server.URL + "/archive.tar.gz"
+	ctx := context.Background()
+
+	t.Run("Retry", func(t *testing.T) {
+		p := &prompter{credentials: []Credential{{Username: "user", Password: "wrong"}, {Username: "user", Password: "pass"}}}
+		fetcher := New([]Resolver{NewTAR()}, nil, WithCredentialPrompter(p))
+		assert.NoError(t, fetcher.Fetch(ctx, source, t.TempDir()))
+		// Credentials are remembered.
+		assert.NoError(t, fetcher.Fetch(ctx, source, t.TempDir()))
+		assert.Equal(t, []CredentialRequest{
+			{Host: host, URL: source, Attempt: 1},
+			{Host: host, URL: source, Attempt: 2},
+		}, p.requests)
+	})
+
+	t.Run("Declined", func(t *testing.T) {
+		fetcher := New([]Resolver{NewTAR()}, nil, WithCredentialPrompter(&prompter{}))
+		err := fetcher.Fetch(ctx, source, t.TempDir())
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "credentials for "+host+": cancelled")
+	})
+
+	t.Run("Exhausted", func(t *testing.T) {
+		wrong := Credential{Token: "wrong"}
+		p := &prompter{credentials: []Credential{wrong, wrong, wrong, wrong}}
+		fetcher := New([]Resolver{NewTAR()}, nil, WithCredentialPrompter(p))
+		err := fetcher.Fetch(ctx, source, t.TempDir())
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "401 Unauthorized")
+		assert.Equal(t, maxCredentialAttempts, len(p.requests))
+	})
+
+	t.Run("ExplicitHeader", func(t *testing.T) {
+		p := &prompter{credentials: []Credential{{Username: "user", Password: "pass"}}}
+		fetcher := New([]Resolver{NewTAR()}, nil, WithCredentialPrompter(p), WithHeaders(http.Header{"Authorization": {"Bearer explicit"}}))
+		assert.Error(t, fetcher.Fetch(ctx, source, t.TempDir()))
+		assert.Equal(t, 0, len(p.requests))
+	})
+}
+
+func TestCredentialPrompterGit(t *testing.T) {
+	repoDir, _ := createTestRepo(t)
+	server := gitHTTPServer(t, repoDir, "Bearer token")
+	host := strings.TrimPrefix(server.URL, "https://")
+	p := &prompter{credentials: []Credential{{Token: "token"}}}
+	fetcher := New([]Resolver{NewGit()}, nil, WithCredentialPrompter(p))
+	ctx := context.Background()
+
+	dest := t.TempDir()
+	assert.NoError(t, fetcher.Fetch(ctx, "git+"+server.URL+"/repo", dest))
+	content, err := os.ReadFile(filepath.Join(dest, "file.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "hello from test\n", string(content))
+	_, err = fetcher.Versions(ctx, "git+"+server.URL+"/repo")
+	assert.NoError(t, err)
+	assert.Equal(t, []CredentialRequest{{Host: host, URL: server.URL + "/repo", Attempt: 1}}, p.requests)
+
+	// Without credentials git fails rather than prompting on the terminal.
+	fetcher = New([]Resolver{NewGit()}, nil, WithCredentialPrompter(&prompter{}))
+	err = fetcher.Fetch(ctx, "git+"+server.URL+"/repo", t.TempDir())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "credentials for "+host+": cancelled")
+}
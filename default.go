@@ -3,22 +3,132 @@ package getit
 import "context"
 
 // Default Fetcher with built-in resolvers and mappers.
-var Default = New(
-	[]Resolver{
-		NewFile(),
-		NewGit(),
-		NewTAR(),
-		NewZIP(),
-	},
-	[]Mapper{
-		GitHub,
-		GitHubOrgRepo,
-		FilePath,
-	},
+//
+// Packages providing further resolvers and mappers should register them with [AddResolver] and [AddMapper], which are
+// safe to call concurrently, rather than replacing Default.
+var Default = DefaultWith(EnableFile, EnableGit, EnableTAR, EnableZIP, EnableHTTP, EnableArtifact)
+
+// Feature configures a Fetcher constructed with [DefaultWith]. It is either a built-in resolver such as [EnableGit],
+// or an [Option].
+type Feature interface {
+	applyFeature(features *featureSet)
+}
+
+// builtin is a built-in resolver, with the mappers that produce its URLs.
+type builtin uint
+
+// Built-in resolvers that may be enabled with [DefaultWith].
+const (
+	// EnableFile enables the [File] resolver and the [FilePath] mapper.
+	EnableFile builtin = 1 << iota
+	// EnableGit enables the [Git] and [Bundle] resolvers and the [Gist], [GitHub] and [GitHubOrgRepo] mappers.
+	EnableGit
+	// EnableTAR enables the [TAR] resolver.
+	EnableTAR
+	// EnableZIP enables the [ZIP] resolver.
+	EnableZIP
+	// EnableHTTP enables the [HTTP] resolver for single files.
+	EnableHTTP
+	// EnableArtifact enables the [Artifact] resolver for CI artifacts.
+	EnableArtifact
 )
 
+func (b builtin) applyFeature(features *featureSet) { features.builtins |= b }
+
+func (o Option) applyFeature(features *featureSet) { features.options = append(features.options, o) }
+
+type featureSet struct {
+	builtins builtin
+	options  []Option
+}
+
+// DefaultWith returns a Fetcher like [Default], with only the given built-in resolvers and their mappers enabled,
+// configured by any options given. Resolvers and mappers keep the order they have in [Default] regardless of the
+// order they are enabled in, eg. for a deployment that must not access the local filesystem:
+//
+//	getit.DefaultWith(getit.EnableGit, getit.EnableTAR, getit.WithLogger(logger))
+func DefaultWith(features ...Feature) *Fetcher {
+	set := &featureSet{}
+	for _, feature := range features {
+		feature.applyFeature(set)
+	}
+	var resolvers []Resolver
+	var mappers []Mapper
+	enabled := func(b builtin) bool { return set.builtins&b != 0 }
+	if enabled(EnableFile) {
+		resolvers = append(resolvers, NewFile())
+	}
+	if enabled(EnableGit) {
+		resolvers = append(resolvers, NewGit(), NewBundle())
+		mappers = append(mappers, Gist, GitHub, GitHubOrgRepo)
+	}
+	if enabled(EnableTAR) {
+		resolvers = append(resolvers, NewTAR())
+	}
+	if enabled(EnableZIP) {
+		resolvers = append(resolvers, NewZIP())
+	}
+	if enabled(EnableArtifact) {
+		resolvers = append(resolvers, NewArtifact())
+	}
+	if enabled(EnableHTTP) {
+		resolvers = append(resolvers, NewHTTP())
+	}
+	if enabled(EnableFile) {
+		mappers = append(mappers, FilePath)
+	}
+	return New(resolvers, mappers, set.options...)
+}
+
+// AddResolver adds resolvers to [Default], ahead of the built-in resolvers. It is safe to call concurrently, eg. from
+// the init functions of plugin packages.
+func AddResolver(resolvers ...Resolver) { Default.AddResolver(resolvers...) }
+
+// AddMapper adds mappers to [Default], ahead of the built-in mappers. It is safe to call concurrently, eg. from the
+// init functions of plugin packages.
+func AddMapper(mappers ...Mapper) { Default.AddMapper(mappers...) }
+
 // Resolve a source string to a Source and URL.
 func Resolve(source string) (Resolver, Source, error) { return Default.Resolve(source) }
 
+// Detect reports the name of the resolver that would handle source, without touching the filesystem.
+func Detect(source string) (string, bool) { return Default.Detect(source) }
+
 // Fetch fetches an archive from a source and unpacks it to a destination.
 func Fetch(ctx context.Context, source, dest string) error { return Default.Fetch(ctx, source, dest) }
+
+// FetchWithOptions fetches an archive from a source and unpacks it to a destination, controlled by options.
+func FetchWithOptions(ctx context.Context, source, dest string, options FetchOptions) (*FetchResult, error) {
+	return Default.FetchWithOptions(ctx, source, dest, options)
+}
+
+// FetchAny fetches the first source that succeeds from an ordered list of sources and unpacks it to a destination.
+func FetchAny(ctx context.Context, sources []string, dest string) error {
+	return Default.FetchAny(ctx, sources, dest)
+}
+
+// FetchAll makes a batch of fetches concurrently, continuing past failures, and reports the outcome of each.
+func FetchAll(ctx context.Context, fetches []BatchFetch, options BatchOptions) (*BatchReport, error) {
+	return Default.FetchAll(ctx, fetches, options)
+}
+
+// Verify re-fetches source and compares it against dest, returning the differences.
+func Verify(ctx context.Context, source, dest string) (ManifestDiff, error) {
+	return Default.Verify(ctx, source, dest)
+}
+
+// Install fetches source and installs the executables it contains into binDir.
+func Install(ctx context.Context, source, binDir string, options InstallOptions) ([]string, error) {
+	return Default.Install(ctx, source, binDir, options)
+}
+
+// Versions lists the available versions of a source, eg. the tags of a git repository, newest first.
+func Versions(ctx context.Context, source string) ([]string, error) {
+	return Default.Versions(ctx, source)
+}
+
+// Pack creates an archive of srcDir and writes it to dest, the inverse of [Fetch].
+func Pack(ctx context.Context, srcDir, dest string) error { return Default.Pack(ctx, srcDir, dest) }
+
+// Push writes the contents of srcDir to dest, the inverse of [Fetch].
+func Push(ctx context.Context, srcDir, dest string) error { return Default.Push(ctx, srcDir, dest) }
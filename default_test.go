@@ -0,0 +1,88 @@
+package getit_test
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/block/getit"
+)
+
+func TestDefaultWith(t *testing.T) {
+	tests := []struct {
+		name     string
+		features []getit.Feature
+		source   string
+		expected string
+	}{
+		{name: "Git", features: []getit.Feature{getit.EnableGit}, source: "github.com/user/repo", expected: "Git"},
+		{name: "GitShorthand", features: []getit.Feature{getit.EnableGit}, source: "user/repo", expected: "Git"},
+		{name: "Gist", features: []getit.Feature{getit.EnableGit}, source: "gist.github.com/user/6cad326836d38bd3a7ae", expected: "Git"},
+		{name: "Bundle", features: []getit.Feature{getit.EnableGit}, source: "https://example.com/repo.bundle", expected: "Bundle"},
+		{name: "FileDisabled", features: []getit.Feature{getit.EnableGit, getit.EnableTAR}, source: "file:///tmp/dir"},
+		{name: "FilePathDisabled", features: []getit.Feature{getit.EnableGit, getit.EnableTAR}, source: "/tmp/dir"},
+		{name: "File", features: []getit.Feature{getit.EnableFile}, source: "file:///tmp/dir", expected: "File"},
+		{name: "ZIPDisabled", features: []getit.Feature{getit.EnableTAR}, source: "https://example.com/archive.zip"},
+		{name: "Order", features: []getit.Feature{getit.EnableHTTP, getit.EnableTAR}, source: "https://example.com/archive.tar.gz", expected: "TAR"},
+		{name: "None", source: "https://example.com/archive.tar.gz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, ok := getit.DefaultWith(tt.features...).Detect(tt.source)
+			assert.Equal(t, tt.expected != "", ok)
+			assert.Equal(t, tt.expected, name)
+		})
+	}
+}
+
+func TestDefaultWithOptions(t *testing.T) {
+	logs := &bytes.Buffer{}
+	logger := slog.New(slog.NewTextHandler(logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	fetcher := getit.DefaultWith(getit.EnableTAR, getit.WithLogger(logger))
+	_, _, err := fetcher.Resolve("https://example.com/archive.tar.gz")
+	assert.NoError(t, err)
+	assert.Contains(t, logs.String(), "msg=resolve")
+}
+
+func TestAddResolverConcurrently(t *testing.T) {
+	fetcher := getit.DefaultWith(getit.EnableGit, getit.EnableTAR)
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			scheme := fmt.Sprintf("plugin%d", i)
+			fetcher.AddResolver(getit.NewMemResolver(nil))
+			fetcher.AddMapper(func(source string) (string, bool) {
+				rest, ok := strings.CutPrefix(source, scheme+":")
+				return "mem://" + rest, ok
+			})
+		}()
+		go func() {
+			defer wg.Done()
+			_, _ = fetcher.Detect("https://example.com/archive.tar.gz")
+		}()
+	}
+	wg.Wait()
+	for i := range 10 {
+		name, ok := fetcher.Detect(fmt.Sprintf("plugin%d:tree", i))
+		assert.True(t, ok)
+		assert.Equal(t, "MemResolver", name)
+	}
+	name, ok := fetcher.Detect("https://example.com/archive.tar.gz")
+	assert.True(t, ok)
+	assert.Equal(t, "TAR", name)
+}
+
+func TestAddResolverPrecedence(t *testing.T) {
+	fetcher := getit.DefaultWith(getit.EnableTAR)
+	fetcher.AddResolver(getit.NewHTTP())
+	name, ok := fetcher.Detect("https://example.com/archive.tar.gz")
+	assert.True(t, ok)
+	assert.Equal(t, "HTTP", name)
+}
@@ -0,0 +1,169 @@
+package getit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// syncTree updates dest to match the tree in staging, moving across only the entries that differ. Files whose
+// content, permissions and link targets are unchanged are left in place, so an unchanged file is never rewritten. If
+// prune is set, entries of dest that are missing from staging are removed.
+//
+// If dest is missing or empty, staging is simply promoted.
+func syncTree(ctx context.Context, staging, dest string, prune bool) error {
+	if isEmptyOrMissing(dest) {
+		return promote(ctx, staging, dest)
+	}
+	cfg := configFromContext(ctx)
+	syncTimes := cfg.options.PreserveTimes || cfg.options.Deterministic
+	seen := map[string]bool{}
+	var written, unchanged, deleted int
+	err := filepath.WalkDir(staging, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := contextError(ctx); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(staging, path)
+		if err != nil {
+			return err //nolint:wrapcheck // wrapped below
+		}
+		if rel == "." {
+			return nil
+		}
+		seen[rel] = true
+		target := filepath.Join(dest, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err //nolint:wrapcheck // wrapped below
+		}
+		same, err := sameEntry(path, target, info, syncTimes)
+		if err != nil {
+			return err
+		}
+		if same {
+			if !d.IsDir() {
+				unchanged++
+			}
+			return nil
+		}
+		if err := os.RemoveAll(target); err != nil {
+			return err //nolint:wrapcheck // wrapped below
+		}
+		if err := os.Rename(path, target); err != nil {
+			return err //nolint:wrapcheck // wrapped below
+		}
+		if d.IsDir() {
+			// The whole directory was moved across, so record its contents rather than walking them.
+			if err := markSeen(target, rel, seen); err != nil {
+				return err
+			}
+			return filepath.SkipDir
+		}
+		written++
+		return nil
+	})
+	if err == nil && prune {
+		deleted, err = pruneTree(dest, seen)
+	}
+	if err != nil {
+		return fmt.Errorf("syncing %s: %w", dest, err)
+	}
+	cfg.logger.DebugContext(ctx, "synced", "dest", dest, "written", written, "unchanged", unchanged, "deleted", deleted)
+	return nil
+}
+
+// sameEntry reports whether target already matches the staged entry at path, which is described by info. Directories
+// and unchanged files whose permissions or, if syncTimes is set, modification times differ are updated in place.
+func sameEntry(path, target string, info fs.FileInfo, syncTimes bool) (bool, error) {
+	existing, err := os.Lstat(target)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err //nolint:wrapcheck // wrapped by the caller
+	}
+	if existing.Mode().Type() != info.Mode().Type() {
+		return false, nil
+	}
+	switch {
+	case info.Mode()&fs.ModeSymlink != 0:
+		want, err := os.Readlink(path)
+		if err != nil {
+			return false, err //nolint:wrapcheck // wrapped by the caller
+		}
+		got, err := os.Readlink(target)
+		return err == nil && got == want, nil
+	case info.Mode().IsRegular():
+		if existing.Size() != info.Size() {
+			return false, nil
+		}
+		want, err := hashFile(path)
+		if err != nil {
+			return false, err
+		}
+		got, err := hashFile(target)
+		if err != nil || got != want {
+			return false, err
+		}
+	case !info.IsDir():
+		// Special files are always replaced.
+		return false, nil
+	}
+	if existing.Mode().Perm() != info.Mode().Perm() {
+		if err := os.Chmod(target, info.Mode().Perm()); err != nil {
+			return false, err //nolint:wrapcheck // wrapped by the caller
+		}
+	}
+	if syncTimes && !info.IsDir() && !existing.ModTime().Equal(info.ModTime()) {
+		if err := os.Chtimes(target, info.ModTime(), info.ModTime()); err != nil {
+			return false, err //nolint:wrapcheck // wrapped by the caller
+		}
+	}
+	return true, nil
+}
+
+// markSeen records the paths beneath dir, a directory moved to rel, in seen.
+func markSeen(dir, rel string, seen map[string]bool) error {
+	return filepath.WalkDir(dir, func(path string, _ fs.DirEntry, err error) error { //nolint:wrapcheck // wrapped by the caller
+		if err != nil {
+			return err
+		}
+		sub, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err //nolint:wrapcheck // wrapped by the caller
+		}
+		seen[filepath.Join(rel, sub)] = true
+		return nil
+	})
+}
+
+// pruneTree removes the entries of dest whose paths are not in seen, returning how many were removed.
+func pruneTree(dest string, seen map[string]bool) (int, error) {
+	deleted := 0
+	err := filepath.WalkDir(dest, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dest, path)
+		if err != nil {
+			return err //nolint:wrapcheck // wrapped by the caller
+		}
+		if rel == "." || seen[rel] {
+			return nil
+		}
+		if err := os.RemoveAll(path); err != nil {
+			return err //nolint:wrapcheck // wrapped by the caller
+		}
+		deleted++
+		if d.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	return deleted, err //nolint:wrapcheck // wrapped by the caller
+}
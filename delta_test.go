@@ -0,0 +1,154 @@
+package getit_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/block/getit"
+)
+
+func TestFetchWithOptionsDelta(t *testing.T) {
+	srcDir := t.TempDir()
+	for name, content := range map[string]string{
+		"same.txt":        "same\n",
+		"changed.txt":     "new\n",
+		"added.txt":       "added\n",
+		"dir/nested.txt":  "nested\n",
+		"newdir/file.txt": "file\n",
+	} {
+		path := filepath.Join(srcDir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	assert.NoError(t, os.Symlink("same.txt", filepath.Join(srcDir, "link")))
+
+	dest := t.TempDir()
+	for name, content := range map[string]string{
+		"same.txt":       "same\n",
+		"changed.txt":    "old\n",
+		"stale.txt":      "stale\n",
+		"dir/nested.txt": "nested\n",
+	} {
+		path := filepath.Join(dest, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	assert.NoError(t, os.Symlink("changed.txt", filepath.Join(dest, "link")))
+	before := map[string]os.FileInfo{}
+	for _, name := range []string{"same.txt", "changed.txt", "dir/nested.txt"} {
+		info, err := os.Stat(filepath.Join(dest, name))
+		assert.NoError(t, err)
+		before[name] = info
+	}
+
+	fetcher := getit.New([]getit.Resolver{getit.NewFile()}, nil)
+	_, err := fetcher.FetchWithOptions(context.Background(), "file://"+srcDir, dest, getit.FetchOptions{Delta: true})
+	assert.NoError(t, err)
+
+	for name, content := range map[string]string{
+		"same.txt":        "same\n",
+		"changed.txt":     "new\n",
+		"added.txt":       "added\n",
+		"stale.txt":       "stale\n",
+		"dir/nested.txt":  "nested\n",
+		"newdir/file.txt": "file\n",
+	} {
+		data, err := os.ReadFile(filepath.Join(dest, name))
+		assert.NoError(t, err, name)
+		assert.Equal(t, content, string(data), name)
+	}
+	target, err := os.Readlink(filepath.Join(dest, "link"))
+	assert.NoError(t, err)
+	assert.Equal(t, "same.txt", target)
+
+	for name, wantSame := range map[string]bool{"same.txt": true, "dir/nested.txt": true, "changed.txt": false} {
+		info, err := os.Stat(filepath.Join(dest, name))
+		assert.NoError(t, err)
+		assert.Equal(t, wantSame, os.SameFile(before[name], info), "%s rewritten", name)
+	}
+	staging, err := filepath.Glob(filepath.Join(filepath.Dir(dest), ".*.getit-*"))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(staging), "staging directory should be removed")
+}
+
+func TestFetchWithOptionsDeltaPermissions(t *testing.T) {
+	srcDir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(srcDir, "tool"), []byte("#!/bin/sh\n"), 0o755))
+	dest := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dest, "tool"), []byte("#!/bin/sh\n"), 0o644))
+	before, err := os.Stat(filepath.Join(dest, "tool"))
+	assert.NoError(t, err)
+
+	fetcher := getit.New([]getit.Resolver{getit.NewFile()}, nil)
+	_, err = fetcher.FetchWithOptions(context.Background(), "file://"+srcDir, dest, getit.FetchOptions{Delta: true})
+	assert.NoError(t, err)
+
+	after, err := os.Stat(filepath.Join(dest, "tool"))
+	assert.NoError(t, err)
+	assert.True(t, os.SameFile(before, after), "unchanged content should not be rewritten")
+	assert.Equal(t, os.FileMode(0o755), after.Mode().Perm())
+}
+
+func TestFetchWithOptionsDeltaMissingDest(t *testing.T) {
+	srcDir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(srcDir, "file.txt"), []byte("file\n"), 0o644))
+	dest := filepath.Join(t.TempDir(), "dest")
+
+	fetcher := getit.New([]getit.Resolver{getit.NewFile()}, nil)
+	_, err := fetcher.FetchWithOptions(context.Background(), "file://"+srcDir, dest, getit.FetchOptions{Delta: true})
+	assert.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(dest, "file.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "file\n", string(data))
+}
+
+func TestFetchWithOptionsDelete(t *testing.T) {
+	for _, delta := range []bool{false, true} {
+		t.Run(map[bool]string{false: "Promote", true: "Delta"}[delta], func(t *testing.T) {
+			srcDir := t.TempDir()
+			assert.NoError(t, os.MkdirAll(filepath.Join(srcDir, "dir"), 0o755))
+			assert.NoError(t, os.WriteFile(filepath.Join(srcDir, "keep.txt"), []byte("keep\n"), 0o644))
+			assert.NoError(t, os.WriteFile(filepath.Join(srcDir, "dir", "keep.txt"), []byte("keep\n"), 0o644))
+
+			dest := t.TempDir()
+			for _, name := range []string{"keep.txt", "stale.txt", "dir/keep.txt", "dir/stale.txt", "staledir/file.txt"} {
+				path := filepath.Join(dest, name)
+				assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+				assert.NoError(t, os.WriteFile(path, []byte("old\n"), 0o644))
+			}
+
+			fetcher := getit.New([]getit.Resolver{getit.NewFile()}, nil)
+			_, err := fetcher.FetchWithOptions(context.Background(), "file://"+srcDir, dest, getit.FetchOptions{Delta: delta, Delete: true})
+			assert.NoError(t, err)
+
+			manifest, err := getit.BuildManifest(context.Background(), dest)
+			assert.NoError(t, err)
+			var paths []string
+			for _, entry := range manifest.Entries {
+				paths = append(paths, entry.Path)
+			}
+			assert.Equal(t, []string{"dir", "dir/keep.txt", "keep.txt"}, paths)
+			data, err := os.ReadFile(filepath.Join(dest, "dir", "keep.txt"))
+			assert.NoError(t, err)
+			assert.Equal(t, "keep\n", string(data))
+		})
+	}
+}
+
+func TestFetchWithOptionsDeleteFailedFetch(t *testing.T) {
+	dest := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dest, "file.txt"), []byte("old\n"), 0o644))
+
+	fetcher := getit.New([]getit.Resolver{getit.NewFile()}, nil)
+	_, err := fetcher.FetchWithOptions(context.Background(), "file://"+filepath.Join(t.TempDir(), "missing"), dest, getit.FetchOptions{Delete: true})
+	assert.Error(t, err)
+
+	data, err := os.ReadFile(filepath.Join(dest, "file.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "old\n", string(data))
+}
@@ -0,0 +1,96 @@
+package getit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+// DiskSpaceCheck configures the check made by [WithDiskSpaceCheck].
+type DiskSpaceCheck struct {
+	// ExpansionRatio multiplies the size of the source to estimate the space needed to unpack it, eg. 3 for typical
+	// compressed tarballs, or 2 for git repositories, which are checked out alongside their packed history. Defaults
+	// to 1.
+	ExpansionRatio float64
+	// Reserve is the number of bytes that must remain free after the fetch.
+	Reserve int64
+}
+
+// WithDiskSpaceCheck checks that the filesystem holding the destination has space for a source before fetching it,
+// failing fast with an *[InsufficientSpaceError] rather than part way through extraction.
+//
+// The size of a source is found with [Stater], eg. the Content-Length of an HTTP response, or for git repositories
+// hosted on GitHub or GitLab, the repository size reported by the forge's API. Sources of unknown size, and
+// platforms where free space can't be determined, are not checked.
+func WithDiskSpaceCheck(check DiskSpaceCheck) Option {
+	return func(f *Fetcher) { f.config.diskSpace = &check }
+}
+
+// InsufficientSpaceError is returned when the destination of a fetch doesn't have space for the source.
+type InsufficientSpaceError struct {
+	// Dest is the destination of the fetch.
+	Dest string
+	// Required is the estimated number of bytes needed, including [DiskSpaceCheck.Reserve].
+	Required int64
+	// Available is the number of bytes free on the filesystem holding Dest.
+	Available int64
+}
+
+func (i *InsufficientSpaceError) Error() string {
+	return fmt.Sprintf("insufficient disk space at %s: %d bytes required, %d available", i.Dest, i.Required, i.Available)
+}
+
+// check returns an *InsufficientSpaceError if the filesystem holding dest doesn't have space for source, given its
+// size if known, or -1.
+func (d *DiskSpaceCheck) check(ctx context.Context, resolver Resolver, source Source, dest string, size int64) error {
+	cfg := configFromContext(ctx)
+	if _, ok := resolver.(*Git); ok && size < 0 {
+		if forge, repo, ok := forgeRepo(source.URL); ok {
+			var err error
+			if size, err = forge.repoSize(ctx, repo); err != nil {
+				cfg.logger.WarnContext(ctx, "repository size lookup failed", "repo", repo, "error", err)
+			}
+		}
+	}
+	if size < 0 {
+		cfg.logger.DebugContext(ctx, "skipping disk space check, source size unknown", "dest", dest)
+		return nil
+	}
+	available, ok, err := freeSpace(existingAncestor(dest))
+	if err != nil {
+		return fmt.Errorf("checking disk space at %s: %w", dest, err)
+	} else if !ok {
+		return nil
+	}
+	ratio := d.ExpansionRatio
+	if ratio <= 0 {
+		ratio = 1
+	}
+	estimate := float64(size)*ratio + float64(d.Reserve)
+	if estimate > float64(available) {
+		required := int64(math.MaxInt64)
+		if estimate < math.MaxInt64 {
+			required = int64(estimate)
+		}
+		return &InsufficientSpaceError{Dest: dest, Required: required, Available: available}
+	}
+	return nil
+}
+
+// existingAncestor returns path, or its nearest ancestor that exists.
+func existingAncestor(path string) string {
+	path = filepath.Clean(path)
+	for {
+		if _, err := os.Stat(path); err == nil || !errors.Is(err, os.ErrNotExist) {
+			return path
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !linux && !darwin && !windows
+
+package getit
+
+// freeSpace reports that free space can't be determined on this platform.
+func freeSpace(string) (int64, bool, error) {
+	return 0, false, nil
+}
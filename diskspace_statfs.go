@@ -0,0 +1,14 @@
+//go:build linux || darwin
+
+package getit
+
+import "syscall"
+
+// freeSpace returns the number of bytes available to unprivileged users on the filesystem holding path.
+func freeSpace(path string) (int64, bool, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, false, err //nolint:wrapcheck // wrapped by the caller
+	}
+	return int64(stat.Bavail) * int64(stat.Bsize), true, nil //nolint:gosec // block counts fit in int64
+}
@@ -0,0 +1,97 @@
+package getit //nolint:testpackage
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestWithDiskSpaceCheck(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" && runtime.GOOS != "windows" {
+		t.Skip("free space is not available on this platform")
+	}
+	data, err := os.ReadFile(filepath.Join("testdata", "archive.tar.gz"))
+	assert.NoError(t, err)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name  string
+		check DiskSpaceCheck
+		fails bool
+	}{
+		{name: "Fits", check: DiskSpaceCheck{ExpansionRatio: 10}},
+		{name: "Reserve", check: DiskSpaceCheck{Reserve: math.MaxInt64 / 2}, fails: true},
+		{name: "Ratio", check: DiskSpaceCheck{ExpansionRatio: math.MaxInt64 / 2}, fails: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetcher := New([]Resolver{NewTAR()}, nil, WithDiskSpaceCheck(tt.check))
+			dest := filepath.Join(t.TempDir(), "missing", "dest")
+			err := fetcher.Fetch(context.Background(), server.URL+"/archive.tar.gz", dest)
+			if !tt.fails {
+				assert.NoError(t, err)
+				return
+			}
+			var spaceErr *InsufficientSpaceError
+			assert.True(t, errors.As(err, &spaceErr), "%v", err)
+			assert.Equal(t, dest, spaceErr.Dest)
+			assert.True(t, spaceErr.Available > 0)
+			_, err = os.Stat(dest)
+			assert.True(t, os.IsNotExist(err), "nothing should be written")
+		})
+	}
+}
+
+func TestDiskSpaceCheckRepoSize(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" && runtime.GOOS != "windows" {
+		t.Skip("free space is not available on this platform")
+	}
+	fakeForges(t, map[string]string{
+		"/api/v3/repos/owner/huge":                         `{"size": 1000000000000}`,
+		"/api/v3/repos/owner/small":                        `{"size": 1}`,
+		"/api/v4/projects/group%2Fhuge?statistics=true":    `{"statistics": {"repository_size": 1000000000000000}}`,
+		"/api/v4/projects/group%2Fprivate?statistics=true": `{}`,
+		"/api/v4/projects/group%2Fsmall?statistics=true":   `{"statistics": {"repository_size": 1024}}`,
+	})
+	tests := []struct {
+		source string
+		fails  bool
+	}{
+		{source: "git+https://github.test/owner/huge", fails: true},
+		{source: "git+https://github.test/owner/small"},
+		{source: "git+https://github.test/owner/missing"},
+		{source: "git+https://gitlab.test/group/huge", fails: true},
+		{source: "git+https://gitlab.test/group/private"},
+		{source: "git+https://gitlab.test/group/small"},
+		{source: "git+https://example.com/owner/huge"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			u, err := url.Parse(tt.source)
+			assert.NoError(t, err)
+			cfg := defaultConfig()
+			cfg.finalise()
+			ctx := contextWithConfig(context.Background(), &cfg)
+			check := &DiskSpaceCheck{}
+			err = check.check(ctx, NewGit(), Source{URL: u}, t.TempDir(), -1)
+			if tt.fails {
+				var spaceErr *InsufficientSpaceError
+				assert.True(t, errors.As(err, &spaceErr), "%v", err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package getit
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var getDiskFreeSpaceEx = syscall.NewLazyDLL("kernel32.dll").NewProc("GetDiskFreeSpaceExW")
+
+// freeSpace returns the number of bytes available to the current user on the volume holding path.
+func freeSpace(path string) (int64, bool, error) {
+	name, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, false, err //nolint:wrapcheck // wrapped by the caller
+	}
+	var available uint64
+	if ok, _, err := getDiskFreeSpaceEx.Call(uintptr(unsafe.Pointer(name)), uintptr(unsafe.Pointer(&available)), 0, 0); ok == 0 {
+		return 0, false, err //nolint:wrapcheck // wrapped by the caller
+	}
+	return int64(available), true, nil //nolint:gosec // free space fits in int64
+}
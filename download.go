@@ -0,0 +1,209 @@
+package getit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// defaultChunkSize is the size of ranged requests when [Downloads.ChunkSize] is not set.
+const defaultChunkSize = 16 << 20
+
+// Downloads controls how remote archives are transferred.
+type Downloads struct {
+	// Concurrency is the number of ranged requests used to download an archive in parallel from servers that support
+	// them. Archives are still streamed to extraction in order. Values below 2 disable parallel downloads.
+	Concurrency int
+	// ChunkSize is the size of each ranged request, defaulting to 16MiB. Up to Concurrency chunks are buffered in
+	// memory, and archives no larger than one chunk are downloaded with a single request.
+	ChunkSize int64
+}
+
+// WithDownloads controls how a [Fetcher] downloads remote archives.
+func WithDownloads(downloads Downloads) Option {
+	return func(f *Fetcher) { f.config.downloads = downloads }
+}
+
+func (d Downloads) chunkSize() int64 {
+	if d.ChunkSize <= 0 {
+		return defaultChunkSize
+	}
+	return d.ChunkSize
+}
+
+// contentRange parses the start offset and total size from a Content-Range header. total is -1 if unknown.
+func contentRange(header string) (start, total int64, err error) {
+	spec, ok := strings.CutPrefix(header, "bytes ")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid Content-Range %q", header)
+	}
+	byteRange, size, ok := strings.Cut(spec, "/")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid Content-Range %q", header)
+	}
+	first, _, ok := strings.Cut(byteRange, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid Content-Range %q", header)
+	}
+	if start, err = strconv.ParseInt(first, 10, 64); err != nil {
+		return 0, 0, fmt.Errorf("invalid Content-Range %q: %w", header, err)
+	}
+	if size == "*" {
+		return start, -1, nil
+	}
+	if total, err = strconv.ParseInt(size, 10, 64); err != nil {
+		return 0, 0, fmt.Errorf("invalid Content-Range %q: %w", header, err)
+	}
+	return start, total, nil
+}
+
+// chunkedBody downloads the remainder of a ranged response in parallel chunks, returning them in order.
+type chunkedBody struct {
+	ctx     context.Context //nolint:containedctx // cancelled when the body is closed
+	cancel  context.CancelFunc
+	cfg     *config
+	u       *url.URL
+	first   io.ReadCloser
+	total   int64
+	size    int64
+	ifRange string
+	// results has one channel per chunk, each receiving exactly one result.
+	results []chan chunkResult
+	// slots bounds the number of chunks in flight or buffered.
+	slots   chan struct{}
+	next    int
+	current *bytes.Reader
+}
+
+type chunkResult struct {
+	data []byte
+	err  error
+}
+
+// newChunkedBody continues a download whose first chunk is the body of resp.
+func newChunkedBody(ctx context.Context, cfg *config, u *url.URL, resp *http.Response, total int64) *chunkedBody {
+	ctx, cancel := context.WithCancel(ctx)
+	size := cfg.downloads.chunkSize()
+	count := int((total + size - 1) / size)
+	c := &chunkedBody{
+		ctx:     ctx,
+		cancel:  cancel,
+		cfg:     cfg,
+		u:       u,
+		first:   resp.Body,
+		total:   total,
+		size:    size,
+		results: make([]chan chunkResult, count),
+		slots:   make(chan struct{}, cfg.downloads.Concurrency),
+	}
+	// Only a strong validator guarantees the remaining chunks come from the same version of the archive.
+	if etag := resp.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		c.ifRange = etag
+	} else {
+		c.ifRange = resp.Header.Get("Last-Modified")
+	}
+	for i := range c.results {
+		c.results[i] = make(chan chunkResult, 1)
+	}
+	go c.dispatch()
+	return c
+}
+
+// dispatch starts downloading each chunk as a slot becomes available.
+func (c *chunkedBody) dispatch() {
+	for i := range c.results {
+		select {
+		case c.slots <- struct{}{}:
+		case <-c.ctx.Done():
+			return
+		}
+		go func() {
+			data, err := c.fetch(i)
+			c.results[i] <- chunkResult{data: data, err: err}
+		}()
+	}
+}
+
+func (c *chunkedBody) fetch(index int) ([]byte, error) {
+	start := int64(index) * c.size
+	expected := min(c.size, c.total-start)
+	body := c.first
+	if index > 0 {
+		req, err := http.NewRequestWithContext(c.ctx, http.MethodGet, c.u.String(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("creating request: %w", err)
+		}
+		c.cfg.applyHeaders(req)
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, start+expected-1))
+		if c.ifRange != "" {
+			req.Header.Set("If-Range", c.ifRange)
+		}
+		resp, err := c.cfg.client.Do(req)
+		if err != nil {
+			if urlErr := (*url.Error)(nil); errors.As(err, &urlErr) {
+				urlErr.URL = RedactURL(c.u)
+			}
+			return nil, fmt.Errorf("chunk %d: %w", index, err)
+		}
+		body = resp.Body
+		switch resp.StatusCode {
+		case http.StatusPartialContent:
+		case http.StatusOK:
+			_ = body.Close()
+			return nil, fmt.Errorf("chunk %d: source changed during download", index)
+		default:
+			_ = body.Close()
+			return nil, fmt.Errorf("chunk %d: %s", index, resp.Status)
+		}
+		if got, _, err := contentRange(resp.Header.Get("Content-Range")); err != nil || got != start {
+			_ = body.Close()
+			return nil, fmt.Errorf("chunk %d: unexpected Content-Range %q", index, resp.Header.Get("Content-Range"))
+		}
+	}
+	defer body.Close()
+	data, err := io.ReadAll(io.LimitReader(body, expected))
+	if err != nil {
+		return nil, fmt.Errorf("chunk %d: %w", index, err)
+	}
+	if int64(len(data)) != expected {
+		return nil, fmt.Errorf("chunk %d: short read of %d/%d bytes", index, len(data), expected)
+	}
+	return data, nil
+}
+
+func (c *chunkedBody) Read(p []byte) (int, error) {
+	for {
+		if c.current != nil {
+			if c.current.Len() > 0 {
+				return c.current.Read(p) //nolint:wrapcheck // bytes.Reader only returns io.EOF
+			}
+			c.current = nil
+			<-c.slots
+		}
+		if c.next == len(c.results) {
+			return 0, io.EOF
+		}
+		select {
+		case result := <-c.results[c.next]:
+			c.next++
+			if result.err != nil {
+				c.cancel()
+				return 0, result.err
+			}
+			c.current = bytes.NewReader(result.data)
+		case <-c.ctx.Done():
+			return 0, contextError(c.ctx)
+		}
+	}
+}
+
+func (c *chunkedBody) Close() error {
+	c.cancel()
+	return c.first.Close() //nolint:wrapcheck // passthrough of the underlying body
+}
@@ -0,0 +1,80 @@
+package getit_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/block/getit"
+)
+
+func TestParallelDownload(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "archive.tar"))
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		ranges        bool
+		chunkSize     int64
+		expectedCalls int64
+	}{
+		{name: "Chunked", ranges: true, chunkSize: 1024, expectedCalls: 10},
+		{name: "SingleChunk", ranges: true, chunkSize: 1 << 20, expectedCalls: 1},
+		{name: "NoRangeSupport", chunkSize: 1024, expectedCalls: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int64
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				if !tt.ranges {
+					_, _ = w.Write(data)
+					return
+				}
+				w.Header().Set("ETag", `"v1"`)
+				http.ServeContent(w, r, "archive.tar", time.Time{}, bytes.NewReader(data))
+			}))
+			defer server.Close()
+
+			fetcher := getit.New([]getit.Resolver{getit.NewTAR()}, nil,
+				getit.WithDownloads(getit.Downloads{Concurrency: 3, ChunkSize: tt.chunkSize}))
+			dest := t.TempDir()
+			err := fetcher.Fetch(context.Background(), server.URL+"/archive.tar", dest)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedCalls, calls.Load())
+
+			content, err := os.ReadFile(filepath.Join(dest, "nested.txt"))
+			assert.NoError(t, err)
+			assert.Equal(t, "nested content\n", string(content))
+		})
+	}
+}
+
+func TestParallelDownloadSourceChanged(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "archive.tar"))
+	assert.NoError(t, err)
+	var calls atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("ETag", `"v1"`)
+		} else {
+			w.Header().Set("ETag", `"v2"`)
+		}
+		http.ServeContent(w, r, "archive.tar", time.Time{}, bytes.NewReader(data))
+	}))
+	defer server.Close()
+
+	fetcher := getit.New([]getit.Resolver{getit.NewTAR()}, nil,
+		getit.WithDownloads(getit.Downloads{Concurrency: 2, ChunkSize: 1024}))
+	err = fetcher.Fetch(context.Background(), server.URL+"/archive.tar", t.TempDir())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "source changed during download")
+}
@@ -0,0 +1,49 @@
+package getit
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+)
+
+// WithEnvExpansion expands ${VAR} references to the environment variables named by allowed in sources, before they
+// are mapped, so that eg. manifests can parameterise refs and hosts per environment:
+//
+//	github.com/org/repo?ref=${RELEASE_REF}
+//
+// Resolving a source fails if it references a variable that is not allowed or not set. Only the braced form is
+// expanded, so "$" may otherwise appear in sources unescaped.
+func WithEnvExpansion(allowed ...string) Option {
+	return func(f *Fetcher) {
+		// Non-nil even if no variables are allowed, so that references to them are rejected.
+		envExpansion := make([]string, 0, len(f.config.envExpansion)+len(allowed))
+		f.config.envExpansion = append(append(envExpansion, f.config.envExpansion...), allowed...)
+	}
+}
+
+var envRefRe = regexp.MustCompile(`\$\{([^}]*)\}`)
+
+// expandEnv expands the environment variable references in source, if [WithEnvExpansion] is in use.
+func (c *config) expandEnv(source string) (string, error) {
+	if c.envExpansion == nil {
+		return source, nil
+	}
+	var err error
+	expanded := envRefRe.ReplaceAllStringFunc(source, func(ref string) string {
+		name := envRefRe.FindStringSubmatch(ref)[1]
+		if !slices.Contains(c.envExpansion, name) {
+			err = fmt.Errorf("environment variable %q is not allowed in sources", name)
+			return ref
+		}
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			err = fmt.Errorf("environment variable %q is not set", name)
+		}
+		return value
+	})
+	if err != nil {
+		return "", fmt.Errorf("expanding %s: %w", redactSource(source), err)
+	}
+	return expanded, nil
+}
@@ -0,0 +1,49 @@
+package getit_test
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/block/getit"
+)
+
+func TestWithEnvExpansion(t *testing.T) {
+	t.Setenv("RELEASE_REF", "v1.2.0")
+	t.Setenv("MIRROR_HOST", "git.example.com")
+	t.Setenv("SECRET_TOKEN", "hunter2")
+	tests := []struct {
+		name     string
+		allowed  []string
+		source   string
+		expected string
+		err      string
+	}{
+		{name: "Ref", allowed: []string{"RELEASE_REF"}, source: "github.com/org/repo?ref=${RELEASE_REF}", expected: "https://github.com/org/repo?ref=v1.2.0"},
+		{name: "Host", allowed: []string{"RELEASE_REF", "MIRROR_HOST"}, source: "git+https://${MIRROR_HOST}/org/repo?ref=${RELEASE_REF}", expected: "https://git.example.com/org/repo?ref=v1.2.0"},
+		{name: "NotAllowed", allowed: []string{"RELEASE_REF"}, source: "https://${SECRET_TOKEN}@example.com/archive.tar.gz", err: `expanding https://xxxxx@example.com/archive.tar.gz: environment variable "SECRET_TOKEN" is not allowed in sources`},
+		{name: "NoneAllowed", allowed: []string{}, source: "github.com/org/repo?ref=${RELEASE_REF}", err: `environment variable "RELEASE_REF" is not allowed in sources`},
+		{name: "Unset", allowed: []string{"UNSET_REF"}, source: "github.com/org/repo?ref=${UNSET_REF}", err: `environment variable "UNSET_REF" is not set`},
+		{name: "Disabled", source: "https://example.com/${RELEASE_REF}/archive.tar.gz", expected: "https://example.com/$%7BRELEASE_REF%7D/archive.tar.gz"},
+		{name: "Unbraced", allowed: []string{"RELEASE_REF"}, source: "https://example.com/$RELEASE_REF/archive.tar.gz", expected: "https://example.com/$RELEASE_REF/archive.tar.gz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var options []getit.Option
+			if tt.allowed != nil {
+				options = append(options, getit.WithEnvExpansion(tt.allowed...))
+			}
+			fetcher := getit.New([]getit.Resolver{getit.NewGit(), getit.NewTAR()}, []getit.Mapper{getit.GitHub}, options...)
+			_, source, err := fetcher.Resolve(tt.source)
+			if tt.err != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			u := *source.URL
+			u.Scheme = "https"
+			assert.Equal(t, tt.expected, u.String())
+		})
+	}
+}
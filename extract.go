@@ -0,0 +1,231 @@
+package getit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// archivePath returns the path of an archive URL for matching its extension. Any //subdir suffix is removed, as is
+// any query or fragment included in the path, as happens when a URL is constructed rather than parsed.
+func archivePath(u *url.URL) string {
+	path, _, _ := strings.Cut(u.Path, "//")
+	path, _, _ = strings.Cut(path, "?")
+	path, _, _ = strings.Cut(path, "#")
+	return path
+}
+
+// openArchive opens an archive for reading, from the local filesystem for file:// URLs or otherwise over HTTP.
+func openArchive(ctx context.Context, u *url.URL) (io.ReadCloser, error) {
+	if u.Scheme == "file" {
+		path := localPath(u)
+		f, err := os.Open(path) // #nosec G304
+		if err != nil {
+			return nil, fmt.Errorf("open %s: %w", path, err)
+		}
+		return f, nil
+	}
+	resp, err := httpGet(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// statArchive describes an archive, from the local filesystem for file:// URLs or otherwise over HTTP.
+func statArchive(ctx context.Context, u *url.URL) (SourceInfo, error) {
+	if u.Scheme != "file" {
+		return httpStat(ctx, u)
+	}
+	path := localPath(u)
+	info, err := os.Stat(path)
+	if err != nil {
+		return SourceInfo{}, fmt.Errorf("stat %s: %w", path, err)
+	}
+	return SourceInfo{Size: info.Size(), Modified: info.ModTime()}, nil
+}
+
+// securePath joins an archive entry name onto dest, rejecting names that would escape dest, either textually or
+// through a symlink in dest, eg. one extracted from an earlier entry of the archive. Leading slashes are ignored, as
+// by tar. On Windows, names with drive letters, colons (which would address alternate data streams) or reserved
+// device names such as NUL are rejected too.
+func securePath(dest, name string) (string, error) {
+	local := strings.TrimLeft(filepath.FromSlash(name), string(filepath.Separator))
+	if local != "" && !filepath.IsLocal(local) {
+		return "", fmt.Errorf("%s: path escapes destination", name)
+	}
+	path := filepath.Join(dest, local)
+	rel, err := filepath.Rel(dest, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s: path escapes destination", name)
+	}
+	if err := checkParents(dest, rel); err != nil {
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+	return path, nil
+}
+
+// checkParents returns an error if any existing parent directory of rel beneath dest is a symlink, which writing to
+// rel would follow.
+func checkParents(dest, rel string) error {
+	dir := dest
+	for part := range strings.SplitSeq(filepath.Dir(rel), string(filepath.Separator)) {
+		if part == "." {
+			return nil
+		}
+		dir = filepath.Join(dir, part)
+		info, err := os.Lstat(dir)
+		if errors.Is(err, os.ErrNotExist) {
+			// Missing directories are created by getit, and can't be symlinks.
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("stat %s: %w", dir, err)
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			return fmt.Errorf("path escapes destination through symlink %s", dir)
+		}
+	}
+	return nil
+}
+
+// writeFile writes the contents of r to path, replacing any existing file or link. The mode written is derived
+// from the source mode by perms.
+func writeFile(path string, r io.Reader, mode fs.FileMode, perms Permissions) (int64, error) {
+	return createFile(path, mode, perms, func(f *os.File) (int64, error) {
+		return io.Copy(f, r) //nolint:wrapcheck // wrapped by createFile
+	})
+}
+
+// writeSparseFile is like writeFile, but blocks of zeros are skipped rather than written, leaving holes in the file
+// on filesystems that support them.
+func writeSparseFile(path string, r io.Reader, mode fs.FileMode, perms Permissions) (int64, error) {
+	return createFile(path, mode, perms, func(f *os.File) (int64, error) {
+		buf := make([]byte, 128<<10)
+		var n int64
+		for {
+			read, err := io.ReadFull(r, buf)
+			for block := range slices.Chunk(buf[:read], sparseBlockSize) {
+				if isZero(block) {
+					if _, err := f.Seek(int64(len(block)), io.SeekCurrent); err != nil {
+						return n, err //nolint:wrapcheck // wrapped by createFile
+					}
+				} else if _, err := f.Write(block); err != nil {
+					return n, err //nolint:wrapcheck // wrapped by createFile
+				}
+				n += int64(len(block))
+			}
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				// Extend the file over any trailing hole.
+				return n, f.Truncate(n)
+			} else if err != nil {
+				return n, err //nolint:wrapcheck // wrapped by createFile
+			}
+		}
+	})
+}
+
+// sparseBlockSize is the granularity at which writeSparseFile detects holes, matching common filesystem block sizes.
+const sparseBlockSize = 4096
+
+func isZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// createFile creates path, replacing any existing file or link, and writes its contents with write.
+func createFile(path string, mode fs.FileMode, perms Permissions, write func(f *os.File) (int64, error)) (int64, error) {
+	if err := prepareEntry(path); err != nil {
+		return 0, err
+	}
+	mode = perms.mode(mode)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode) // #nosec G304
+	if err != nil {
+		return 0, fmt.Errorf("create %s: %w", path, err)
+	}
+	defer f.Close()
+	n, err := write(f)
+	if err != nil {
+		return n, fmt.Errorf("write %s: %w", path, err)
+	}
+	if err := f.Close(); err != nil {
+		return n, fmt.Errorf("close %s: %w", path, err)
+	}
+	return n, perms.chmod(path, mode)
+}
+
+func writeDir(path string, mode fs.FileMode, perms Permissions) error {
+	// Directories must always be writable by us so their contents can be extracted.
+	mode = perms.mode(mode | fs.ModeDir)
+	// Replace rather than follow any symlink at path.
+	if info, err := os.Lstat(path); err == nil && info.Mode()&fs.ModeSymlink != 0 {
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("remove %s: %w", path, err)
+		}
+	}
+	if err := os.MkdirAll(path, mode.Perm()|0o700); err != nil {
+		return fmt.Errorf("mkdir %s: %w", path, err)
+	}
+	return perms.chmod(path, mode|0o700)
+}
+
+func writeSymlink(path, target string) error {
+	if err := prepareEntry(path); err != nil {
+		return err
+	}
+	if err := os.Symlink(target, path); err != nil {
+		return fmt.Errorf("symlink %s: %w", path, err)
+	}
+	return nil
+}
+
+func writeHardlink(path, target string) error {
+	if err := prepareEntry(path); err != nil {
+		return err
+	}
+	if err := os.Link(target, path); err != nil {
+		return fmt.Errorf("link %s: %w", path, err)
+	}
+	return nil
+}
+
+// writeArchiveHardlink creates a hardlink from an archive, copying the target instead if the filesystem can't link
+// it, eg. because it doesn't support hardlinks or the target has too many links already.
+func writeArchiveHardlink(path, target string) error {
+	err := writeHardlink(path, target)
+	if err == nil {
+		return nil
+	}
+	info, statErr := os.Lstat(target)
+	if statErr != nil || !info.Mode().IsRegular() {
+		return err
+	}
+	if _, err := copyFile(target, path, Permissions{}, false); err != nil {
+		return fmt.Errorf("copy hardlink target: %w", err)
+	}
+	return nil
+}
+
+// prepareEntry creates the parent directory of path and removes any existing non-directory at path, so that
+// links in the destination are replaced rather than followed.
+func prepareEntry(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return fmt.Errorf("mkdir %s: %w", filepath.Dir(path), err)
+	}
+	if info, err := os.Lstat(path); err == nil && !info.IsDir() {
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("remove %s: %w", path, err)
+		}
+	}
+	return nil
+}
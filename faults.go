@@ -0,0 +1,100 @@
+package getit
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// ErrInjectedFault is the error injected by [Faults] when no other error is given.
+var ErrInjectedFault = errors.New("injected fault")
+
+// Faults are injected into HTTP downloads by [WithFaults], so that applications can test their retry and rollback
+// handling against realistic fetch failures.
+//
+// A zero value for any field disables that fault.
+type Faults struct {
+	// Requests limits faults to the first n HTTP requests, eg. so that a retry succeeds. Zero injects faults into
+	// every request.
+	Requests int
+	// Delay before each response, as for a slow or unresponsive server. Delays end early if the request is
+	// cancelled.
+	Delay time.Duration
+	// TruncateAfter ends response bodies cleanly after this many bytes, as for a server that closes the connection
+	// early without declaring the length of the body.
+	TruncateAfter int64
+	// FailAfter fails reads of response bodies with Err after this many bytes, as for a connection reset mid-stream.
+	FailAfter int64
+	// Err is injected by FailAfter. If FailAfter is zero, requests fail with Err without being sent. Defaults to
+	// [ErrInjectedFault] when FailAfter is set.
+	Err error
+}
+
+// WithFaults injects faults into the HTTP requests made by a [Fetcher]. It is intended for tests only.
+func WithFaults(faults Faults) Option {
+	return func(f *Fetcher) { f.config.faults = &faults }
+}
+
+// faultTransport injects faults into HTTP responses.
+type faultTransport struct {
+	faults   Faults
+	next     http.RoundTripper
+	requests atomic.Int64
+}
+
+func (t *faultTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if n := t.requests.Add(1); t.faults.Requests > 0 && n > int64(t.faults.Requests) {
+		return t.next.RoundTrip(req) //nolint:wrapcheck // transport errors are passed through
+	}
+	if t.faults.Delay > 0 {
+		timer := time.NewTimer(t.faults.Delay)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err() //nolint:wrapcheck // as returned by net/http
+		}
+	}
+	if t.faults.Err != nil && t.faults.FailAfter <= 0 {
+		return nil, t.faults.Err
+	}
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // transport errors are passed through
+	}
+	if t.faults.TruncateAfter > 0 || t.faults.FailAfter > 0 {
+		resp.Body = &faultReader{ReadCloser: resp.Body, faults: t.faults}
+	}
+	return resp, nil
+}
+
+// faultReader truncates or fails a response body.
+type faultReader struct {
+	io.ReadCloser
+	faults Faults
+	read   int64
+}
+
+func (f *faultReader) Read(p []byte) (int, error) {
+	limit, err := int64(-1), error(nil)
+	if f.faults.FailAfter > 0 {
+		limit, err = f.faults.FailAfter, f.faults.Err
+		if err == nil {
+			err = ErrInjectedFault
+		}
+	}
+	if f.faults.TruncateAfter > 0 && (limit < 0 || f.faults.TruncateAfter < limit) {
+		limit, err = f.faults.TruncateAfter, io.EOF
+	}
+	if f.read >= limit {
+		return 0, err
+	}
+	if remaining := limit - f.read; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n, readErr := f.ReadCloser.Read(p)
+	f.read += int64(n)
+	return n, readErr //nolint:wrapcheck // io.Reader errors such as io.EOF must not be wrapped
+}
@@ -0,0 +1,73 @@
+package getit_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/block/getit"
+)
+
+func TestFaults(t *testing.T) {
+	body := tarball(t, "file.txt", "hello\n")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	errReset := errors.New("connection reset")
+	tests := []struct {
+		name    string
+		faults  getit.Faults
+		timeout time.Duration
+		err     error
+		error   string
+	}{
+		{name: "Delay", faults: getit.Faults{Delay: time.Minute}, timeout: 20 * time.Millisecond, err: context.DeadlineExceeded},
+		{name: "Truncate", faults: getit.Faults{TruncateAfter: 10}, error: "unexpected EOF"},
+		{name: "FailAfter", faults: getit.Faults{FailAfter: 10}, err: getit.ErrInjectedFault},
+		{name: "FailAfterWithError", faults: getit.Faults{FailAfter: 10, Err: errReset}, err: errReset},
+		{name: "FailRequest", faults: getit.Faults{Err: errReset}, err: errReset},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+			fetcher := getit.New([]getit.Resolver{getit.NewTAR()}, nil, getit.WithFaults(tt.faults))
+			err := fetcher.Fetch(ctx, server.URL+"/archive.tar.gz", t.TempDir())
+			assert.Error(t, err)
+			if tt.err != nil {
+				assert.IsError(t, err, tt.err)
+			}
+			assert.Contains(t, err.Error(), tt.error)
+		})
+	}
+}
+
+func TestFaultsRequests(t *testing.T) {
+	body := tarball(t, "file.txt", "hello\n")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	fetcher := getit.New([]getit.Resolver{getit.NewTAR()}, nil, getit.WithFaults(getit.Faults{Requests: 1, FailAfter: 1}))
+	source := server.URL + "/archive.tar.gz"
+	dest := filepath.Join(t.TempDir(), "dest")
+	err := fetcher.FetchAny(context.Background(), []string{source, source}, dest)
+	assert.NoError(t, err)
+	content, err := os.ReadFile(filepath.Join(dest, "file.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "hello\n", string(content))
+}
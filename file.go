@@ -2,12 +2,17 @@ package getit
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"net/url"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
+	"sync"
 )
 
 // File is a [Resolver] that copies local directories.
@@ -16,9 +21,41 @@ import (
 //
 //	file:///absolute/path/to/dir
 //	file://relative/path/to/dir
-type File struct{}
+//
+// Local tarballs, zip archives and git bundles, recognised by their extension, are extracted as with the [TAR], [ZIP]
+// and [Bundle] resolvers:
+//
+//	file:///path/to/archive.tar.gz
+//
+// The optional "mode" query parameter selects how regular files are copied:
+//
+//	file:///path/to/dir?mode=copy      (default) copy file contents
+//	file:///path/to/dir?mode=hardlink  hard link files to the source; their permissions, ownership and times are
+//	                                   shared with the source and left unmodified. Files are cloned as for
+//	                                   reflink instead if a PostFetch hook or WithStore could modify them
+//	file:///path/to/dir?mode=reflink   clone files copy-on-write where the filesystem supports it, otherwise copy
+type File struct {
+	// Concurrency is the maximum number of files copied concurrently. Defaults to GOMAXPROCS.
+	Concurrency int
+	// Ignore skips files matched by .gitignore and .getitignore files in the source tree, eg. to avoid copying
+	// node_modules or build output from a working directory.
+	Ignore bool
+	// PreserveXattrs copies extended attributes of files and directories, including POSIX ACLs and file
+	// capabilities, and applies those recorded in local tarballs as for [TAR.PreserveXattrs]. It is only supported on
+	// Linux, and is ignored elsewhere or if the filesystem doesn't support extended attributes. Privileged attributes
+	// are skipped unless running as root.
+	PreserveXattrs bool
+	// SingleFile allows sources that are a single regular file, which is copied into dest keeping its name. Without
+	// it, sources other than directories and archives are rejected. Archives are always extracted, unless the source
+	// has the ?archive=none query parameter, which also copies them as a single file.
+	SingleFile bool
+}
 
-var _ Resolver = (*File)(nil)
+var (
+	_ Resolver = (*File)(nil)
+	_ Stater   = (*File)(nil)
+	_ Pusher   = (*File)(nil)
+)
 
 func NewFile() *File { return &File{} }
 
@@ -26,33 +63,236 @@ func (f *File) Match(source *url.URL) bool {
 	return source.Scheme == "file"
 }
 
-func (f *File) Fetch(ctx context.Context, source Source, dest string) error {
-	srcPath := source.URL.Path
-	if source.URL.Host != "" {
-		srcPath = filepath.Join(source.URL.Host, srcPath)
+// Stat reports the total size of the files in the source directory.
+func (f *File) Stat(ctx context.Context, source Source) (SourceInfo, error) {
+	srcPath := localPath(source.URL)
+	info, err := os.Stat(srcPath)
+	if err != nil {
+		return SourceInfo{}, fmt.Errorf("stat %s: %w", srcPath, err)
+	}
+	if archive := f.archiveResolver(source, info); archive != nil {
+		return archive.Stat(ctx, source)
 	}
+	if f.isSingleFile(source, info) {
+		return SourceInfo{Size: info.Size(), Modified: info.ModTime()}, nil
+	}
+	if !info.IsDir() {
+		return SourceInfo{}, fmt.Errorf("%s is not a directory", srcPath)
+	}
+	result := SourceInfo{Modified: info.ModTime()}
+	err = filepath.WalkDir(srcPath, func(_ string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := contextError(ctx); err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err //nolint:wrapcheck // wrapped below
+			}
+			result.Size += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return SourceInfo{}, fmt.Errorf("walk %s: %w", srcPath, err)
+	}
+	return result, nil
+}
+
+func (f *File) Fetch(ctx context.Context, source Source, dest string) error {
+	srcPath := localPath(source.URL)
 
 	info, err := os.Stat(srcPath)
 	if err != nil {
 		return fmt.Errorf("stat %s: %w", srcPath, err)
 	}
-	if !info.IsDir() {
+	if archive := f.archiveResolver(source, info); archive != nil {
+		return archive.Fetch(ctx, source, dest)
+	}
+	singleFile := f.isSingleFile(source, info)
+	if !info.IsDir() && !singleFile {
 		return fmt.Errorf("%s is not a directory", srcPath)
 	}
+	options := copyOptions{
+		concurrency: f.Concurrency,
+		mode:        copyMode(source.URL.Query().Get("mode")),
+		ignore:      f.Ignore,
+		xattrs:      f.PreserveXattrs,
+	}
+	switch options.mode {
+	case "", copyModeCopy, copyModeHardlink, copyModeReflink:
+	default:
+		return fmt.Errorf("unsupported copy mode %q", options.mode)
+	}
+	if cfg := configFromContext(ctx); options.mode == copyModeHardlink && (cfg.options.PostFetch != nil || cfg.store != "") {
+		// Both modify fetched files in place, which must not reach the source through shared links.
+		cfg.logger.DebugContext(ctx, "hardlinks would be modified, copying instead", "src", srcPath)
+		options.mode = copyModeReflink
+	}
+	if singleFile {
+		return copySingleFile(ctx, srcPath, info, dest, options)
+	}
+
+	configFromContext(ctx).logger.DebugContext(ctx, "copy", "src", srcPath, "dest", dest, "mode", options.mode)
+	ctx, span := startSpan(ctx, "getit.copy", map[string]string{"src": srcPath})
+	ctx, cancel := withPhaseTimeout(ctx, "extract", configFromContext(ctx).timeouts.Extract)
+	defer cancel()
+	if err := copyDir(ctx, srcPath, dest, options); err != nil {
+		err = fmt.Errorf("copying %s: %w", srcPath, err)
+		span.End(err)
+		return err
+	}
+	span.End(nil)
+	return nil
+}
+
+// Push replaces the destination directory with a copy of srcDir, or writes an archive of srcDir if the destination
+// is a tarball or zip archive.
+func (f *File) Push(ctx context.Context, source Source, srcDir string) error {
+	if source.Archive == "none" {
+		return errors.New("directories can't be pushed as a single file")
+	}
+	if source.Archive != "" || NewTAR().Match(source.URL) || NewZIP().Match(source.URL) {
+		return pushArchive(ctx, source, srcDir)
+	}
+	path := localPath(source.URL)
+	staging, err := newStaging(path)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(staging)
+	options := copyOptions{concurrency: f.Concurrency, ignore: f.Ignore, xattrs: f.PreserveXattrs}
+	if err := copyDir(ctx, srcDir, staging, options); err != nil {
+		return fmt.Errorf("copying %s: %w", srcDir, err)
+	}
+	return replace(staging, path)
+}
 
-	if err := copyDir(ctx, srcPath, dest); err != nil {
-		return fmt.Errorf("copying %s: %w", srcPath, err)
+// archiveResolver returns the resolver that extracts source if it is a local archive file, or nil otherwise.
+func (f *File) archiveResolver(source Source, info os.FileInfo) interface {
+	Resolver
+	Stater
+} {
+	if !info.Mode().IsRegular() || source.Archive == "none" {
+		return nil
+	}
+	if tar := (&TAR{PreserveXattrs: f.PreserveXattrs}); tar.Match(source.URL) {
+		return tar
+	}
+	if zip := (&ZIP{Concurrency: f.Concurrency}); zip.Match(source.URL) {
+		return zip
+	}
+	if bundle := NewBundle(); bundle.Match(source.URL) {
+		return bundle
 	}
 	return nil
 }
 
-func copyDir(ctx context.Context, src, dest string) error {
+// isSingleFile reports whether source is a regular file to be copied as is.
+func (f *File) isSingleFile(source Source, info os.FileInfo) bool {
+	return info.Mode().IsRegular() && (f.SingleFile || source.Archive == "none")
+}
+
+// copySingleFile copies the regular file src into dest, keeping its name.
+func copySingleFile(ctx context.Context, src string, info os.FileInfo, dest string, options copyOptions) error {
+	cfg := configFromContext(ctx)
+	if err := os.MkdirAll(dest, 0750); err != nil {
+		return fmt.Errorf("creating destination directory: %w", err)
+	}
+	destPath := filepath.Join(dest, info.Name())
+	cfg.logger.DebugContext(ctx, "copy", "src", src, "dest", destPath, "mode", options.mode)
+	if options.mode == copyModeHardlink {
+		size, err := linkFile(src, destPath)
+		if err != nil {
+			return err
+		}
+		cfg.fileExtracted(info.Name(), size)
+		return nil
+	}
+	size, err := copyFile(src, destPath, cfg.permissions, options.mode == copyModeReflink)
+	if err != nil {
+		return fmt.Errorf("copying %s: %w", src, err)
+	}
+	times := newTimestamper(cfg.options)
+	if err := copyMetadata(cfg, options, times, fs.FileInfoToDirEntry(info), src, destPath, info.Name(), size); err != nil {
+		return err
+	}
+	return times.finish()
+}
+
+// localPath returns the filesystem path referenced by a file:// URL.
+//
+// On Windows, drive letters may be given as the first path element (file:///C:/dir) or as the host (file://C:/dir).
+func localPath(u *url.URL) string {
+	if u.Host != "" {
+		return filepath.Join(u.Host, filepath.FromSlash(u.Path))
+	}
+	path := u.Path
+	if runtime.GOOS == "windows" && len(path) >= 3 && path[0] == '/' && path[2] == ':' {
+		path = path[1:]
+	}
+	return filepath.FromSlash(path)
+}
+
+// fileURL returns the file:// URL for an absolute filesystem path.
+func fileURL(path string) string {
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		// Windows paths start with a drive letter.
+		path = "/" + path
+	}
+	return "file://" + path
+}
+
+// copyMode selects how the File resolver copies regular files.
+type copyMode string
+
+const (
+	copyModeCopy     copyMode = "copy"
+	copyModeHardlink copyMode = "hardlink"
+	copyModeReflink  copyMode = "reflink"
+)
+
+type copyOptions struct {
+	concurrency int
+	mode        copyMode
+	ignore      bool
+	xattrs      bool
+}
+
+// copyDir copies the tree at src to dest. Directories and links are created as they are walked, while regular files
+// are copied by a pool of workers.
+//
+// Directory modes are preserved, subject to perms. Directories that are not writable by their owner are only made so
+// once their contents have been copied.
+func copyDir(ctx context.Context, src, dest string, options copyOptions) error {
+	cfg := configFromContext(ctx)
+	perms := cfg.permissions
+	times := newTimestamper(cfg.options)
+	concurrency := options.concurrency
+	if concurrency <= 0 {
+		concurrency = runtime.GOMAXPROCS(0)
+	}
+	pool := newWorkerPool(ctx, concurrency)
+	var ignore *ignorer
+	if options.ignore {
+		ignore = &ignorer{}
+	}
+	var readOnlyDirs []string
+	var readOnlyModes []os.FileMode
 	err := filepath.WalkDir(src, func(path string, d os.DirEntry, err error) error {
 		if err != nil {
 			return fmt.Errorf("walk %s: %w", path, err)
 		}
-		if err := ctx.Err(); err != nil {
-			return fmt.Errorf("context: %w", err)
+		if err := contextError(pool.ctx); err != nil {
+			return err
+		}
+
+		if cfg.options.Deterministic && d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
 		}
 
 		relPath, err := filepath.Rel(src, path)
@@ -60,55 +300,171 @@ func copyDir(ctx context.Context, src, dest string) error {
 			return fmt.Errorf("rel path %s: %w", path, err)
 		}
 		destPath := filepath.Join(dest, relPath)
+		if ignore != nil && relPath != "." && ignore.match(filepath.ToSlash(relPath), d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
 
-		if d.Type()&os.ModeSymlink != 0 {
+		switch {
+		case d.Type()&os.ModeSymlink != 0:
 			target, err := os.Readlink(path)
 			if err != nil {
 				return fmt.Errorf("readlink %s: %w", path, err)
 			}
-			return os.Symlink(target, destPath)
+			if err := os.Symlink(target, destPath); err != nil {
+				return fmt.Errorf("symlink %s: %w", destPath, err)
+			}
+		case d.IsDir():
+			mode := os.FileMode(0750)
+			if relPath != "." {
+				info, err := d.Info()
+				if err != nil {
+					return fmt.Errorf("stat %s: %w", path, err)
+				}
+				mode = info.Mode()
+			}
+			if err := writeDir(destPath, mode, perms); err != nil {
+				return err
+			}
+			if final := perms.mode(mode); final&0o700 != 0o700 {
+				readOnlyDirs = append(readOnlyDirs, destPath)
+				readOnlyModes = append(readOnlyModes, final)
+			}
+			if ignore != nil {
+				if err := ignore.load(path, filepath.ToSlash(relPath)); err != nil {
+					return err
+				}
+			}
+		default:
+			pool.run(func() error {
+				if options.mode == copyModeHardlink {
+					size, err := linkFile(path, destPath)
+					if err != nil {
+						return err
+					}
+					// Ownership and times belong to the source, so only report the file.
+					return pool.finish(func() error {
+						cfg.fileExtracted(filepath.ToSlash(relPath), size)
+						return nil
+					})
+				}
+				size, err := copyFile(path, destPath, perms, options.mode == copyModeReflink)
+				if err != nil {
+					return err
+				}
+				return pool.finish(func() error {
+					return copyMetadata(cfg, options, times, d, path, destPath, relPath, size)
+				})
+			})
+			return nil
 		}
-
-		if d.IsDir() {
-			return os.MkdirAll(destPath, 0750)
+		if relPath == "." {
+			return nil
 		}
-
-		return copyFile(path, destPath)
+		return pool.finish(func() error {
+			return copyMetadata(cfg, options, times, d, path, destPath, relPath, 0)
+		})
 	})
+	if perr := pool.wait(); perr != nil {
+		return perr
+	}
 	if err != nil {
 		return fmt.Errorf("walk %s: %w", src, err)
 	}
+	for i, dir := range slices.Backward(readOnlyDirs) {
+		if err := os.Chmod(dir, readOnlyModes[i]); err != nil {
+			return fmt.Errorf("chmod %s: %w", dir, err)
+		}
+	}
+	return times.finish()
+}
+
+// copyMetadata applies ownership, extended attributes and times to a copied entry and reports it to the hooks.
+func copyMetadata(cfg *config, options copyOptions, times *timestamper, d os.DirEntry, path, destPath, relPath string, size int64) error {
+	info, err := d.Info()
+	if err != nil {
+		return fmt.Errorf("stat %s: %w", path, err)
+	}
+	if uid, gid, ok := fileOwner(info); ok {
+		if err := cfg.permissions.chown(destPath, uid, gid); err != nil {
+			return err
+		}
+	}
+	// Attributes are copied after ownership, as changing the owner clears file capabilities.
+	if options.xattrs && d.Type()&os.ModeSymlink == 0 {
+		if err := copyXattrs(path, destPath); err != nil {
+			return err
+		}
+	}
+	if err := times.record(destPath, info.Mode(), info.ModTime()); err != nil {
+		return err
+	}
+	cfg.fileExtracted(filepath.ToSlash(relPath), size)
 	return nil
 }
 
-func copyFile(src, dest string) error {
+// copyBuffers holds buffers for copyFile, which are larger than io.Copy's default.
+var copyBuffers = sync.Pool{New: func() any {
+	buf := make([]byte, 256*1024)
+	return &buf
+}}
+
+// copyFile copies src to dest. If reflink is set the contents are cloned where the filesystem supports it.
+func copyFile(src, dest string, perms Permissions, reflink bool) (int64, error) {
 	srcFile, err := os.Open(src)
 	if err != nil {
-		return fmt.Errorf("open %s: %w", src, err)
+		return 0, fmt.Errorf("open %s: %w", src, err)
 	}
 	defer srcFile.Close()
 
 	info, err := srcFile.Stat()
 	if err != nil {
-		return fmt.Errorf("stat %s: %w", src, err)
+		return 0, fmt.Errorf("stat %s: %w", src, err)
 	}
 
-	destFile, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode())
+	mode := perms.mode(info.Mode())
+	destFile, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
 	if err != nil {
-		return fmt.Errorf("create %s: %w", dest, err)
+		return 0, fmt.Errorf("create %s: %w", dest, err)
 	}
 	defer destFile.Close()
 
-	if _, err = io.Copy(destFile, srcFile); err != nil {
-		return fmt.Errorf("copy to %s: %w", dest, err)
+	if reflink {
+		if err := cloneFile(destFile, srcFile); err == nil {
+			return info.Size(), perms.chmod(dest, mode)
+		} else if !errors.Is(err, errors.ErrUnsupported) {
+			return 0, fmt.Errorf("clone to %s: %w", dest, err)
+		}
 	}
-	return nil
+	buf := copyBuffers.Get().(*[]byte) //nolint:forcetypeassert // the pool only holds *[]byte
+	defer copyBuffers.Put(buf)
+	// Hide ReadFrom and WriteTo so that the pooled buffer is used rather than a fresh allocation per file.
+	n, err := io.CopyBuffer(struct{ io.Writer }{destFile}, struct{ io.Reader }{srcFile}, *buf)
+	if err != nil {
+		return n, fmt.Errorf("copy to %s: %w", dest, err)
+	}
+	return n, perms.chmod(dest, mode)
+}
+
+// linkFile hard links dest to src, returning the size of the file.
+func linkFile(src, dest string) (int64, error) {
+	info, err := os.Stat(src)
+	if err != nil {
+		return 0, fmt.Errorf("stat %s: %w", src, err)
+	}
+	if err := writeHardlink(dest, src); err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
 }
 
 // FilePath is a [Mapper] that maps filesystem paths to file:// URLs.
 //
 // It handles absolute paths, relative paths (./..., ../...), home-relative paths (~/...),
-// and bare directory names. The path must exist and be a directory.
+// and bare directory names. The path must exist and be a directory; see [SyntacticFilePath] for a mapper that
+// doesn't check.
 func FilePath(source string) (string, bool) {
 	if source == "" {
 		return "", false
@@ -118,16 +474,65 @@ func FilePath(source string) (string, bool) {
 		return source, true
 	}
 
-	path := source
+	path, ok := absPath(source)
+	if !ok {
+		return "", false
+	}
+
+	info, err := os.Stat(path)
+	if err != nil || !info.IsDir() {
+		return "", false
+	}
+
+	// Resolve symlinks for canonical path
+	path, err = filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", false
+	}
+
+	return fileURL(path), true
+}
+
+// SyntacticFilePath is a [Mapper] that maps filesystem paths to file:// URLs without checking the filesystem, so that
+// sources may be resolved before they exist, eg. outside the container they will be fetched in.
+//
+// Only sources that are unambiguously paths are mapped: absolute paths, and paths beginning with ./, ../ or ~/. Bare
+// names such as "dir" are left for other mappers, as they may also be eg. GitHub repositories.
+func SyntacticFilePath(source string) (string, bool) {
+	if strings.HasPrefix(source, "file://") {
+		return source, true
+	}
+	if !isPathSyntax(source) {
+		return "", false
+	}
+	path, ok := absPath(source)
+	if !ok {
+		return "", false
+	}
+	return fileURL(path), true
+}
+
+func isPathSyntax(source string) bool {
+	if filepath.IsAbs(source) || source == "." || source == ".." {
+		return true
+	}
+	for _, prefix := range []string{"./", "../", "~/"} {
+		if strings.HasPrefix(source, prefix) || strings.HasPrefix(source, strings.ReplaceAll(prefix, "/", string(filepath.Separator))) {
+			return true
+		}
+	}
+	return false
+}
 
-	if strings.HasPrefix(path, "~/") {
+// absPath expands a leading ~/ in path to the home directory and makes it absolute.
+func absPath(path string) (string, bool) {
+	if strings.HasPrefix(path, "~/") || strings.HasPrefix(path, "~"+string(filepath.Separator)) {
 		home, err := os.UserHomeDir()
 		if err != nil {
 			return "", false
 		}
 		path = filepath.Join(home, path[2:])
 	}
-
 	if !filepath.IsAbs(path) {
 		abs, err := filepath.Abs(path)
 		if err != nil {
@@ -135,17 +540,5 @@ func FilePath(source string) (string, bool) {
 		}
 		path = abs
 	}
-
-	info, err := os.Stat(path)
-	if err != nil || !info.IsDir() {
-		return "", false
-	}
-
-	// Resolve symlinks for canonical path
-	path, err = filepath.EvalSymlinks(path)
-	if err != nil {
-		return "", false
-	}
-
-	return "file://" + path, true
+	return path, true
 }
@@ -2,9 +2,13 @@ package getit_test
 
 import (
 	"context"
+	"fmt"
 	"net/url"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/alecthomas/assert/v2"
@@ -189,6 +193,125 @@ func TestFilePathRelative(t *testing.T) {
 	assert.Equal(t, "file://"+subDirResolved, result)
 }
 
+func TestFileFetchArchive(t *testing.T) {
+	srcDir := t.TempDir()
+	for _, name := range []string{"archive.tar.gz", "archive.zip", "split.z01", "split.zip"} {
+		data, err := os.ReadFile(filepath.Join("testdata", name))
+		assert.NoError(t, err)
+		assert.NoError(t, os.WriteFile(filepath.Join(srcDir, name), data, 0o644))
+	}
+	assert.NoError(t, os.Rename(filepath.Join(srcDir, "archive.tar.gz"), filepath.Join(srcDir, "opaque")))
+	data, err := os.ReadFile(filepath.Join("testdata", "archive.tar.gz"))
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(filepath.Join(srcDir, "Archive.TGZ"), data, 0o644))
+
+	tests := []struct {
+		name   string
+		source string
+		file   string
+	}{
+		{name: "Tarball", source: "Archive.TGZ", file: "nested.txt"},
+		{name: "Zip", source: "archive.zip", file: "nested.txt"},
+		{name: "SplitZip", source: "split.zip", file: "small.txt"},
+		{name: "ArchiveOverride", source: "opaque?archive=tar.gz", file: "nested.txt"},
+	}
+	fetcher := getit.New([]getit.Resolver{getit.NewFile(), getit.NewTAR(), getit.NewZIP()}, nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := t.TempDir()
+			result, err := fetcher.FetchWithOptions(context.Background(), fileURLForTest(srcDir)+"/"+tt.source, dest, getit.FetchOptions{DryRun: true})
+			assert.NoError(t, err)
+			assert.True(t, result.Info.Size > 0)
+			err = fetcher.Fetch(context.Background(), fileURLForTest(srcDir)+"/"+tt.source, dest)
+			assert.NoError(t, err)
+			_, err = os.Stat(filepath.Join(dest, tt.file))
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestFileFetchSingleFile(t *testing.T) {
+	srcDir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(srcDir, "tool.sh"), []byte("#!/bin/sh\n"), 0o755))
+	data, err := os.ReadFile(filepath.Join("testdata", "archive.zip"))
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(filepath.Join(srcDir, "bundle.zip"), data, 0o644))
+
+	tests := []struct {
+		name       string
+		singleFile bool
+		source     string
+		file       string
+		err        string
+	}{
+		{name: "Copy", singleFile: true, source: "tool.sh", file: "tool.sh"},
+		{name: "Hardlink", singleFile: true, source: "tool.sh?mode=hardlink", file: "tool.sh"},
+		{name: "Disabled", source: "tool.sh", err: "is not a directory"},
+		{name: "ArchiveExtracted", singleFile: true, source: "bundle.zip", file: "nested.txt"},
+		{name: "ArchiveNone", source: "bundle.zip?archive=none", file: "bundle.zip"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := &getit.File{SingleFile: tt.singleFile}
+			fetcher := getit.New([]getit.Resolver{file}, nil)
+			dest := filepath.Join(t.TempDir(), "dest")
+			err := fetcher.Fetch(context.Background(), fileURLForTest(srcDir)+"/"+tt.source, dest)
+			if tt.err != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			info, err := os.Stat(filepath.Join(dest, tt.file))
+			assert.NoError(t, err)
+			if tt.file == "tool.sh" && runtime.GOOS != "windows" {
+				assert.Equal(t, os.FileMode(0o755), info.Mode().Perm())
+			}
+		})
+	}
+}
+
+func TestSyntacticFilePath(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Chdir(tmpDir)
+	home := filepath.Join(tmpDir, "home")
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	missing := filepath.Join(tmpDir, "missing", "dir")
+
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{name: "MissingAbsolutePath", source: missing, expected: fileURLForTest(missing)},
+		{name: "Relative", source: "./later", expected: fileURLForTest(filepath.Join(tmpDir, "later"))},
+		{name: "Parent", source: "../sibling", expected: fileURLForTest(filepath.Join(filepath.Dir(tmpDir), "sibling"))},
+		{name: "CurrentDir", source: ".", expected: fileURLForTest(tmpDir)},
+		{name: "Home", source: "~/config", expected: fileURLForTest(filepath.Join(home, "config"))},
+		{name: "FileURLPassthrough", source: "file:///somewhere", expected: "file:///somewhere"},
+		{name: "BareName", source: "dir"},
+		{name: "OrgRepo", source: "user/repo"},
+		{name: "URL", source: "https://example.com/archive.tar.gz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, ok := getit.SyntacticFilePath(tt.source)
+			assert.Equal(t, tt.expected != "", ok)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+// fileURLForTest returns the file:// URL for an absolute path, including a leading slash before any drive letter.
+func fileURLForTest(path string) string {
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return "file://" + path
+}
+
 func TestFileFetchSymlinks(t *testing.T) {
 	srcDir := t.TempDir()
 
@@ -226,3 +349,150 @@ func TestFileFetchSymlinks(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "hello\n", string(content))
 }
+
+func TestFileFetchConcurrent(t *testing.T) {
+	srcDir := t.TempDir()
+	for i := range 50 {
+		dir := filepath.Join(srcDir, fmt.Sprintf("dir%d", i%5))
+		assert.NoError(t, os.MkdirAll(dir, 0o755))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, fmt.Sprintf("file%d.txt", i)), []byte(strconv.Itoa(i)), 0o644))
+	}
+
+	u, err := url.Parse("file://" + srcDir)
+	assert.NoError(t, err)
+
+	for _, concurrency := range []int{1, 4, 0} {
+		t.Run(strconv.Itoa(concurrency), func(t *testing.T) {
+			dest := t.TempDir()
+			f := &getit.File{Concurrency: concurrency}
+			err = f.Fetch(context.Background(), getit.Source{URL: u}, dest)
+			assert.NoError(t, err)
+			for i := range 50 {
+				content, err := os.ReadFile(filepath.Join(dest, fmt.Sprintf("dir%d", i%5), fmt.Sprintf("file%d.txt", i)))
+				assert.NoError(t, err)
+				assert.Equal(t, strconv.Itoa(i), string(content))
+			}
+		})
+	}
+}
+
+func TestFileFetchConcurrentError(t *testing.T) {
+	srcDir := t.TempDir()
+	for i := range 10 {
+		assert.NoError(t, os.WriteFile(filepath.Join(srcDir, fmt.Sprintf("file%d.txt", i)), []byte("content\n"), 0o644))
+	}
+	// A non-empty directory in the destination where a file should be written makes that copy fail.
+	dest := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(dest, "file3.txt", "blocker"), 0o755))
+
+	u, err := url.Parse("file://" + srcDir)
+	assert.NoError(t, err)
+	f := &getit.File{Concurrency: 4}
+	err = f.Fetch(context.Background(), getit.Source{URL: u}, dest)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "file3.txt")
+}
+
+func TestFileFetchModes(t *testing.T) {
+	srcDir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(srcDir, "subdir"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(srcDir, "subdir", "file.txt"), []byte("hello\n"), 0o644))
+
+	tests := []struct {
+		mode   string
+		linked bool
+	}{
+		{mode: "copy"},
+		{mode: "hardlink", linked: true},
+		{mode: "reflink"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			u, err := url.Parse("file://" + srcDir + "?mode=" + tt.mode)
+			assert.NoError(t, err)
+			dest := t.TempDir()
+			err = getit.NewFile().Fetch(context.Background(), getit.Source{URL: u}, dest)
+			assert.NoError(t, err)
+
+			content, err := os.ReadFile(filepath.Join(dest, "subdir", "file.txt"))
+			assert.NoError(t, err)
+			assert.Equal(t, "hello\n", string(content))
+
+			srcInfo, err := os.Stat(filepath.Join(srcDir, "subdir", "file.txt"))
+			assert.NoError(t, err)
+			destInfo, err := os.Stat(filepath.Join(dest, "subdir", "file.txt"))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.linked, os.SameFile(srcInfo, destInfo))
+		})
+	}
+}
+
+func TestFileFetchHardlinkPostFetch(t *testing.T) {
+	srcDir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(srcDir, "file.txt"), []byte("original\n"), 0o644))
+
+	fetcher := getit.New([]getit.Resolver{getit.NewFile()}, nil)
+	dest := t.TempDir()
+	_, err := fetcher.FetchWithOptions(context.Background(), "file://"+srcDir+"?mode=hardlink", dest, getit.FetchOptions{
+		PostFetch: func(_ context.Context, dir string) error {
+			return os.WriteFile(filepath.Join(dir, "file.txt"), []byte("patched\n"), 0o644)
+		},
+	})
+	assert.NoError(t, err)
+
+	content, err := os.ReadFile(filepath.Join(dest, "file.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "patched\n", string(content))
+	content, err = os.ReadFile(filepath.Join(srcDir, "file.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "original\n", string(content), "the hook must not modify the source")
+}
+
+func TestFileFetchInvalidMode(t *testing.T) {
+	u, err := url.Parse("file://" + t.TempDir() + "?mode=teleport")
+	assert.NoError(t, err)
+	err = getit.NewFile().Fetch(context.Background(), getit.Source{URL: u}, t.TempDir())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported copy mode")
+}
+
+func TestFileFetchPreservesModes(t *testing.T) {
+	srcDir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(srcDir, "empty"), 0o700))
+	assert.NoError(t, os.MkdirAll(filepath.Join(srcDir, "private"), 0o700))
+	assert.NoError(t, os.WriteFile(filepath.Join(srcDir, "private", "key"), []byte("key\n"), 0o600))
+	assert.NoError(t, os.MkdirAll(filepath.Join(srcDir, "readonly"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(srcDir, "readonly", "file.txt"), []byte("ro\n"), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(srcDir, "run.sh"), []byte("#!/bin/sh\n"), 0o755))
+	for _, dir := range []string{"empty", "private"} {
+		assert.NoError(t, os.Chmod(filepath.Join(srcDir, dir), 0o700))
+	}
+	assert.NoError(t, os.Chmod(filepath.Join(srcDir, "readonly"), 0o555))
+	t.Cleanup(func() { _ = os.Chmod(filepath.Join(srcDir, "readonly"), 0o755) })
+
+	u, err := url.Parse("file://" + srcDir)
+	assert.NoError(t, err)
+	dest := t.TempDir()
+	t.Cleanup(func() { _ = os.Chmod(filepath.Join(dest, "readonly"), 0o755) })
+	err = getit.NewFile().Fetch(context.Background(), getit.Source{URL: u}, dest)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		path string
+		mode os.FileMode
+	}{
+		{path: "empty", mode: 0o700 | os.ModeDir},
+		{path: "private", mode: 0o700 | os.ModeDir},
+		{path: "private/key", mode: 0o600},
+		{path: "readonly", mode: 0o555 | os.ModeDir},
+		{path: "readonly/file.txt", mode: 0o644},
+		{path: "run.sh", mode: 0o755},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			info, err := os.Stat(filepath.Join(dest, tt.path))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.mode, info.Mode())
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package getit_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/block/getit"
+)
+
+func TestFileFetchDriveLetter(t *testing.T) {
+	srcDir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(srcDir, "file.txt"), []byte("hello\n"), 0o644))
+
+	source, ok := getit.FilePath(srcDir)
+	assert.True(t, ok)
+	assert.True(t, strings.HasPrefix(source, "file:///"+filepath.VolumeName(srcDir)), source)
+
+	dest := t.TempDir()
+	fetcher := getit.New([]getit.Resolver{getit.NewFile()}, []getit.Mapper{getit.FilePath})
+	for _, source := range []string{source, "file://" + filepath.ToSlash(srcDir)} {
+		t.Run(source, func(t *testing.T) {
+			err := fetcher.Fetch(context.Background(), source, dest)
+			assert.NoError(t, err)
+			content, err := os.ReadFile(filepath.Join(dest, "file.txt"))
+			assert.NoError(t, err)
+			assert.Equal(t, "hello\n", string(content))
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package getit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// forge describes the API of a git hosting service.
+type forge struct {
+	// kind is "github" or "gitlab".
+	kind string
+	// api is the base URL of the REST API.
+	api string
+}
+
+// forges maps the hosts of supported git hosting services to their APIs.
+var forges = map[string]forge{
+	"github.com": {kind: "github", api: "https://api.github.com"},
+	"gitlab.com": {kind: "gitlab", api: "https://gitlab.com/api/v4"},
+}
+
+// forgeRepo returns the forge hosting a git URL, and the path of the repository on it, eg. "owner/repo".
+func forgeRepo(u *url.URL) (forge, string, bool) {
+	f, ok := forges[u.Host]
+	if !ok {
+		return forge{}, "", false
+	}
+	repo, _, _ := strings.Cut(strings.Trim(u.Path, "/"), "//")
+	repo = strings.TrimSuffix(repo, ".git")
+	if f.kind == "github" {
+		// GitHub repositories are always owner/repo, and anything after is a subdirectory.
+		parts := strings.SplitN(repo, "/", 3)
+		if len(parts) < 2 {
+			return forge{}, "", false
+		}
+		repo = parts[0] + "/" + parts[1]
+	}
+	return f, repo, repo != ""
+}
+
+// latestRelease returns the tag of the latest release of a repository, or "" if it has no releases.
+func (f forge) latestRelease(ctx context.Context, repo string) (string, error) {
+	var endpoint string
+	switch f.kind {
+	case "github":
+		endpoint = f.api + "/repos/" + repo + "/releases/latest"
+	case "gitlab":
+		endpoint = f.api + "/projects/" + url.PathEscape(repo) + "/releases/permalink/latest"
+	}
+	var release struct {
+		TagName string `json:"tag_name"`
+	}
+	found, err := f.get(ctx, endpoint, &release)
+	if err != nil || !found {
+		return "", err
+	}
+	return release.TagName, nil
+}
+
+// get decodes the JSON response to an API request into v, reporting false if the resource was not found.
+func (f forge) get(ctx context.Context, endpoint string, v any) (bool, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s API URL: %w", f.kind, err)
+	}
+	cfg := configFromContext(ctx)
+	resp, err := httpDo(ctx, cfg, http.MethodGet, u)
+	if err != nil {
+		return false, fmt.Errorf("%s API: %w", f.kind, err)
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return false, nil
+	case resp.StatusCode != http.StatusOK:
+		return false, fmt.Errorf("%s API: %s: %s", f.kind, RedactURL(u), resp.Status)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 16<<20)).Decode(v); err != nil {
+		return false, fmt.Errorf("%s API: decoding %s: %w", f.kind, RedactURL(u), err)
+	}
+	return true, nil
+}
+
+// repoSize returns the approximate size in bytes of a repository, or -1 if the forge doesn't report it.
+func (f forge) repoSize(ctx context.Context, repo string) (int64, error) {
+	switch f.kind {
+	case "github":
+		var info struct {
+			// Size is in kilobytes.
+			Size int64 `json:"size"`
+		}
+		found, err := f.get(ctx, f.api+"/repos/"+repo, &info)
+		if err != nil || !found {
+			return -1, err
+		}
+		return info.Size * 1024, nil
+	case "gitlab":
+		var info struct {
+			Statistics *struct {
+				RepositorySize int64 `json:"repository_size"`
+			} `json:"statistics"`
+		}
+		found, err := f.get(ctx, f.api+"/projects/"+url.PathEscape(repo)+"?statistics=true", &info)
+		if err != nil || !found || info.Statistics == nil {
+			// Statistics are only returned to project members.
+			return -1, err
+		}
+		return info.Statistics.RepositorySize, nil
+	}
+	return -1, nil
+}
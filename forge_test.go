@@ -0,0 +1,72 @@
+package getit //nolint:testpackage
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+// fakeForges serves the given API responses, keyed by request URI, and registers the server as both a GitHub host
+// ("github.test") and a GitLab host ("gitlab.test").
+func fakeForges(t *testing.T, responses map[string]string) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := responses[r.URL.RequestURI()]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	saved := forges
+	t.Cleanup(func() { forges = saved })
+	forges = map[string]forge{
+		"github.test": {kind: "github", api: server.URL + "/api/v3"},
+		"gitlab.test": {kind: "gitlab", api: server.URL + "/api/v4"},
+	}
+}
+
+func TestResolveLatest(t *testing.T) {
+	fakeForges(t, map[string]string{
+		"/api/v3/repos/owner/repo/releases/latest":                      `{"tag_name": "v1.4.2"}`,
+		"/api/v4/projects/group%2Fsub%2Frepo/releases/permalink/latest": `{"tag_name": "v2.0.0"}`,
+		"/api/v3/repos/owner/broken/releases/latest":                    `{`,
+	})
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+		err      string
+	}{
+		{name: "GitHub", source: "git+https://github.test/owner/repo.git?ref=latest", expected: "v1.4.2"},
+		{name: "GitHubSubdir", source: "git+https://github.test/owner/repo//sub/dir?ref=latest", expected: "v1.4.2"},
+		{name: "GitLabSubgroup", source: "git+https://gitlab.test/group/sub/repo?ref=latest", expected: "v2.0.0"},
+		{name: "NoReleases", source: "git+https://github.test/owner/empty?ref=latest", expected: ""},
+		{name: "OtherHost", source: "git+https://example.com/owner/repo?ref=latest", expected: "latest"},
+		{name: "InvalidResponse", source: "git+https://github.test/owner/broken?ref=latest", err: "decoding"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(tt.source)
+			assert.NoError(t, err)
+			cfg := defaultConfig()
+			cfg.finalise()
+			ref, err := resolveGitRef(contextWithConfig(context.Background(), &cfg), Source{URL: u})
+			if tt.err != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, ref)
+			if tt.expected != "latest" {
+				assert.Equal(t, tt.expected, cfg.version)
+			}
+		})
+	}
+}
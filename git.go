@@ -1,10 +1,17 @@
 package getit
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"net/url"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/kballard/go-shellquote"
@@ -22,9 +29,35 @@ import (
 //
 //	ref=<ref>
 //	depth=<depth>
+//	single-branch=<true|false>
+//	tags=<true|false>
+//	recurse=<true|false>
+//	since=<date>
+//	shallow-exclude=<ref>
+//
+// single-branch=true fetches only the history of the cloned ref rather than the refs of every branch, tags=false skips
+// fetching tags, and recurse=true clones submodules too, shallowly if the clone is shallow. When unset, git's defaults
+// apply.
+//
+// since and shallow-exclude bound the history cloned, like depth: since=2024-01-01 clones the commits made after a
+// date, in any format accepted by git, and shallow-exclude=v1.0.0 clones the commits not reachable from a ref. Neither
+// may be combined with depth, and shallow-exclude may be repeated.
+//
+// Instead of a ref, a semantic version constraint such as "^1.2" or ">=1.4, <2" may be given as either
+// ref=semver:<constraint> or version=<constraint>. The remote tags are listed with git ls-remote, and the tag with the
+// highest version satisfying the constraint is cloned and reported in [FetchResult.Version]. Tags may have a "v"
+// prefix, and prereleases are only selected by constraints that name a prerelease of the same version.
+//
+// For GitHub and GitLab repositories, ref=latest clones the tag of the latest release, found with the forge's API,
+// or the default branch if the repository has no releases.
 type Git struct{}
 
-var _ Resolver = (*Git)(nil)
+var (
+	_ Resolver           = (*Git)(nil)
+	_ Stater             = (*Git)(nil)
+	_ Versioner          = (*Git)(nil)
+	_ CapabilityReporter = (*Git)(nil)
+)
 
 func NewGit() *Git { return &Git{} }
 
@@ -32,26 +65,255 @@ func (g *Git) Match(source *url.URL) bool {
 	return source.Scheme == "git+https" || source.Scheme == "git+ssh" || source.Scheme == "git"
 }
 
+func (g *Git) Capabilities() Capabilities {
+	return Capabilities{Refs: true, Binaries: []string{"git"}}
+}
+
+// Stat resolves the requested ref, or HEAD, to a commit using git ls-remote.
+func (g *Git) Stat(ctx context.Context, source Source) (SourceInfo, error) {
+	ref, err := resolveGitRef(ctx, source)
+	if err != nil {
+		return SourceInfo{}, err
+	}
+	if ref == "" {
+		ref = "HEAD"
+	}
+	repoURL := convertGitURL(source.URL)
+	display := redactSource(repoURL)
+	output, err := gitAuthenticated(ctx, repoURL, func(ctx context.Context) ([]byte, error) {
+		return gitOutput(ctx, "ls-remote", repoURL, ref)
+	})
+	if err != nil {
+		if cause := contextError(ctx); cause != nil {
+			err = cause
+		}
+		return SourceInfo{}, fmt.Errorf("git ls-remote %s: %w", display, err)
+	}
+	revision, _, _ := strings.Cut(string(output), "\t")
+	if revision == "" {
+		return SourceInfo{}, fmt.Errorf("git ls-remote %s: ref %q not found", display, ref)
+	}
+	return SourceInfo{Size: -1, Revision: revision}, nil
+}
+
+// Versions lists the tags of the repository using git ls-remote.
+func (g *Git) Versions(ctx context.Context, source Source) ([]string, error) {
+	tags, err := gitTags(ctx, convertGitURL(source.URL))
+	if err != nil {
+		return nil, err
+	}
+	sortVersions(tags)
+	return tags, nil
+}
+
 func (g *Git) Fetch(ctx context.Context, source Source, dest string) error {
-	args := []string{"clone"}
-	if depth := source.URL.Query().Get("depth"); depth != "" {
+	var args []string
+	if runtime.GOOS == "windows" {
+		// Allow checking out paths longer than MAX_PATH.
+		args = append(args, "-c", "core.longpaths=true")
+	}
+	args = append(args, "clone")
+	query := source.URL.Query()
+	depth := query.Get("depth")
+	if depth != "" {
 		args = append(args, "--depth", depth)
 	}
-	if ref := source.URL.Query().Get("ref"); ref != "" {
+	flags, err := gitCloneFlags(query)
+	if err != nil {
+		return err
+	}
+	args = append(args, flags...)
+	ref, err := resolveGitRef(ctx, source)
+	if err != nil {
+		return err
+	}
+	if ref != "" {
 		args = append(args, "--branch", ref)
 	}
 
 	repoURL := convertGitURL(source.URL)
 	args = append(args, repoURL, dest)
+	key := slices.Concat([]string{repoURL, "ref=" + ref, "depth=" + depth}, flags)
+
+	display := redactSource(repoURL)
+	displayArgs := slices.Clone(args)
+	displayArgs[len(displayArgs)-2] = display
 
-	cmd := exec.CommandContext(ctx, "git", args...)
-	if output, err := cmd.CombinedOutput(); err != nil {
-		argsStr := shellquote.Join(args...)
-		return fmt.Errorf("git clone failed: git %s: %w: %s", argsStr, err, output)
+	configFromContext(ctx).logger.DebugContext(ctx, "clone", "url", display, "dest", dest, "args", displayArgs)
+	ctx, span := startSpan(ctx, "getit.clone", map[string]string{"url": display})
+	ctx, cancel := withPhaseTimeout(ctx, "download", configFromContext(ctx).timeouts.Download)
+	defer cancel()
+	output, err := gitAuthenticated(ctx, repoURL, func(ctx context.Context) ([]byte, error) {
+		return gitClone(ctx, args, key, dest)
+	})
+	if err != nil {
+		if cause := contextError(ctx); cause != nil {
+			err = cause
+		}
+		argsStr := shellquote.Join(displayArgs...)
+		output = bytes.ReplaceAll(output, []byte(repoURL), []byte(display))
+		err = fmt.Errorf("git clone failed: git %s: %w: %s", argsStr, err, output)
+		span.End(err)
+		return err
+	}
+	span.End(nil)
+	return finishClone(ctx, dest)
+}
+
+// finishClone applies the options of the current fetch to a tree cloned into dest.
+func finishClone(ctx context.Context, dest string) error {
+	cfg := configFromContext(ctx)
+	if cfg.options.Deterministic {
+		if err := os.RemoveAll(filepath.Join(dest, ".git")); err != nil {
+			return fmt.Errorf("removing git metadata: %w", err)
+		}
+	}
+	if err := cfg.permissions.applyTree(ctx, dest); err != nil {
+		return err
+	}
+	if err := cfg.treeWritten(ctx, dest); err != nil {
+		return err
+	}
+	// Git does not record modification times, so only deterministic times can be applied.
+	if cfg.options.Deterministic {
+		return stampTree(ctx, dest, deterministicTime)
 	}
 	return nil
 }
 
+// gitCloneFlags returns the flags of git clone selected by the query parameters of a source, other than ref and
+// depth. Unset parameters leave git's defaults in place.
+func gitCloneFlags(query url.Values) ([]string, error) {
+	var flags []string
+	if query.Get("depth") != "" && (query.Get("since") != "" || query.Has("shallow-exclude")) {
+		return nil, errors.New("depth query parameter can't be combined with since or shallow-exclude")
+	}
+	if since := query.Get("since"); since != "" {
+		flags = append(flags, "--shallow-since", since)
+	}
+	for _, exclude := range query["shallow-exclude"] {
+		flags = append(flags, "--shallow-exclude", exclude)
+	}
+	for _, param := range []struct{ name, enable, disable string }{
+		{"single-branch", "--single-branch", "--no-single-branch"},
+		{"tags", "--tags", "--no-tags"},
+		{"recurse", "--recurse-submodules", "--no-recurse-submodules"},
+	} {
+		value := query.Get(param.name)
+		if value == "" {
+			continue
+		}
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s query parameter %q: must be true or false", param.name, value)
+		}
+		if !enabled {
+			flags = append(flags, param.disable)
+			continue
+		}
+		flags = append(flags, param.enable)
+		if param.name == "recurse" && (query.Get("depth") != "" || query.Get("since") != "" || query.Has("shallow-exclude")) {
+			flags = append(flags, "--shallow-submodules")
+		}
+	}
+	return flags, nil
+}
+
+// resolveGitRef returns the ref requested by source, if any. If a version constraint is given instead, the remote
+// tag with the highest satisfying version is returned and recorded as the version of the current fetch.
+func resolveGitRef(ctx context.Context, source Source) (string, error) {
+	query := source.URL.Query()
+	ref := query.Get("ref")
+	constraint, ok := strings.CutPrefix(ref, "semver:")
+	if version := query.Get("version"); version != "" {
+		if ref != "" {
+			return "", errors.New("ref and version query parameters are mutually exclusive")
+		}
+		constraint, ok = version, true
+	}
+	if ref == "latest" {
+		if forge, repo, ok := forgeRepo(source.URL); ok {
+			return resolveLatest(ctx, forge, repo)
+		}
+	}
+	if !ok {
+		return ref, nil
+	}
+	parsed, err := parseVersionConstraint(constraint)
+	if err != nil {
+		return "", err
+	}
+	repoURL := convertGitURL(source.URL)
+	tags, err := gitTags(ctx, repoURL)
+	if err != nil {
+		return "", err
+	}
+	tag, ok := highestVersion(tags, parsed)
+	if !ok {
+		return "", fmt.Errorf("git ls-remote %s: no tag matches version %q", redactSource(repoURL), constraint)
+	}
+	cfg := configFromContext(ctx)
+	cfg.logger.DebugContext(ctx, "resolved version", "url", redactSource(repoURL), "constraint", constraint, "tag", tag)
+	cfg.version = tag
+	return tag, nil
+}
+
+// resolveLatest returns the tag of the latest release of a repository on a forge, recording it as the version of
+// the current fetch. If the repository has no releases, "" is returned so that the default branch is cloned.
+func resolveLatest(ctx context.Context, forge forge, repo string) (string, error) {
+	tag, err := forge.latestRelease(ctx, repo)
+	if err != nil {
+		return "", fmt.Errorf("resolving latest release of %s: %w", repo, err)
+	}
+	cfg := configFromContext(ctx)
+	if tag == "" {
+		cfg.logger.DebugContext(ctx, "no releases, using default branch", "repo", repo)
+		return "", nil
+	}
+	cfg.logger.DebugContext(ctx, "resolved latest release", "repo", repo, "tag", tag)
+	cfg.version = tag
+	return tag, nil
+}
+
+// gitCommand returns a command running git with args, in the environment configured for the current fetch.
+func gitCommand(ctx context.Context, args ...string) *exec.Cmd {
+	cmd := command(ctx, "git", args...)
+	cfg := configFromContext(ctx)
+	var env []string
+	if cfg.proxy != nil {
+		env = append(env, cfg.proxy.env()...)
+	}
+	env = append(env, gitConfigEnv(cfg.gitConfig)...)
+	env = append(env, cfg.gitEnv...)
+	if cfg.gitIsolation != nil {
+		cmd.Env = append(cfg.gitIsolation.environ(os.Environ()), env...)
+	} else if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	return cmd
+}
+
+// gitTags lists the tags of a remote repository.
+func gitTags(ctx context.Context, repoURL string) ([]string, error) {
+	output, err := gitAuthenticated(ctx, repoURL, func(ctx context.Context) ([]byte, error) {
+		return gitOutput(ctx, "ls-remote", "--tags", "--refs", repoURL)
+	})
+	if err != nil {
+		if cause := contextError(ctx); cause != nil {
+			err = cause
+		}
+		return nil, fmt.Errorf("git ls-remote %s: %w", redactSource(repoURL), err)
+	}
+	var tags []string
+	for line := range strings.Lines(string(output)) {
+		_, ref, _ := strings.Cut(strings.TrimSpace(line), "\t")
+		if tag, ok := strings.CutPrefix(ref, "refs/tags/"); ok {
+			tags = append(tags, tag)
+		}
+	}
+	return tags, nil
+}
+
 // convertGitURL converts a getit git URL to a standard git URL.
 // git+https://host/path -> https://host/path
 // git+ssh://host/path -> git@host:path (SCP-style)
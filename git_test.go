@@ -6,6 +6,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/alecthomas/assert/v2"
@@ -187,6 +188,104 @@ func TestGitFetchWithDepth(t *testing.T) {
 	assert.Equal(t, "1\n", string(output))
 }
 
+func TestGitFetchCloneFlags(t *testing.T) {
+	// Allow submodules to be cloned from the local filesystem.
+	t.Setenv("GIT_CONFIG_COUNT", "1")
+	t.Setenv("GIT_CONFIG_KEY_0", "protocol.file.allow")
+	t.Setenv("GIT_CONFIG_VALUE_0", "always")
+	subDir, _ := createTestRepo(t)
+	repoDir, runGit := createTestRepo(t)
+	runGit("branch", "other")
+	runGit("tag", "v1.0.0")
+	runGit("submodule", "add", subDir, "sub")
+	runGit("commit", "-m", "Add submodule")
+
+	tests := []struct {
+		query     string
+		branches  string
+		tags      string
+		submodule bool
+		err       string
+	}{
+		{query: "", branches: "origin/master\norigin/other\n", tags: "v1.0.0\n"},
+		{query: "?single-branch=true", branches: "origin/master\n", tags: "v1.0.0\n"},
+		{query: "?single-branch=false&depth=1", branches: "origin/master\norigin/other\n", tags: "v1.0.0\n"},
+		{query: "?tags=false", branches: "origin/master\norigin/other\n", tags: ""},
+		{query: "?recurse=true&single-branch=1&tags=0", branches: "origin/master\n", tags: "", submodule: true},
+		{query: "?recurse=false", branches: "origin/master\norigin/other\n", tags: "v1.0.0\n"},
+		{query: "?tags=no", err: `invalid tags query parameter "no": must be true or false`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			u, err := url.Parse("git+file://" + repoDir + tt.query)
+			assert.NoError(t, err)
+			dest := t.TempDir()
+			err = NewGit().Fetch(context.Background(), Source{URL: u}, dest)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			output := func(args ...string) string {
+				cmd := exec.Command("git", args...)
+				cmd.Dir = dest
+				output, err := cmd.Output()
+				assert.NoError(t, err)
+				return string(output)
+			}
+			// Whether origin/HEAD is created varies between versions of git.
+			branches := strings.ReplaceAll(output("for-each-ref", "--format=%(refname:short)", "refs/remotes"), "origin/HEAD\n", "")
+			assert.Equal(t, tt.branches, branches)
+			assert.Equal(t, tt.tags, output("tag"))
+			_, err = os.Stat(filepath.Join(dest, "sub", "file.txt"))
+			assert.Equal(t, tt.submodule, err == nil, "%v", err)
+		})
+	}
+}
+
+func TestGitFetchShallowHistory(t *testing.T) {
+	t.Setenv("GIT_COMMITTER_DATE", "2023-06-01T00:00:00Z")
+	repoDir, runGit := createTestRepo(t)
+	runGit("tag", "v1.0.0")
+	for _, date := range []string{"2024-02-01T00:00:00Z", "2024-03-01T00:00:00Z", "2024-04-01T00:00:00Z"} {
+		t.Setenv("GIT_COMMITTER_DATE", date)
+		assert.NoError(t, os.WriteFile(filepath.Join(repoDir, "file.txt"), []byte(date+"\n"), 0o644))
+		runGit("commit", "-am", date)
+		if date == "2024-02-01T00:00:00Z" {
+			runGit("tag", "v1.1.0")
+		}
+	}
+
+	tests := []struct {
+		query   string
+		commits string
+		err     string
+	}{
+		{query: "?since=2024-01-01", commits: "3\n"},
+		{query: "?since=2024-01-01&depth=2", err: "depth query parameter can't be combined with since or shallow-exclude"},
+		{query: "?shallow-exclude=v1.0.0", commits: "3\n"},
+		{query: "?shallow-exclude=v1.0.0&shallow-exclude=v1.1.0", commits: "2\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			u, err := url.Parse("git+file://" + repoDir + tt.query)
+			assert.NoError(t, err)
+			dest := t.TempDir()
+			err = NewGit().Fetch(context.Background(), Source{URL: u}, dest)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			cmd := exec.Command("git", "rev-list", "--count", "HEAD")
+			cmd.Dir = dest
+			output, err := cmd.Output()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.commits, string(output))
+		})
+	}
+}
+
 func TestGitFetchCancelledContext(t *testing.T) {
 	repoDir, _ := createTestRepo(t)
 
@@ -212,3 +311,140 @@ func TestGitFetchInvalidRepo(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "git clone failed")
 }
+
+func TestGitFetchWithUmask(t *testing.T) {
+	repoDir, _ := createTestRepo(t)
+
+	u, err := url.Parse("git+file://" + repoDir)
+	assert.NoError(t, err)
+
+	dest := t.TempDir()
+	cfg := defaultConfig()
+	cfg.permissions = Permissions{Umask: 0o077}
+	cfg.finalise()
+	ctx := contextWithConfig(context.Background(), &cfg)
+	err = NewGit().Fetch(ctx, Source{URL: u}, dest)
+	assert.NoError(t, err)
+
+	info, err := os.Stat(filepath.Join(dest, "file.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+}
+
+func TestGitFetchDeterministic(t *testing.T) {
+	repoDir, _ := createTestRepo(t)
+
+	u, err := url.Parse("git+file://" + repoDir)
+	assert.NoError(t, err)
+
+	dest := t.TempDir()
+	cfg := defaultConfig()
+	cfg.options = FetchOptions{Deterministic: true}
+	cfg.permissions = Permissions{Normalize: true}
+	cfg.finalise()
+	ctx := contextWithConfig(context.Background(), &cfg)
+	err = NewGit().Fetch(ctx, Source{URL: u}, dest)
+	assert.NoError(t, err)
+
+	_, err = os.Stat(filepath.Join(dest, ".git"))
+	assert.True(t, os.IsNotExist(err))
+	info, err := os.Stat(filepath.Join(dest, "file.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o644), info.Mode().Perm())
+	assert.True(t, deterministicTime.Equal(info.ModTime()))
+}
+
+func TestGitStat(t *testing.T) {
+	repoDir, runGit := createTestRepo(t)
+	runGit("branch", "feature-branch")
+	cmd := exec.Command("git", "rev-parse", "HEAD")
+	cmd.Dir = repoDir
+	head, err := cmd.Output()
+	assert.NoError(t, err)
+
+	u, err := url.Parse("git+file://" + repoDir + "?ref=feature-branch")
+	assert.NoError(t, err)
+	info, err := NewGit().Stat(context.Background(), Source{URL: u})
+	assert.NoError(t, err)
+	assert.Equal(t, strings.TrimSpace(string(head)), info.Revision)
+
+	u, err = url.Parse("git+file://" + repoDir + "?ref=missing")
+	assert.NoError(t, err)
+	_, err = NewGit().Stat(context.Background(), Source{URL: u})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not found")
+}
+
+func TestGitFetchWithVersion(t *testing.T) {
+	repoDir, runGit := createTestRepo(t)
+	for _, tag := range []string{"v1.0.0", "v1.2.0", "v1.3.0-rc.1", "v2.0.0"} {
+		err := os.WriteFile(filepath.Join(repoDir, "file.txt"), []byte(tag+"\n"), 0o644)
+		assert.NoError(t, err)
+		runGit("commit", "-am", tag)
+		runGit("tag", "-a", "-m", tag, tag)
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		expected string
+		err      string
+	}{
+		{name: "RefSemver", query: "?ref=semver:^1.0", expected: "v1.2.0"},
+		{name: "Version", query: "?version=" + url.QueryEscape(">=1.0, <2"), expected: "v1.2.0"},
+		{name: "Major", query: "?version=2", expected: "v2.0.0"},
+		{name: "NoMatch", query: "?version=^3", err: `no tag matches version "^3"`},
+		{name: "Conflict", query: "?ref=main&version=^1", err: "mutually exclusive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse("git+file://" + repoDir + tt.query)
+			assert.NoError(t, err)
+			cfg := defaultConfig()
+			ctx := contextWithConfig(context.Background(), &cfg)
+			dest := t.TempDir()
+			err = NewGit().Fetch(ctx, Source{URL: u}, dest)
+			if tt.err != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, cfg.version)
+			content, err := os.ReadFile(filepath.Join(dest, "file.txt"))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected+"\n", string(content))
+		})
+	}
+}
+
+func TestGitVersions(t *testing.T) {
+	repoDir, runGit := createTestRepo(t)
+	for _, tag := range []string{"v1.10.0", "v1.2.0", "v2.0.0-rc.1", "v2.0.0", "nightly", "v1.9.0"} {
+		runGit("tag", tag)
+	}
+	u, err := url.Parse("git+file://" + repoDir)
+	assert.NoError(t, err)
+	versions, err := NewGit().Versions(context.Background(), Source{URL: u})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"v2.0.0", "v2.0.0-rc.1", "v1.10.0", "v1.9.0", "v1.2.0", "nightly"}, versions)
+}
+
+func TestGitFetchUsage(t *testing.T) {
+	repoDir, _ := createTestRepo(t)
+	u, err := url.Parse("git+file://" + repoDir)
+	assert.NoError(t, err)
+
+	cfg := defaultConfig()
+	cfg.finalise()
+	cfg.options.Deterministic = true
+	cfg.usage = &usageCounter{}
+	var progress []DiskUsage
+	cfg.hooks.OnProgress = func(usage DiskUsage) { progress = append(progress, usage) }
+	err = NewGit().Fetch(contextWithConfig(context.Background(), &cfg), Source{URL: u}, t.TempDir())
+	assert.NoError(t, err)
+
+	expected := DiskUsage{Files: 2, Bytes: int64(len("hello from test\n") + len("nested content\n"))}
+	assert.Equal(t, expected, cfg.usage.usage())
+	assert.Equal(t, []DiskUsage{expected}, progress)
+}
@@ -0,0 +1,88 @@
+package getit
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// WithGitConfig sets a git config variable for the git commands run by a [Fetcher], as with git -c key=value, eg.
+// "http.sslCAInfo" or "http.https://github.com/.extraHeader". It may be repeated to set multi-valued variables.
+//
+// Variables are passed to git in its environment rather than its arguments, so that secrets don't appear in process
+// listings.
+func WithGitConfig(key, value string) Option {
+	return func(f *Fetcher) {
+		f.config.gitConfig = append(f.config.gitConfig, gitConfigEntry{key: key, value: value})
+	}
+}
+
+// WithGitHeaders adds headers to the HTTP requests made by git to the remote being fetched, eg. "Authorization: Basic
+// ..." for a GitHub App installation token.
+//
+// Headers are scoped to the scheme and host of the remote, after any url.<base>.insteadOf rewrites set with
+// [WithGitConfig], as with "http.https://host/.extraHeader". They are sent to other repositories on the same host,
+// eg. submodules, but never to other hosts. Use [WithGitConfig] with "http.extraHeader" to send headers to every
+// remote, and beware that this includes plain http:// remotes and submodules hosted elsewhere.
+func WithGitHeaders(headers http.Header) Option {
+	return func(f *Fetcher) {
+		for key, values := range headers {
+			for _, value := range values {
+				f.config.gitHeaders = append(f.config.gitHeaders, http.CanonicalHeaderKey(key)+": "+value)
+			}
+		}
+	}
+}
+
+// gitHeaderConfig returns the config entries sending the headers of [WithGitHeaders] to the remote repoURL.
+func gitHeaderConfig(cfg *config, repoURL string) []gitConfigEntry {
+	if len(cfg.gitHeaders) == 0 {
+		return nil
+	}
+	u, err := url.Parse(gitRewrite(cfg.gitConfig, repoURL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil
+	}
+	key := "http." + u.Scheme + "://" + u.Host + "/.extraHeader"
+	entries := make([]gitConfigEntry, 0, len(cfg.gitHeaders))
+	for _, header := range cfg.gitHeaders {
+		entries = append(entries, gitConfigEntry{key: key, value: header})
+	}
+	return entries
+}
+
+// gitRewrite applies the longest matching url.<base>.insteadOf rewrite in entries to rawURL, as git does.
+func gitRewrite(entries []gitConfigEntry, rawURL string) string {
+	longest, rewritten := 0, rawURL
+	for _, entry := range entries {
+		base, ok := strings.CutPrefix(entry.key, "url.")
+		i := strings.LastIndex(base, ".")
+		if !ok || i < 0 || !strings.EqualFold(base[i+1:], "insteadOf") {
+			continue
+		}
+		if strings.HasPrefix(rawURL, entry.value) && len(entry.value) > longest {
+			longest, rewritten = len(entry.value), base[:i]+rawURL[len(entry.value):]
+		}
+	}
+	return rewritten
+}
+
+type gitConfigEntry struct {
+	key   string
+	value string
+}
+
+// gitConfigEnv returns the environment variables passing entries to git.
+func gitConfigEnv(entries []gitConfigEntry) []string {
+	if len(entries) == 0 {
+		return nil
+	}
+	env := make([]string, 0, 2*len(entries)+1)
+	env = append(env, "GIT_CONFIG_COUNT="+strconv.Itoa(len(entries)))
+	for i, entry := range entries {
+		n := strconv.Itoa(i)
+		env = append(env, "GIT_CONFIG_KEY_"+n+"="+entry.key, "GIT_CONFIG_VALUE_"+n+"="+entry.value)
+	}
+	return env
+}
@@ -43,3 +43,17 @@ func SingleGitHubOrg(org string) Mapper {
 		return "", false
 	}
 }
+
+var gistRe = regexp.MustCompile(`^(?:https://)?gist\.github\.com/(?:[a-zA-Z0-9_-]+/)?([0-9a-fA-F]+)(?:\.git)?/?([?#].*)?$`)
+
+// Gist is a [Mapper] that supports GitHub gist URLs, with or without a scheme or user, eg.
+// "gist.github.com/user/<id>". The gist is cloned with git, so its files are fetched into the destination and the ref
+// query parameter selects a revision.
+//
+// Query parameters and anchors are preserved.
+func Gist(source string) (string, bool) {
+	if gistRe.MatchString(source) {
+		return gistRe.ReplaceAllString(source, `git+https://gist.github.com/$1.git$2`), true
+	}
+	return "", false
+}
@@ -230,3 +230,56 @@ func TestSingleGitHubOrg(t *testing.T) {
 		})
 	}
 }
+
+func TestGist(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+		ok       bool
+	}{
+		{
+			name:     "UserAndID",
+			source:   "gist.github.com/octocat/6cad326836d38bd3a7ae",
+			expected: "git+https://gist.github.com/6cad326836d38bd3a7ae.git",
+			ok:       true,
+		},
+		{
+			name:     "HTTPSWithQuery",
+			source:   "https://gist.github.com/octocat/6cad326836d38bd3a7ae?ref=abc123",
+			expected: "git+https://gist.github.com/6cad326836d38bd3a7ae.git?ref=abc123",
+			ok:       true,
+		},
+		{
+			name:     "IDOnly",
+			source:   "gist.github.com/6cad326836d38bd3a7ae",
+			expected: "git+https://gist.github.com/6cad326836d38bd3a7ae.git",
+			ok:       true,
+		},
+		{
+			name:     "CloneURL",
+			source:   "https://gist.github.com/6cad326836d38bd3a7ae.git#anchor",
+			expected: "git+https://gist.github.com/6cad326836d38bd3a7ae.git#anchor",
+			ok:       true,
+		},
+		{
+			name:   "NotHex",
+			source: "gist.github.com/octocat/notes",
+		},
+		{
+			name:   "GitHubRepo",
+			source: "github.com/octocat/6cad326836d38bd3a7ae",
+		},
+		{
+			name:   "HTTPScheme",
+			source: "http://gist.github.com/octocat/6cad326836d38bd3a7ae",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, ok := getit.Gist(tt.source)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package getit
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// GitIsolation configures how git commands are isolated from the configuration and environment of the user running
+// them, so that eg. url.insteadOf rewrites, hooks and credential helpers set up on a shared CI runner can't silently
+// alter fetches. See [WithGitIsolation].
+type GitIsolation struct {
+	// NoSystemConfig ignores the system git config, eg. /etc/gitconfig, as with GIT_CONFIG_NOSYSTEM.
+	NoSystemConfig bool
+	// NoGlobalConfig ignores the global git config of the user, eg. ~/.gitconfig and ~/.config/git/config.
+	NoGlobalConfig bool
+	// Home, if set, is used as the home directory of git, so that its global config, ~/.git-credentials and the SSH
+	// keys and known hosts in ~/.ssh are read from there instead.
+	Home string
+	// ScrubEnvironment runs git with a minimal environment rather than the environment of the process: only PATH,
+	// SSH_AUTH_SOCK, the temporary directory variables, those needed by Windows and those named by KeepEnv are kept.
+	// This discards GIT_* variables, eg. GIT_SSH_COMMAND or GIT_CONFIG_PARAMETERS, along with any proxy variables;
+	// use [WithProxy] to configure proxies explicitly.
+	ScrubEnvironment bool
+	// KeepEnv names further environment variables kept by ScrubEnvironment.
+	KeepEnv []string
+}
+
+// WithGitIsolation isolates the git commands run by a [Fetcher] from the configuration and environment of the user
+// running them.
+func WithGitIsolation(isolation GitIsolation) Option {
+	return func(f *Fetcher) { f.config.gitIsolation = &isolation }
+}
+
+// scrubbedEnv names the environment variables kept by [GitIsolation.ScrubEnvironment].
+var scrubbedEnv = []string{"PATH", "SSH_AUTH_SOCK", "TMPDIR", "TMP", "TEMP", "SYSTEMROOT", "WINDIR", "COMSPEC", "PATHEXT"}
+
+// environ returns the environment git runs in, derived from the environment of the process.
+func (g *GitIsolation) environ(environ []string) []string {
+	env := []string{}
+	for _, variable := range environ {
+		name, _, _ := strings.Cut(variable, "=")
+		if g.Home != "" && strings.EqualFold(name, "GIT_CONFIG_GLOBAL") {
+			// The global config is read from Home instead.
+			continue
+		}
+		if !g.ScrubEnvironment || slices.ContainsFunc(slices.Concat(scrubbedEnv, g.KeepEnv), func(keep string) bool {
+			return strings.EqualFold(name, keep)
+		}) {
+			env = append(env, variable)
+		}
+	}
+	if g.NoSystemConfig {
+		env = append(env, "GIT_CONFIG_NOSYSTEM=1")
+	}
+	if g.NoGlobalConfig {
+		env = append(env, "GIT_CONFIG_GLOBAL="+os.DevNull)
+	}
+	if g.Home != "" {
+		// XDG_CONFIG_HOME would otherwise locate a second global config outside of Home.
+		env = append(env, "HOME="+g.Home, "XDG_CONFIG_HOME="+filepath.Join(g.Home, ".config"))
+	}
+	return env
+}
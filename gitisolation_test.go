@@ -0,0 +1,75 @@
+package getit //nolint:testpackage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestWithGitIsolation(t *testing.T) {
+	repoDir, _ := createTestRepo(t)
+	server := gitHTTPServer(t, repoDir, "")
+	// The user's global config rewrites the repository to a host that doesn't exist.
+	config := filepath.Join(t.TempDir(), "gitconfig")
+	assert.NoError(t, os.WriteFile(config, []byte("[url \"https://invalid.test/\"]\n\tinsteadOf = "+server.URL+"/\n"), 0o600))
+	t.Setenv("GIT_CONFIG_GLOBAL", config)
+
+	tests := []struct {
+		name      string
+		isolation *GitIsolation
+		fails     bool
+		errSubstr string
+	}{
+		{name: "None", fails: true, errSubstr: "invalid.test"},
+		{name: "NoGlobalConfig", isolation: &GitIsolation{NoGlobalConfig: true, NoSystemConfig: true}},
+		{name: "Home", isolation: &GitIsolation{Home: t.TempDir()}},
+		{name: "ScrubEnvironment", isolation: &GitIsolation{ScrubEnvironment: true, KeepEnv: []string{"GIT_SSL_NO_VERIFY"}}},
+		// GIT_SSL_NO_VERIFY is scrubbed, so the test server's certificate is rejected.
+		{name: "ScrubEnvironmentFully", isolation: &GitIsolation{ScrubEnvironment: true, Home: t.TempDir()}, fails: true, errSubstr: "certificate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var options []Option
+			if tt.isolation != nil {
+				options = append(options, WithGitIsolation(*tt.isolation))
+			}
+			fetcher := New([]Resolver{NewGit()}, nil, options...)
+			dest := t.TempDir()
+			err := fetcher.Fetch(context.Background(), "git+"+server.URL+"/repo", dest)
+			if tt.fails {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errSubstr)
+				return
+			}
+			assert.NoError(t, err)
+			content, err := os.ReadFile(filepath.Join(dest, "file.txt"))
+			assert.NoError(t, err)
+			assert.Equal(t, "hello from test\n", string(content))
+		})
+	}
+}
+
+func TestGitIsolationEnviron(t *testing.T) {
+	environ := []string{"PATH=/bin", "HOME=/home/user", "GIT_SSH_COMMAND=ssh -i key", "GIT_CONFIG_GLOBAL=/etc/evil", "Tmp=/tmp"}
+	tests := []struct {
+		name      string
+		isolation GitIsolation
+		expected  []string
+	}{
+		{name: "Inherit", isolation: GitIsolation{}, expected: environ},
+		{name: "NoConfig", isolation: GitIsolation{NoSystemConfig: true, NoGlobalConfig: true},
+			expected: append(environ[:len(environ):len(environ)], "GIT_CONFIG_NOSYSTEM=1", "GIT_CONFIG_GLOBAL="+os.DevNull)},
+		{name: "Home", isolation: GitIsolation{Home: "/isolated"},
+			expected: []string{"PATH=/bin", "HOME=/home/user", "GIT_SSH_COMMAND=ssh -i key", "Tmp=/tmp", "HOME=/isolated", "XDG_CONFIG_HOME=" + filepath.Join("/isolated", ".config")}},
+		{name: "Scrub", isolation: GitIsolation{ScrubEnvironment: true, KeepEnv: []string{"git_ssh_command"}},
+			expected: []string{"PATH=/bin", "GIT_SSH_COMMAND=ssh -i key", "Tmp=/tmp"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.isolation.environ(environ))
+		})
+	}
+}
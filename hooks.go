@@ -0,0 +1,69 @@
+package getit
+
+import "net/url"
+
+// Hooks are lifecycle callbacks invoked by a [Fetcher] as a fetch progresses.
+//
+// All fields are optional. Hooks are called synchronously from the fetching goroutine, so they should return
+// promptly.
+type Hooks struct {
+	// OnResolve is called once a source has been resolved, before anything is fetched. Returning an error aborts
+	// the fetch, which makes this a convenient place to apply policy checks. As with the other hooks, credentials are
+	// redacted from both source and the resolved URL.
+	OnResolve func(source string, resolved Source) error
+	// OnDownloadStart is called when a download begins, with credentials redacted from u. size is -1 if unknown.
+	OnDownloadStart func(u *url.URL, size int64)
+	// OnFileExtracted is called for each file, directory or link written to the destination by the archive and
+	// file resolvers. path is relative to the destination.
+	OnFileExtracted func(path string, size int64)
+	// OnProgress is called with the disk usage of the fetch so far each time files are written to the destination,
+	// once per file for the archive and file resolvers, or once a git clone completes.
+	OnProgress func(usage DiskUsage)
+	// OnComplete is called when a fetch succeeds, with credentials redacted from source.
+	OnComplete func(source, dest string)
+	// OnError is called when a fetch fails, with credentials redacted from source.
+	OnError func(source string, err error)
+}
+
+// WithHooks registers lifecycle callbacks on a [Fetcher].
+func WithHooks(hooks Hooks) Option {
+	return func(f *Fetcher) { f.config.hooks = hooks }
+}
+
+func (h Hooks) resolve(source string, resolved Source) error {
+	if h.OnResolve == nil {
+		return nil
+	}
+	resolved.URL = redactURL(resolved.URL)
+	return h.OnResolve(redactSource(source), resolved)
+}
+
+func (h Hooks) downloadStart(u *url.URL, size int64) {
+	if h.OnDownloadStart != nil {
+		h.OnDownloadStart(u, size)
+	}
+}
+
+func (h Hooks) fileExtracted(path string, size int64) {
+	if h.OnFileExtracted != nil {
+		h.OnFileExtracted(path, size)
+	}
+}
+
+func (h Hooks) progress(usage DiskUsage) {
+	if h.OnProgress != nil {
+		h.OnProgress(usage)
+	}
+}
+
+func (h Hooks) complete(source, dest string) {
+	if h.OnComplete != nil {
+		h.OnComplete(source, dest)
+	}
+}
+
+func (h Hooks) error(source string, err error) {
+	if h.OnError != nil {
+		h.OnError(source, err)
+	}
+}
@@ -0,0 +1,61 @@
+package getit
+
+import (
+	"context"
+	"net"
+	"strings"
+)
+
+// WithHostMapping connects to an alternate address in place of a host, as if by an entry in /etc/hosts, eg. for
+// split-horizon DNS or to test against a staging artifact host:
+//
+//	getit.WithHostMapping(map[string]string{
+//		"artifacts.example.com":     "10.0.0.5",
+//		"downloads.example.com:443": "staging.example.com:8443",
+//	})
+//
+// Keys are host names, or host:port to map a single port. Values are IP addresses or host names, with an optional port
+// replacing that of the original address. Mapping happens at dial time, so requests keep their original Host header
+// and TLS server name, and certificates must be valid for the original host. Git uses its own transport, so git+https
+// sources are not affected.
+func WithHostMapping(mapping map[string]string) Option {
+	return func(f *Fetcher) {
+		if f.config.hostMapping == nil {
+			f.config.hostMapping = hostMapping{}
+		}
+		for from, to := range mapping {
+			f.config.hostMapping[strings.ToLower(from)] = to
+		}
+	}
+}
+
+// hostMapping maps dialled addresses to alternates.
+type hostMapping map[string]string
+
+// dialContext wraps dial to connect to mapped addresses.
+func (h hostMapping) dialContext(dial func(ctx context.Context, network, address string) (net.Conn, error)) func(ctx context.Context, network, address string) (net.Conn, error) {
+	if len(h) == 0 {
+		return dial
+	}
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		return dial(ctx, network, h.mapAddress(address))
+	}
+}
+
+// mapAddress returns the address to connect to in place of address.
+func (h hostMapping) mapAddress(address string) string {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return address
+	}
+	to, ok := h[strings.ToLower(address)]
+	if !ok {
+		if to, ok = h[strings.ToLower(host)]; !ok {
+			return address
+		}
+	}
+	if _, _, err := net.SplitHostPort(to); err == nil {
+		return to
+	}
+	return net.JoinHostPort(strings.Trim(to, "[]"), port)
+}
@@ -0,0 +1,65 @@
+package getit_test
+
+import (
+	"context"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/block/getit"
+)
+
+func TestHostMapping(t *testing.T) {
+	body := tarball(t, "file.txt", "hello\n")
+	var hosts []string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hosts = append(hosts, r.Host)
+		_, _ = w.Write(body)
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	// The test certificate is valid for example.com.
+	tlsServer := httptest.NewTLSServer(handler)
+	defer tlsServer.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(tlsServer.Certificate())
+	addr := server.Listener.Addr().String()
+	_, port, err := net.SplitHostPort(addr)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		mapping map[string]string
+		source  string
+		host    string
+		error   string
+	}{
+		{name: "IP", mapping: map[string]string{"artifacts.example.invalid": "127.0.0.1"}, source: "http://artifacts.example.invalid:" + port + "/archive.tar.gz", host: "artifacts.example.invalid:" + port},
+		{name: "CaseInsensitive", mapping: map[string]string{"Artifacts.Example.Invalid": "127.0.0.1"}, source: "http://artifacts.example.invalid:" + port + "/archive.tar.gz", host: "artifacts.example.invalid:" + port},
+		{name: "Port", mapping: map[string]string{"artifacts.example.invalid": addr}, source: "http://artifacts.example.invalid/archive.tar.gz", host: "artifacts.example.invalid"},
+		{name: "HostPort", mapping: map[string]string{"artifacts.example.invalid:80": "localhost:" + port}, source: "http://artifacts.example.invalid/archive.tar.gz", host: "artifacts.example.invalid"},
+		{name: "OtherPort", mapping: map[string]string{"artifacts.example.invalid:8080": addr}, source: "http://artifacts.example.invalid/archive.tar.gz", error: "artifacts.example.invalid"},
+		{name: "TLS", mapping: map[string]string{"example.com": tlsServer.Listener.Addr().String()}, source: "https://example.com/archive.tar.gz", host: "example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hosts = nil
+			fetcher := getit.New([]getit.Resolver{getit.NewTAR()}, nil,
+				getit.WithHostMapping(tt.mapping),
+				getit.WithTLS(getit.TLS{RootCAs: roots}),
+			)
+			err := fetcher.Fetch(context.Background(), tt.source, t.TempDir())
+			if tt.error != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.error)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, []string{tt.host}, hosts)
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package getit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// newHTTPClient builds the HTTP client used by a Fetcher from its configuration.
+func newHTTPClient(cfg *config) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone() //nolint:forcetypeassert,errcheck // always an *http.Transport
+	// As for http.DefaultTransport.
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if timeout := cfg.timeouts.Connect; timeout > 0 {
+		dialer.Timeout = timeout
+		transport.TLSHandshakeTimeout = timeout
+		transport.ResponseHeaderTimeout = timeout
+	}
+	cfg.connections.apply(transport, dialer)
+	transport.DialContext = cfg.hostMapping.dialContext(cfg.connections.dialContext(dialer))
+	if cfg.proxy != nil {
+		transport.Proxy = cfg.proxy.proxyFunc()
+	}
+	if cfg.tls != nil {
+		transport.TLSClientConfig = cfg.tls.clientConfig()
+	}
+	var rt http.RoundTripper = transport
+	for _, middleware := range slices.Backward(cfg.middleware) {
+		rt = middleware(rt)
+	}
+	if cfg.credentials != nil {
+		rt = &authTransport{credentials: cfg.credentials, next: rt}
+	}
+	if len(cfg.hostHeaders) > 0 {
+		rt = &hostHeaderTransport{headers: cfg.hostHeaders, next: rt}
+	}
+	if cfg.recorder != nil {
+		rt = &recordingTransport{recorder: cfg.recorder, next: rt}
+	}
+	if cfg.faults != nil {
+		rt = &faultTransport{faults: *cfg.faults, next: rt}
+	}
+	client := &http.Client{Transport: rt}
+	if cfg.cookies != nil {
+		client.Jar = cfg.cookies.newJar()
+	}
+	return client
+}
+
+// httpGet issues a GET request for u, returning an error if the response is not 200 OK.
+//
+// If parallel [Downloads] are configured and the server supports ranged requests, the body of the returned response
+// is downloaded in parallel chunks.
+//
+// The caller is responsible for closing the response body.
+func httpGet(ctx context.Context, u *url.URL) (*http.Response, error) {
+	return httpGetRange(ctx, u, "")
+}
+
+// httpGetRange is like httpGet, but if byteRange is set it is requested with a Range header and a 206 Partial
+// Content response is also accepted. Servers that don't support ranges respond with the whole source.
+func httpGetRange(ctx context.Context, u *url.URL, byteRange string) (resp *http.Response, err error) {
+	display := RedactURL(u)
+	ctx, span := startSpan(ctx, "getit.request", map[string]string{"url": display})
+	defer func() { span.End(err) }()
+	cfg := configFromContext(ctx)
+	ctx, cancel := withPhaseTimeout(ctx, "download", cfg.timeouts.Download)
+	defer func() {
+		if err != nil {
+			cancel()
+		}
+	}()
+	logger := cfg.logger
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	cfg.applyHeaders(req)
+	parallel := byteRange == "" && cfg.downloads.Concurrency > 1
+	if byteRange != "" {
+		req.Header.Set("Range", byteRange)
+	} else if parallel {
+		req.Header.Set("Range", fmt.Sprintf("bytes=0-%d", cfg.downloads.chunkSize()-1))
+	}
+	logger.DebugContext(ctx, "request", "method", req.Method, "url", display)
+	resp, err = cfg.client.Do(req)
+	if err != nil {
+		if timeout := (*TimeoutError)(nil); errors.As(context.Cause(ctx), &timeout) {
+			return nil, fmt.Errorf("fetching %s: %w", display, timeout)
+		}
+		if urlErr := (*url.Error)(nil); errors.As(err, &urlErr) {
+			urlErr.URL = display
+		}
+		return nil, fmt.Errorf("fetching %s: %w", display, err)
+	}
+	logger.DebugContext(ctx, "response", "url", display, "status", resp.StatusCode, "content_length", resp.ContentLength)
+	switch {
+	case byteRange != "" && resp.StatusCode == http.StatusPartialContent:
+	case parallel && resp.StatusCode == http.StatusPartialContent:
+		if err := continueRanged(ctx, cfg, u, resp); err != nil {
+			_ = resp.Body.Close()
+			return nil, fmt.Errorf("fetching %s: %w", display, err)
+		}
+	case resp.StatusCode != http.StatusOK:
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("fetching %s: %s", display, resp.Status)
+	}
+	resp.Body = &timeoutBody{contextReader: contextReader{ctx: ctx, r: resp.Body}, body: resp.Body, cancel: cancel}
+	size := resp.ContentLength
+	if resp.StatusCode == http.StatusPartialContent {
+		if _, total, err := contentRange(resp.Header.Get("Content-Range")); err == nil {
+			size = total
+		}
+	}
+	cfg.hooks.downloadStart(redactURL(u), size)
+	return resp, nil
+}
+
+// continueRanged turns a response to a ranged request for the first chunk into a response for the whole source,
+// downloading any remaining chunks in parallel.
+func continueRanged(ctx context.Context, cfg *config, u *url.URL, resp *http.Response) error {
+	start, total, err := contentRange(resp.Header.Get("Content-Range"))
+	if err != nil {
+		return err
+	}
+	if start != 0 || total < 0 {
+		return fmt.Errorf("unexpected Content-Range %q", resp.Header.Get("Content-Range"))
+	}
+	resp.ContentLength = total
+	if total > cfg.downloads.chunkSize() {
+		cfg.logger.DebugContext(ctx, "parallel download", "url", RedactURL(u), "size", total, "concurrency", cfg.downloads.Concurrency)
+		resp.Body = newChunkedBody(ctx, cfg, u, resp, total)
+	}
+	return nil
+}
+
+// applyHeaders sets the configured User-Agent and extra headers on req. Per-host headers are added by
+// hostHeaderTransport instead, as the client copies the headers of a request to any redirects it follows.
+func (c *config) applyHeaders(req *http.Request) {
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+	mergeHeaders(req.Header, c.headers)
+}
+
+// hostHeaderTransport adds the headers configured with [WithHostHeaders] to each request, including redirects,
+// according to the host it is sent to.
+type hostHeaderTransport struct {
+	headers map[string]http.Header
+	next    http.RoundTripper
+}
+
+func (t *hostHeaderTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	headers, ok := t.headers[req.URL.Host]
+	if !ok {
+		headers, ok = t.headers[req.URL.Hostname()]
+	}
+	if ok {
+		req = req.Clone(req.Context())
+		mergeHeaders(req.Header, headers)
+	}
+	return t.next.RoundTrip(req) //nolint:wrapcheck // transport errors are passed through
+}
+
+// dispositionFilename returns the filename suggested by a Content-Disposition header, reduced to its final path
+// element so that it can't escape the destination, or "" if there is none.
+func dispositionFilename(header http.Header) string {
+	_, params, err := mime.ParseMediaType(header.Get("Content-Disposition"))
+	if err != nil {
+		return ""
+	}
+	name := path.Base(strings.ReplaceAll(params["filename"], `\`, "/"))
+	if name == "." || name == ".." || name == "/" {
+		return ""
+	}
+	return name
+}
+
+// httpStat describes a remote file using a HEAD request, falling back to a GET whose body is discarded unread if
+// the server doesn't support HEAD.
+func httpStat(ctx context.Context, u *url.URL) (SourceInfo, error) {
+	cfg := configFromContext(ctx)
+	display := RedactURL(u)
+	resp, err := httpDo(ctx, cfg, http.MethodHead, u)
+	if err == nil && (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented) {
+		resp, err = httpDo(ctx, cfg, http.MethodGet, u)
+	}
+	if err != nil {
+		return SourceInfo{}, fmt.Errorf("stat %s: %w", display, err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return SourceInfo{}, fmt.Errorf("stat %s: %s", display, resp.Status)
+	}
+	info := SourceInfo{Size: resp.ContentLength, Revision: resp.Header.Get("ETag")}
+	if modified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		info.Modified = modified
+	}
+	return info, nil
+}
+
+func httpDo(ctx context.Context, cfg *config, method string, u *url.URL) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	return sendRequest(ctx, cfg, req)
+}
+
+// sendRequest sends req with the configured headers and client, redacting credentials from errors.
+func sendRequest(ctx context.Context, cfg *config, req *http.Request) (*http.Response, error) {
+	cfg.applyHeaders(req)
+	cfg.logger.DebugContext(ctx, "request", "method", req.Method, "url", RedactURL(req.URL))
+	resp, err := cfg.client.Do(req)
+	if err != nil {
+		if urlErr := (*url.Error)(nil); errors.As(err, &urlErr) {
+			urlErr.URL = RedactURL(req.URL)
+		}
+		return nil, err //nolint:wrapcheck // wrapped by the caller
+	}
+	return resp, nil
+}
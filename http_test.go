@@ -0,0 +1,75 @@
+package getit_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/block/getit"
+)
+
+func TestWithUserAgentAndHeaders(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "archive.tar.gz"))
+	assert.NoError(t, err)
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	assert.NoError(t, err)
+
+	fetcher := getit.New([]getit.Resolver{getit.NewTAR()}, nil,
+		getit.WithUserAgent("getit-test/1.0"),
+		getit.WithHeaders(http.Header{"X-Global": {"global"}, "X-Overridden": {"global"}}),
+		getit.WithHostHeaders(u.Hostname(), http.Header{"x-overridden": {"host"}}),
+		getit.WithHostHeaders("other.example.com", http.Header{"X-Other": {"other"}}),
+	)
+	err = fetcher.Fetch(context.Background(), server.URL+"/archive.tar.gz", t.TempDir())
+	assert.NoError(t, err)
+
+	assert.Equal(t, "getit-test/1.0", got.Get("User-Agent"))
+	assert.Equal(t, "global", got.Get("X-Global"))
+	assert.Equal(t, "host", got.Get("X-Overridden"))
+	assert.Equal(t, "", got.Get("X-Other"))
+}
+
+func TestWithHostHeadersCrossHostRedirect(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "archive.tar.gz"))
+	assert.NoError(t, err)
+	var got http.Header
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		_, _ = w.Write(data)
+	}))
+	defer target.Close()
+	targetURL, err := url.Parse(target.URL)
+	assert.NoError(t, err)
+	// Redirect to the same server by another name, so that it is a different host.
+	targetURL.Host = "localhost:" + targetURL.Port()
+	var token string
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token = r.Header.Get("X-Token")
+		http.Redirect(w, r, targetURL.String()+"/archive.tar.gz", http.StatusFound)
+	}))
+	defer origin.Close()
+	u, err := url.Parse(origin.URL)
+	assert.NoError(t, err)
+
+	fetcher := getit.New([]getit.Resolver{getit.NewTAR()}, nil,
+		getit.WithHostHeaders(u.Host, http.Header{"X-Token": {"secret"}}),
+	)
+	err = fetcher.Fetch(context.Background(), origin.URL+"/archive.tar.gz", t.TempDir())
+	assert.NoError(t, err)
+
+	assert.Equal(t, "secret", token)
+	assert.Equal(t, "", got.Get("X-Token"))
+}
@@ -0,0 +1,91 @@
+package getit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+)
+
+// HTTP is a [Resolver] that downloads a single file from an http:// or https:// URL into dest, without unpacking it.
+// The file is named by the filename of any Content-Disposition response header, or otherwise the last segment of the
+// URL path.
+//
+// Where the Content-Disposition filename has a tarball or zip extension the download is unpacked as that archive
+// instead, so that archives served from opaque URLs are still recognised.
+//
+// As it matches any HTTP URL, it should be the last resolver, after those handling archives. It is also selected by
+// the ?archive=none query parameter. A single file has no subdirectories, so fetching one with a //subdir fails.
+type HTTP struct{}
+
+var (
+	_ Resolver = (*HTTP)(nil)
+	_ Stater   = (*HTTP)(nil)
+)
+
+func NewHTTP() *HTTP { return &HTTP{} }
+
+func (h *HTTP) Match(source *url.URL) bool {
+	return source.Scheme == "http" || source.Scheme == "https"
+}
+
+func (h *HTTP) Stat(ctx context.Context, source Source) (SourceInfo, error) {
+	return httpStat(ctx, source.URL)
+}
+
+func (h *HTTP) Fetch(ctx context.Context, source Source, dest string) error {
+	if err := os.MkdirAll(dest, 0750); err != nil {
+		return fmt.Errorf("creating destination directory: %w", err)
+	}
+	resp, err := httpGet(ctx, source.URL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	name := dispositionFilename(resp.Header)
+	if name != "" && source.Archive == "" {
+		// The server named an archive that couldn't be recognised from the URL.
+		switch {
+		case tarRe.MatchString(name):
+			return extractTarBody(ctx, source.URL, resp.Body, name, dest, source.SubDir, false)
+		case strings.HasSuffix(strings.ToLower(name), ".zip"):
+			return extractZipResponse(ctx, source.URL, resp.Body, dest, source.SubDir)
+		}
+	}
+	if source.SubDir != "" {
+		return fmt.Errorf("subdirectory %s: %s is not an archive", source.SubDir, RedactURL(source.URL))
+	}
+	if name == "" {
+		name = downloadName(source.URL)
+	}
+	cfg := configFromContext(ctx)
+	target, err := securePath(dest, name)
+	if err != nil {
+		return err
+	}
+	cfg.logger.DebugContext(ctx, "download", "url", RedactURL(source.URL), "dest", target)
+	limits := newLimiter(cfg.limits, nil)
+	size, err := writeFile(target, limits.reader(name, newCountingReader(ctx, resp.Body, source.URL.Host)), 0o644, cfg.permissions)
+	if err != nil {
+		return err
+	}
+	modified, _ := http.ParseTime(resp.Header.Get("Last-Modified")) //nolint:errcheck // the zero time is ignored
+	if err := newTimestamper(cfg.options).record(target, 0o644, modified); err != nil {
+		return err
+	}
+	cfg.fileExtracted(name, size)
+	return nil
+}
+
+// downloadName returns the name of a file downloaded from u.
+func downloadName(u *url.URL) string {
+	name := path.Base(archivePath(u))
+	if name == "/" || name == "." {
+		return "download"
+	}
+	return name
+}
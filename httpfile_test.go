@@ -0,0 +1,106 @@
+package getit_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/block/getit"
+)
+
+func TestHTTPFetch(t *testing.T) {
+	modified := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+		_, _ = w.Write([]byte("file content\n"))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name string
+		path string
+		file string
+	}{
+		{name: "NamedAfterPath", path: "/releases/tool-1.0.bin", file: "tool-1.0.bin"},
+		{name: "OverriddenArchive", path: "/releases/archive.tar.gz?archive=none", file: "archive.tar.gz"},
+		{name: "NoName", path: "/", file: "download"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetcher := getit.New([]getit.Resolver{getit.NewTAR(), getit.NewHTTP()}, nil)
+			dest := t.TempDir()
+			_, err := fetcher.FetchWithOptions(context.Background(), server.URL+tt.path, dest, getit.FetchOptions{PreserveTimes: true})
+			assert.NoError(t, err)
+			content, err := os.ReadFile(filepath.Join(dest, tt.file))
+			assert.NoError(t, err)
+			assert.Equal(t, "file content\n", string(content))
+			info, err := os.Stat(filepath.Join(dest, tt.file))
+			assert.NoError(t, err)
+			assert.True(t, info.ModTime().Equal(modified))
+		})
+	}
+}
+
+func TestHTTPFetchRejectsSubDir(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("file content\n"))
+	}))
+	defer server.Close()
+
+	fetcher := getit.New([]getit.Resolver{getit.NewTAR(), getit.NewHTTP()}, nil)
+	dest := t.TempDir()
+	err := fetcher.Fetch(context.Background(), server.URL+"/releases/tool-1.0.bin//bin", dest)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "subdirectory bin")
+	assert.Contains(t, err.Error(), "is not an archive")
+	entries, err := os.ReadDir(dest)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(entries))
+}
+
+func TestHTTPFetchContentDisposition(t *testing.T) {
+	tarball, err := os.ReadFile(filepath.Join("testdata", "archive.tar.gz"))
+	assert.NoError(t, err)
+	zipfile, err := os.ReadFile(filepath.Join("testdata", "archive.zip"))
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		disposition string
+		body        []byte
+		query       string
+		file        string
+	}{
+		{name: "Filename", disposition: `attachment; filename="tool-1.0.bin"`, body: []byte("file content\n"), file: "tool-1.0.bin"},
+		{name: "ExtendedFilename", disposition: `attachment; filename="fallback.bin"; filename*=UTF-8''t%C3%B6%C3%B6l.bin`, body: []byte("file content\n"), file: "tööl.bin"},
+		{name: "TraversalStripped", disposition: `attachment; filename="../../evil.bin"`, body: []byte("file content\n"), file: "evil.bin"},
+		{name: "Invalid", disposition: `attachment; filename=`, body: []byte("file content\n"), file: "a1b2c3"},
+		{name: "Tarball", disposition: `attachment; filename="Archive.TAR.GZ"`, body: tarball, file: "nested.txt"},
+		{name: "Zip", disposition: `attachment; filename="archive.zip"`, body: zipfile, file: "nested.txt"},
+		{name: "ArchiveNone", disposition: `attachment; filename="archive.zip"`, body: zipfile, query: "?archive=none", file: "archive.zip"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Disposition", tt.disposition)
+				_, _ = w.Write(tt.body)
+			}))
+			defer server.Close()
+
+			fetcher := getit.New([]getit.Resolver{getit.NewTAR(), getit.NewZIP(), getit.NewHTTP()}, nil)
+			dest := t.TempDir()
+			err := fetcher.Fetch(context.Background(), server.URL+"/download/a1b2c3"+tt.query, dest)
+			assert.NoError(t, err)
+			entries, err := os.ReadDir(dest)
+			assert.NoError(t, err)
+			_, err = os.Stat(filepath.Join(dest, tt.file))
+			assert.NoError(t, err, "entries: %v", entries)
+		})
+	}
+}
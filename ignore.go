@@ -0,0 +1,132 @@
+package getit
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ignoreFiles are the files, in order of precedence, whose patterns are honoured by [File.Ignore].
+var ignoreFiles = []string{".gitignore", ".getitignore"}
+
+// ignoreRule is a single pattern from an ignore file.
+type ignoreRule struct {
+	base     string   // Slash-separated directory containing the ignore file, relative to the root.
+	segments []string // Pattern split on "/".
+	negate   bool
+	dirOnly  bool
+	anchored bool // The pattern is matched against the path relative to base rather than just the name.
+}
+
+// ignorer matches paths against the gitignore-style rules of a tree.
+//
+// It supports comments, negation, directory-only patterns, anchoring and "**". As with git, later rules take
+// precedence and rules in nested directories only apply to that directory.
+type ignorer struct {
+	rules []ignoreRule
+}
+
+// load the ignore files in dir, whose slash-separated path relative to the root is rel.
+func (i *ignorer) load(dir, rel string) error {
+	if rel == "." {
+		rel = ""
+	}
+	for _, name := range ignoreFiles {
+		file := filepath.Join(dir, name)
+		f, err := os.Open(file) // #nosec G304
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return fmt.Errorf("open %s: %w", file, err)
+		}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			if rule, ok := parseIgnoreRule(rel, scanner.Text()); ok {
+				i.rules = append(i.rules, rule)
+			}
+		}
+		err = scanner.Err()
+		_ = f.Close()
+		if err != nil {
+			return fmt.Errorf("read %s: %w", file, err)
+		}
+	}
+	return nil
+}
+
+func parseIgnoreRule(base, line string) (ignoreRule, bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+	rule := ignoreRule{base: base}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if strings.Contains(line, "/") {
+		rule.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return ignoreRule{}, false
+	}
+	rule.segments = strings.Split(line, "/")
+	return rule, true
+}
+
+// match reports whether the slash-separated path rel is ignored.
+func (i *ignorer) match(rel string, isDir bool) bool {
+	ignored := false
+	for _, rule := range i.rules {
+		if rule.negate == !ignored {
+			// The rule can't change the outcome.
+			continue
+		}
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		sub := rel
+		if rule.base != "" {
+			var ok bool
+			if sub, ok = strings.CutPrefix(rel, rule.base+"/"); !ok {
+				continue
+			}
+		}
+		var matched bool
+		if rule.anchored {
+			matched = matchSegments(rule.segments, strings.Split(sub, "/"))
+		} else {
+			matched, _ = path.Match(rule.segments[0], path.Base(sub)) //nolint:errcheck // malformed patterns never match
+		}
+		if matched {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// matchSegments matches path segments against pattern segments, where "**" matches zero or more segments.
+func matchSegments(pattern, name []string) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+	if pattern[0] == "**" {
+		return matchSegments(pattern[1:], name) || (len(name) > 0 && matchSegments(pattern, name[1:]))
+	}
+	if len(name) == 0 {
+		return false
+	}
+	ok, _ := path.Match(pattern[0], name[0]) //nolint:errcheck // malformed patterns never match
+	return ok && matchSegments(pattern[1:], name[1:])
+}
@@ -0,0 +1,69 @@
+package getit_test
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/block/getit"
+)
+
+func TestFileFetchIgnore(t *testing.T) {
+	srcDir := t.TempDir()
+	files := map[string]string{
+		".gitignore":                "# dependencies\nnode_modules/\n*.log\n!keep.log\n/build\ndocs/**/*.tmp\n",
+		".getitignore":              "secret.txt\n",
+		"main.go":                   "package main\n",
+		"debug.log":                 "log\n",
+		"keep.log":                  "keep\n",
+		"secret.txt":                "secret\n",
+		"node_modules/pkg/index.js": "js\n",
+		"build/out":                 "bin\n",
+		"sub/build/out":             "bin\n",
+		"sub/.gitignore":            "local.txt\n",
+		"sub/local.txt":             "local\n",
+		"local.txt":                 "root\n",
+		"docs/a/b/scratch.tmp":      "tmp\n",
+		"docs/a/readme.md":          "docs\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(srcDir, filepath.FromSlash(name))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+
+	u, err := url.Parse("file://" + srcDir)
+	assert.NoError(t, err)
+	dest := t.TempDir()
+	f := &getit.File{Ignore: true}
+	err = f.Fetch(context.Background(), getit.Source{URL: u}, dest)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		path   string
+		exists bool
+	}{
+		{path: ".gitignore", exists: true},
+		{path: "main.go", exists: true},
+		{path: "debug.log"},
+		{path: "keep.log", exists: true},
+		{path: "secret.txt"},
+		{path: "node_modules"},
+		{path: "build"},
+		{path: "sub/build/out", exists: true},
+		{path: "sub/local.txt"},
+		{path: "local.txt", exists: true},
+		{path: "docs/a/b/scratch.tmp"},
+		{path: "docs/a/readme.md", exists: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			_, err := os.Lstat(filepath.Join(dest, filepath.FromSlash(tt.path)))
+			assert.Equal(t, tt.exists, err == nil, "%v", err)
+		})
+	}
+}
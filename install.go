@@ -0,0 +1,188 @@
+package getit
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// InstallOptions control [Fetcher.Install].
+type InstallOptions struct {
+	// Names of the executables to install, eg. "tool". On Windows "tool.exe" also matches. Defaults to every
+	// executable in the fetched tree.
+	Names []string
+	// Fetch options used to fetch the source.
+	Fetch FetchOptions
+}
+
+// Install fetches source, locates the executables in the fetched tree, and installs them into binDir, marked
+// executable. It returns the paths of the installed executables. This is effectively "go install" for release
+// archives:
+//
+//	fetcher.Install(ctx, "https://example.com/tool-{os}-{arch}.tar.gz", "/usr/local/bin", getit.InstallOptions{})
+//
+// Executables are recognised by their ELF, Mach-O or PE headers, which survive archive formats that don't record
+// permissions, or as scripts with a "#!" line and an executable bit. Shared libraries are not installed. Each
+// executable is copied into binDir and replaces any existing file of the same name with a rename, so a running
+// executable is not overwritten in place.
+//
+// The source is fetched into a staging directory in binDir, which is removed afterwards. Install fails without
+// installing anything if no executables are found, if a name in [InstallOptions.Names] is not found, or if several
+// executables share a name.
+func (f *Fetcher) Install(ctx context.Context, source, binDir string, options InstallOptions) ([]string, error) {
+	if err := os.MkdirAll(binDir, 0750); err != nil {
+		return nil, fmt.Errorf("creating %s: %w", binDir, err)
+	}
+	staging, err := os.MkdirTemp(binDir, ".getit-install-*")
+	if err != nil {
+		return nil, fmt.Errorf("creating staging directory: %w", err)
+	}
+	defer os.RemoveAll(staging)
+	if _, err := f.FetchWithOptions(ctx, source, staging, options.Fetch); err != nil {
+		return nil, fmt.Errorf("installing: %w", err)
+	}
+	executables, err := findExecutables(staging, options.Names)
+	if err != nil {
+		return nil, fmt.Errorf("installing %s: %w", redactSource(source), err)
+	}
+	installed := make([]string, 0, len(executables))
+	for _, path := range executables {
+		target := filepath.Join(binDir, filepath.Base(path))
+		if err := installFile(path, target); err != nil {
+			return installed, fmt.Errorf("installing %s: %w", target, err)
+		}
+		f.config.logger.DebugContext(ctx, "installed", "source", redactSource(source), "path", target)
+		installed = append(installed, target)
+	}
+	return installed, nil
+}
+
+// installFile copies the executable at path to target, marked executable. It is copied rather than renamed, as path
+// may share its storage with other files, eg. as an object of [WithStore], whose mode must not change.
+func installFile(path, target string) error {
+	src, err := os.Open(path) // #nosec G304
+	if err != nil {
+		return err //nolint:wrapcheck // wrapped by the caller
+	}
+	defer src.Close()
+	tmp, err := os.CreateTemp(filepath.Dir(target), "."+filepath.Base(target)+".getit-*")
+	if err != nil {
+		return err //nolint:wrapcheck // wrapped by the caller
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	if _, err := io.Copy(tmp, src); err != nil {
+		return err //nolint:wrapcheck // wrapped by the caller
+	}
+	if err := tmp.Chmod(0o755); err != nil { //nolint:gosec // Installed executables must be executable.
+		return err //nolint:wrapcheck // wrapped by the caller
+	}
+	if err := tmp.Close(); err != nil {
+		return err //nolint:wrapcheck // wrapped by the caller
+	}
+	return os.Rename(tmp.Name(), target) //nolint:wrapcheck // wrapped by the caller
+}
+
+// findExecutables returns the executables in dir, restricted to those matching names if any are given.
+func findExecutables(dir string, names []string) ([]string, error) {
+	byName := map[string]string{}
+	var executables []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		name := d.Name()
+		if len(names) > 0 && !slices.Contains(names, name) && !slices.Contains(names, strings.TrimSuffix(name, ".exe")) {
+			return nil
+		}
+		if ok, err := isExecutable(path, d); err != nil || !ok {
+			return err
+		}
+		if other, ok := byName[name]; ok {
+			rel, _ := filepath.Rel(dir, other)
+			return fmt.Errorf("several executables named %s, including %s", name, filepath.ToSlash(rel))
+		}
+		byName[name] = path
+		executables = append(executables, path)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		if byName[name] == "" && byName[name+".exe"] == "" {
+			return nil, fmt.Errorf("executable %s not found", name)
+		}
+	}
+	if len(executables) == 0 {
+		return nil, fmt.Errorf("no executables found")
+	}
+	return executables, nil
+}
+
+// libraryRe matches the names of shared libraries and object files, which share the headers of executables.
+var libraryRe = regexp.MustCompile(`\.(so(\.\d+)*|dylib|dll|o|class)$`)
+
+// Magic numbers of ELF and Mach-O (32 and 64-bit, either byte order, and universal) executables. PE executables
+// are recognised by isPE.
+var executableMagics = [][]byte{
+	[]byte("\x7fELF"),
+	{0xfe, 0xed, 0xfa, 0xce}, {0xfe, 0xed, 0xfa, 0xcf}, {0xce, 0xfa, 0xed, 0xfe}, {0xcf, 0xfa, 0xed, 0xfe},
+	{0xca, 0xfe, 0xba, 0xbe},
+}
+
+// isPE reports whether r holds a PE executable: an MS-DOS header whose e_lfanew field points at a "PE\0\0" signature.
+func isPE(r io.ReaderAt) bool {
+	var dos [64]byte
+	if _, err := r.ReadAt(dos[:], 0); err != nil || !bytes.HasPrefix(dos[:], []byte("MZ")) {
+		return false
+	}
+	offset := int64(binary.LittleEndian.Uint32(dos[0x3c:]))
+	var signature [4]byte
+	if _, err := r.ReadAt(signature[:], offset); err != nil {
+		return false
+	}
+	return signature == [4]byte{'P', 'E', 0, 0}
+}
+
+// isExecutable reports whether the file at path is an executable binary, or a script marked executable.
+func isExecutable(path string, d fs.DirEntry) (bool, error) {
+	if libraryRe.MatchString(d.Name()) {
+		return false, nil
+	}
+	file, err := os.Open(path) // #nosec G304
+	if err != nil {
+		return false, fmt.Errorf("detecting executables: %w", err)
+	}
+	defer file.Close()
+	header := make([]byte, 4)
+	n, err := io.ReadFull(file, header)
+	if err != nil && n == 0 {
+		return false, nil //nolint:nilerr // Empty files aren't executables.
+	}
+	header = header[:n]
+	for _, magic := range executableMagics {
+		if bytes.HasPrefix(header, magic) {
+			return true, nil
+		}
+	}
+	if isPE(file) {
+		return true, nil
+	}
+	if !bytes.HasPrefix(header, []byte("#!")) {
+		return false, nil
+	}
+	info, err := d.Info()
+	if err != nil {
+		return false, fmt.Errorf("detecting executables: %w", err)
+	}
+	return info.Mode().Perm()&0o111 != 0, nil
+}
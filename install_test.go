@@ -0,0 +1,131 @@
+package getit_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/block/getit"
+)
+
+// peBinary is the start of a PE executable: an MS-DOS header whose e_lfanew field points at the PE signature.
+var peBinary = "MZ" + strings.Repeat("\x00", 0x3a) + "\x40\x00\x00\x00" + "PE\x00\x00binary"
+
+// releaseTree writes a release-like tree to a directory, returning it as a file:// source.
+func releaseTree(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := []struct {
+		path    string
+		content string
+		mode    os.FileMode
+	}{
+		{path: "tool-1.0/bin/tool", content: "\x7fELF binary", mode: 0o644},
+		{path: "tool-1.0/bin/helper.exe", content: peBinary, mode: 0o644},
+		{path: "tool-1.0/bin/notes", content: "MZ is not enough to make an executable", mode: 0o644},
+		{path: "tool-1.0/bin/wrapper", content: "#!/bin/sh\nexec tool\n", mode: 0o755},
+		{path: "tool-1.0/bin/script.sh", content: "#!/bin/sh\n", mode: 0o644},
+		{path: "tool-1.0/lib/libtool.so.1", content: "\x7fELF library", mode: 0o755},
+		{path: "tool-1.0/README.md", content: "# tool\n", mode: 0o644},
+	}
+	for _, file := range files {
+		path := filepath.Join(dir, filepath.FromSlash(file.path))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o750))
+		assert.NoError(t, os.WriteFile(path, []byte(file.content), file.mode))
+	}
+	return "file://" + filepath.ToSlash(dir)
+}
+
+func TestInstall(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("executable bits are not supported on Windows")
+	}
+	source := releaseTree(t)
+	fetcher := getit.New([]getit.Resolver{getit.NewFile()}, nil)
+	tests := []struct {
+		name     string
+		names    []string
+		expected []string
+		err      string
+	}{
+		{name: "All", expected: []string{"helper.exe", "tool", "wrapper"}},
+		{name: "Names", names: []string{"tool", "helper"}, expected: []string{"helper.exe", "tool"}},
+		{name: "Missing", names: []string{"tool", "other"}, err: "executable other not found"},
+		{name: "NotExecutable", names: []string{"README.md"}, err: "executable README.md not found"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			binDir := filepath.Join(t.TempDir(), "bin")
+			installed, err := fetcher.Install(context.Background(), source, binDir, getit.InstallOptions{Names: test.names})
+			entries, readErr := os.ReadDir(binDir)
+			assert.NoError(t, readErr)
+			var names []string
+			for _, entry := range entries {
+				names = append(names, entry.Name())
+			}
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), test.err)
+				assert.Equal(t, 0, len(names), "nothing is installed, and staging is removed")
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, names)
+			slices.Sort(installed)
+			for i, path := range installed {
+				assert.Equal(t, filepath.Join(binDir, test.expected[i]), path)
+				info, err := os.Stat(path)
+				assert.NoError(t, err)
+				assert.Equal(t, os.FileMode(0o755), info.Mode().Perm())
+			}
+		})
+	}
+}
+
+func TestInstallDuplicateNames(t *testing.T) {
+	dir := t.TempDir()
+	for _, path := range []string{"linux/tool", "darwin/tool"} {
+		assert.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(path)), 0o750))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, path), []byte("\x7fELF"), 0o644))
+	}
+	fetcher := getit.New([]getit.Resolver{getit.NewFile()}, nil)
+	_, err := fetcher.Install(context.Background(), "file://"+filepath.ToSlash(dir), t.TempDir(), getit.InstallOptions{})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "several executables named tool")
+}
+
+func TestInstallWithStore(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("executable bits are not supported on Windows")
+	}
+	root := t.TempDir()
+	store := filepath.Join(root, "store")
+	fetcher := getit.New([]getit.Resolver{getit.NewFile()}, nil, getit.WithStore(store))
+	installed, err := fetcher.Install(context.Background(), releaseTree(t), filepath.Join(root, "bin"), getit.InstallOptions{Names: []string{"tool"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(installed))
+
+	// Installing must not change the mode of the stored object the executable was fetched as.
+	objects, err := filepath.Glob(filepath.Join(store, "objects", "*", "*"))
+	assert.NoError(t, err)
+	assert.NotEqual(t, 0, len(objects))
+	found := false
+	for _, object := range objects {
+		content, err := os.ReadFile(object)
+		assert.NoError(t, err)
+		if string(content) != "\x7fELF binary" {
+			continue
+		}
+		found = true
+		info, err := os.Stat(object)
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o444), info.Mode().Perm(), object)
+	}
+	assert.True(t, found, "executable not stored")
+}
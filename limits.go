@@ -0,0 +1,104 @@
+package getit
+
+import (
+	"fmt"
+	"io"
+	"sync/atomic"
+)
+
+// Limits protects against decompression bombs when extracting TAR and ZIP archives.
+//
+// A zero value for any field disables that limit.
+type Limits struct {
+	// MaxFiles is the maximum number of entries extracted from an archive.
+	MaxFiles int
+	// MaxFileSize is the maximum size in bytes of any single extracted file.
+	MaxFileSize int64
+	// MaxTotalSize is the maximum total size in bytes of all extracted files.
+	MaxTotalSize int64
+	// MaxExpansionRatio is the maximum ratio of extracted bytes to compressed archive bytes.
+	MaxExpansionRatio float64
+}
+
+// WithLimits sets extraction limits on a [Fetcher].
+func WithLimits(limits Limits) Option {
+	return func(f *Fetcher) { f.config.limits = limits }
+}
+
+// LimitError is returned when extracting an archive exceeds one of the configured [Limits].
+type LimitError struct {
+	// Limit is the name of the exceeded [Limits] field, eg. "MaxFileSize".
+	Limit string
+	// Path is the archive entry being extracted when the limit was exceeded.
+	Path string
+}
+
+func (l *LimitError) Error() string {
+	return fmt.Sprintf("%s: extraction limit %s exceeded", l.Path, l.Limit)
+}
+
+// limiter enforces Limits across the entries of a single archive.
+type limiter struct {
+	limits Limits
+	files  int
+	total  atomic.Int64
+	// compressed reports the number of archive bytes consumed so far, or is nil if unknown.
+	compressed func() int64
+}
+
+func newLimiter(limits Limits, compressed func() int64) *limiter {
+	return &limiter{limits: limits, compressed: compressed}
+}
+
+// entry accounts for a new archive entry.
+func (l *limiter) entry(path string) error {
+	l.files++
+	if l.limits.MaxFiles > 0 && l.files > l.limits.MaxFiles {
+		return &LimitError{Limit: "MaxFiles", Path: path}
+	}
+	return nil
+}
+
+// reader wraps the contents of an archive entry so that size limits are enforced as it is read.
+func (l *limiter) reader(path string, r io.Reader) io.Reader {
+	return &limitedReader{limiter: l, path: path, r: r}
+}
+
+type limitedReader struct {
+	limiter *limiter
+	path    string
+	r       io.Reader
+	n       int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.n += int64(n)
+	total := l.limiter.total.Add(int64(n))
+	limits := l.limiter.limits
+	switch {
+	case limits.MaxFileSize > 0 && l.n > limits.MaxFileSize:
+		return n, &LimitError{Limit: "MaxFileSize", Path: l.path}
+	case limits.MaxTotalSize > 0 && total > limits.MaxTotalSize:
+		return n, &LimitError{Limit: "MaxTotalSize", Path: l.path}
+	case limits.MaxExpansionRatio > 0 && l.limiter.compressed != nil:
+		if compressed := l.limiter.compressed(); compressed > 0 && float64(total)/float64(compressed) > limits.MaxExpansionRatio {
+			return n, &LimitError{Limit: "MaxExpansionRatio", Path: l.path}
+		}
+	}
+	return n, err //nolint:wrapcheck // io.Reader errors such as io.EOF must not be wrapped
+}
+
+// byteCounter counts the bytes read through it.
+type byteCounter struct {
+	r io.Reader
+	n int64
+}
+
+func (b *byteCounter) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	b.n += int64(n)
+	return n, err //nolint:wrapcheck // io.Reader errors such as io.EOF must not be wrapped
+}
+
+func (b *byteCounter) count() int64 { return b.n }
@@ -0,0 +1,66 @@
+package getit_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/block/getit"
+)
+
+// zipBomb returns a zip archive containing files entries each of size bytes of zeros.
+func zipBomb(t *testing.T, files int, size int) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	for i := range files {
+		w, err := zw.Create(string(rune('a'+i)) + ".bin")
+		assert.NoError(t, err)
+		_, err = w.Write(make([]byte, size))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestWithLimits(t *testing.T) {
+	tests := []struct {
+		name     string
+		limits   getit.Limits
+		expected string
+	}{
+		{name: "Unlimited", limits: getit.Limits{}},
+		{name: "WithinLimits", limits: getit.Limits{MaxFiles: 3, MaxFileSize: 1 << 20, MaxTotalSize: 3 << 20, MaxExpansionRatio: 10000}},
+		{name: "MaxFiles", limits: getit.Limits{MaxFiles: 2}, expected: "MaxFiles"},
+		{name: "MaxFileSize", limits: getit.Limits{MaxFileSize: 1024}, expected: "MaxFileSize"},
+		{name: "MaxTotalSize", limits: getit.Limits{MaxTotalSize: 2 << 20}, expected: "MaxTotalSize"},
+		{name: "MaxExpansionRatio", limits: getit.Limits{MaxExpansionRatio: 10}, expected: "MaxExpansionRatio"},
+	}
+
+	data := zipBomb(t, 3, 1<<20)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetcher := getit.New([]getit.Resolver{getit.NewZIP()}, nil, getit.WithLimits(tt.limits))
+			err := fetcher.Fetch(context.Background(), server.URL+"/bomb.zip", t.TempDir())
+			if tt.expected == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var limitErr *getit.LimitError
+			assert.True(t, errors.As(err, &limitErr), "expected a LimitError, got %v", err)
+			assert.Equal(t, tt.expected, limitErr.Limit)
+		})
+	}
+}
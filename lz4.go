@@ -0,0 +1,328 @@
+package getit
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+)
+
+const (
+	lz4FrameMagic     = 0x184d2204
+	lz4SkippableMagic = 0x184d2a50 // The low 4 bits vary.
+	lz4MinMatch       = 4
+	lz4WindowSize     = 64 << 10
+
+	lz4FlagIndependent   = 0x20
+	lz4FlagBlockChecksum = 0x10
+	lz4FlagContentSize   = 0x08
+	lz4FlagChecksum      = 0x04
+	lz4FlagDictID        = 0x01
+)
+
+// lz4Reader decompresses a stream of LZ4 frames, as written by the lz4 tool.
+type lz4Reader struct {
+	r     *bufio.Reader
+	flags byte
+	// maxBlock is the maximum size of a block in the current frame.
+	maxBlock int
+	// window holds the end of the decompressed output, which blocks of linked frames refer back to, followed by the
+	// output of the current block from pending onwards.
+	window  []byte
+	pending int
+	content *xxh32
+	block   []byte
+	inFrame bool
+}
+
+// newLZ4Reader returns a reader decompressing the LZ4 frames in r.
+func newLZ4Reader(r io.Reader) io.Reader {
+	return &lz4Reader{r: bufio.NewReader(r)}
+}
+
+func (z *lz4Reader) Read(p []byte) (int, error) {
+	for z.pending == len(z.window) {
+		if err := z.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, z.window[z.pending:])
+	z.pending += n
+	return n, nil
+}
+
+// next decompresses the next block into the window, reading frame headers and trailers as necessary.
+func (z *lz4Reader) next() error {
+	if !z.inFrame {
+		if err := z.readFrameHeader(); err != nil {
+			return err
+		}
+	}
+	var size uint32
+	if err := binary.Read(z.r, binary.LittleEndian, &size); err != nil {
+		return fmt.Errorf("lz4: reading block: %w", noEOF(err))
+	}
+	if size == 0 {
+		z.inFrame = false
+		if z.flags&lz4FlagChecksum != 0 {
+			var sum uint32
+			if err := binary.Read(z.r, binary.LittleEndian, &sum); err != nil {
+				return fmt.Errorf("lz4: reading checksum: %w", noEOF(err))
+			}
+			if sum != z.content.sum() {
+				return errors.New("lz4: content checksum mismatch")
+			}
+		}
+		return nil
+	}
+	compressed := size&0x80000000 == 0
+	size &^= 0x80000000
+	if int(size) > z.maxBlock {
+		return errors.New("lz4: block too large")
+	}
+	if cap(z.block) < int(size) {
+		z.block = make([]byte, size)
+	}
+	z.block = z.block[:size]
+	if _, err := io.ReadFull(z.r, z.block); err != nil {
+		return fmt.Errorf("lz4: reading block: %w", noEOF(err))
+	}
+	if z.flags&lz4FlagBlockChecksum != 0 {
+		var sum uint32
+		if err := binary.Read(z.r, binary.LittleEndian, &sum); err != nil {
+			return fmt.Errorf("lz4: reading block checksum: %w", noEOF(err))
+		}
+		if sum != xxh32Sum(z.block) {
+			return errors.New("lz4: block checksum mismatch")
+		}
+	}
+
+	// Keep the window needed by linked blocks, then append this block's output.
+	keep := 0
+	if z.flags&lz4FlagIndependent == 0 {
+		keep = min(len(z.window), lz4WindowSize)
+	}
+	z.window = append(z.window[:0], z.window[len(z.window)-keep:]...)
+	z.pending = len(z.window)
+	var err error
+	if compressed {
+		z.window, err = lz4DecodeBlock(z.window, z.block, z.maxBlock)
+		if err != nil {
+			return err
+		}
+	} else {
+		z.window = append(z.window, z.block...)
+	}
+	if z.flags&lz4FlagChecksum != 0 {
+		z.content.write(z.window[z.pending:])
+	}
+	return nil
+}
+
+func (z *lz4Reader) readFrameHeader() error {
+	for {
+		var magic uint32
+		if err := binary.Read(z.r, binary.LittleEndian, &magic); errors.Is(err, io.EOF) {
+			return io.EOF
+		} else if err != nil {
+			return fmt.Errorf("lz4: reading frame: %w", noEOF(err))
+		}
+		if magic&0xfffffff0 == lz4SkippableMagic {
+			var size uint32
+			if err := binary.Read(z.r, binary.LittleEndian, &size); err != nil {
+				return fmt.Errorf("lz4: reading frame: %w", noEOF(err))
+			}
+			if _, err := z.r.Discard(int(size)); err != nil {
+				return fmt.Errorf("lz4: reading frame: %w", noEOF(err))
+			}
+			continue
+		}
+		if magic != lz4FrameMagic {
+			return fmt.Errorf("lz4: invalid frame magic %#08x", magic)
+		}
+		break
+	}
+	descriptor := make([]byte, 2, 15)
+	if _, err := io.ReadFull(z.r, descriptor); err != nil {
+		return fmt.Errorf("lz4: reading frame: %w", noEOF(err))
+	}
+	flags, bd := descriptor[0], descriptor[1]
+	if flags>>6 != 1 {
+		return fmt.Errorf("lz4: unsupported frame version %d", flags>>6)
+	}
+	blockSize := int(bd>>4) & 7
+	if blockSize < 4 {
+		return fmt.Errorf("lz4: invalid block size %d", blockSize)
+	}
+	extra := 1 // header checksum
+	if flags&lz4FlagContentSize != 0 {
+		extra += 8
+	}
+	if flags&lz4FlagDictID != 0 {
+		extra += 4
+	}
+	descriptor = descriptor[:2+extra]
+	if _, err := io.ReadFull(z.r, descriptor[2:]); err != nil {
+		return fmt.Errorf("lz4: reading frame: %w", noEOF(err))
+	}
+	if flags&lz4FlagDictID != 0 {
+		return errors.New("lz4: frames using a dictionary are not supported")
+	}
+	if byte(xxh32Sum(descriptor[:len(descriptor)-1])>>8) != descriptor[len(descriptor)-1] {
+		return errors.New("lz4: header checksum mismatch")
+	}
+	z.flags = flags
+	z.maxBlock = 1 << (8 + 2*blockSize)
+	z.window = z.window[:0]
+	z.pending = 0
+	z.content = newXXH32()
+	z.inFrame = true
+	return nil
+}
+
+// lz4DecodeBlock appends the decompressed contents of an LZ4 block to dst, whose existing contents may be referred
+// to by matches.
+func lz4DecodeBlock(dst, src []byte, maxSize int) ([]byte, error) {
+	start := len(dst)
+	corrupt := errors.New("lz4: corrupt block")
+	length := func(i *int, n int) (int, error) {
+		if n != 15 {
+			return n, nil
+		}
+		for {
+			if *i >= len(src) {
+				return 0, corrupt
+			}
+			b := src[*i]
+			*i++
+			n += int(b)
+			if b != 255 {
+				return n, nil
+			}
+		}
+	}
+	for i := 0; i < len(src); {
+		token := src[i]
+		i++
+		literals, err := length(&i, int(token>>4))
+		if err != nil {
+			return nil, err
+		}
+		if literals > len(src)-i {
+			return nil, corrupt
+		}
+		dst = append(dst, src[i:i+literals]...)
+		i += literals
+		if i == len(src) {
+			break
+		}
+		if i+2 > len(src) {
+			return nil, corrupt
+		}
+		offset := int(binary.LittleEndian.Uint16(src[i:]))
+		i += 2
+		match, err := length(&i, int(token&15))
+		if err != nil {
+			return nil, err
+		}
+		match += lz4MinMatch
+		if offset == 0 || offset > len(dst) || len(dst)-start+match > maxSize {
+			return nil, corrupt
+		}
+		from := len(dst) - offset
+		if offset >= match {
+			dst = append(dst, dst[from:from+match]...)
+			continue
+		}
+		// The match overlaps its own output, so copy forwards a byte at a time.
+		for j := range match {
+			dst = append(dst, dst[from+j])
+		}
+	}
+	if len(dst)-start > maxSize {
+		return nil, corrupt
+	}
+	return dst, nil
+}
+
+// xxh32 computes the 32-bit xxHash with a zero seed, as used for LZ4 checksums.
+type xxh32 struct {
+	v     [4]uint32
+	buf   [16]byte
+	n     int
+	total uint64
+}
+
+const (
+	xxhPrime1 uint32 = 2654435761
+	xxhPrime2 uint32 = 2246822519
+	xxhPrime3 uint32 = 3266489917
+	xxhPrime4 uint32 = 668265263
+	xxhPrime5 uint32 = 374761393
+)
+
+func newXXH32() *xxh32 {
+	// The initial state wraps around, which constant arithmetic doesn't allow.
+	p1, p2 := xxhPrime1, xxhPrime2
+	return &xxh32{v: [4]uint32{p1 + p2, p2, 0, -p1}}
+}
+
+func xxh32Sum(b []byte) uint32 {
+	h := newXXH32()
+	h.write(b)
+	return h.sum()
+}
+
+func xxhRound(acc, input uint32) uint32 {
+	return bits.RotateLeft32(acc+input*xxhPrime2, 13) * xxhPrime1
+}
+
+func (h *xxh32) write(b []byte) {
+	h.total += uint64(len(b))
+	if h.n > 0 {
+		copied := copy(h.buf[h.n:], b)
+		h.n += copied
+		b = b[copied:]
+		if h.n < len(h.buf) {
+			return
+		}
+		h.stripe(h.buf[:])
+		h.n = 0
+	}
+	for len(b) >= 16 {
+		h.stripe(b[:16])
+		b = b[16:]
+	}
+	h.n = copy(h.buf[:], b)
+}
+
+func (h *xxh32) stripe(b []byte) {
+	for i := range h.v {
+		h.v[i] = xxhRound(h.v[i], binary.LittleEndian.Uint32(b[4*i:]))
+	}
+}
+
+func (h *xxh32) sum() uint32 {
+	var acc uint32
+	if h.total >= 16 {
+		acc = bits.RotateLeft32(h.v[0], 1) + bits.RotateLeft32(h.v[1], 7) + bits.RotateLeft32(h.v[2], 12) + bits.RotateLeft32(h.v[3], 18)
+	} else {
+		acc = h.v[2] + xxhPrime5
+	}
+	acc += uint32(h.total) //nolint:gosec // the length is mixed in modulo 2^32
+	b := h.buf[:h.n]
+	for ; len(b) >= 4; b = b[4:] {
+		acc = bits.RotateLeft32(acc+binary.LittleEndian.Uint32(b)*xxhPrime3, 17) * xxhPrime4
+	}
+	for _, c := range b {
+		acc = bits.RotateLeft32(acc+uint32(c)*xxhPrime5, 11) * xxhPrime1
+	}
+	acc ^= acc >> 15
+	acc *= xxhPrime2
+	acc ^= acc >> 13
+	acc *= xxhPrime3
+	acc ^= acc >> 16
+	return acc
+}
@@ -0,0 +1,80 @@
+package getit //nolint:testpackage
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os/exec"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestLZ4Reader(t *testing.T) {
+	if _, err := exec.LookPath("lz4"); err != nil {
+		t.Skip("lz4 not installed")
+	}
+	// Mix compressible text with random data so that the output has both literals and long matches.
+	data := &bytes.Buffer{}
+	rng := rand.NewChaCha8([32]byte{})
+	for i := range 20000 {
+		fmt.Fprintf(data, "line %d of lz4 test data\n", i%500)
+		if i%100 == 0 {
+			random := make([]byte, 1000)
+			_, _ = rng.Read(random)
+			data.Write(random)
+		}
+	}
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "Default"},
+		{name: "SmallLinkedBlocks", args: []string{"-B4", "-BD"}},
+		{name: "BlockChecksums", args: []string{"-B4", "-BX"}},
+		{name: "ContentSize", args: []string{"--content-size"}},
+		{name: "NoContentChecksum", args: []string{"--no-frame-crc"}},
+		{name: "HighCompression", args: []string{"-9", "-B4", "-BD"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := exec.Command("lz4", append(tt.args, "-q", "-c")...)
+			cmd.Stdin = bytes.NewReader(data.Bytes())
+			compressed, err := cmd.Output()
+			assert.NoError(t, err)
+			// Concatenated frames are decompressed in turn.
+			actual, err := io.ReadAll(newLZ4Reader(bytes.NewReader(append(compressed, compressed...))))
+			assert.NoError(t, err)
+			assert.True(t, bytes.Equal(append(data.Bytes(), data.Bytes()...), actual), "decompressed content mismatch")
+		})
+	}
+}
+
+func TestLZ4ReaderErrors(t *testing.T) {
+	cmd := exec.Command("lz4", "-q", "-c")
+	cmd.Stdin = bytes.NewReader(bytes.Repeat([]byte("corrupt me "), 1000))
+	valid, err := cmd.Output()
+	if err != nil {
+		t.Skip("lz4 not installed")
+	}
+	corrupt := bytes.Clone(valid)
+	corrupt[len(corrupt)/2] ^= 0xff
+	tests := []struct {
+		name  string
+		input []byte
+		err   string
+	}{
+		{name: "Magic", input: []byte("not lz4 data"), err: "lz4: invalid frame magic"},
+		{name: "Truncated", input: valid[:len(valid)-6], err: "unexpected EOF"},
+		{name: "Corrupt", input: corrupt, err: "lz4:"},
+		{name: "Header", input: append(bytes.Clone(valid[:6]), valid[6]^1), err: "lz4: header checksum mismatch"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := io.ReadAll(newLZ4Reader(bytes.NewReader(tt.input)))
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package getit
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	lzwInitBits = 9
+	lzwClear    = 256
+	// lzwBlockMode is set in the header of streams that use the clear code, which all modern compress(1) output does.
+	lzwBlockMode = 0x80
+	lzwBitsMask  = 0x1f
+)
+
+// lzwReader decompresses the output of compress(1).
+//
+// This is not the LZW variant implemented by compress/lzw: codes are packed in groups of eight, and whenever the
+// code width changes or the table is cleared, the remainder of the current group is skipped.
+type lzwReader struct {
+	r         io.ByteReader
+	maxBits   int
+	blockMode bool
+
+	bits     int    // current code width
+	acc      uint32 // bits read but not yet consumed
+	accBits  int
+	groupPos int // codes read at the current width since the last group boundary
+
+	prefix  []uint16
+	suffix  []byte
+	next    int // next free table entry
+	old     int // previous code, or -1 at the start of the stream
+	first   byte
+	pending []byte // decoded output not yet returned, in reverse order
+	stack   []byte
+	err     error
+}
+
+// newLZWReader returns a reader decompressing the compress(1) stream r, whose header is read immediately.
+func newLZWReader(r io.Reader) (io.Reader, error) {
+	br, ok := r.(io.ByteReader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	var header [3]byte
+	for i := range header {
+		b, err := br.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("compress: reading header: %w", noEOF(err))
+		}
+		header[i] = b
+	}
+	if header[0] != 0x1f || header[1] != 0x9d {
+		return nil, errors.New("compress: invalid header")
+	}
+	maxBits := int(header[2] & lzwBitsMask)
+	if maxBits < lzwInitBits || maxBits > 16 {
+		return nil, fmt.Errorf("compress: unsupported maximum code width %d", maxBits)
+	}
+	z := &lzwReader{
+		r:         br,
+		maxBits:   maxBits,
+		blockMode: header[2]&lzwBlockMode != 0,
+		bits:      lzwInitBits,
+		prefix:    make([]uint16, 1<<maxBits),
+		suffix:    make([]byte, 1<<maxBits),
+		old:       -1,
+	}
+	for i := range 256 {
+		z.suffix[i] = byte(i)
+	}
+	z.next = 256
+	if z.blockMode {
+		z.next = 257
+	}
+	return z, nil
+}
+
+func (z *lzwReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(z.pending) > 0 {
+			// pending is in reverse order, so copy from its end.
+			for n < len(p) && len(z.pending) > 0 {
+				p[n] = z.pending[len(z.pending)-1]
+				z.pending = z.pending[:len(z.pending)-1]
+				n++
+			}
+			continue
+		}
+		if z.err != nil {
+			break
+		}
+		z.err = z.decode()
+	}
+	if n > 0 {
+		return n, nil
+	}
+	return 0, z.err
+}
+
+// maxCode returns the largest code representable at the current width.
+func (z *lzwReader) maxCode() int {
+	if z.bits == z.maxBits {
+		return 1 << z.maxBits
+	}
+	return 1<<z.bits - 1
+}
+
+// decode reads the next code, adding its expansion to pending.
+func (z *lzwReader) decode() error {
+	if z.next > z.maxCode() {
+		if err := z.skipGroup(); err != nil {
+			return err
+		}
+		z.bits++
+	}
+	code, err := z.readCode()
+	if err != nil {
+		return err
+	}
+	if z.old == -1 {
+		if code >= 256 {
+			return errors.New("compress: corrupt input")
+		}
+		z.old, z.first = code, byte(code)
+		z.pending = append(z.stack[:0], z.first)
+		return nil
+	}
+	if code == lzwClear && z.blockMode {
+		clear(z.prefix[:256])
+		z.next = 256
+		if err := z.skipGroup(); err != nil {
+			return err
+		}
+		z.bits = lzwInitBits
+		return nil
+	}
+	in := code
+	stack := z.stack[:0]
+	if code >= z.next {
+		if code > z.next {
+			return errors.New("compress: corrupt input")
+		}
+		// The code being defined by this step, which expands to the previous string plus its own first byte.
+		stack = append(stack, z.first)
+		code = z.old
+	}
+	for code >= 256 {
+		stack = append(stack, z.suffix[code])
+		code = int(z.prefix[code])
+	}
+	z.first = z.suffix[code]
+	stack = append(stack, z.first)
+	z.stack, z.pending = stack, stack
+	if z.next < 1<<z.maxBits {
+		z.prefix[z.next] = uint16(z.old) //nolint:gosec // codes are at most 16 bits
+		z.suffix[z.next] = z.first
+		z.next++
+	}
+	z.old = in
+	return nil
+}
+
+// readCode reads a code of the current width. A partial code at the end of the stream marks its end.
+func (z *lzwReader) readCode() (int, error) {
+	for z.accBits < z.bits {
+		b, err := z.r.ReadByte()
+		if errors.Is(err, io.EOF) {
+			return 0, io.EOF
+		} else if err != nil {
+			return 0, fmt.Errorf("compress: %w", err)
+		}
+		z.acc |= uint32(b) << z.accBits
+		z.accBits += 8
+	}
+	code := int(z.acc & (1<<z.bits - 1))
+	z.acc >>= z.bits
+	z.accBits -= z.bits
+	z.groupPos = (z.groupPos + 1) % 8
+	return code, nil
+}
+
+// skipGroup discards the codes remaining in the current group of eight.
+func (z *lzwReader) skipGroup() error {
+	for z.groupPos != 0 {
+		if _, err := z.readCode(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
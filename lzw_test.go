@@ -0,0 +1,80 @@
+package getit //nolint:testpackage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestLZWReader(t *testing.T) {
+	tar, err := os.ReadFile(filepath.Join("testdata", "archive.tar"))
+	assert.NoError(t, err)
+	// clear.Z uses 10 bit codes, so the table fills and is cleared several times.
+	text := &strings.Builder{}
+	for i := 1; i <= 400; i++ {
+		fmt.Fprintf(text, "line %d of some repetitive text for the compress test %d\n", i, i*i)
+	}
+	tests := []struct {
+		name     string
+		filename string
+		expected []byte
+	}{
+		{name: "Tar", filename: "archive.tar.Z", expected: tar},
+		{name: "Clear", filename: "clear.Z", expected: []byte(text.String())},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compressed, err := os.ReadFile(filepath.Join("testdata", tt.filename))
+			assert.NoError(t, err)
+			r, err := newLZWReader(bytes.NewReader(compressed))
+			assert.NoError(t, err)
+			actual, err := io.ReadAll(r)
+			assert.NoError(t, err)
+			assert.True(t, bytes.Equal(tt.expected, actual), "decompressed content mismatch")
+		})
+	}
+}
+
+func TestLZWReaderErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+		err   string
+	}{
+		{name: "Gzip", input: []byte{0x1f, 0x8b, 0x08}, err: "compress: invalid header"},
+		{name: "Truncated", input: []byte{0x1f, 0x9d}, err: "compress: reading header: unexpected EOF"},
+		{name: "MaxBits", input: []byte{0x1f, 0x9d, 0x80 | 17}, err: "compress: unsupported maximum code width 17"},
+		// The first code is 0x1ff, which can't be a literal.
+		{name: "Corrupt", input: []byte{0x1f, 0x9d, 0x90, 0xff, 0xff}, err: "compress: corrupt input"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := newLZWReader(bytes.NewReader(tt.input))
+			if err == nil {
+				_, err = io.ReadAll(r)
+			}
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestDecompressCompressWithoutGzip(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	compressed, err := os.ReadFile(filepath.Join("testdata", "archive.tar.Z"))
+	assert.NoError(t, err)
+	expected, err := os.ReadFile(filepath.Join("testdata", "archive.tar"))
+	assert.NoError(t, err)
+	r, err := decompress(context.Background(), bytes.NewReader(compressed), "-Z")
+	assert.NoError(t, err)
+	actual, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.NoError(t, r.Close())
+	assert.True(t, bytes.Equal(expected, actual), "decompressed content mismatch")
+}
@@ -0,0 +1,224 @@
+package getit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Manifest describes the contents of a destination tree.
+type Manifest struct {
+	// Entries are sorted by path.
+	Entries []ManifestEntry `json:"entries"`
+	// TreeHash is a digest over all entries, of the form "sha256:<hex>".
+	TreeHash string `json:"treeHash"`
+}
+
+// ManifestEntry describes a single file, directory or symlink in a [Manifest].
+type ManifestEntry struct {
+	// Path relative to the root of the tree, using forward slashes.
+	Path string `json:"path"`
+	// Type is one of "file", "dir" or "symlink", or for special files "fifo", "chardev", "blockdev" or "other".
+	// Special files are recorded without a digest, as they have no content to hash.
+	Type string `json:"type"`
+	// Mode holds the permission bits.
+	Mode fs.FileMode `json:"mode"`
+	// Size of a file in bytes.
+	Size int64 `json:"size,omitempty"`
+	// SHA256 is the hex digest of a file's contents.
+	SHA256 string `json:"sha256,omitempty"`
+	// Target of a symlink.
+	Target string `json:"target,omitempty"`
+}
+
+// BuildManifest walks dir and returns a [Manifest] of its contents.
+//
+// A .git directory at the root of dir is excluded, as its contents are not reproducible.
+func BuildManifest(ctx context.Context, dir string) (*Manifest, error) {
+	manifest := &Manifest{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := contextError(ctx); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err //nolint:wrapcheck // wrapped below
+		}
+		if rel == "." {
+			return nil
+		}
+		if rel == ".git" && d.IsDir() {
+			return filepath.SkipDir
+		}
+		entry, err := manifestEntry(path, filepath.ToSlash(rel), d)
+		if err != nil {
+			return err
+		}
+		manifest.Entries = append(manifest.Entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("building manifest of %s: %w", dir, err)
+	}
+	sort.Slice(manifest.Entries, func(i, j int) bool { return manifest.Entries[i].Path < manifest.Entries[j].Path })
+	manifest.TreeHash = treeHash(manifest.Entries)
+	return manifest, nil
+}
+
+// WriteFile writes the manifest as JSON to path.
+func (m *Manifest) WriteFile(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding manifest: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("writing manifest: %w", err)
+	}
+	return nil
+}
+
+func manifestEntry(path, rel string, d fs.DirEntry) (ManifestEntry, error) {
+	info, err := d.Info()
+	if err != nil {
+		return ManifestEntry{}, err //nolint:wrapcheck // wrapped by BuildManifest
+	}
+	entry := ManifestEntry{Path: rel, Mode: info.Mode().Perm()}
+	switch {
+	case info.Mode()&fs.ModeSymlink != 0:
+		entry.Type = "symlink"
+		entry.Mode = 0
+		if entry.Target, err = os.Readlink(path); err != nil {
+			return entry, err //nolint:wrapcheck // wrapped by BuildManifest
+		}
+	case info.IsDir():
+		entry.Type = "dir"
+	case info.Mode().IsRegular():
+		entry.Type = "file"
+		entry.Size = info.Size()
+		if entry.SHA256, err = hashFile(path); err != nil {
+			return entry, err
+		}
+	default:
+		// Special files are never opened, as opening a FIFO blocks until it has a writer.
+		entry.Type = specialManifestType(info.Mode())
+	}
+	return entry, nil
+}
+
+// specialManifestType returns the manifest type of a file that is not a regular file, directory or symlink.
+func specialManifestType(mode fs.FileMode) string {
+	switch {
+	case mode&fs.ModeNamedPipe != 0:
+		return "fifo"
+	case mode&fs.ModeCharDevice != 0:
+		return "chardev"
+	case mode&fs.ModeDevice != 0:
+		return "blockdev"
+	}
+	return "other"
+}
+
+// HashTree returns the digest recorded for dir by the checksum database of [WithChecksumDB], so tools can precompute
+// expected digests of a tree, eg. for review. It is the tree hash of [Manifest.TreeHash] with permissions reduced to
+// whether each file is executable.
+func HashTree(ctx context.Context, dir string) (string, error) {
+	manifest, err := BuildManifest(ctx, dir)
+	if err != nil {
+		return "", err
+	}
+	return checksumDigest(manifest), nil
+}
+
+// HashReader returns the digest of the content read from r, of the form "sha256:<hex>". The hex digest is that of
+// [ManifestEntry.SHA256] for a file with the same content.
+func HashReader(r io.Reader) (string, error) {
+	sum, err := hashReader(r)
+	if err != nil {
+		return "", fmt.Errorf("hashing: %w", err)
+	}
+	return "sha256:" + sum, nil
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path) // #nosec G304
+	if err != nil {
+		return "", fmt.Errorf("open %s: %w", path, err)
+	}
+	defer f.Close()
+	sum, err := hashReader(f)
+	if err != nil {
+		return "", fmt.Errorf("read %s: %w", path, err)
+	}
+	return sum, nil
+}
+
+// hashReader returns the hex SHA-256 digest of the content read from r.
+func hashReader(r io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err //nolint:wrapcheck // wrapped by callers
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// treeHash digests sorted manifest entries, one line per entry.
+func treeHash(entries []ManifestEntry) string {
+	h := sha256.New()
+	for _, e := range entries {
+		_, _ = fmt.Fprintf(h, "%s %o %s %s %s\n", e.Type, uint32(e.Mode), e.SHA256, e.Target, e.Path)
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil))
+}
+
+// ManifestDiff lists the differences between an expected and an actual [Manifest].
+type ManifestDiff struct {
+	// Added paths are present in the actual tree but not the expected tree.
+	Added []string `json:"added,omitempty"`
+	// Modified paths are present in both trees but differ in type, mode, content or link target.
+	Modified []string `json:"modified,omitempty"`
+	// Deleted paths are present in the expected tree but not the actual tree.
+	Deleted []string `json:"deleted,omitempty"`
+}
+
+// Empty returns true if there are no differences.
+func (d ManifestDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Modified) == 0 && len(d.Deleted) == 0
+}
+
+// Diff compares m, the expected tree, against actual.
+func (m *Manifest) Diff(actual *Manifest) ManifestDiff {
+	diff := ManifestDiff{}
+	if m.TreeHash == actual.TreeHash {
+		return diff
+	}
+	expected := make(map[string]ManifestEntry, len(m.Entries))
+	for _, entry := range m.Entries {
+		expected[entry.Path] = entry
+	}
+	for _, entry := range actual.Entries {
+		want, ok := expected[entry.Path]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, entry.Path)
+		case want != entry:
+			diff.Modified = append(diff.Modified, entry.Path)
+		}
+		delete(expected, entry.Path)
+	}
+	for _, entry := range m.Entries {
+		if _, ok := expected[entry.Path]; ok {
+			diff.Deleted = append(diff.Deleted, entry.Path)
+		}
+	}
+	return diff
+}
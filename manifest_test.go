@@ -0,0 +1,86 @@
+package getit_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/block/getit"
+)
+
+func TestBuildManifest(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "sub"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "file.txt"), []byte("hello\n"), 0o644))
+	assert.NoError(t, os.Symlink("sub/file.txt", filepath.Join(dir, "link")))
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, ".git"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, ".git", "HEAD"), []byte("ref: main\n"), 0o644))
+
+	manifest, err := getit.BuildManifest(context.Background(), dir)
+	assert.NoError(t, err)
+	assert.Equal(t, []getit.ManifestEntry{
+		{Path: "link", Type: "symlink", Target: "sub/file.txt"},
+		{Path: "sub", Type: "dir", Mode: 0o755},
+		{
+			Path:   "sub/file.txt",
+			Type:   "file",
+			Mode:   0o644,
+			Size:   6,
+			SHA256: "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03",
+		},
+	}, manifest.Entries)
+
+	// The tree hash changes when content changes.
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "file.txt"), []byte("changed\n"), 0o644))
+	changed, err := getit.BuildManifest(context.Background(), dir)
+	assert.NoError(t, err)
+	assert.NotEqual(t, manifest.TreeHash, changed.TreeHash)
+}
+
+func TestFetchWithOptionsManifestPath(t *testing.T) {
+	srcDir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(srcDir, "file.txt"), []byte("hello\n"), 0o644))
+
+	manifestPath := filepath.Join(t.TempDir(), "manifest.json")
+	dest := t.TempDir()
+	fetcher := getit.New([]getit.Resolver{getit.NewFile()}, nil)
+	_, err := fetcher.FetchWithOptions(context.Background(), "file://"+srcDir, dest, getit.FetchOptions{ManifestPath: manifestPath})
+	assert.NoError(t, err)
+
+	data, err := os.ReadFile(manifestPath)
+	assert.NoError(t, err)
+	var written getit.Manifest
+	assert.NoError(t, json.Unmarshal(data, &written))
+	expected, err := getit.BuildManifest(context.Background(), dest)
+	assert.NoError(t, err)
+	assert.Equal(t, *expected, written)
+}
+
+func TestHashTree(t *testing.T) {
+	mem := getit.NewMemResolver(map[string]string{"repo/file.txt": "v1\n", "repo/sub/other.txt": "other\n"})
+	db := filepath.Join(t.TempDir(), "getit.sum")
+	fetcher := getit.New([]getit.Resolver{mem}, nil, getit.WithChecksumDB(db))
+	dest := t.TempDir()
+	assert.NoError(t, fetcher.Fetch(context.Background(), "mem://repo", dest))
+
+	// The tree hash is the digest recorded by the checksum database.
+	hash, err := getit.HashTree(context.Background(), dest)
+	assert.NoError(t, err)
+	data, err := os.ReadFile(db)
+	assert.NoError(t, err)
+	assert.Equal(t, "mem://repo "+hash, strings.TrimSpace(string(data)))
+
+	_, err = getit.HashTree(context.Background(), filepath.Join(dest, "missing"))
+	assert.Error(t, err)
+}
+
+func TestHashReader(t *testing.T) {
+	hash, err := getit.HashReader(strings.NewReader("hello\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, "sha256:5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03", hash)
+}
@@ -0,0 +1,220 @@
+package getit
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"maps"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// MemResolver resolves mem:// sources from an in-memory tree of files, so that applications embedding getit can be
+// unit tested without HTTP servers or git binaries.
+//
+// The host and path of the URL together name a directory or file in the tree:
+//
+//	mem://repo          fetches every file under "repo/"
+//	mem://repo/docs     fetches every file under "repo/docs/"
+//	mem://repo/go.mod   fetches the single file "repo/go.mod"
+//
+// Files are written with mode 0644 and directories created as needed. Sources may also be pushed to, replacing the
+// files under their path. A MemResolver is safe for concurrent use.
+type MemResolver struct {
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+var (
+	_ Resolver = (*MemResolver)(nil)
+	_ Stater   = (*MemResolver)(nil)
+	_ Pusher   = (*MemResolver)(nil)
+)
+
+// NewMemResolver creates a MemResolver holding files, keyed by slash-separated paths, eg. "repo/README.md".
+func NewMemResolver(files map[string]string) *MemResolver {
+	m := &MemResolver{files: make(map[string][]byte, len(files))}
+	for name, content := range files {
+		m.files[path.Clean(name)] = []byte(content)
+	}
+	return m
+}
+
+// SetFile adds or replaces a file in the tree.
+func (m *MemResolver) SetFile(name, content string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.files[path.Clean(name)] = []byte(content)
+}
+
+// File returns the contents of a file in the tree.
+func (m *MemResolver) File(name string) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	content, ok := m.files[path.Clean(name)]
+	return string(content), ok
+}
+
+func (m *MemResolver) Match(source *url.URL) bool {
+	return source.Scheme == "mem"
+}
+
+// Stat reports the total size of the files under the source, and a digest of their paths and contents as the
+// revision.
+func (m *MemResolver) Stat(_ context.Context, source Source) (SourceInfo, error) {
+	files, err := m.lookup(source)
+	if err != nil {
+		return SourceInfo{}, err
+	}
+	h := sha256.New()
+	info := SourceInfo{}
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		fmt.Fprintf(h, "%s\x00%d\x00", name, len(files[name]))
+		h.Write(files[name])
+		info.Size += int64(len(files[name]))
+	}
+	info.Revision = "sha256:" + hex.EncodeToString(h.Sum(nil))
+	return info, nil
+}
+
+func (m *MemResolver) Fetch(ctx context.Context, source Source, dest string) error {
+	files, err := m.lookup(source)
+	if err != nil {
+		return err
+	}
+	cfg := configFromContext(ctx)
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		if err := contextError(ctx); err != nil {
+			return err
+		}
+		target, err := securePath(dest, name)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0750); err != nil {
+			return fmt.Errorf("creating directory: %w", err)
+		}
+		size, err := writeFile(target, bytes.NewReader(files[name]), 0o644, cfg.permissions)
+		if err != nil {
+			return err
+		}
+		cfg.fileExtracted(name, size)
+	}
+	return nil
+}
+
+// Push replaces the files under the source with the regular files in srcDir.
+func (m *MemResolver) Push(ctx context.Context, source Source, srcDir string) error {
+	root := memPath(source.URL)
+	files := map[string][]byte{}
+	err := filepath.WalkDir(srcDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := contextError(ctx); err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(srcDir, p)
+		if err != nil {
+			return err //nolint:wrapcheck // wrapped below
+		}
+		content, err := os.ReadFile(p) // #nosec G304
+		if err != nil {
+			return err //nolint:wrapcheck // wrapped below
+		}
+		files[path.Join(root, filepath.ToSlash(rel))] = content
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", srcDir, err)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for name := range m.files {
+		if name == root || strings.HasPrefix(name, root+"/") {
+			delete(m.files, name)
+		}
+	}
+	maps.Copy(m.files, files)
+	return nil
+}
+
+// lookup returns the files under a source, keyed by their path relative to it. A source naming a single file
+// returns just that file, keyed by its base name.
+func (m *MemResolver) lookup(source Source) (map[string][]byte, error) {
+	root := memPath(source.URL)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if content, ok := m.files[root]; ok {
+		return map[string][]byte{path.Base(root): content}, nil
+	}
+	files := map[string][]byte{}
+	for name, content := range m.files {
+		if rel, ok := strings.CutPrefix(name, root+"/"); ok {
+			files[rel] = content
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("%s: %w", RedactURL(source.URL), fs.ErrNotExist)
+	}
+	return files, nil
+}
+
+// memPath returns the path in the tree named by a mem:// URL.
+func memPath(u *url.URL) string {
+	return path.Clean(strings.TrimSuffix(u.Host+"/"+strings.TrimPrefix(u.Path, "/"), "/"))
+}
+
+// FetchCall records a call to [Resolver.Fetch].
+type FetchCall struct {
+	Source Source
+	// Dest is the directory the resolver wrote to, which is a staging directory if the fetched tree may yet be
+	// rejected, eg. by a [FetchOptions.PostFetch] hook or [WithQuarantine].
+	Dest string
+	// Err returned by the fetch, if any.
+	Err error
+}
+
+// RecordingResolver wraps a [Resolver], recording the calls to its Fetch method, so that tests can assert on what an
+// application fetched.
+//
+//	recorder := getit.NewRecordingResolver(getit.NewMemResolver(files))
+//	fetcher := getit.New([]getit.Resolver{recorder}, nil)
+type RecordingResolver struct {
+	Resolver
+	mu    sync.Mutex
+	calls []FetchCall
+}
+
+var _ Resolver = (*RecordingResolver)(nil)
+
+// NewRecordingResolver creates a RecordingResolver delegating to resolver.
+func NewRecordingResolver(resolver Resolver) *RecordingResolver {
+	return &RecordingResolver{Resolver: resolver}
+}
+
+func (r *RecordingResolver) Fetch(ctx context.Context, source Source, dest string) error {
+	err := r.Resolver.Fetch(ctx, source, dest)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, FetchCall{Source: source, Dest: dest, Err: err})
+	return err //nolint:wrapcheck // errors are passed through unchanged
+}
+
+// Calls returns the calls to Fetch so far, in order.
+func (r *RecordingResolver) Calls() []FetchCall {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.calls)
+}
@@ -0,0 +1,105 @@
+package getit_test
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/block/getit"
+)
+
+func TestMemResolverFetch(t *testing.T) {
+	mem := getit.NewMemResolver(map[string]string{
+		"repo/README.md":   "hello\n",
+		"repo/docs/a.md":   "a\n",
+		"repo/docs/b.md":   "b\n",
+		"other/ignored.md": "ignored\n",
+	})
+	tests := []struct {
+		name     string
+		source   string
+		expected map[string]string
+		error    string
+	}{
+		{name: "Root", source: "mem://repo", expected: map[string]string{"README.md": "hello\n", "docs/a.md": "a\n", "docs/b.md": "b\n"}},
+		{name: "Directory", source: "mem://repo/docs/", expected: map[string]string{"a.md": "a\n", "b.md": "b\n"}},
+		{name: "SingleFile", source: "mem://repo/README.md", expected: map[string]string{"README.md": "hello\n"}},
+		{name: "Missing", source: "mem://missing", error: "file does not exist"},
+	}
+	fetcher := getit.New([]getit.Resolver{mem}, nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := t.TempDir()
+			err := fetcher.Fetch(context.Background(), tt.source, dest)
+			if tt.error != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.error)
+				return
+			}
+			assert.NoError(t, err)
+			actual := map[string]string{}
+			err = filepath.WalkDir(dest, func(path string, d fs.DirEntry, err error) error {
+				if err != nil || d.IsDir() {
+					return err
+				}
+				content, err := os.ReadFile(path)
+				rel, _ := filepath.Rel(dest, path)
+				actual[filepath.ToSlash(rel)] = string(content)
+				return err
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestMemResolverStat(t *testing.T) {
+	mem := getit.NewMemResolver(map[string]string{"repo/a.txt": "one\n"})
+	fetcher := getit.New([]getit.Resolver{mem}, nil)
+	result, err := fetcher.FetchWithOptions(context.Background(), "mem://repo", t.TempDir(), getit.FetchOptions{DryRun: true})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), result.Info.Size)
+	before := result.Info.Revision
+
+	mem.SetFile("repo/a.txt", "two\n")
+	result, err = fetcher.FetchWithOptions(context.Background(), "mem://repo", t.TempDir(), getit.FetchOptions{DryRun: true})
+	assert.NoError(t, err)
+	assert.NotEqual(t, before, result.Info.Revision)
+}
+
+func TestMemResolverPush(t *testing.T) {
+	mem := getit.NewMemResolver(map[string]string{"repo/stale.txt": "stale\n", "repository/kept.txt": "kept\n"})
+	src := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(src, "sub"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(src, "sub", "new.txt"), []byte("new\n"), 0o644))
+
+	fetcher := getit.New([]getit.Resolver{mem}, nil)
+	err := fetcher.Push(context.Background(), src, "mem://repo")
+	assert.NoError(t, err)
+	content, ok := mem.File("repo/sub/new.txt")
+	assert.True(t, ok)
+	assert.Equal(t, "new\n", content)
+	_, ok = mem.File("repo/stale.txt")
+	assert.False(t, ok)
+	_, ok = mem.File("repository/kept.txt")
+	assert.True(t, ok)
+}
+
+func TestRecordingResolver(t *testing.T) {
+	recorder := getit.NewRecordingResolver(getit.NewMemResolver(map[string]string{"repo/a.txt": "a\n"}))
+	fetcher := getit.New([]getit.Resolver{recorder}, nil)
+	dest := t.TempDir()
+	assert.NoError(t, fetcher.Fetch(context.Background(), "mem://repo?ref=main", dest))
+	assert.Error(t, fetcher.Fetch(context.Background(), "mem://missing", dest))
+
+	calls := recorder.Calls()
+	assert.Equal(t, 2, len(calls))
+	assert.Equal(t, "mem://repo?ref=main", calls[0].Source.URL.String())
+	assert.NoError(t, calls[0].Err)
+	assert.Equal(t, "mem://missing", calls[1].Source.URL.String())
+	assert.Error(t, calls[1].Err)
+}
@@ -0,0 +1,84 @@
+package getit
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// MetricLabels identify the resolver and host a metric was recorded for.
+type MetricLabels struct {
+	Resolver string
+	Host     string
+}
+
+// Metrics receives measurements from a [Fetcher].
+//
+// Implementations typically map these onto counters (fetches, failures, bytes) and a duration histogram in their
+// metrics system of choice. Implementations must be safe for concurrent use.
+type Metrics interface {
+	// FetchStarted is called when a resolved source starts fetching.
+	FetchStarted(labels MetricLabels)
+	// FetchFinished is called when a fetch completes, with a non-nil err if it failed.
+	FetchFinished(labels MetricLabels, duration time.Duration, err error)
+	// BytesDownloaded is called as bytes are read from the network.
+	BytesDownloaded(labels MetricLabels, n int64)
+}
+
+// CacheMetrics may be implemented by [Metrics] to also receive cache hits and misses.
+type CacheMetrics interface {
+	// CacheLookup is called when an entry is looked up in a cache, with whether it was found. Cache is "store" for
+	// each file looked up in the object store of [WithStore], or "archive-index" for each archive looked up in the
+	// index cache of [WithArchiveIndexCache].
+	CacheLookup(labels MetricLabels, cache string, hit bool)
+}
+
+// WithMetrics records fetch measurements to the given [Metrics].
+func WithMetrics(metrics Metrics) Option {
+	return func(f *Fetcher) { f.config.metrics = metrics }
+}
+
+type noopMetrics struct{}
+
+func (noopMetrics) FetchStarted(MetricLabels)                        {}
+func (noopMetrics) FetchFinished(MetricLabels, time.Duration, error) {}
+func (noopMetrics) BytesDownloaded(MetricLabels, int64)              {}
+
+// cacheLookup reports a cache lookup for the current fetch from host, if the configured Metrics implement
+// [CacheMetrics].
+func (c *config) cacheLookup(host, cache string, hit bool) {
+	if metrics, ok := c.metrics.(CacheMetrics); ok {
+		metrics.CacheLookup(MetricLabels{Resolver: c.resolver, Host: host}, cache, hit)
+	}
+}
+
+// resolverName returns a short name for a Resolver, eg. "TAR" for *getit.TAR.
+func resolverName(resolver Resolver) string {
+	name := fmt.Sprintf("%T", resolver)
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
+
+// countingReader reports bytes read to Metrics.
+type countingReader struct {
+	r       io.Reader
+	metrics Metrics
+	labels  MetricLabels
+}
+
+func newCountingReader(ctx context.Context, r io.Reader, host string) *countingReader {
+	cfg := configFromContext(ctx)
+	return &countingReader{r: r, metrics: cfg.metrics, labels: MetricLabels{Resolver: cfg.resolver, Host: host}}
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if n > 0 {
+		c.metrics.BytesDownloaded(c.labels, int64(n))
+	}
+	return n, err //nolint:wrapcheck // io.Reader errors such as io.EOF must not be wrapped
+}
@@ -0,0 +1,103 @@
+package getit_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/block/getit"
+)
+
+type recordingMetrics struct {
+	lock     sync.Mutex
+	started  int
+	finished int
+	failures int
+	bytes    int64
+	labels   getit.MetricLabels
+	// lookups of each cache, recording whether each was a hit.
+	lookups map[string][]bool
+}
+
+func (r *recordingMetrics) FetchStarted(getit.MetricLabels) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.started++
+}
+
+func (r *recordingMetrics) FetchFinished(labels getit.MetricLabels, _ time.Duration, err error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.finished++
+	if err != nil {
+		r.failures++
+	}
+	r.labels = labels
+}
+
+func (r *recordingMetrics) BytesDownloaded(_ getit.MetricLabels, n int64) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.bytes += n
+}
+
+func (r *recordingMetrics) CacheLookup(_ getit.MetricLabels, cache string, hit bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.lookups == nil {
+		r.lookups = map[string][]bool{}
+	}
+	r.lookups[cache] = append(r.lookups[cache], hit)
+}
+
+func TestWithMetrics(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "archive.tar.gz"))
+	assert.NoError(t, err)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing.tar.gz" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	assert.NoError(t, err)
+
+	metrics := &recordingMetrics{}
+	fetcher := getit.New([]getit.Resolver{getit.NewTAR()}, nil, getit.WithMetrics(metrics))
+	err = fetcher.Fetch(context.Background(), server.URL+"/archive.tar.gz", t.TempDir())
+	assert.NoError(t, err)
+	err = fetcher.Fetch(context.Background(), server.URL+"/missing.tar.gz", t.TempDir())
+	assert.Error(t, err)
+
+	assert.Equal(t, 2, metrics.started)
+	assert.Equal(t, 2, metrics.finished)
+	assert.Equal(t, 1, metrics.failures)
+	assert.Equal(t, int64(len(data)), metrics.bytes)
+	assert.Equal(t, getit.MetricLabels{Resolver: "TAR", Host: u.Host}, metrics.labels)
+}
+
+func TestWithMetricsCacheLookups(t *testing.T) {
+	root := t.TempDir()
+	src := filepath.Join(root, "src")
+	assert.NoError(t, os.MkdirAll(src, 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(src, "file.txt"), []byte("file\n"), 0o644))
+
+	metrics := &recordingMetrics{}
+	fetcher := getit.New([]getit.Resolver{getit.NewFile()}, nil,
+		getit.WithMetrics(metrics), getit.WithStore(filepath.Join(root, "store")))
+	assert.NoError(t, fetcher.Fetch(context.Background(), "file://"+src, filepath.Join(root, "first")))
+	assert.NoError(t, fetcher.Fetch(context.Background(), "file://"+src, filepath.Join(root, "second")))
+
+	assert.Equal(t, map[string][]bool{"store": {false, true}}, metrics.lookups)
+}
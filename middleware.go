@@ -0,0 +1,22 @@
+package getit
+
+import "net/http"
+
+// Middleware wraps the [http.RoundTripper] used for HTTP requests, eg. to sign, cache or log requests.
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc adapts a function to an [http.RoundTripper], for use in [Middleware].
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+// RoundTrip calls f(req).
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// WithMiddleware adds middleware to the HTTP requests made by a [Fetcher], including those of the HTTP-based
+// resolvers and requests to forge APIs. Git uses its own transport, so git+https sources are not affected.
+//
+// Middleware is applied in the order given, across calls, so the first wraps all those after it and sees each
+// request first. All middleware sits directly above the network, so requests replayed by [WithRecording] or failed
+// by [WithFaults] do not reach it.
+func WithMiddleware(middleware ...Middleware) Option {
+	return func(f *Fetcher) { f.config.middleware = append(f.config.middleware, middleware...) }
+}
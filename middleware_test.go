@@ -0,0 +1,57 @@
+package getit_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/block/getit"
+)
+
+func TestMiddleware(t *testing.T) {
+	body := tarball(t, "file.txt", "hello\n")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	var calls []string
+	trace := func(name string) getit.Middleware {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return getit.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				calls = append(calls, name+" "+req.Method)
+				resp, err := next.RoundTrip(req)
+				if err == nil {
+					calls = append(calls, name+" "+resp.Status)
+				}
+				return resp, err
+			})
+		}
+	}
+	sign := func(next http.RoundTripper) http.RoundTripper {
+		return getit.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			req = req.Clone(req.Context())
+			req.Header.Set("Authorization", "Bearer token")
+			return next.RoundTrip(req)
+		})
+	}
+	fetcher := getit.New([]getit.Resolver{getit.NewTAR()}, nil,
+		getit.WithMiddleware(trace("outer")),
+		getit.WithMiddleware(trace("inner"), sign),
+	)
+	dest := t.TempDir()
+	assert.NoError(t, fetcher.Fetch(context.Background(), server.URL+"/archive.tar.gz", dest))
+	content, err := os.ReadFile(filepath.Join(dest, "file.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "hello\n", string(content))
+	assert.Equal(t, []string{"outer GET", "inner GET", "inner 200 OK", "outer 200 OK"}, calls)
+}
@@ -0,0 +1,44 @@
+package getit
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Monorepo is a [Mapper] that supports terse references to the subtrees of a monorepo, using a custom scheme. The
+// path of the source becomes the subdirectory of repo, eg. with:
+//
+//	getit.Monorepo("mono", "git+https://github.com/org/monorepo?ref=main")
+//
+// "mono://tools/foo" maps to "git+https://github.com/org/monorepo//tools/foo?ref=main".
+//
+// Query parameters of the source are added to those of repo, replacing any with the same name, so eg.
+// "mono://tools/foo?ref=v2" selects another ref. Anchors are preserved.
+func Monorepo(scheme, repo string) Mapper {
+	return func(source string) (string, bool) {
+		rest, ok := strings.CutPrefix(source, scheme+"://")
+		if !ok {
+			return "", false
+		}
+		u, err := url.Parse(repo)
+		if err != nil {
+			return "", false
+		}
+		rest, u.Fragment, _ = strings.Cut(rest, "#")
+		path, rawQuery, _ := strings.Cut(rest, "?")
+		if path = strings.Trim(path, "/"); path != "" {
+			u.Path = strings.TrimSuffix(u.Path, "/") + "//" + path
+			u.RawPath = ""
+		}
+		overrides, err := url.ParseQuery(rawQuery)
+		if err != nil {
+			return "", false
+		}
+		query := u.Query()
+		for key, values := range overrides {
+			query[key] = values
+		}
+		u.RawQuery = query.Encode()
+		return u.String(), true
+	}
+}
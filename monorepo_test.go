@@ -0,0 +1,68 @@
+package getit_test
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/block/getit"
+)
+
+func TestMonorepo(t *testing.T) {
+	mapper := getit.Monorepo("mono", "git+https://github.com/org/monorepo?ref=main")
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+		ok       bool
+	}{
+		{
+			name:     "SubDir",
+			source:   "mono://tools/foo",
+			expected: "git+https://github.com/org/monorepo//tools/foo?ref=main",
+			ok:       true,
+		},
+		{
+			name:     "QueryOverridesRepo",
+			source:   "mono://tools/foo/?ref=v2&depth=1",
+			expected: "git+https://github.com/org/monorepo//tools/foo?depth=1&ref=v2",
+			ok:       true,
+		},
+		{
+			name:     "Anchor",
+			source:   "mono://tools/foo#readme",
+			expected: "git+https://github.com/org/monorepo//tools/foo?ref=main#readme",
+			ok:       true,
+		},
+		{
+			name:     "Root",
+			source:   "mono://",
+			expected: "git+https://github.com/org/monorepo?ref=main",
+			ok:       true,
+		},
+		{
+			name:   "OtherScheme",
+			source: "monorepo://tools/foo",
+		},
+		{
+			name:   "OrgRepo",
+			source: "org/repo",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, ok := mapper(tt.source)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestMonorepoResolve(t *testing.T) {
+	fetcher := getit.New([]getit.Resolver{getit.NewGit()}, []getit.Mapper{getit.Monorepo("mono", "git+https://github.com/org/monorepo?ref=main")})
+	_, source, err := fetcher.Resolve("mono://tools/foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "tools/foo", source.SubDir)
+	assert.Equal(t, "/org/monorepo", source.URL.Path)
+	assert.Equal(t, "main", source.URL.Query().Get("ref"))
+}
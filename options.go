@@ -0,0 +1,262 @@
+package getit
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"os"
+	"slices"
+	"sync"
+)
+
+// Option configures a [Fetcher].
+type Option func(*Fetcher)
+
+// WithLogger sets the logger used to emit structured events while resolving and fetching sources.
+//
+// Events include "resolve", "request", "retry" and "extract", and "verify" whenever the tree fetched by a resolver is
+// checked against the checksum database of [WithChecksumDB], labelled with the resolver. [Fetcher.Verify] also logs
+// a "verify" event. By default events are discarded.
+func WithLogger(logger *slog.Logger) Option {
+	return func(f *Fetcher) { f.config.logger = logger }
+}
+
+// WithUserAgent sets the User-Agent header sent with HTTP requests.
+func WithUserAgent(userAgent string) Option {
+	return func(f *Fetcher) { f.config.userAgent = userAgent }
+}
+
+// WithHeaders adds headers to every HTTP request.
+func WithHeaders(headers http.Header) Option {
+	return func(f *Fetcher) {
+		if f.config.headers == nil {
+			f.config.headers = http.Header{}
+		}
+		mergeHeaders(f.config.headers, headers)
+	}
+}
+
+// WithHostHeaders adds headers to HTTP requests for a single host, eg. "example.com" or "example.com:8443".
+//
+// Host headers are applied after those from [WithHeaders], so they take precedence. They are applied to each request,
+// including redirects, by the host it is sent to, so they are never forwarded to another host.
+func WithHostHeaders(host string, headers http.Header) Option {
+	return func(f *Fetcher) {
+		if f.config.hostHeaders == nil {
+			f.config.hostHeaders = map[string]http.Header{}
+		}
+		if f.config.hostHeaders[host] == nil {
+			f.config.hostHeaders[host] = http.Header{}
+		}
+		mergeHeaders(f.config.hostHeaders[host], headers)
+	}
+}
+
+// WithTempDir sets the directory where temporary files are created, such as zip archives downloaded before they are
+// extracted and the trees fetched by [Fetcher.Verify]. It is created if missing. By default [os.TempDir] is used,
+// which may be a small tmpfs.
+//
+// Staging directories, from which fetched trees are renamed into place, are always created alongside the destination
+// so that they are on the same filesystem. See [WithQuarantine] to stage elsewhere.
+func WithTempDir(dir string) Option {
+	return func(f *Fetcher) { f.config.tempDir = dir }
+}
+
+// WithContextOptions returns a copy of ctx carrying options, which are applied on top of those of the [Fetcher] by
+// fetches made with the returned context, or any context derived from it. This lets code deep in a call stack that
+// only passes a context influence fetches, eg. to authenticate as the current user with [WithTokenProvider], report
+// progress with [WithHooks], or apply tighter [WithLimits].
+//
+// Options added to a context that already carries options are applied after them. Fetches with context options use
+// their own HTTP client, so they don't share connections or remembered credentials with other fetches.
+func WithContextOptions(ctx context.Context, options ...Option) context.Context {
+	return context.WithValue(ctx, optionsKey{}, slices.Concat(contextOptions(ctx), options))
+}
+
+type optionsKey struct{}
+
+func contextOptions(ctx context.Context) []Option {
+	if options, ok := ctx.Value(optionsKey{}).([]Option); ok {
+		return options
+	}
+	return nil
+}
+
+// withContextOptions returns f, or a copy of f with the options carried by ctx applied if there are any.
+func (f *Fetcher) withContextOptions(ctx context.Context) *Fetcher {
+	options := contextOptions(ctx)
+	if len(options) == 0 {
+		return f
+	}
+	scoped := f.withConfig(func(cfg *config) { cfg.unshare() })
+	for _, option := range options {
+		option(scoped)
+	}
+	scoped.config.finalise()
+	return scoped
+}
+
+func mergeHeaders(dest, src http.Header) {
+	for key, values := range src {
+		dest[http.CanonicalHeaderKey(key)] = append([]string(nil), values...)
+	}
+}
+
+// config is the Fetcher configuration made available to resolvers during a fetch.
+//
+// Each fetch operates on its own copy, so per-fetch fields may be set without affecting the Fetcher.
+type config struct {
+	logger      *slog.Logger
+	tracer      Tracer
+	metrics     Metrics
+	hooks       Hooks
+	timeouts    Timeouts
+	limits      Limits
+	downloads   Downloads
+	connections Connections
+
+	permissions    Permissions
+	caseCollisions CaseCollisionPolicy
+	zipNames       ZipNamePolicy
+	pathRules      PathRules
+	specialFiles   SpecialFilePolicy
+	conflicts      ConflictPolicy
+	client         *http.Client
+	store          string
+	tempDir        string
+	recorder       *recorder
+	faults         *Faults
+	checksums      *checksumDB
+	archiveIndexes *archiveIndexCache
+	audit          *auditLog
+	allowedSchemes map[string]bool
+	tls            *TLS
+	proxy          *Proxy
+	middleware     []Middleware
+	cookies        *cookiesConfig
+	hostMapping    hostMapping
+	credentials    *credentials
+	quarantine     *quarantine
+	diskSpace      *DiskSpaceCheck
+	gitIsolation   *GitIsolation
+	gitConfig      []gitConfigEntry
+	gitHeaders     []string
+	envExpansion   []string
+
+	userAgent   string
+	headers     http.Header
+	hostHeaders map[string]http.Header
+
+	// resolver is the name of the Resolver handling the current fetch.
+	resolver string
+	// options for the current fetch.
+	options FetchOptions
+	// usage accumulates the disk usage of the current fetch.
+	usage *usageCounter
+	// version is the concrete version selected by a resolver for a version constraint in the current fetch.
+	version string
+	// gitEnv is added to the environment of git commands run by the current operation.
+	gitEnv []string
+}
+
+func defaultConfig() config {
+	return config{
+		logger:  slog.New(slog.DiscardHandler),
+		tracer:  noopTracer{},
+		metrics: noopMetrics{},
+	}
+}
+
+// unshare copies the fields of c that options modify in place, so that options applied to c don't affect the config
+// it was copied from. Remembered credentials are discarded, as they may have come from different providers.
+func (c *config) unshare() {
+	c.headers = c.headers.Clone()
+	if c.hostHeaders != nil {
+		hostHeaders := make(map[string]http.Header, len(c.hostHeaders))
+		for host, headers := range c.hostHeaders {
+			hostHeaders[host] = headers.Clone()
+		}
+		c.hostHeaders = hostHeaders
+	}
+	c.allowedSchemes = maps.Clone(c.allowedSchemes)
+	c.hostMapping = maps.Clone(c.hostMapping)
+	c.middleware = slices.Clip(c.middleware)
+	c.gitConfig = slices.Clip(c.gitConfig)
+	c.gitHeaders = slices.Clip(c.gitHeaders)
+	c.envExpansion = slices.Clip(c.envExpansion)
+	if c.cookies != nil {
+		c.cookies = &cookiesConfig{jar: c.cookies.jar, seeds: maps.Clone(c.cookies.seeds)}
+	}
+	if c.credentials != nil {
+		c.credentials = &credentials{
+			prompter:  c.credentials.prompter,
+			tokens:    maps.Clone(c.credentials.tokens),
+			stores:    slices.Clip(c.credentials.stores),
+			hosts:     map[string]Credential{},
+			nextStore: map[string]int{},
+		}
+	}
+}
+
+// finalise derives configuration that depends on the options that have been applied.
+func (c *config) finalise() {
+	c.client = newHTTPClient(c)
+}
+
+var fallbackConfig = sync.OnceValue(func() config {
+	cfg := defaultConfig()
+	cfg.finalise()
+	return cfg
+})
+
+type configKey struct{}
+
+func contextWithConfig(ctx context.Context, cfg *config) context.Context {
+	return context.WithValue(ctx, configKey{}, cfg)
+}
+
+// configFromContext returns the configuration of the Fetcher driving the current fetch, or the default
+// configuration if a [Resolver] is being used directly.
+func configFromContext(ctx context.Context) *config {
+	if cfg, ok := ctx.Value(configKey{}).(*config); ok {
+		return cfg
+	}
+	cfg := fallbackConfig()
+	return &cfg
+}
+
+// createTemp creates a temporary file in the configured temporary directory, as for [os.CreateTemp].
+func (c *config) createTemp(pattern string) (*os.File, error) {
+	if err := c.ensureTempDir(); err != nil {
+		return nil, err
+	}
+	tmp, err := os.CreateTemp(c.tempDir, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("creating temporary file: %w", err)
+	}
+	return tmp, nil
+}
+
+// mkdirTemp creates a temporary directory in the configured temporary directory, as for [os.MkdirTemp].
+func (c *config) mkdirTemp(pattern string) (string, error) {
+	if err := c.ensureTempDir(); err != nil {
+		return "", err
+	}
+	dir, err := os.MkdirTemp(c.tempDir, pattern)
+	if err != nil {
+		return "", fmt.Errorf("creating temporary directory: %w", err)
+	}
+	return dir, nil
+}
+
+func (c *config) ensureTempDir() error {
+	if c.tempDir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(c.tempDir, 0750); err != nil {
+		return fmt.Errorf("creating temporary directory %s: %w", c.tempDir, err)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package getit //nolint:testpackage
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestWithContextOptions(t *testing.T) {
+	server := authServer(t, "Bearer user-token")
+	host := strings.TrimPrefix(server.URL, "http://")
+	fetcher := New([]Resolver{NewTAR()}, nil, WithHeaders(http.Header{"X-Base": {"base"}}))
+
+	var downloads int
+	ctx := WithContextOptions(context.Background(), WithTokenProvider(host, TokenProviderFunc(func(context.Context) (string, error) {
+		return "user-token", nil
+	})))
+	ctx = WithContextOptions(ctx, WithHooks(Hooks{OnDownloadStart: func(*url.URL, int64) { downloads++ }}))
+	assert.NoError(t, fetcher.Fetch(ctx, server.URL+"/archive.tar.gz", t.TempDir()))
+	assert.Equal(t, 1, downloads)
+
+	// Derived contexts carry the options.
+	child, cancel := context.WithCancel(ctx)
+	defer cancel()
+	assert.NoError(t, fetcher.Fetch(child, server.URL+"/archive.tar.gz", t.TempDir()))
+	assert.Equal(t, 2, downloads)
+
+	// Later options take precedence.
+	ctx = WithContextOptions(ctx, WithHooks(Hooks{}))
+	assert.NoError(t, fetcher.Fetch(ctx, server.URL+"/archive.tar.gz", t.TempDir()))
+	assert.Equal(t, 2, downloads)
+
+	// The Fetcher itself is unaffected.
+	err := fetcher.Fetch(context.Background(), server.URL+"/archive.tar.gz", t.TempDir())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "401 Unauthorized")
+	scoped := fetcher.withContextOptions(WithContextOptions(context.Background(), WithHeaders(http.Header{"X-Base": {"scoped"}})))
+	assert.Equal(t, "scoped", scoped.config.headers.Get("X-Base"))
+	assert.Equal(t, "base", fetcher.config.headers.Get("X-Base"))
+	assert.True(t, fetcher.withContextOptions(context.Background()) == fetcher)
+}
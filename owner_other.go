@@ -0,0 +1,10 @@
+//go:build !unix
+
+package getit
+
+import "io/fs"
+
+// fileOwner returns the uid and gid of a local file, which is not supported on this platform.
+func fileOwner(fs.FileInfo) (uid, gid int, ok bool) {
+	return 0, 0, false
+}
@@ -0,0 +1,99 @@
+//go:build unix
+
+package getit //nolint:testpackage
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestPermissionsOwnerMapping(t *testing.T) {
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	assert.NoError(t, tw.WriteHeader(&tar.Header{Name: "etc/", Mode: 0o755, Uid: 0, Gid: 0, Typeflag: tar.TypeDir}))
+	assert.NoError(t, tw.WriteHeader(&tar.Header{Name: "etc/passwd", Mode: 0o644, Size: 5, Uid: 1000, Gid: 1001, Typeflag: tar.TypeReg}))
+	_, err := tw.Write([]byte("root\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, tw.Close())
+	data := buf.Bytes()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+
+	uid, gid := os.Getuid(), os.Getgid()
+	tests := []struct {
+		name        string
+		permissions Permissions
+		root        bool
+		expected    map[string][2]int
+		err         string
+	}{
+		{
+			name:        "CurrentOwner",
+			permissions: Permissions{Owner: CurrentOwner()},
+			expected:    map[string][2]int{"etc": {uid, gid}, "etc/passwd": {uid, gid}},
+		},
+		{
+			name: "MapToCurrentUser",
+			permissions: Permissions{
+				UIDMap: []IDMap{{Source: 0, Target: uid, Size: 1}, {Source: 1000, Target: uid, Size: 1}},
+				GIDMap: []IDMap{{Source: 0, Target: gid, Size: 1001}, {Source: 1001, Target: gid, Size: 1}},
+			},
+			expected: map[string][2]int{"etc": {uid, gid}, "etc/passwd": {uid, gid}},
+		},
+		{
+			name: "Unmapped",
+			permissions: Permissions{
+				UIDMap: []IDMap{{Source: 0, Target: uid, Size: 1}},
+			},
+			err: "uid 1000 is not mapped",
+		},
+		{
+			name: "Subordinate",
+			permissions: Permissions{
+				UIDMap: []IDMap{{Source: 0, Target: 100000, Size: 65536}},
+				GIDMap: []IDMap{{Source: 0, Target: 100000, Size: 65536}},
+			},
+			root:     true,
+			expected: map[string][2]int{"etc": {100000, 100000}, "etc/passwd": {101000, 101001}},
+		},
+		{
+			name:        "FixedOwner",
+			permissions: Permissions{Owner: &Owner{UID: 1234, GID: 5678}},
+			root:        true,
+			expected:    map[string][2]int{"etc": {1234, 5678}, "etc/passwd": {1234, 5678}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.root && uid != 0 {
+				t.Skip("changing ownership requires root")
+			}
+			fetcher := New([]Resolver{NewTAR()}, nil, WithPermissions(tt.permissions))
+			dest := t.TempDir()
+			err := fetcher.Fetch(context.Background(), server.URL+"/rootfs.tar", dest)
+			if tt.err != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			for name, owner := range tt.expected {
+				info, err := os.Lstat(filepath.Join(dest, name))
+				assert.NoError(t, err)
+				fileUID, fileGID, ok := fileOwner(info)
+				assert.True(t, ok)
+				assert.Equal(t, owner, [2]int{fileUID, fileGID}, name)
+			}
+		})
+	}
+}
@@ -0,0 +1,253 @@
+package getit
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Pack creates an archive of srcDir and writes it to dest, the inverse of [Fetcher.Fetch].
+//
+// The archive format is selected by the extension of dest, which may be .tar, .tar.gz, .tgz or .zip. dest may be a
+// local file:// URL, which is replaced atomically, or an http:// or https:// URL that the archive is uploaded to with
+// a PUT request, eg. a presigned object storage URL:
+//
+//	file:///path/to/artifact.tar.gz
+//	https://bucket.s3.amazonaws.com/artifact.zip?X-Amz-Signature=...
+//
+// Entries are written in lexical order with their permissions and modification times. A .git directory at the root
+// of srcDir is omitted.
+func (f *Fetcher) Pack(ctx context.Context, srcDir, dest string) (err error) {
+	fetcher := f.withContextOptions(ctx)
+	if fetcher != f {
+		defer fetcher.config.client.CloseIdleConnections()
+	}
+	cfg := fetcher.config
+	ctx = contextWithConfig(ctx, &cfg)
+	u, err := url.Parse(dest)
+	if err != nil {
+		return fmt.Errorf("invalid destination %q", redactSource(dest))
+	}
+	display := RedactURL(u)
+	ctx, span := startSpan(ctx, "getit.Pack", map[string]string{"src": srcDir, "dest": display})
+	defer func() { span.End(err) }()
+	cfg.logger.InfoContext(ctx, "pack", "src", srcDir, "dest", display)
+	if err := packArchive(ctx, u, archivePath(u), srcDir); err != nil {
+		return fmt.Errorf("packing %s: %w", display, err)
+	}
+	return nil
+}
+
+// packArchive writes an archive of srcDir to u, in the format named by the extension of name.
+func packArchive(ctx context.Context, u *url.URL, name, srcDir string) error {
+	write, err := archiveWriter(name)
+	if err != nil {
+		return err
+	}
+	switch u.Scheme {
+	case "file", "http", "https":
+	default:
+		return fmt.Errorf("unsupported destination scheme %q", u.Scheme)
+	}
+
+	// The archive is written to a temporary file first, so that uploads have a known length and local destinations
+	// are never left partially written. Local archives are written alongside their destination so they can be
+	// renamed into place, and uploads to the configured temporary directory.
+	var tmp *os.File
+	if u.Scheme == "file" {
+		tmp, err = os.CreateTemp(filepath.Dir(localPath(u)), ".getit-pack-*")
+		if err != nil {
+			return fmt.Errorf("creating temporary file: %w", err)
+		}
+	} else if tmp, err = configFromContext(ctx).createTemp(".getit-pack-*"); err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	if err := write(ctx, tmp, srcDir); err != nil {
+		return err
+	}
+	if u.Scheme == "file" {
+		if err := tmp.Close(); err != nil {
+			return fmt.Errorf("writing %s: %w", tmp.Name(), err)
+		}
+		if err := os.Rename(tmp.Name(), localPath(u)); err != nil {
+			return fmt.Errorf("writing archive: %w", err)
+		}
+		return nil
+	}
+	return httpPut(ctx, u, tmp)
+}
+
+// archiveWriter returns a function writing an archive of a directory in the format named by the extension of path.
+func archiveWriter(path string) (func(ctx context.Context, w io.Writer, dir string) error, error) {
+	lower := strings.ToLower(path)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return writeZipArchive, nil
+	case strings.HasSuffix(lower, ".tar"):
+		return writeTarArchive, nil
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return func(ctx context.Context, w io.Writer, dir string) error {
+			gz := gzip.NewWriter(w)
+			if err := writeTarArchive(ctx, gz, dir); err != nil {
+				return err
+			}
+			if err := gz.Close(); err != nil {
+				return fmt.Errorf("gzip: %w", err)
+			}
+			return nil
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported archive format %q, expected .tar, .tar.gz, .tgz or .zip", filepath.Base(path))
+}
+
+// walkArchive calls fn for each entry under dir in lexical order, with its slash-separated path relative to dir.
+func walkArchive(ctx context.Context, dir string, fn func(path, name string, info fs.FileInfo) error) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error { //nolint:wrapcheck // wrapped by the caller
+		if err != nil {
+			return err
+		}
+		if err := contextError(ctx); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err //nolint:wrapcheck // wrapped by the caller
+		}
+		if rel == "." {
+			return nil
+		}
+		if rel == ".git" && d.IsDir() {
+			return filepath.SkipDir
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err //nolint:wrapcheck // wrapped by the caller
+		}
+		return fn(path, filepath.ToSlash(rel), info)
+	})
+}
+
+func writeTarArchive(ctx context.Context, w io.Writer, dir string) error {
+	tw := tar.NewWriter(w)
+	err := walkArchive(ctx, dir, func(path, name string, info fs.FileInfo) error {
+		var link string
+		if info.Mode()&fs.ModeSymlink != 0 {
+			var err error
+			if link, err = os.Readlink(path); err != nil {
+				return err //nolint:wrapcheck // wrapped below
+			}
+		}
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err //nolint:wrapcheck // wrapped below
+		}
+		hdr.Name = name
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		// Ownership is specific to the packing host.
+		hdr.Uid, hdr.Gid, hdr.Uname, hdr.Gname = 0, 0, "", ""
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err //nolint:wrapcheck // wrapped below
+		}
+		if info.Mode().IsRegular() {
+			return copyInto(tw, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("tar %s: %w", dir, err)
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("tar %s: %w", dir, err)
+	}
+	return nil
+}
+
+func writeZipArchive(ctx context.Context, w io.Writer, dir string) error {
+	zw := zip.NewWriter(w)
+	err := walkArchive(ctx, dir, func(path, name string, info fs.FileInfo) error {
+		hdr, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err //nolint:wrapcheck // wrapped below
+		}
+		hdr.Name = name
+		switch {
+		case info.IsDir():
+			hdr.Name += "/"
+		case info.Mode().IsRegular():
+			hdr.Method = zip.Deflate
+		}
+		fw, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return err //nolint:wrapcheck // wrapped below
+		}
+		switch {
+		case info.Mode()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err //nolint:wrapcheck // wrapped below
+			}
+			_, err = io.WriteString(fw, link)
+			return err //nolint:wrapcheck // wrapped below
+		case info.Mode().IsRegular():
+			return copyInto(fw, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("zip %s: %w", dir, err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("zip %s: %w", dir, err)
+	}
+	return nil
+}
+
+func copyInto(w io.Writer, path string) error {
+	f, err := os.Open(path) // #nosec G304
+	if err != nil {
+		return err //nolint:wrapcheck // wrapped by the caller
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err //nolint:wrapcheck // wrapped by the caller
+}
+
+// httpPut uploads the contents of f to u.
+func httpPut(ctx context.Context, u *url.URL, f *os.File) error {
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("stat %s: %w", f.Name(), err)
+	}
+	cfg := configFromContext(ctx)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.String(), io.NewSectionReader(f, 0, info.Size()))
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.ContentLength = info.Size()
+	// Allow the body to be resent on redirects.
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(io.NewSectionReader(f, 0, info.Size())), nil
+	}
+	resp, err := sendRequest(ctx, cfg, req)
+	if err != nil {
+		return fmt.Errorf("uploading: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("uploading: %s", resp.Status)
+	}
+	return nil
+}
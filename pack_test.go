@@ -0,0 +1,124 @@
+package getit_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/block/getit"
+)
+
+func packTree(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "sub", "empty"), 0o755))
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, ".git"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, ".git", "HEAD"), []byte("ref: refs/heads/main\n"), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("hello\n"), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "tool.sh"), []byte("#!/bin/sh\n"), 0o755))
+	assert.NoError(t, os.Symlink("README.md", filepath.Join(dir, "link")))
+	return dir
+}
+
+func TestPack(t *testing.T) {
+	src := packTree(t)
+	expected, err := getit.BuildManifest(context.Background(), src)
+	assert.NoError(t, err)
+	fetcher := getit.New([]getit.Resolver{getit.NewFile(), getit.NewTAR(), getit.NewZIP()}, nil)
+	for _, name := range []string{"out.tar", "out.tar.gz", "out.tgz", "out.zip"} {
+		t.Run(name, func(t *testing.T) {
+			archive := fileURLForTest(filepath.Join(t.TempDir(), name))
+			err := fetcher.Pack(context.Background(), src, archive)
+			assert.NoError(t, err)
+
+			dest := t.TempDir()
+			err = fetcher.Fetch(context.Background(), archive, dest)
+			assert.NoError(t, err)
+			actual, err := getit.BuildManifest(context.Background(), dest)
+			assert.NoError(t, err)
+			assert.Equal(t, getit.ManifestDiff{}, expected.Diff(actual))
+			_, err = os.Stat(filepath.Join(dest, ".git"))
+			assert.True(t, os.IsNotExist(err))
+		})
+	}
+}
+
+func TestPackUpload(t *testing.T) {
+	src := packTree(t)
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method)
+		assert.Equal(t, "secret", r.URL.Query().Get("signature"))
+		var err error
+		body, err = io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(len(body)), r.ContentLength)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	fetcher := getit.New(nil, nil)
+	err := fetcher.Pack(context.Background(), src, server.URL+"/artifact.zip?signature=secret")
+	assert.NoError(t, err)
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	assert.NoError(t, err)
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	assert.Equal(t, []string{"README.md", "link", "sub/", "sub/empty/", "sub/tool.sh"}, names)
+}
+
+func TestPackErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	src := packTree(t)
+	tests := []struct {
+		name  string
+		dest  string
+		error string
+	}{
+		{name: "UnsupportedFormat", dest: fileURLForTest(filepath.Join(t.TempDir(), "out.rar")), error: `unsupported archive format "out.rar"`},
+		{name: "UnsupportedScheme", dest: "s3://bucket/out.tar.gz", error: `unsupported destination scheme "s3"`},
+		{name: "UploadRejected", dest: server.URL + "/out.tar.gz", error: "403 Forbidden"},
+	}
+	fetcher := getit.New(nil, nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := fetcher.Pack(context.Background(), src, tt.dest)
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), tt.error)
+		})
+	}
+}
+
+func TestPackUploadWithTempDir(t *testing.T) {
+	src := packTree(t)
+	tempDir := filepath.Join(t.TempDir(), "scratch")
+	var buffered []os.DirEntry
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The archive is buffered in the temporary directory until it has been uploaded.
+		buffered, _ = os.ReadDir(tempDir)
+		_, _ = io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	fetcher := getit.New(nil, nil, getit.WithTempDir(tempDir))
+	assert.NoError(t, fetcher.Pack(context.Background(), src, server.URL+"/artifact.tar.gz"))
+	assert.Equal(t, 1, len(buffered))
+	remaining, err := os.ReadDir(tempDir)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(remaining))
+}
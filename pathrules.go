@@ -0,0 +1,99 @@
+package getit
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// PathRules validates the paths of entries extracted from TAR and ZIP archives, protecting downstream tools from
+// pathological archives. Entries that break a rule fail extraction with a *[PathRuleError].
+//
+// A zero value for any field disables that rule.
+type PathRules struct {
+	// MaxDepth is the maximum number of components in an entry's path, eg. 3 for "a/b/c.txt".
+	MaxDepth int
+	// MaxNameLength is the maximum length in bytes of any component of an entry's path.
+	MaxNameLength int
+	// MaxPathLength is the maximum length in bytes of an entry's path.
+	MaxPathLength int
+	// ForbiddenChars are characters that may not appear in an entry's path, eg. `<>:"|?*\` for paths that are valid
+	// on Windows.
+	ForbiddenChars string
+	// ForbidControlChars rejects paths containing ASCII control characters, including newlines and tabs.
+	ForbidControlChars bool
+	// ForbidReservedNames rejects names that are reserved on Windows, such as "CON", "nul.txt" or "COM1", and names
+	// ending in a space or period, which Windows silently strips.
+	ForbidReservedNames bool
+}
+
+// WithPathRules sets the rules that the paths of extracted archive entries must follow on a [Fetcher].
+func WithPathRules(rules PathRules) Option {
+	return func(f *Fetcher) { f.config.pathRules = rules }
+}
+
+// PathRuleError is returned when extracting an archive entry whose path breaks one of the configured [PathRules].
+type PathRuleError struct {
+	// Rule is the name of the broken [PathRules] field, eg. "MaxDepth".
+	Rule string
+	// Path is the archive entry being extracted.
+	Path string
+	// Reason describes how the path breaks the rule.
+	Reason string
+}
+
+func (p *PathRuleError) Error() string {
+	return fmt.Sprintf("%s: path rule %s broken: %s", p.Path, p.Rule, p.Reason)
+}
+
+// windowsReservedNames are the device names reserved by Windows in any directory, with or without an extension.
+var windowsReservedNames = func() map[string]bool {
+	names := map[string]bool{"CON": true, "PRN": true, "AUX": true, "NUL": true, "CONIN$": true, "CONOUT$": true}
+	for i := 1; i <= 9; i++ {
+		names[fmt.Sprintf("COM%d", i)] = true
+		names[fmt.Sprintf("LPT%d", i)] = true
+	}
+	return names
+}()
+
+// check validates the slash-separated path of an archive entry.
+func (p PathRules) check(name string) error {
+	if p == (PathRules{}) {
+		return nil
+	}
+	clean := strings.TrimPrefix(path.Clean("/"+name), "/")
+	fail := func(rule, format string, args ...any) error {
+		return &PathRuleError{Rule: rule, Path: name, Reason: fmt.Sprintf(format, args...)}
+	}
+	if p.MaxPathLength > 0 && len(clean) > p.MaxPathLength {
+		return fail("MaxPathLength", "length %d exceeds %d", len(clean), p.MaxPathLength)
+	}
+	if clean == "" {
+		return nil
+	}
+	components := strings.Split(clean, "/")
+	if p.MaxDepth > 0 && len(components) > p.MaxDepth {
+		return fail("MaxDepth", "depth %d exceeds %d", len(components), p.MaxDepth)
+	}
+	for _, component := range components {
+		if p.MaxNameLength > 0 && len(component) > p.MaxNameLength {
+			return fail("MaxNameLength", "name %q is %d bytes, exceeding %d", component, len(component), p.MaxNameLength)
+		}
+		if i := strings.IndexAny(component, p.ForbiddenChars); p.ForbiddenChars != "" && i >= 0 {
+			return fail("ForbiddenChars", "name %q contains %q", component, component[i])
+		}
+		if p.ForbidControlChars && strings.ContainsFunc(component, func(r rune) bool { return r < 0x20 || r == 0x7f }) {
+			return fail("ForbidControlChars", "name %q contains a control character", component)
+		}
+		if p.ForbidReservedNames {
+			if strings.HasSuffix(component, " ") || strings.HasSuffix(component, ".") {
+				return fail("ForbidReservedNames", "name %q ends in a space or period", component)
+			}
+			base, _, _ := strings.Cut(component, ".")
+			if windowsReservedNames[strings.ToUpper(strings.TrimRight(base, " "))] {
+				return fail("ForbidReservedNames", "name %q is reserved on Windows", component)
+			}
+		}
+	}
+	return nil
+}
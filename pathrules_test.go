@@ -0,0 +1,77 @@
+package getit_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/block/getit"
+)
+
+// zipArchive returns a zip archive containing a single file.
+func zipArchive(t *testing.T, name, content string) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	w, err := zw.Create(name)
+	assert.NoError(t, err)
+	_, err = w.Write([]byte(content))
+	assert.NoError(t, err)
+	assert.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestWithPathRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules getit.PathRules
+		entry string
+		rule  string
+	}{
+		{name: "NoRules", entry: "a/b/c/d/e.txt"},
+		{name: "WithinRules", rules: getit.PathRules{MaxDepth: 5, MaxNameLength: 5, MaxPathLength: 13, ForbiddenChars: `<>:"|?*\`, ForbidControlChars: true, ForbidReservedNames: true}, entry: "a/b/c/d/e.txt"},
+		{name: "MaxDepth", rules: getit.PathRules{MaxDepth: 4}, entry: "a/b/c/d/e.txt", rule: "MaxDepth"},
+		{name: "MaxNameLength", rules: getit.PathRules{MaxNameLength: 4}, entry: "a/b/c/d/e.txt", rule: "MaxNameLength"},
+		{name: "MaxPathLength", rules: getit.PathRules{MaxPathLength: 12}, entry: "a/b/c/d/e.txt", rule: "MaxPathLength"},
+		{name: "ForbiddenChars", rules: getit.PathRules{ForbiddenChars: `<>:"|?*\`}, entry: "a/b:c.txt", rule: "ForbiddenChars"},
+		{name: "ControlChars", rules: getit.PathRules{ForbidControlChars: true}, entry: "a/b\nc.txt", rule: "ForbidControlChars"},
+		{name: "ReservedName", rules: getit.PathRules{ForbidReservedNames: true}, entry: "a/con", rule: "ForbidReservedNames"},
+		{name: "ReservedNameWithExtension", rules: getit.PathRules{ForbidReservedNames: true}, entry: "a/Nul.tar.gz", rule: "ForbidReservedNames"},
+		{name: "ReservedDirectory", rules: getit.PathRules{ForbidReservedNames: true}, entry: "lpt1/file.txt", rule: "ForbidReservedNames"},
+		{name: "NotReserved", rules: getit.PathRules{ForbidReservedNames: true}, entry: "a/console.txt"},
+		{name: "TrailingPeriod", rules: getit.PathRules{ForbidReservedNames: true}, entry: "a/file.", rule: "ForbidReservedNames"},
+		{name: "TrailingSpace", rules: getit.PathRules{ForbidReservedNames: true}, entry: "a /file.txt", rule: "ForbidReservedNames"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, archive := range []string{"tar.gz", "zip"} {
+				var data []byte
+				if archive == "zip" {
+					data = zipArchive(t, tt.entry, "hello\n")
+				} else {
+					data = tarball(t, tt.entry, "hello\n")
+				}
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+					_, _ = w.Write(data)
+				}))
+				defer server.Close()
+				fetcher := getit.New([]getit.Resolver{getit.NewTAR(), getit.NewZIP()}, nil, getit.WithPathRules(tt.rules))
+				err := fetcher.Fetch(context.Background(), server.URL+"/archive."+archive, t.TempDir())
+				if tt.rule == "" {
+					assert.NoError(t, err, archive)
+					continue
+				}
+				var ruleErr *getit.PathRuleError
+				assert.True(t, errors.As(err, &ruleErr), "%s: %v", archive, err)
+				assert.Equal(t, tt.rule, ruleErr.Rule)
+				assert.Equal(t, tt.entry, ruleErr.Path)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package getit
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Permissions controls the modes and ownership of files written to a destination, consistently across resolvers.
+type Permissions struct {
+	// Umask bits are cleared from the mode of every file and directory written, in addition to the process umask.
+	Umask fs.FileMode
+	// PreserveSpecialBits retains setuid, setgid and sticky bits from the source. By default they are stripped.
+	PreserveSpecialBits bool
+	// PreserveOwner applies the uid/gid recorded in tar archives or on local source files, which typically requires
+	// running as root. By default files are owned by the current user.
+	PreserveOwner bool
+	// Normalize sets directories and executable files to exactly 0755 and other files to exactly 0644, regardless of
+	// source modes or the process umask.
+	Normalize bool
+	// Owner, if set, owns every file extracted from tar archives or copied from local files, regardless of the
+	// recorded uid/gid, eg. [CurrentOwner] to take ownership of a tree extracted as root. Changing ownership to anyone
+	// other than the current user typically requires running as root.
+	Owner *Owner
+	// UIDMap and GIDMap translate the uids and gids recorded in tar archives or on local source files before they
+	// are applied, as for the ID mappings of a user namespace, eg. to populate a container rootfs for a rootless
+	// runtime. Setting either implies PreserveOwner, and IDs outside every range fail with an error. IDs are applied
+	// unchanged if the map for their kind is empty.
+	UIDMap []IDMap
+	GIDMap []IDMap
+}
+
+// Owner is a uid and gid to own files.
+type Owner struct {
+	UID int
+	GID int
+}
+
+// CurrentOwner returns the uid and gid of the current process, for [Permissions.Owner].
+func CurrentOwner() *Owner {
+	return &Owner{UID: os.Getuid(), GID: os.Getgid()}
+}
+
+// IDMap maps a range of Size IDs starting at Source, as recorded in the source, to those starting at Target.
+type IDMap struct {
+	Source int
+	Target int
+	Size   int
+}
+
+// mapID maps id through mappings, which must not be empty.
+func mapID(mappings []IDMap, id int) (int, bool) {
+	for _, m := range mappings {
+		if id >= m.Source && id < m.Source+m.Size {
+			return m.Target + id - m.Source, true
+		}
+	}
+	return 0, false
+}
+
+// WithPermissions controls the modes and ownership of fetched files.
+func WithPermissions(permissions Permissions) Option {
+	return func(f *Fetcher) { f.config.permissions = permissions }
+}
+
+const specialBits = fs.ModeSetuid | fs.ModeSetgid | fs.ModeSticky
+
+// mode returns the mode to write for a file whose source mode is m.
+func (p Permissions) mode(m fs.FileMode) fs.FileMode {
+	if p.Normalize {
+		if m.IsDir() || m&0o111 != 0 {
+			return 0o755
+		}
+		return 0o644
+	}
+	mode := m.Perm() &^ p.Umask.Perm()
+	if p.PreserveSpecialBits {
+		mode |= m & specialBits
+	}
+	return mode
+}
+
+// chmod explicitly applies mode to path if the process umask must not apply, or if mode has setuid, setgid or
+// sticky bits, which are not reliably set on creation.
+func (p Permissions) chmod(path string, mode fs.FileMode) error {
+	if !p.Normalize && mode&specialBits == 0 {
+		return nil
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		return fmt.Errorf("chmod %s: %w", path, err)
+	}
+	return nil
+}
+
+// chown applies ownership to path if PreserveOwner, Owner or an ID map is set, where uid and gid are the owner
+// recorded in the source.
+func (p Permissions) chown(path string, uid, gid int) error {
+	switch {
+	case p.Owner != nil:
+		uid, gid = p.Owner.UID, p.Owner.GID
+	case len(p.UIDMap) > 0 || len(p.GIDMap) > 0:
+		if len(p.UIDMap) > 0 {
+			mapped, ok := mapID(p.UIDMap, uid)
+			if !ok {
+				return fmt.Errorf("chown %s: uid %d is not mapped", path, uid)
+			}
+			uid = mapped
+		}
+		if len(p.GIDMap) > 0 {
+			mapped, ok := mapID(p.GIDMap, gid)
+			if !ok {
+				return fmt.Errorf("chown %s: gid %d is not mapped", path, gid)
+			}
+			gid = mapped
+		}
+	case !p.PreserveOwner:
+		return nil
+	}
+	if err := os.Lchown(path, uid, gid); err != nil {
+		return fmt.Errorf("chown %s: %w", path, err)
+	}
+	return nil
+}
+
+// applyTree applies perms to everything already under dir, for resolvers that don't control the modes they write.
+// The .git directory is skipped.
+func (p Permissions) applyTree(ctx context.Context, dir string) error {
+	if p.Umask.Perm() == 0 && !p.Normalize {
+		return nil
+	}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := contextError(ctx); err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if d.Type()&fs.ModeSymlink != 0 {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err //nolint:wrapcheck // wrapped below
+		}
+		return os.Chmod(path, p.mode(info.Mode())) //nolint:wrapcheck // wrapped below
+	})
+	if err != nil {
+		return fmt.Errorf("applying permissions to %s: %w", dir, err)
+	}
+	return nil
+}
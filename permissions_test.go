@@ -0,0 +1,105 @@
+package getit_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/block/getit"
+)
+
+func TestWithPermissionsZIP(t *testing.T) {
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	hdr := &zip.FileHeader{Name: "bin/tool", Method: zip.Deflate}
+	hdr.SetMode(0o755 | fs.ModeSetuid)
+	w, err := zw.CreateHeader(hdr)
+	assert.NoError(t, err)
+	_, err = w.Write([]byte("#!/bin/sh\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, zw.Close())
+	data := buf.Bytes()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		permissions getit.Permissions
+		expected    fs.FileMode
+	}{
+		{name: "Umask", permissions: getit.Permissions{Umask: 0o077}, expected: 0o700},
+		{name: "PreserveSpecialBits", permissions: getit.Permissions{Umask: 0o022, PreserveSpecialBits: true}, expected: 0o755 | fs.ModeSetuid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetcher := getit.New([]getit.Resolver{getit.NewZIP()}, nil, getit.WithPermissions(tt.permissions))
+			dest := t.TempDir()
+			err := fetcher.Fetch(context.Background(), server.URL+"/archive.zip", dest)
+			assert.NoError(t, err)
+			info, err := os.Stat(filepath.Join(dest, "bin", "tool"))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, info.Mode()&(fs.ModePerm|fs.ModeSetuid))
+		})
+	}
+}
+
+func TestWithPermissionsFile(t *testing.T) {
+	srcDir := t.TempDir()
+	err := os.WriteFile(filepath.Join(srcDir, "tool"), []byte("#!/bin/sh\n"), 0o755)
+	assert.NoError(t, err)
+	err = os.Chmod(filepath.Join(srcDir, "tool"), 0o755|fs.ModeSetgid)
+	assert.NoError(t, err)
+
+	fetcher := getit.New([]getit.Resolver{getit.NewFile()}, nil, getit.WithPermissions(getit.Permissions{Umask: 0o077}))
+	dest := t.TempDir()
+	err = fetcher.Fetch(context.Background(), "file://"+srcDir, dest)
+	assert.NoError(t, err)
+
+	info, err := os.Stat(filepath.Join(dest, "tool"))
+	assert.NoError(t, err)
+	assert.Equal(t, fs.FileMode(0o700), info.Mode()&(fs.ModePerm|fs.ModeSetgid))
+}
+
+func TestFetchDeterministicPermissions(t *testing.T) {
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	for name, mode := range map[string]fs.FileMode{"private.txt": 0o600, "run.sh": 0o700} {
+		hdr := &zip.FileHeader{Name: name, Method: zip.Deflate}
+		hdr.SetMode(mode)
+		w, err := zw.CreateHeader(hdr)
+		assert.NoError(t, err)
+		_, err = w.Write([]byte(name))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, zw.Close())
+	data := buf.Bytes()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+
+	fetcher := getit.New([]getit.Resolver{getit.NewZIP()}, nil)
+	dest := t.TempDir()
+	_, err := fetcher.FetchWithOptions(context.Background(), server.URL+"/archive.zip", dest, getit.FetchOptions{Deterministic: true})
+	assert.NoError(t, err)
+
+	for name, expected := range map[string]fs.FileMode{"private.txt": 0o644, "run.sh": 0o755} {
+		info, err := os.Stat(filepath.Join(dest, name))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, info.Mode().Perm(), name)
+	}
+}
@@ -0,0 +1,49 @@
+package getit
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// PolicyError is returned when a source is rejected by a policy configured on a [Fetcher].
+type PolicyError struct {
+	// Policy is the option that rejected the source, eg. "WithAllowedSchemes".
+	Policy string
+	// Source that was rejected, with credentials redacted.
+	Source string
+	// Reason the source was rejected.
+	Reason string
+}
+
+func (p *PolicyError) Error() string {
+	return fmt.Sprintf("%s: rejected by %s: %s", p.Source, p.Policy, p.Reason)
+}
+
+// WithAllowedSchemes restricts a [Fetcher] to sources whose URLs have one of the given schemes, eg. "https" or
+// "git+https". Schemes are compared after mappers are applied, so "user/repo" is checked as "git+https", and local
+// paths as "file".
+//
+// Disallowed sources fail to resolve with a [*PolicyError], before any network or filesystem access, regardless of
+// the resolvers available. If "file" is not allowed, the [FilePath] mapper recognises paths without checking whether
+// they exist, so that they are rejected rather than treated as other kinds of source.
+func WithAllowedSchemes(schemes ...string) Option {
+	return func(f *Fetcher) {
+		f.config.allowedSchemes = map[string]bool{}
+		for _, scheme := range schemes {
+			f.config.allowedSchemes[strings.ToLower(scheme)] = true
+		}
+	}
+}
+
+// checkScheme returns a [*PolicyError] if the scheme of u is not allowed.
+func (c *config) checkScheme(u *url.URL) error {
+	if c.allowedSchemes == nil || c.allowedSchemes[strings.ToLower(u.Scheme)] {
+		return nil
+	}
+	scheme := u.Scheme
+	if scheme == "" {
+		scheme = "(none)"
+	}
+	return &PolicyError{Policy: "WithAllowedSchemes", Source: RedactURL(u), Reason: fmt.Sprintf("scheme %s is not allowed", scheme)}
+}
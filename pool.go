@@ -0,0 +1,63 @@
+package getit
+
+import (
+	"context"
+	"sync"
+)
+
+// workerPool runs tasks on a bounded number of goroutines, stopping at the first error.
+type workerPool struct {
+	ctx    context.Context //nolint:containedctx // cancelled when a task fails
+	cancel context.CancelCauseFunc
+	sem    chan struct{}
+	wg     sync.WaitGroup
+	// mu serialises bookkeeping so that timestamps and hooks are not called concurrently.
+	mu  sync.Mutex
+	err error
+}
+
+func newWorkerPool(ctx context.Context, concurrency int) *workerPool {
+	ctx, cancel := context.WithCancelCause(ctx)
+	return &workerPool{ctx: ctx, cancel: cancel, sem: make(chan struct{}, concurrency)}
+}
+
+// run fn on a worker, blocking until one is available.
+func (p *workerPool) run(fn func() error) {
+	select {
+	case p.sem <- struct{}{}:
+	case <-p.ctx.Done():
+		p.fail(contextError(p.ctx))
+		return
+	}
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer func() { <-p.sem }()
+		if err := fn(); err != nil {
+			p.fail(err)
+		}
+	}()
+}
+
+// finish runs fn while holding the bookkeeping lock.
+func (p *workerPool) finish(fn func() error) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return fn()
+}
+
+func (p *workerPool) fail(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err == nil {
+		p.err = err
+		p.cancel(err)
+	}
+}
+
+// wait for all tasks to complete, returning the first error.
+func (p *workerPool) wait() error {
+	p.wg.Wait()
+	p.cancel(nil)
+	return p.err
+}
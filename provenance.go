@@ -0,0 +1,102 @@
+package getit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// provenanceBuilder identifies getit as the producer of provenance documents.
+const provenanceBuilder = "https://github.com/block/getit"
+
+// provenanceStatement is an in-toto v1 statement with a SLSA v1 provenance predicate.
+//
+// See https://github.com/in-toto/attestation/blob/main/spec/v1/statement.md and https://slsa.dev/spec/v1.0/provenance.
+type provenanceStatement struct {
+	Type          string               `json:"_type"`
+	Subject       []provenanceResource `json:"subject"`
+	PredicateType string               `json:"predicateType"`
+	Predicate     struct {
+		BuildDefinition struct {
+			BuildType            string               `json:"buildType"`
+			ExternalParameters   map[string]string    `json:"externalParameters"`
+			ResolvedDependencies []provenanceResource `json:"resolvedDependencies"`
+		} `json:"buildDefinition"`
+		RunDetails struct {
+			Builder struct {
+				ID string `json:"id"`
+			} `json:"builder"`
+			Metadata struct {
+				StartedOn  string `json:"startedOn"`
+				FinishedOn string `json:"finishedOn"`
+			} `json:"metadata"`
+		} `json:"runDetails"`
+	} `json:"predicate"`
+}
+
+// provenanceResource is an in-toto ResourceDescriptor.
+type provenanceResource struct {
+	Name        string            `json:"name,omitempty"`
+	URI         string            `json:"uri,omitempty"`
+	Digest      map[string]string `json:"digest,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// writeProvenance writes an in-toto provenance statement for a completed fetch to path. Each regular file in dest is
+// a subject, and the source, with its revision and version if known, is the resolved dependency.
+func writeProvenance(ctx context.Context, path string, source string, result *FetchResult, started, finished time.Time) error {
+	manifest, err := BuildManifest(ctx, result.Dest)
+	if err != nil {
+		return fmt.Errorf("writing provenance: %w", err)
+	}
+	statement := provenanceStatement{
+		Type:          "https://in-toto.io/Statement/v1",
+		Subject:       []provenanceResource{},
+		PredicateType: "https://slsa.dev/provenance/v1",
+	}
+	for _, entry := range manifest.Entries {
+		if entry.Type == "file" {
+			statement.Subject = append(statement.Subject, provenanceResource{Name: entry.Path, Digest: map[string]string{"sha256": entry.SHA256}})
+		}
+	}
+	build := &statement.Predicate.BuildDefinition
+	build.BuildType = provenanceBuilder + "/fetch/v1"
+	build.ExternalParameters = map[string]string{"source": source, "resolver": result.Resolver}
+	dependency := provenanceResource{
+		URI:         result.URL,
+		Digest:      map[string]string{},
+		Annotations: map[string]string{},
+	}
+	if result.Info.Revision != "" {
+		dependency.Annotations["revision"] = result.Info.Revision
+		if result.Resolver == "Git" {
+			dependency.Digest["gitCommit"] = result.Info.Revision
+		}
+	}
+	if result.Version != "" {
+		dependency.Annotations["version"] = result.Version
+	}
+	if !result.Info.Modified.IsZero() {
+		dependency.Annotations["modified"] = result.Info.Modified.UTC().Format(time.RFC3339)
+	}
+	if hash, ok := strings.CutPrefix(manifest.TreeHash, "sha256:"); ok {
+		dependency.Digest["getitTreeSha256"] = hash
+	}
+	build.ResolvedDependencies = []provenanceResource{dependency}
+	run := &statement.Predicate.RunDetails
+	run.Builder.ID = provenanceBuilder
+	run.Metadata.StartedOn = started.UTC().Format(time.RFC3339)
+	run.Metadata.FinishedOn = finished.UTC().Format(time.RFC3339)
+
+	data, err := json.MarshalIndent(statement, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding provenance: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("writing provenance: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package getit_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/block/getit"
+)
+
+func TestFetchProvenance(t *testing.T) {
+	mem := getit.NewMemResolver(map[string]string{"repo/a.txt": "a\n", "repo/sub/b.txt": "b\n"})
+	fetcher := getit.New([]getit.Resolver{mem}, nil)
+	dest := t.TempDir()
+	path := filepath.Join(t.TempDir(), "provenance.json")
+	before := time.Now().Add(-time.Second)
+	_, err := fetcher.FetchWithOptions(context.Background(), "mem://repo?token=secret", dest, getit.FetchOptions{ProvenancePath: path})
+	assert.NoError(t, err)
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "secret")
+	var statement struct {
+		Type    string `json:"_type"`
+		Subject []struct {
+			Name   string            `json:"name"`
+			Digest map[string]string `json:"digest"`
+		} `json:"subject"`
+		PredicateType string `json:"predicateType"`
+		Predicate     struct {
+			BuildDefinition struct {
+				ExternalParameters   map[string]string `json:"externalParameters"`
+				ResolvedDependencies []struct {
+					URI         string            `json:"uri"`
+					Digest      map[string]string `json:"digest"`
+					Annotations map[string]string `json:"annotations"`
+				} `json:"resolvedDependencies"`
+			} `json:"buildDefinition"`
+			RunDetails struct {
+				Metadata struct {
+					StartedOn  time.Time `json:"startedOn"`
+					FinishedOn time.Time `json:"finishedOn"`
+				} `json:"metadata"`
+			} `json:"runDetails"`
+		} `json:"predicate"`
+	}
+	assert.NoError(t, json.Unmarshal(data, &statement))
+	assert.Equal(t, "https://in-toto.io/Statement/v1", statement.Type)
+	assert.Equal(t, "https://slsa.dev/provenance/v1", statement.PredicateType)
+	assert.Equal(t, 2, len(statement.Subject))
+	assert.Equal(t, "a.txt", statement.Subject[0].Name)
+	sum := sha256.Sum256([]byte("a\n"))
+	assert.Equal(t, hex.EncodeToString(sum[:]), statement.Subject[0].Digest["sha256"])
+	assert.Equal(t, "sub/b.txt", statement.Subject[1].Name)
+
+	build := statement.Predicate.BuildDefinition
+	assert.Equal(t, "MemResolver", build.ExternalParameters["resolver"])
+	assert.Equal(t, 1, len(build.ResolvedDependencies))
+	dependency := build.ResolvedDependencies[0]
+	assert.Equal(t, "mem://repo?token=xxxxx", dependency.URI)
+	assert.True(t, strings.HasPrefix(dependency.Annotations["revision"], "sha256:"))
+	manifest, err := getit.BuildManifest(context.Background(), dest)
+	assert.NoError(t, err)
+	assert.Equal(t, manifest.TreeHash, "sha256:"+dependency.Digest["getitTreeSha256"])
+
+	metadata := statement.Predicate.RunDetails.Metadata
+	assert.True(t, metadata.StartedOn.After(before))
+	assert.False(t, metadata.FinishedOn.Before(metadata.StartedOn))
+}
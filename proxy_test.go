@@ -0,0 +1,97 @@
+package getit //nolint:testpackage
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestProxy(t *testing.T) {
+	archive, err := os.ReadFile(filepath.Join("testdata", "archive.tar.gz"))
+	assert.NoError(t, err)
+	var mu sync.Mutex
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		proxied = append(proxied, r.URL.String())
+		mu.Unlock()
+		_, _ = w.Write(archive)
+	}))
+	defer proxy.Close()
+
+	fetcher := New([]Resolver{NewTAR()}, nil, WithProxy(Proxy{HTTP: proxy.URL}))
+	dest := t.TempDir()
+	assert.NoError(t, fetcher.Fetch(context.Background(), "http://example.invalid/archive.tar.gz", dest))
+	_, err = os.Stat(filepath.Join(dest, "nested.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"http://example.invalid/archive.tar.gz"}, proxied)
+
+	fetcher = New([]Resolver{NewTAR()}, nil, WithProxy(Proxy{HTTP: "://invalid"}))
+	err = fetcher.Fetch(context.Background(), "http://example.invalid/archive.tar.gz", t.TempDir())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid proxy URL")
+}
+
+func TestProxySelection(t *testing.T) {
+	proxy := &Proxy{
+		HTTP:    "http://proxy:3128",
+		HTTPS:   "socks5://proxy:1080",
+		NoProxy: "internal.example.com, .corp, 10.0.0.0/8, 192.168.1.1, [::1], example.org:8080",
+	}
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{url: "http://example.com/a", expected: "http://proxy:3128"},
+		{url: "https://example.com/a", expected: "socks5://proxy:1080"},
+		{url: "https://internal.example.com/a"},
+		{url: "https://api.internal.example.com/a"},
+		{url: "https://notinternal.example.com/a", expected: "socks5://proxy:1080"},
+		{url: "https://host.corp/a"},
+		{url: "https://corp/a"},
+		{url: "http://10.1.2.3/a"},
+		{url: "http://11.1.2.3/a", expected: "http://proxy:3128"},
+		{url: "http://192.168.1.1/a"},
+		{url: "http://[::1]:8000/a"},
+		{url: "http://example.org:8080/a"},
+		{url: "http://example.org/a", expected: "http://proxy:3128"},
+		{url: "ftp://example.com/a"},
+	}
+	proxyFunc := proxy.proxyFunc()
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			assert.NoError(t, err)
+			selected, err := proxyFunc(&http.Request{URL: u})
+			assert.NoError(t, err)
+			actual := ""
+			if selected != nil {
+				actual = selected.String()
+			}
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+
+	everything, err := (&Proxy{HTTP: "http://proxy:3128", NoProxy: "*"}).proxyFunc()(&http.Request{URL: &url.URL{Scheme: "http", Host: "example.com"}})
+	assert.NoError(t, err)
+	assert.Zero(t, everything)
+}
+
+func TestProxyGitEnv(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.proxy = &Proxy{HTTPS: "http://proxy:3128", NoProxy: "internal"}
+	cmd := gitCommand(contextWithConfig(context.Background(), &cfg), "version")
+	env := strings.Join(cmd.Env, "\n")
+	assert.Contains(t, env, "\nhttps_proxy=http://proxy:3128\n")
+	assert.Contains(t, env, "\nno_proxy=internal\n")
+	assert.Contains(t, env, "\nhttp_proxy=\n")
+	assert.Zero(t, gitCommand(context.Background(), "version").Env)
+}
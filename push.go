@@ -0,0 +1,66 @@
+package getit
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// Pusher is an optional interface implemented by a [Resolver] that can write to a source.
+type Pusher interface {
+	// Push writes the contents of srcDir to source, such that fetching source afterwards reproduces it.
+	Push(ctx context.Context, source Source, srcDir string) error
+}
+
+// Push writes the contents of srcDir to dest, the inverse of [Fetcher.Fetch], using the same URL grammar. Fetching
+// dest afterwards reproduces srcDir:
+//
+//	file:///path/to/dir                          replaces the directory with a copy of srcDir
+//	file:///path/to/artifact.tar.gz              writes an archive of srcDir
+//	https://bucket.example.com/artifact.zip?...  uploads an archive of srcDir with a PUT request
+//
+// An error is returned if the resolver for dest does not implement [Pusher].
+func (f *Fetcher) Push(ctx context.Context, srcDir, dest string) (err error) {
+	fetcher := f.withContextOptions(ctx)
+	if fetcher != f {
+		defer fetcher.config.client.CloseIdleConnections()
+	}
+	cfg := fetcher.config
+	ctx = contextWithConfig(ctx, &cfg)
+	display := redactSource(dest)
+	resolver, source, err := fetcher.Resolve(dest)
+	if err != nil {
+		return err
+	}
+	pusher, ok := resolver.(Pusher)
+	if !ok {
+		return fmt.Errorf("pushing to %s: not supported by the %s resolver", display, resolverName(resolver))
+	}
+	if source.SubDir != "" {
+		return fmt.Errorf("pushing to %s: subdirectories are not supported", display)
+	}
+	info, err := os.Stat(srcDir)
+	if err != nil {
+		return fmt.Errorf("pushing to %s: %w", display, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("pushing to %s: %s is not a directory", display, srcDir)
+	}
+	ctx, span := startSpan(ctx, "getit.Push", map[string]string{"src": srcDir, "dest": display, "resolver": resolverName(resolver)})
+	defer func() { span.End(err) }()
+	cfg.logger.InfoContext(ctx, "push", "src", srcDir, "dest", display, "resolver", resolverName(resolver))
+	if err := pusher.Push(ctx, source, srcDir); err != nil {
+		return fmt.Errorf("pushing to %s: %w", display, err)
+	}
+	return nil
+}
+
+// pushArchive writes an archive of srcDir to source, in the format forced by [Source.Archive] or otherwise named by
+// the extension of the URL path.
+func pushArchive(ctx context.Context, source Source, srcDir string) error {
+	name := archivePath(source.URL)
+	if source.Archive != "" {
+		name = "archive." + source.Archive
+	}
+	return packArchive(ctx, source.URL, name, srcDir)
+}
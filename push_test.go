@@ -0,0 +1,107 @@
+package getit_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/block/getit"
+)
+
+func TestPush(t *testing.T) {
+	src := packTree(t)
+	assert.NoError(t, os.RemoveAll(filepath.Join(src, ".git")))
+	expected, err := getit.BuildManifest(context.Background(), src)
+	assert.NoError(t, err)
+
+	// A minimal object store, serving what was last PUT to each path.
+	var mu sync.Mutex
+	objects := map[string][]byte{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodPut:
+			body, err := io.ReadAll(r.Body)
+			assert.NoError(t, err)
+			objects[r.URL.Path] = body
+		default:
+			body, ok := objects[r.URL.Path]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			http.ServeContent(w, r, r.URL.Path, time.Time{}, bytes.NewReader(body))
+		}
+	}))
+	defer server.Close()
+
+	tmp := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(tmp, "existing"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(tmp, "existing", "stale.txt"), []byte("stale\n"), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(tmp, "opaque"), nil, 0o644))
+	tests := []struct {
+		name string
+		dest string
+	}{
+		{name: "Directory", dest: fileURLForTest(filepath.Join(tmp, "dir"))},
+		{name: "ReplaceDirectory", dest: fileURLForTest(filepath.Join(tmp, "existing"))},
+		{name: "LocalTarball", dest: fileURLForTest(filepath.Join(tmp, "out.tar.gz"))},
+		{name: "LocalZip", dest: fileURLForTest(filepath.Join(tmp, "out.zip"))},
+		{name: "ArchiveOverride", dest: fileURLForTest(filepath.Join(tmp, "opaque")) + "?archive=tgz"},
+		{name: "UploadTarball", dest: server.URL + "/out.tar.gz"},
+		{name: "UploadZip", dest: server.URL + "/out.zip"},
+	}
+	fetcher := getit.New([]getit.Resolver{getit.NewTAR(), getit.NewZIP(), getit.NewFile()}, nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := fetcher.Push(context.Background(), src, tt.dest)
+			assert.NoError(t, err)
+
+			dest := t.TempDir()
+			err = fetcher.Fetch(context.Background(), tt.dest, dest)
+			assert.NoError(t, err)
+			actual, err := getit.BuildManifest(context.Background(), dest)
+			assert.NoError(t, err)
+			assert.Equal(t, getit.ManifestDiff{}, expected.Diff(actual))
+		})
+	}
+	entries, err := os.ReadDir(tmp)
+	assert.NoError(t, err)
+	for _, entry := range entries {
+		assert.NotContains(t, entry.Name(), ".getit", "staging left behind")
+	}
+}
+
+func TestPushErrors(t *testing.T) {
+	src := packTree(t)
+	tmp := t.TempDir()
+	tests := []struct {
+		name  string
+		src   string
+		dest  string
+		error string
+	}{
+		{name: "Unsupported", src: src, dest: "git+https://example.com/repo.git", error: "not supported by the Git resolver"},
+		{name: "SingleFile", src: src, dest: fileURLForTest(filepath.Join(tmp, "file")) + "?archive=none", error: "can't be pushed as a single file"},
+		{name: "SubDir", src: src, dest: fileURLForTest(tmp) + "//sub", error: "subdirectories are not supported"},
+		{name: "NotDirectory", src: filepath.Join(src, "README.md"), dest: fileURLForTest(filepath.Join(tmp, "dir")), error: "is not a directory"},
+	}
+	fetcher := getit.New([]getit.Resolver{getit.NewGit(), getit.NewFile()}, nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := fetcher.Push(context.Background(), tt.src, tt.dest)
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), tt.error)
+		})
+	}
+}
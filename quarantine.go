@@ -0,0 +1,85 @@
+package getit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ErrQuarantined is returned when a [ScanFunc] rejects fetched content, which is then discarded without reaching the
+// destination.
+var ErrQuarantined = errors.New("rejected by scan")
+
+// ScanFunc inspects fetched content in quarantine, eg. with an antivirus or license scanner. dir holds the fetched
+// tree, and source is the resolved URL with credentials redacted. Returning an error rejects the content.
+type ScanFunc func(ctx context.Context, dir, source string) error
+
+// WithQuarantine fetches into a quarantine directory under dir, and calls scan before promoting the fetched tree to
+// its destination, so that no unscanned content is ever visible at the destination.
+//
+// If dir is empty, content is quarantined alongside the destination. Otherwise dir may be on another file system,
+// eg. one monitored by a virus scanner, in which case scanned content is copied alongside the destination before it
+// is atomically moved into place.
+//
+// Scans run after checksums are verified with [WithChecksumDB], and before any [FetchOptions.PostFetch] hook, so
+// hooks never run on unscanned content.
+func WithQuarantine(dir string, scan ScanFunc) Option {
+	return func(f *Fetcher) { f.config.quarantine = &quarantine{dir: dir, scan: scan} }
+}
+
+type quarantine struct {
+	dir  string
+	scan ScanFunc
+}
+
+// newStaging creates a directory to quarantine dest in.
+func (q *quarantine) newStaging(dest string) (string, error) {
+	if q.dir == "" {
+		return newStaging(dest)
+	}
+	if err := os.MkdirAll(q.dir, 0o700); err != nil {
+		return "", fmt.Errorf("creating quarantine: %w", err)
+	}
+	staging, err := os.MkdirTemp(q.dir, filepath.Base(dest)+".getit-*")
+	if err != nil {
+		return "", fmt.Errorf("creating quarantine: %w", err)
+	}
+	return staging, nil
+}
+
+// check scans the quarantined tree in dir.
+func (q *quarantine) check(ctx context.Context, dir string, source Source) error {
+	display := RedactURL(source.URL)
+	if err := q.scan(ctx, dir, display); err != nil {
+		configFromContext(ctx).logger.WarnContext(ctx, "quarantined", "source", display, "error", err)
+		return fmt.Errorf("%w: %w", ErrQuarantined, err)
+	}
+	return nil
+}
+
+// promote moves the quarantined tree in dir into dest with apply, eg. [promote]. If dir can't be renamed into place,
+// eg. as it is on another file system, it is first copied alongside dest.
+func (q *quarantine) promote(ctx context.Context, dir, dest string, apply func(ctx context.Context, staging, dest string) error) error {
+	if q.dir == "" {
+		return apply(ctx, dir, dest)
+	}
+	if err := apply(ctx, dir, dest); err == nil {
+		return nil
+	} else if linkErr := (*os.LinkError)(nil); !errors.As(err, &linkErr) {
+		return err
+	}
+	staging, err := newStaging(dest)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(staging)
+	// The tree has already been extracted, so the copy is not reported to hooks or counted again.
+	copyCfg := *configFromContext(ctx)
+	copyCfg.hooks, copyCfg.usage = Hooks{}, nil
+	if err := copyDir(contextWithConfig(ctx, &copyCfg), dir, staging, copyOptions{}); err != nil {
+		return fmt.Errorf("promoting from quarantine: %w", err)
+	}
+	return apply(ctx, staging, dest)
+}
@@ -0,0 +1,93 @@
+package getit_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/block/getit"
+)
+
+func TestQuarantine(t *testing.T) {
+	mem := getit.NewMemResolver(map[string]string{"clean/file.txt": "clean\n", "infected/file.txt": "EICAR\n"})
+	errInfected := errors.New("infected")
+	quarantineDir := filepath.Join(t.TempDir(), "quarantine")
+	var scanned []string
+	scan := func(_ context.Context, dir, source string) error {
+		scanned = append(scanned, source)
+		assert.True(t, strings.HasPrefix(dir, quarantineDir), dir)
+		content, err := os.ReadFile(filepath.Join(dir, "file.txt"))
+		assert.NoError(t, err)
+		if strings.Contains(string(content), "EICAR") {
+			return errInfected
+		}
+		return nil
+	}
+	var postFetched []string
+	options := getit.FetchOptions{PostFetch: func(_ context.Context, dir string) error {
+		postFetched = append(postFetched, dir)
+		return nil
+	}}
+	fetcher := getit.New([]getit.Resolver{mem}, nil, getit.WithQuarantine(quarantineDir, scan))
+	ctx := context.Background()
+	dest := filepath.Join(t.TempDir(), "dest")
+
+	_, err := fetcher.FetchWithOptions(ctx, "mem://clean", dest, options)
+	assert.NoError(t, err)
+	content, err := os.ReadFile(filepath.Join(dest, "file.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "clean\n", string(content))
+
+	// Rejected content never reaches the destination, nor the post-fetch hook.
+	_, err = fetcher.FetchWithOptions(ctx, "mem://infected", dest, options)
+	assert.IsError(t, err, getit.ErrQuarantined)
+	assert.IsError(t, err, errInfected)
+	content, err = os.ReadFile(filepath.Join(dest, "file.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "clean\n", string(content))
+
+	assert.Equal(t, []string{"mem://clean", "mem://infected"}, scanned)
+	assert.Equal(t, 1, len(postFetched))
+	entries, err := os.ReadDir(quarantineDir)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(entries))
+}
+
+func TestQuarantineAlongsideDest(t *testing.T) {
+	mem := getit.NewMemResolver(map[string]string{"repo/file.txt": "hello\n"})
+	parent := t.TempDir()
+	dest := filepath.Join(parent, "dest")
+	fetcher := getit.New([]getit.Resolver{mem}, nil, getit.WithQuarantine("", func(_ context.Context, dir, _ string) error {
+		assert.Equal(t, parent, filepath.Dir(dir))
+		_, err := os.Stat(dest)
+		assert.True(t, os.IsNotExist(err))
+		return nil
+	}))
+	assert.NoError(t, fetcher.Fetch(context.Background(), "mem://repo", dest))
+	content, err := os.ReadFile(filepath.Join(dest, "file.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "hello\n", string(content))
+}
+
+func TestQuarantineOtherFileSystem(t *testing.T) {
+	shm, err := os.MkdirTemp("/dev/shm", "getit-test-*")
+	if err != nil {
+		t.Skip("no /dev/shm")
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(shm) })
+	mem := getit.NewMemResolver(map[string]string{"repo/file.txt": "hello\n"})
+	dest := filepath.Join(t.TempDir(), "dest")
+	fetcher := getit.New([]getit.Resolver{mem}, nil, getit.WithQuarantine(shm, func(context.Context, string, string) error { return nil }))
+	assert.NoError(t, fetcher.Fetch(context.Background(), "mem://repo", dest))
+	content, err := os.ReadFile(filepath.Join(dest, "file.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "hello\n", string(content))
+	entries, err := os.ReadDir(filepath.Dir(dest))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(entries))
+}
@@ -0,0 +1,172 @@
+package getit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const (
+	// rangeBlockSize is the size of each ranged request made by a rangeReader.
+	rangeBlockSize = 1 << 20
+	// rangeCacheBlocks is the number of blocks a rangeReader keeps in memory.
+	rangeCacheBlocks = 8
+	// rangeTailSize is the size of the initial request for the end of a file, which is enough to hold a zip
+	// end of central directory record with the maximum length comment.
+	rangeTailSize = 64<<10 + 22
+)
+
+// rangeReader is an [io.ReaderAt] over a remote file, read with ranged requests in fixed size blocks.
+//
+// Recently read blocks are cached, so sequential reads of small sizes, as made by archive/zip, result in one request
+// per block.
+type rangeReader struct {
+	ctx     context.Context //nolint:containedctx // scoped to a single fetch
+	cfg     *config
+	u       *url.URL
+	size    int64
+	ifRange string
+	// tail holds the end of the file from the initial request, starting at tailStart.
+	tail      []byte
+	tailStart int64
+
+	lock   sync.Mutex
+	blocks map[int64][]byte
+	// recent lists cached block indexes, most recently used last.
+	recent []int64
+}
+
+var _ io.ReaderAt = (*rangeReader)(nil)
+
+// newRangeReader creates a rangeReader for u, whose size is known from resp, a partial response to a request for
+// the end of the file. The body of resp is consumed.
+func newRangeReader(ctx context.Context, u *url.URL, resp *http.Response) (*rangeReader, error) {
+	start, size, err := contentRange(resp.Header.Get("Content-Range"))
+	if err != nil {
+		return nil, err
+	}
+	if size < 0 {
+		return nil, fmt.Errorf("unknown size in Content-Range %q", resp.Header.Get("Content-Range"))
+	}
+	tail, err := io.ReadAll(newCountingReader(ctx, resp.Body, u.Host))
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", RedactURL(u), err)
+	}
+	if start+int64(len(tail)) != size {
+		return nil, fmt.Errorf("reading %s: short read", RedactURL(u))
+	}
+	r := &rangeReader{
+		ctx:       ctx,
+		cfg:       configFromContext(ctx),
+		u:         u,
+		size:      size,
+		tail:      tail,
+		tailStart: start,
+		blocks:    map[int64][]byte{},
+	}
+	// Only a strong validator guarantees that every block comes from the same version of the file.
+	if etag := resp.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		r.ifRange = etag
+	} else {
+		r.ifRange = resp.Header.Get("Last-Modified")
+	}
+	return r, nil
+}
+
+func (r *rangeReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+	if off >= r.size {
+		return 0, io.EOF
+	}
+	if off >= r.tailStart {
+		n := copy(p, r.tail[off-r.tailStart:])
+		if n < len(p) {
+			return n, io.EOF
+		}
+		return n, nil
+	}
+	read := 0
+	for read < len(p) && off < r.size {
+		index := off / rangeBlockSize
+		block, err := r.block(index)
+		if err != nil {
+			return read, err
+		}
+		n := copy(p[read:], block[off-index*rangeBlockSize:])
+		read += n
+		off += int64(n)
+	}
+	if read < len(p) {
+		return read, io.EOF
+	}
+	return read, nil
+}
+
+// block returns the contents of a block, fetching it if it is not cached.
+func (r *rangeReader) block(index int64) ([]byte, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if block, ok := r.blocks[index]; ok {
+		r.recent = append(slices.DeleteFunc(r.recent, func(i int64) bool { return i == index }), index)
+		return block, nil
+	}
+	block, err := r.fetch(index)
+	if err != nil {
+		return nil, err
+	}
+	if len(r.recent) >= rangeCacheBlocks {
+		delete(r.blocks, r.recent[0])
+		r.recent = r.recent[1:]
+	}
+	r.blocks[index] = block
+	r.recent = append(r.recent, index)
+	return block, nil
+}
+
+func (r *rangeReader) fetch(index int64) ([]byte, error) {
+	start := index * rangeBlockSize
+	expected := min(rangeBlockSize, r.size-start)
+	req, err := http.NewRequestWithContext(r.ctx, http.MethodGet, r.u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	r.cfg.applyHeaders(req)
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, start+expected-1))
+	if r.ifRange != "" {
+		req.Header.Set("If-Range", r.ifRange)
+	}
+	resp, err := r.cfg.client.Do(req)
+	if err != nil {
+		if err := contextError(r.ctx); err != nil {
+			return nil, err
+		}
+		if urlErr := (*url.Error)(nil); errors.As(err, &urlErr) {
+			urlErr.URL = RedactURL(r.u)
+		}
+		return nil, fmt.Errorf("reading %s: %w", RedactURL(r.u), err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusOK:
+		return nil, fmt.Errorf("reading %s: source changed during download", RedactURL(r.u))
+	default:
+		return nil, fmt.Errorf("reading %s: %s", RedactURL(r.u), resp.Status)
+	}
+	block, err := io.ReadAll(io.LimitReader(newCountingReader(r.ctx, resp.Body, r.u.Host), expected))
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", RedactURL(r.u), err)
+	}
+	if int64(len(block)) != expected {
+		return nil, fmt.Errorf("reading %s: short read of %d/%d bytes", RedactURL(r.u), len(block), expected)
+	}
+	return block, nil
+}
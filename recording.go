@@ -0,0 +1,214 @@
+package getit
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNoFixture is returned in [ReplayOnly] mode for requests that have not been recorded.
+var ErrNoFixture = errors.New("no recorded fixture")
+
+// RecordMode controls how [WithRecording] uses its fixtures.
+type RecordMode int
+
+const (
+	// RecordMissing replays recorded fixtures, and records those that are missing.
+	RecordMissing RecordMode = iota
+	// ReplayOnly replays recorded fixtures, failing with [ErrNoFixture] for any that are missing, so that nothing
+	// touches the network.
+	ReplayOnly
+	// RecordAll records every fixture afresh, replacing any already recorded.
+	RecordAll
+)
+
+// WithRecording records HTTP responses and the results of git operations as fixtures in dir, and replays them in
+// place of the network on later runs, so that tests of applications embedding getit are hermetic.
+//
+// Typically fixtures are recorded once with [RecordMissing] and checked in, and tests then run with [ReplayOnly]:
+//
+//	getit.New(resolvers, mappers, getit.WithRecording("testdata/fixtures", getit.ReplayOnly))
+//
+// HTTP responses are keyed by method, URL and Range header, git ls-remote output by its arguments, and git clones by
+// repository URL, ref and depth. Failed requests and clones are not recorded. Fixture names are digests of their
+// keys, so credentials in URLs are not written to disk, though recorded responses are stored as is.
+func WithRecording(dir string, mode RecordMode) Option {
+	return func(f *Fetcher) { f.config.recorder = &recorder{dir: dir, mode: mode} }
+}
+
+// recorder records and replays fixtures.
+type recorder struct {
+	dir  string
+	mode RecordMode
+}
+
+// fixture returns the path of the fixture for a key, with the given suffix, and whether it should be replayed rather
+// than recorded.
+func (r *recorder) fixture(kind, suffix string, key ...string) (string, bool, error) {
+	sum := sha256.Sum256([]byte(strings.Join(key, "\x00")))
+	path := filepath.Join(r.dir, kind, hex.EncodeToString(sum[:])) + suffix
+	if r.mode == RecordAll {
+		return path, false, nil
+	}
+	if _, err := os.Stat(path); err == nil {
+		return path, true, nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return "", false, fmt.Errorf("fixture: %w", err)
+	}
+	if r.mode == ReplayOnly {
+		display := make([]string, len(key))
+		for i, k := range key {
+			display[i] = redactSource(k)
+		}
+		return "", false, fmt.Errorf("%s %s: %w", kind, strings.TrimSpace(strings.Join(display, " ")), ErrNoFixture)
+	}
+	return path, false, nil
+}
+
+// recordedResponse is the JSON form of a recorded HTTP response. Its body is stored alongside.
+type recordedResponse struct {
+	Method     string      `json:"method"`
+	URL        string      `json:"url"`
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header"`
+}
+
+// recordingTransport records and replays HTTP responses.
+type recordingTransport struct {
+	recorder *recorder
+	next     http.RoundTripper
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	path, replay, err := t.recorder.fixture("http", ".json", req.Method, req.URL.String(), req.Header.Get("Range"))
+	if err != nil {
+		return nil, err
+	}
+	if !replay {
+		return t.record(req, path)
+	}
+	data, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		return nil, fmt.Errorf("replaying fixture: %w", err)
+	}
+	recorded := recordedResponse{}
+	if err := json.Unmarshal(data, &recorded); err != nil {
+		return nil, fmt.Errorf("replaying fixture %s: %w", path, err)
+	}
+	body, err := os.Open(strings.TrimSuffix(path, ".json") + ".body") // #nosec G304
+	if err != nil {
+		return nil, fmt.Errorf("replaying fixture: %w", err)
+	}
+	info, err := body.Stat()
+	if err != nil {
+		_ = body.Close()
+		return nil, fmt.Errorf("replaying fixture: %w", err)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.StatusCode, http.StatusText(recorded.StatusCode)),
+		StatusCode:    recorded.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        recorded.Header,
+		Body:          body,
+		ContentLength: info.Size(),
+		Request:       req,
+	}, nil
+}
+
+// record sends req, then writes the response to the fixture at path and replays it from there.
+func (t *recordingTransport) record(req *http.Request, path string) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // transport errors are passed through
+	}
+	defer resp.Body.Close()
+	bodyPath := strings.TrimSuffix(path, ".json") + ".body"
+	if err := writeFileAtomic(bodyPath, resp.Body); err != nil {
+		return nil, fmt.Errorf("recording fixture: %w", err)
+	}
+	recorded, err := json.MarshalIndent(recordedResponse{
+		Method:     req.Method,
+		URL:        RedactURL(req.URL),
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+	}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("recording fixture: %w", err)
+	}
+	if err := writeFileAtomic(path, bytes.NewReader(recorded)); err != nil {
+		return nil, fmt.Errorf("recording fixture: %w", err)
+	}
+	body, err := os.Open(bodyPath) // #nosec G304
+	if err != nil {
+		return nil, fmt.Errorf("recording fixture: %w", err)
+	}
+	resp.Body = body
+	return resp, nil
+}
+
+// gitOutput runs git with args, returning its standard output, which is recorded or replayed if [WithRecording] is
+// in use.
+func gitOutput(ctx context.Context, args ...string) ([]byte, error) {
+	rec := configFromContext(ctx).recorder
+	if rec == nil {
+		return gitCommand(ctx, args...).Output() //nolint:wrapcheck // wrapped by the caller
+	}
+	path, replay, err := rec.fixture("git", ".out", append([]string{"git"}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	if replay {
+		return os.ReadFile(path) //nolint:wrapcheck // wrapped by the caller
+	}
+	output, err := gitCommand(ctx, args...).Output()
+	if err != nil {
+		return nil, err //nolint:wrapcheck // wrapped by the caller
+	}
+	if err := writeFileAtomic(path, bytes.NewReader(output)); err != nil {
+		return nil, fmt.Errorf("recording fixture: %w", err)
+	}
+	return output, nil
+}
+
+// gitClone runs git with args to clone a repository into dest, returning its combined output. If [WithRecording] is
+// in use, the cloned tree is recorded or replayed under key.
+func gitClone(ctx context.Context, args []string, key []string, dest string) ([]byte, error) {
+	rec := configFromContext(ctx).recorder
+	if rec == nil {
+		return gitCommand(ctx, args...).CombinedOutput() //nolint:wrapcheck // wrapped by the caller
+	}
+	path, replay, err := rec.fixture("git", "", append([]string{"git", "clone"}, key...)...)
+	if err != nil {
+		return nil, err
+	}
+	// Fixtures are copied as plain trees, without the hooks and policies of the current fetch.
+	copyCfg := *configFromContext(ctx)
+	copyCfg.hooks, copyCfg.options, copyCfg.permissions, copyCfg.usage = Hooks{}, FetchOptions{}, Permissions{}, nil
+	copyCtx := contextWithConfig(ctx, &copyCfg)
+	if replay {
+		return nil, copyDir(copyCtx, path, dest, copyOptions{})
+	}
+	output, err := gitCommand(ctx, args...).CombinedOutput()
+	if err != nil {
+		return output, err //nolint:wrapcheck // wrapped by the caller
+	}
+	staging, err := newStaging(path)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(staging)
+	if err := copyDir(copyCtx, dest, staging, copyOptions{}); err != nil {
+		return nil, fmt.Errorf("recording fixture: %w", err)
+	}
+	return nil, replace(staging, path)
+}
@@ -0,0 +1,82 @@
+package getit //nolint:testpackage
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestRecordingHTTP(t *testing.T) {
+	archive, err := os.ReadFile(filepath.Join("testdata", "archive.tar.gz"))
+	assert.NoError(t, err)
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write(archive)
+	}))
+	fixtures := t.TempDir()
+	source := server.URL + "/archive.tar.gz?token=secret"
+
+	fetcher := New([]Resolver{NewTAR()}, nil, WithRecording(fixtures, RecordMissing))
+	assert.NoError(t, fetcher.Fetch(context.Background(), source, t.TempDir()))
+	assert.Equal(t, int32(1), requests.Load())
+	assert.NoError(t, fetcher.Fetch(context.Background(), source, t.TempDir()))
+	assert.Equal(t, int32(1), requests.Load(), "recorded response should be replayed")
+	_, err = fetcher.FetchWithOptions(context.Background(), source, t.TempDir(), FetchOptions{DryRun: true})
+	assert.NoError(t, err)
+	server.Close()
+
+	fetcher = New([]Resolver{NewTAR()}, nil, WithRecording(fixtures, ReplayOnly))
+	dest := t.TempDir()
+	result, err := fetcher.FetchWithOptions(context.Background(), source, dest, FetchOptions{DryRun: true})
+	assert.NoError(t, err)
+	assert.Equal(t, `"v1"`, result.Info.Revision)
+	assert.NoError(t, fetcher.Fetch(context.Background(), source, dest))
+	_, err = os.Stat(filepath.Join(dest, "nested.txt"))
+	assert.NoError(t, err)
+
+	err = fetcher.Fetch(context.Background(), server.URL+"/other.tar.gz?token=secret", t.TempDir())
+	assert.IsError(t, err, ErrNoFixture)
+	assert.NotContains(t, err.Error(), "secret")
+}
+
+func TestRecordingGit(t *testing.T) {
+	repoDir, _ := createTestRepo(t)
+	u, err := url.Parse("git+file://" + repoDir)
+	assert.NoError(t, err)
+	fixtures := t.TempDir()
+
+	cfg := defaultConfig()
+	cfg.recorder = &recorder{dir: fixtures, mode: RecordMissing}
+	ctx := contextWithConfig(context.Background(), &cfg)
+	recordedInfo, err := NewGit().Stat(ctx, Source{URL: u})
+	assert.NoError(t, err)
+	assert.NoError(t, NewGit().Fetch(ctx, Source{URL: u}, t.TempDir()))
+
+	// Replay once the repository is gone.
+	assert.NoError(t, os.RemoveAll(repoDir))
+	cfg.recorder = &recorder{dir: fixtures, mode: ReplayOnly}
+	info, err := NewGit().Stat(ctx, Source{URL: u})
+	assert.NoError(t, err)
+	assert.Equal(t, recordedInfo, info)
+	dest := t.TempDir()
+	assert.NoError(t, NewGit().Fetch(ctx, Source{URL: u}, dest))
+	content, err := os.ReadFile(filepath.Join(dest, "file.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "hello from test\n", string(content))
+	_, err = os.Stat(filepath.Join(dest, ".git"))
+	assert.NoError(t, err)
+
+	u, err = url.Parse("git+file://" + repoDir + "?ref=other")
+	assert.NoError(t, err)
+	err = NewGit().Fetch(ctx, Source{URL: u}, t.TempDir())
+	assert.IsError(t, err, ErrNoFixture)
+}
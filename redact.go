@@ -0,0 +1,71 @@
+package getit
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const redacted = "xxxxx"
+
+// RedactURL returns u as a string with credentials removed, suitable for errors and logs.
+//
+// Passwords in userinfo are replaced, as is a bare username since it is commonly a token
+// (eg. https://<token>@github.com/org/repo). Query parameters whose names suggest secrets, such as "token",
+// "access_token" or "X-Amz-Signature", have their values replaced.
+func RedactURL(u *url.URL) string {
+	return redactURL(u).String()
+}
+
+// redactURL returns a copy of u with credentials removed.
+func redactURL(u *url.URL) *url.URL {
+	if u == nil {
+		return nil
+	}
+	clone := *u
+	if clone.User != nil {
+		if _, ok := clone.User.Password(); ok {
+			clone.User = url.UserPassword(clone.User.Username(), redacted)
+		} else {
+			clone.User = url.User(redacted)
+		}
+	}
+	if clone.RawQuery != "" {
+		query := clone.Query()
+		changed := false
+		for key := range query {
+			if isSensitiveParam(key) {
+				query[key] = []string{redacted}
+				changed = true
+			}
+		}
+		if changed {
+			clone.RawQuery = query.Encode()
+		}
+	}
+	return &clone
+}
+
+var userinfoRe = regexp.MustCompile(`//[^/@?#]+@`)
+
+// redactSource redacts credentials from a source string, which may not be a valid URL.
+func redactSource(source string) string {
+	if u, err := url.Parse(source); err == nil {
+		return RedactURL(u)
+	}
+	return userinfoRe.ReplaceAllString(source, "//"+redacted+"@")
+}
+
+func isSensitiveParam(name string) bool {
+	name = strings.ToLower(name)
+	switch name {
+	case "sig", "key", "apikey", "api_key", "auth", "authorization", "pass", "pw":
+		return true
+	}
+	for _, fragment := range []string{"token", "secret", "password", "signature", "credential"} {
+		if strings.Contains(name, fragment) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,186 @@
+package getit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// OCI whiteout files mark paths removed by an image layer.
+const (
+	whiteoutPrefix = ".wh."
+	opaqueWhiteout = ".wh..wh..opq"
+)
+
+// FetchRootFS fetches container image layers in order and applies each on top of dest, as a container runtime does
+// when building a root filesystem, eg.
+//
+//	fetcher.FetchRootFS(ctx, []string{"https://example.com/base.tar.gz", "https://example.com/app.tar.gz"}, "rootfs")
+//
+// Each layer is fetched into a staging directory alongside dest, then merged into dest: files replace those of lower
+// layers, and directories are merged. OCI whiteout files are applied rather than written: ".wh.<name>" removes name
+// from the lower layers, and ".wh..wh..opq" removes everything in its directory from the lower layers.
+//
+// Ownership recorded in the layers is applied as with [Permissions.PreserveOwner], which typically requires running
+// as root. To build a root filesystem as another user, pass [WithPermissions] with [Permissions.Owner] or ID maps.
+//
+// If a layer fails, dest is left with the layers below it applied.
+func (f *Fetcher) FetchRootFS(ctx context.Context, layers []string, dest string) error {
+	if len(layers) == 0 {
+		return errors.New("no layers to fetch")
+	}
+	layerFetcher := f.withConfig(func(cfg *config) { cfg.permissions.PreserveOwner = true })
+	if err := os.MkdirAll(dest, 0750); err != nil {
+		return fmt.Errorf("creating destination directory: %w", err)
+	}
+	for _, layer := range layers {
+		err := layerFetcher.fetchMerged(ctx, layer, dest, func(ctx context.Context, dir string) error {
+			return mergeTree(ctx, dir, dest, mergeOptions{whiteouts: true})
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fetchMerged fetches source into a staging directory alongside dest, then calls merge to merge it into dest.
+func (f *Fetcher) fetchMerged(ctx context.Context, source, dest string, merge func(ctx context.Context, dir string) error) error {
+	staging, err := newStaging(dest)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(staging)
+	if err := f.Fetch(ctx, source, staging); err != nil {
+		return err
+	}
+	if err := merge(ctx, staging); err != nil {
+		return fmt.Errorf("merging %s: %w", redactSource(source), err)
+	}
+	return nil
+}
+
+// mergeOptions control how [mergeTree] merges a tree into a destination.
+type mergeOptions struct {
+	// whiteouts applies OCI whiteout files to the destination rather than merging them.
+	whiteouts bool
+	// keep leaves existing files in the destination in place, rather than replacing them.
+	keep bool
+}
+
+// mergeTree merges the tree in dir into dest, consuming dir. Directories are merged, and other entries replace those
+// in dest unless options.keep is set.
+func mergeTree(ctx context.Context, dir, dest string, options mergeOptions) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", dir, err)
+	}
+	if options.whiteouts {
+		for _, entry := range entries {
+			if entry.Name() == opaqueWhiteout {
+				if err := resetDest(dest); err != nil {
+					return err
+				}
+				break
+			}
+		}
+	}
+	for _, entry := range entries {
+		if err := contextError(ctx); err != nil {
+			return err
+		}
+		name := entry.Name()
+		if options.whiteouts && name == opaqueWhiteout {
+			continue
+		}
+		destPath := filepath.Join(dest, name)
+		if target, ok := strings.CutPrefix(name, whiteoutPrefix); ok && options.whiteouts {
+			if target == "" || target == "." || target == ".." {
+				return fmt.Errorf("invalid whiteout %s", filepath.Join(dir, name))
+			}
+			if err := os.RemoveAll(filepath.Join(dest, target)); err != nil {
+				return fmt.Errorf("applying whiteout: %w", err)
+			}
+			continue
+		}
+		path := filepath.Join(dir, name)
+		destInfo, err := os.Lstat(destPath)
+		switch {
+		case err == nil && entry.IsDir() && destInfo.IsDir():
+			if err := mergeTree(ctx, path, destPath, options); err != nil {
+				return err
+			}
+			if !options.keep {
+				if err := copyDirMetadata(path, destPath); err != nil {
+					return err
+				}
+			}
+			continue
+		case err == nil && options.keep:
+			continue
+		case err != nil && !errors.Is(err, os.ErrNotExist):
+			return fmt.Errorf("stat %s: %w", destPath, err)
+		}
+		if entry.IsDir() && options.whiteouts {
+			// Whiteouts only apply to lower layers, so none are left in a directory that replaces them.
+			if err := removeWhiteouts(path); err != nil {
+				return err
+			}
+		}
+		if err := os.RemoveAll(destPath); err != nil {
+			return fmt.Errorf("replacing %s: %w", destPath, err)
+		}
+		if err := os.Rename(path, destPath); err != nil {
+			return fmt.Errorf("replacing %s: %w", destPath, err)
+		}
+	}
+	return nil
+}
+
+// copyDirMetadata applies the mode, ownership and modification time of the directory src to the directory dest it
+// has been merged into.
+func copyDirMetadata(src, dest string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return fmt.Errorf("stat %s: %w", src, err)
+	}
+	if uid, gid, ok := fileOwner(info); ok {
+		destInfo, err := os.Lstat(dest)
+		if err != nil {
+			return fmt.Errorf("stat %s: %w", dest, err)
+		}
+		if destUID, destGID, _ := fileOwner(destInfo); uid != destUID || gid != destGID {
+			if err := os.Lchown(dest, uid, gid); err != nil {
+				return fmt.Errorf("chown %s: %w", dest, err)
+			}
+		}
+	}
+	if err := os.Chmod(dest, info.Mode()&(fs.ModePerm|fs.ModeSetuid|fs.ModeSetgid|fs.ModeSticky)); err != nil {
+		return fmt.Errorf("chmod %s: %w", dest, err)
+	}
+	if err := os.Chtimes(dest, info.ModTime(), info.ModTime()); err != nil {
+		return fmt.Errorf("chtimes %s: %w", dest, err)
+	}
+	return nil
+}
+
+// removeWhiteouts removes any whiteout files under dir.
+func removeWhiteouts(dir string) error {
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.HasPrefix(d.Name(), whiteoutPrefix) {
+			return os.Remove(path)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("removing whiteouts from %s: %w", dir, err)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package getit_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/block/getit"
+)
+
+// layerTar returns a tarball of files, keyed by path, where paths ending in "/" are directories.
+func layerTar(t *testing.T, files ...[2]string) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	for _, file := range files {
+		name, content := file[0], file[1]
+		if strings.HasSuffix(name, "/") {
+			assert.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0o755, Typeflag: tar.TypeDir}))
+			continue
+		}
+		assert.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(content))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, tw.Close())
+	return buf.Bytes()
+}
+
+// listTree returns the paths of the files and directories under dir, with the contents of files.
+func listTree(t *testing.T, dir string) []string {
+	t.Helper()
+	var paths []string
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || path == dir {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			paths = append(paths, rel+"/")
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, rel+"="+string(content))
+		return nil
+	})
+	assert.NoError(t, err)
+	sort.Strings(paths)
+	return paths
+}
+
+func TestFetchRootFS(t *testing.T) {
+	layers := map[string][]byte{
+		"/base.tar": layerTar(t,
+			[2]string{"bin/", ""},
+			[2]string{"bin/sh", "sh v1"},
+			[2]string{"etc/", ""},
+			[2]string{"etc/passwd", "root"},
+			[2]string{"etc/group", "wheel"},
+			[2]string{"var/cache/", ""},
+			[2]string{"var/cache/a", "a"},
+			[2]string{"var/cache/b", "b"},
+			[2]string{"opt/tool/", ""},
+			[2]string{"opt/tool/old", "old"},
+		),
+		"/app.tar": layerTar(t,
+			[2]string{"bin/sh", "sh v2"},
+			[2]string{"etc/.wh.group", ""},
+			[2]string{"var/cache/c", "c"},
+			[2]string{"var/cache/.wh..wh..opq", ""},
+			[2]string{"opt/.wh.tool", ""},
+			[2]string{"opt/tool/new", "new"},
+			[2]string{"usr/local/bin/app", "app"},
+			[2]string{"usr/local/.wh.missing", ""},
+		),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := layers[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+
+	fetcher := getit.New([]getit.Resolver{getit.NewTAR()}, nil, getit.WithPermissions(getit.Permissions{Owner: getit.CurrentOwner()}))
+	dest := filepath.Join(t.TempDir(), "rootfs")
+	err := fetcher.FetchRootFS(context.Background(), []string{server.URL + "/base.tar", server.URL + "/app.tar"}, dest)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"bin/",
+		"bin/sh=sh v2",
+		"etc/",
+		"etc/passwd=root",
+		"opt/",
+		"opt/tool/",
+		"opt/tool/new=new",
+		"usr/",
+		"usr/local/",
+		"usr/local/bin/",
+		"usr/local/bin/app=app",
+		"var/",
+		"var/cache/",
+		"var/cache/c=c",
+	}, listTree(t, dest))
+
+	entries, err := os.ReadDir(filepath.Dir(dest))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(entries), "staging directories should be removed")
+
+	err = fetcher.FetchRootFS(context.Background(), []string{server.URL + "/missing.tar"}, dest)
+	assert.Error(t, err)
+	assert.Equal(t, 14, len(listTree(t, dest)))
+}
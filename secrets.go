@@ -0,0 +1,110 @@
+package getit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// SecretStore looks up stored credentials, so that tokens need not be kept in environment variables or URLs.
+//
+// Stores are consulted when a request is denied, as for a [CredentialPrompter], so credentials are only offered to
+// hosts that ask for them.
+type SecretStore interface {
+	// Lookup returns the credentials stored for the host of a denied request, and whether any were found.
+	Lookup(ctx context.Context, req CredentialRequest) (credential Credential, found bool, err error)
+}
+
+// WithSecretStore looks up credentials in stores, in order, when an HTTP request is denied with 401 Unauthorized or
+// git fails to authenticate with an https:// remote. Each store is tried at most once per host, and if none has
+// working credentials the [CredentialPrompter], if any, is called.
+func WithSecretStore(stores ...SecretStore) Option {
+	return func(f *Fetcher) {
+		credentials := f.config.ensureCredentials()
+		credentials.stores = append(credentials.stores, stores...)
+	}
+}
+
+// GitCredentialStore looks up credentials with "git credential fill", using the credential helpers configured for
+// git, such as the macOS keychain, Git Credential Manager or libsecret.
+type GitCredentialStore struct{}
+
+var _ SecretStore = (*GitCredentialStore)(nil)
+
+func NewGitCredentialStore() *GitCredentialStore { return &GitCredentialStore{} }
+
+func (g *GitCredentialStore) Lookup(ctx context.Context, req CredentialRequest) (Credential, bool, error) {
+	u, err := url.Parse(req.URL)
+	if err != nil {
+		return Credential{}, false, fmt.Errorf("git credential: %w", err)
+	}
+	protocol := strings.TrimPrefix(u.Scheme, "git+")
+	input := fmt.Sprintf("protocol=%s\nhost=%s\n\n", protocol, req.Host)
+	cmd := command(ctx, "git", "credential", "fill")
+	cmd.Stdin = strings.NewReader(input)
+	// Fail rather than prompt if no helper has credentials.
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_ASKPASS=", "SSH_ASKPASS=")
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr := (*exec.ExitError)(nil); errors.As(err, &exitErr) {
+			return Credential{}, false, nil
+		}
+		return Credential{}, false, fmt.Errorf("git credential: %w", err)
+	}
+	var credential Credential
+	for line := range strings.Lines(string(output)) {
+		key, value, _ := strings.Cut(strings.TrimSpace(line), "=")
+		switch key {
+		case "username":
+			credential.Username = value
+		case "password":
+			credential.Password = value
+		}
+	}
+	return credential, credential.Password != "", nil
+}
+
+// KeychainStore looks up tokens in the operating system's keychain: the login keychain on macOS, via the security
+// command, and the Secret Service (GNOME Keyring or KWallet) elsewhere, via secret-tool. Tokens are sent as bearer
+// tokens.
+//
+// Tokens are stored per host under a service name, eg. for the service "getit" with:
+//
+//	security add-generic-password -s getit -a artifacts.example.com -w <token>  # macOS
+//	secret-tool store --label "getit artifacts.example.com" service getit host artifacts.example.com  # Linux
+type KeychainStore struct {
+	service string
+}
+
+var _ SecretStore = (*KeychainStore)(nil)
+
+// NewKeychainStore returns a [KeychainStore] looking up tokens stored under service.
+func NewKeychainStore(service string) *KeychainStore { return &KeychainStore{service: service} }
+
+func (k *KeychainStore) Lookup(ctx context.Context, req CredentialRequest) (Credential, bool, error) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = command(ctx, "security", "find-generic-password", "-s", k.service, "-a", req.Host, "-w")
+	case "windows":
+		return Credential{}, false, errors.New("keychain: not supported on windows")
+	default:
+		cmd = command(ctx, "secret-tool", "lookup", "service", k.service, "host", req.Host)
+	}
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr := (*exec.ExitError)(nil); errors.As(err, &exitErr) {
+			// Both tools exit with an error if the item is not found.
+			return Credential{}, false, nil
+		}
+		return Credential{}, false, fmt.Errorf("keychain: %w", err)
+	}
+	token := string(bytes.TrimRight(output, "\r\n"))
+	return Credential{Token: token}, token != "", nil
+}
@@ -0,0 +1,109 @@
+package getit //nolint:testpackage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+type staticStore map[string]Credential
+
+func (s staticStore) Lookup(_ context.Context, req CredentialRequest) (Credential, bool, error) {
+	credential, ok := s[req.Host]
+	return credential, ok, nil
+}
+
+type failingStore struct{}
+
+func (failingStore) Lookup(context.Context, CredentialRequest) (Credential, bool, error) {
+	return Credential{}, false, errors.New("locked")
+}
+
+func TestSecretStore(t *testing.T) {
+	server := authServer(t, "Bearer token")
+	host := strings.TrimPrefix(server.URL, "http://")
+	source := server.URL + "/archive.tar.gz"
+	ctx := context.Background()
+
+	t.Run("Found", func(t *testing.T) {
+		p := &prompter{}
+		fetcher := New([]Resolver{NewTAR()}, nil, WithSecretStore(staticStore{}, staticStore{host: {Token: "token"}}), WithCredentialPrompter(p))
+		assert.NoError(t, fetcher.Fetch(ctx, source, t.TempDir()))
+		assert.Equal(t, 0, len(p.requests))
+	})
+
+	t.Run("RejectedFallsBackToPrompt", func(t *testing.T) {
+		p := &prompter{credentials: []Credential{{Token: "token"}}}
+		fetcher := New([]Resolver{NewTAR()}, nil, WithSecretStore(staticStore{host: {Token: "stale"}}), WithCredentialPrompter(p))
+		assert.NoError(t, fetcher.Fetch(ctx, source, t.TempDir()))
+		assert.Equal(t, []CredentialRequest{{Host: host, URL: source, Attempt: 2}}, p.requests)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		fetcher := New([]Resolver{NewTAR()}, nil, WithSecretStore(staticStore{}))
+		err := fetcher.Fetch(ctx, source, t.TempDir())
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "401 Unauthorized")
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		fetcher := New([]Resolver{NewTAR()}, nil, WithSecretStore(failingStore{}))
+		err := fetcher.Fetch(ctx, source, t.TempDir())
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "credentials for "+host+": locked")
+	})
+}
+
+func TestGitCredentialStore(t *testing.T) {
+	server := authServer(t, "Basic dXNlcjpwYXNz")
+	host := strings.TrimPrefix(server.URL, "http://")
+	dir := t.TempDir()
+	store := filepath.Join(dir, "credentials")
+	assert.NoError(t, os.WriteFile(store, []byte("http://user:pass@"+host+"\n"), 0o600))
+	config := filepath.Join(dir, "gitconfig")
+	assert.NoError(t, os.WriteFile(config, []byte("[credential]\n\thelper = store --file="+filepath.ToSlash(store)+"\n"), 0o600))
+	t.Setenv("GIT_CONFIG_GLOBAL", config)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	ctx := context.Background()
+
+	credential, found, err := NewGitCredentialStore().Lookup(ctx, CredentialRequest{Host: host, URL: server.URL + "/archive.tar.gz"})
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, Credential{Username: "user", Password: "pass"}, credential)
+
+	_, found, err = NewGitCredentialStore().Lookup(ctx, CredentialRequest{Host: "example.com", URL: "https://example.com/"})
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	fetcher := New([]Resolver{NewTAR()}, nil, WithSecretStore(NewGitCredentialStore()))
+	assert.NoError(t, fetcher.Fetch(ctx, server.URL+"/archive.tar.gz", t.TempDir()))
+}
+
+func TestKeychainStore(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("uses a fake secret-tool")
+	}
+	bin := t.TempDir()
+	script := `#!/bin/sh
+[ "$*" = "lookup service getit host artifacts.example.com" ] || exit 1
+echo token
+`
+	assert.NoError(t, os.WriteFile(filepath.Join(bin, "secret-tool"), []byte(script), 0o700)) //nolint:gosec // executable
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	ctx := context.Background()
+
+	credential, found, err := NewKeychainStore("getit").Lookup(ctx, CredentialRequest{Host: "artifacts.example.com"})
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, Credential{Token: "token"}, credential)
+
+	_, found, err = NewKeychainStore("getit").Lookup(ctx, CredentialRequest{Host: "example.com"})
+	assert.NoError(t, err)
+	assert.False(t, found)
+}
@@ -0,0 +1,275 @@
+package getit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// semver is a parsed semantic version. Build metadata is ignored.
+type semver struct {
+	major, minor, patch uint64
+	pre                 string
+}
+
+// parseSemver parses a version such as "v1.2.3" or "1.2.3-rc.1+build". Missing minor and patch components are
+// zero, as tags such as "v1.2" are common.
+func parseSemver(s string) (semver, bool) {
+	v, n, ok := parsePartialSemver(s)
+	if !ok || n == 0 {
+		return semver{}, false
+	}
+	// Reject wildcards, which leave components unspecified.
+	core, _, _ := strings.Cut(s, "+")
+	core, _, _ = strings.Cut(core, "-")
+	return v, n == strings.Count(core, ".")+1
+}
+
+// parsePartialSemver parses a version that may have fewer than three components, or wildcard components such as
+// "1.x" or "1.2.*", returning the number of components specified before the first wildcard.
+func parsePartialSemver(s string) (semver, int, bool) {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "v"), "V")
+	s, _, _ = strings.Cut(s, "+")
+	s, pre, hasPre := strings.Cut(s, "-")
+	if hasPre && pre == "" {
+		return semver{}, 0, false
+	}
+	v := semver{pre: pre}
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return semver{}, 0, false
+	}
+	fields := []*uint64{&v.major, &v.minor, &v.patch}
+	n := 0
+	for i, part := range parts {
+		if part == "x" || part == "X" || part == "*" {
+			// Everything after a wildcard is also a wildcard.
+			if hasPre || i+1 < len(parts) && !isWildcard(parts[i+1:]) {
+				return semver{}, 0, false
+			}
+			return v, n, true
+		}
+		number, err := strconv.ParseUint(part, 10, 64)
+		if err != nil || (len(part) > 1 && part[0] == '0') {
+			return semver{}, 0, false
+		}
+		*fields[i] = number
+		n++
+	}
+	if hasPre && n < 3 {
+		return semver{}, 0, false
+	}
+	return v, n, true
+}
+
+func isWildcard(parts []string) bool {
+	for _, part := range parts {
+		if part != "x" && part != "X" && part != "*" {
+			return false
+		}
+	}
+	return true
+}
+
+// compare returns -1, 0 or 1 as v is less than, equal to or greater than o, by semantic version precedence.
+func (v semver) compare(o semver) int {
+	for _, c := range [][2]uint64{{v.major, o.major}, {v.minor, o.minor}, {v.patch, o.patch}} {
+		if c[0] != c[1] {
+			if c[0] < c[1] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case v.pre == o.pre:
+		return 0
+	case v.pre == "":
+		return 1
+	case o.pre == "":
+		return -1
+	}
+	a, b := strings.Split(v.pre, "."), strings.Split(o.pre, ".")
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := comparePrerelease(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case len(a) < len(b):
+		return -1
+	case len(a) > len(b):
+		return 1
+	}
+	return 0
+}
+
+// comparePrerelease compares prerelease identifiers: numeric identifiers sort numerically and before alphanumeric
+// identifiers, which sort lexically.
+func comparePrerelease(a, b string) int {
+	an, aErr := strconv.ParseUint(a, 10, 64)
+	bn, bErr := strconv.ParseUint(b, 10, 64)
+	switch {
+	case aErr == nil && bErr == nil:
+		if an == bn {
+			return 0
+		} else if an < bn {
+			return -1
+		}
+		return 1
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// versionConstraint is a set of alternative version ranges, any of which may match.
+type versionConstraint [][]comparator
+
+// anyStableVersion matches any version that is not a prerelease.
+var anyStableVersion = versionConstraint{nil}
+
+type comparator struct {
+	op string
+	v  semver
+}
+
+// parseVersionConstraint parses a version constraint in the style of npm and Cargo, eg. "^1.2", "~1.2.3",
+// ">=1.0, <2.0", "1.x" or "1.2 || ^2". Alternatives are separated by "||", and the comparators within an
+// alternative by commas or spaces.
+func parseVersionConstraint(s string) (versionConstraint, error) {
+	var constraint versionConstraint
+	for alternative := range strings.SplitSeq(s, "||") {
+		fields := strings.FieldsFunc(alternative, func(r rune) bool { return r == ',' || r == ' ' })
+		comparators := []comparator{}
+		for i := 0; i < len(fields); i++ {
+			field := fields[i]
+			// Allow a space between an operator and its version, eg. ">= 1.2".
+			if strings.Trim(field, "<>=^~") == "" && i+1 < len(fields) {
+				i++
+				field += fields[i]
+			}
+			parsed, err := parseComparator(field)
+			if err != nil {
+				return nil, fmt.Errorf("invalid version constraint %q: %w", s, err)
+			}
+			comparators = append(comparators, parsed...)
+		}
+		constraint = append(constraint, comparators)
+	}
+	return constraint, nil
+}
+
+// parseComparator expands a single comparator, such as "^1.2" or "<=2", into bounds.
+func parseComparator(s string) ([]comparator, error) {
+	op := s[:len(s)-len(strings.TrimLeft(s, "<>=^~"))]
+	v, n, ok := parsePartialSemver(s[len(op):])
+	if !ok {
+		return nil, fmt.Errorf("invalid version %q", s[len(op):])
+	}
+	next := func(n int) semver {
+		switch n {
+		case 1:
+			return semver{major: v.major + 1}
+		case 2:
+			return semver{major: v.major, minor: v.minor + 1}
+		}
+		return semver{major: v.major, minor: v.minor, patch: v.patch + 1}
+	}
+	if n == 0 {
+		if op != "" && op != "=" && op != ">=" && op != "<=" {
+			return nil, fmt.Errorf("invalid version %q", s)
+		}
+		return nil, nil
+	}
+	switch op {
+	case "^":
+		upper := n
+		switch {
+		case v.major > 0 || n == 1:
+			upper = 1
+		case v.minor > 0 || n == 2:
+			upper = 2
+		}
+		return []comparator{{">=", v}, {"<", next(upper)}}, nil
+	case "~":
+		return []comparator{{">=", v}, {"<", next(min(n, 2))}}, nil
+	case "", "=":
+		if n == 3 {
+			return []comparator{{"=", v}}, nil
+		}
+		return []comparator{{">=", v}, {"<", next(n)}}, nil
+	case ">=", "<":
+		return []comparator{{op, v}}, nil
+	case ">":
+		if n < 3 {
+			return []comparator{{">=", next(n)}}, nil
+		}
+		return []comparator{{op, v}}, nil
+	case "<=":
+		if n < 3 {
+			return []comparator{{"<", next(n)}}, nil
+		}
+		return []comparator{{op, v}}, nil
+	}
+	return nil, fmt.Errorf("invalid operator %q", op)
+}
+
+// match reports whether v satisfies the constraint.
+//
+// Prereleases only match an alternative that includes a prerelease of the same major, minor and patch version, so
+// that eg. "^1.2" never selects "1.3.0-beta".
+func (c versionConstraint) match(v semver) bool {
+	for _, comparators := range c {
+		if matchComparators(comparators, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchComparators(comparators []comparator, v semver) bool {
+	allowPre := v.pre == ""
+	for _, c := range comparators {
+		cmp := v.compare(c.v)
+		var ok bool
+		switch c.op {
+		case "=":
+			ok = cmp == 0
+		case ">":
+			ok = cmp > 0
+		case ">=":
+			ok = cmp >= 0
+		case "<":
+			ok = cmp < 0
+		case "<=":
+			ok = cmp <= 0
+		}
+		if !ok {
+			return false
+		}
+		if c.v.pre != "" && c.v.major == v.major && c.v.minor == v.minor && c.v.patch == v.patch {
+			allowPre = true
+		}
+	}
+	return allowPre
+}
+
+// highestVersion returns the tag with the highest semantic version satisfying constraint, ignoring tags that are
+// not semantic versions.
+func highestVersion(tags []string, constraint versionConstraint) (string, bool) {
+	var best string
+	var bestVersion semver
+	for _, tag := range tags {
+		v, ok := parseSemver(tag)
+		if !ok || !constraint.match(v) {
+			continue
+		}
+		if best == "" || v.compare(bestVersion) > 0 {
+			best, bestVersion = tag, v
+		}
+	}
+	return best, best != ""
+}
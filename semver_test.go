@@ -0,0 +1,51 @@
+package getit //nolint:testpackage
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestHighestVersion(t *testing.T) {
+	tags := []string{
+		"v0.9.0", "v1.0.0", "v1.2.0", "v1.2.5", "1.3.0", "v1.4.0-rc.1", "v1.4.0-rc.2", "v2.0.0", "v2.1",
+		"latest", "release-3.0", "v3.0.0.1", "v01.0.0",
+	}
+	tests := []struct {
+		constraint string
+		expected   string
+	}{
+		{constraint: "^1.2", expected: "1.3.0"},
+		{constraint: "~1.2", expected: "v1.2.5"},
+		{constraint: "~1.2.0", expected: "v1.2.5"},
+		{constraint: "^0.9", expected: "v0.9.0"},
+		{constraint: "1.2.x", expected: "v1.2.5"},
+		{constraint: "1", expected: "1.3.0"},
+		{constraint: "=1.2.0", expected: "v1.2.0"},
+		{constraint: ">=1.0, <1.2", expected: "v1.0.0"},
+		{constraint: ">= 1.0 < 1.2", expected: "v1.0.0"},
+		{constraint: ">1.2", expected: "v2.1"},
+		{constraint: "<=1.2", expected: "v1.2.5"},
+		{constraint: "*", expected: "v2.1"},
+		{constraint: "^1.4.0-rc.1", expected: "v1.4.0-rc.2"},
+		{constraint: "^0.1 || ~1.0", expected: "v1.0.0"},
+		{constraint: "^4", expected: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.constraint, func(t *testing.T) {
+			constraint, err := parseVersionConstraint(tt.constraint)
+			assert.NoError(t, err)
+			actual, _ := highestVersion(tags, constraint)
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestParseVersionConstraintErrors(t *testing.T) {
+	for _, constraint := range []string{"^1.x.2", "1.2.3.4", ">>1", "^*", "1.2-rc", "abc"} {
+		t.Run(constraint, func(t *testing.T) {
+			_, err := parseVersionConstraint(constraint)
+			assert.Error(t, err)
+		})
+	}
+}
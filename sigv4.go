@@ -0,0 +1,239 @@
+package getit
+
+import (
+	"cmp"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// AWSCredentials sign requests for [SigV4].
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken of temporary credentials, if any.
+	SessionToken string
+}
+
+// SigV4 signs HTTP requests with AWS Signature Version 4, so that private S3 objects, API Gateway endpoints and other
+// AWS services can be fetched from plain https:// URLs, eg.
+//
+//	getit.WithSigV4(getit.SigV4{Region: "us-west-2"})
+//	fetcher.Fetch(ctx, "https://bucket.s3.us-west-2.amazonaws.com/releases/app.tar.gz", dest)
+//
+// The Host, Range and X-Amz-* headers of each request are signed.
+type SigV4 struct {
+	// Region of the service. Defaults to $AWS_REGION or $AWS_DEFAULT_REGION.
+	Region string
+	// Service name, eg. "s3" or "execute-api". Defaults to "s3".
+	Service string
+	// Hosts whose requests are signed. Entries match a host name exactly, or with a leading "." any subdomain.
+	// Defaults to ".amazonaws.com", so that credentials are not offered to other hosts.
+	Hosts []string
+	// Credentials returns the credentials to sign each request with. Defaults to $AWS_ACCESS_KEY_ID,
+	// $AWS_SECRET_ACCESS_KEY and $AWS_SESSION_TOKEN.
+	Credentials func(ctx context.Context) (AWSCredentials, error)
+}
+
+// WithSigV4 signs the HTTP requests made by a [Fetcher] with AWS Signature Version 4. It is shorthand for
+// [WithMiddleware] with [SigV4.Middleware].
+func WithSigV4(sigv4 SigV4) Option {
+	return WithMiddleware(sigv4.Middleware)
+}
+
+// Middleware is the [Middleware] signing requests to the configured hosts.
+func (s SigV4) Middleware(next http.RoundTripper) http.RoundTripper {
+	if s.Region == "" {
+		s.Region = cmp.Or(os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"))
+	}
+	if s.Service == "" {
+		s.Service = "s3"
+	}
+	if len(s.Hosts) == 0 {
+		s.Hosts = []string{".amazonaws.com"}
+	}
+	if s.Credentials == nil {
+		s.Credentials = awsEnvCredentials
+	}
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if !s.signs(req.URL.Hostname()) {
+			return next.RoundTrip(req) //nolint:wrapcheck // transport errors are passed through
+		}
+		credentials, err := s.Credentials(req.Context())
+		if err != nil {
+			return nil, fmt.Errorf("sigv4: credentials: %w", err)
+		}
+		req = req.Clone(req.Context())
+		if err := s.sign(req, credentials, time.Now()); err != nil {
+			return nil, fmt.Errorf("sigv4: %w", err)
+		}
+		return next.RoundTrip(req) //nolint:wrapcheck // transport errors are passed through
+	})
+}
+
+func awsEnvCredentials(context.Context) (AWSCredentials, error) {
+	credentials := AWSCredentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if credentials.AccessKeyID == "" || credentials.SecretAccessKey == "" {
+		return credentials, errors.New("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are not set")
+	}
+	return credentials, nil
+}
+
+func (s *SigV4) signs(host string) bool {
+	host = strings.ToLower(host)
+	for _, entry := range s.Hosts {
+		entry = strings.ToLower(entry)
+		if host == entry || (strings.HasPrefix(entry, ".") && strings.HasSuffix(host, entry)) {
+			return true
+		}
+	}
+	return false
+}
+
+// sign adds the headers signing req at now to req, which must already be a copy owned by the caller.
+func (s *SigV4) sign(req *http.Request, credentials AWSCredentials, now time.Time) error {
+	payloadHash, err := s.payloadHash(req)
+	if err != nil {
+		return err
+	}
+	now = now.UTC()
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	if s.Service == "s3" {
+		req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	}
+	if credentials.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", credentials.SessionToken)
+	}
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if name == "range" || strings.HasPrefix(name, "x-amz-") {
+			headers[name] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := slices.Sorted(maps.Keys(headers))
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		s.canonicalPath(req.URL.Path),
+		canonicalQuery(req),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + s.Region + "/" + s.Service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + req.Header.Get("X-Amz-Date") + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+	key := []byte("AWS4" + credentials.SecretAccessKey)
+	for _, part := range []string{date, s.Region, s.Service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		credentials.AccessKeyID, scope, signedHeaders, signature))
+	return nil
+}
+
+// payloadHash returns the hex SHA-256 of the request body. S3 accepts unsigned payloads, so bodies are not read for it.
+func (s *SigV4) payloadHash(req *http.Request) (string, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return sha256Hex(nil), nil
+	}
+	if s.Service == "s3" {
+		return "UNSIGNED-PAYLOAD", nil
+	}
+	if req.GetBody == nil {
+		return "", errors.New("cannot sign a request body that cannot be reread")
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return "", fmt.Errorf("reading body: %w", err)
+	}
+	defer body.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, body); err != nil {
+		return "", fmt.Errorf("reading body: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// canonicalPath URI-encodes each segment of path, twice for services other than S3.
+func (s *SigV4) canonicalPath(path string) string {
+	if path == "" {
+		return "/"
+	}
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = awsEscape(segment)
+		if s.Service != "s3" {
+			segments[i] = awsEscape(segments[i])
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func canonicalQuery(req *http.Request) string {
+	var params [][2]string
+	for key, values := range req.URL.Query() {
+		for _, value := range values {
+			params = append(params, [2]string{awsEscape(key), awsEscape(value)})
+		}
+	}
+	slices.SortFunc(params, func(a, b [2]string) int {
+		return cmp.Or(strings.Compare(a[0], b[0]), strings.Compare(a[1], b[1]))
+	})
+	encoded := make([]string, len(params))
+	for i, param := range params {
+		encoded[i] = param[0] + "=" + param[1]
+	}
+	return strings.Join(encoded, "&")
+}
+
+// awsEscape percent-encodes all but the unreserved characters of RFC 3986, as SigV4 requires.
+func awsEscape(s string) string {
+	var b strings.Builder
+	for i := range len(s) {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || strings.IndexByte("-_.~", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
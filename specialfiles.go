@@ -0,0 +1,78 @@
+package getit
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"os"
+)
+
+// SpecialFilePolicy controls how device nodes and FIFOs in TAR archives are extracted.
+type SpecialFilePolicy int
+
+const (
+	// SpecialFilesSkip skips special files, logging each at debug level. This is the default.
+	SpecialFilesSkip SpecialFilePolicy = iota
+	// SpecialFilesReject fails extraction with a *[SpecialFileError].
+	SpecialFilesReject
+	// SpecialFilesCreate creates FIFOs and device nodes as recorded in the archive. Creating device nodes usually
+	// requires root privileges, and special files can only be created on Linux and macOS.
+	SpecialFilesCreate
+)
+
+// WithSpecialFilePolicy sets how a [Fetcher] extracts device nodes and FIFOs from TAR archives.
+func WithSpecialFilePolicy(policy SpecialFilePolicy) Option {
+	return func(f *Fetcher) { f.config.specialFiles = policy }
+}
+
+// SpecialFileError is returned when extracting a special file from an archive and the policy is
+// [SpecialFilesReject], or the file can't be created.
+type SpecialFileError struct {
+	// Path of the entry being extracted.
+	Path string
+	// Type of the entry, eg. "FIFO".
+	Type string
+	// Err is the reason the file could not be created, if the policy is [SpecialFilesCreate].
+	Err error
+}
+
+func (s *SpecialFileError) Error() string {
+	if s.Err != nil {
+		return fmt.Sprintf("%s: creating %s: %s", s.Path, s.Type, s.Err)
+	}
+	return fmt.Sprintf("%s: %s not allowed", s.Path, s.Type)
+}
+
+func (s *SpecialFileError) Unwrap() error { return s.Err }
+
+// specialFileType returns the type of special file a tar entry is, if any.
+func specialFileType(typeflag byte) (string, bool) {
+	switch typeflag {
+	case tar.TypeFifo:
+		return "FIFO", true
+	case tar.TypeChar:
+		return "character device", true
+	case tar.TypeBlock:
+		return "block device", true
+	}
+	return "", false
+}
+
+// extractSpecial applies the policy to a special file in a tar archive, reporting whether it was created at path.
+func (p SpecialFilePolicy) extractSpecial(ctx context.Context, path string, hdr *tar.Header, kind string) (bool, error) {
+	switch p {
+	case SpecialFilesReject:
+		return false, &SpecialFileError{Path: hdr.Name, Type: kind}
+	case SpecialFilesCreate:
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return false, &SpecialFileError{Path: hdr.Name, Type: kind, Err: err}
+		}
+		if err := makeSpecial(path, hdr); err != nil {
+			return false, &SpecialFileError{Path: hdr.Name, Type: kind, Err: err}
+		}
+		return true, nil
+	default:
+		configFromContext(ctx).logger.DebugContext(ctx, "skipping special file", "name", hdr.Name, "type", kind)
+		return false, nil
+	}
+}
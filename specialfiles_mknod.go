@@ -0,0 +1,31 @@
+//go:build linux || darwin
+
+package getit
+
+import (
+	"archive/tar"
+	"runtime"
+	"syscall"
+)
+
+// makeSpecial creates the FIFO or device node described by hdr at path.
+func makeSpecial(path string, hdr *tar.Header) error {
+	mode := uint32(hdr.Mode & 0o7777) //nolint:gosec // masked to permission bits
+	switch hdr.Typeflag {
+	case tar.TypeFifo:
+		mode |= syscall.S_IFIFO
+	case tar.TypeChar:
+		mode |= syscall.S_IFCHR
+	case tar.TypeBlock:
+		mode |= syscall.S_IFBLK
+	}
+	return syscall.Mknod(path, mode, mkdev(hdr.Devmajor, hdr.Devminor)) //nolint:wrapcheck // wrapped by the caller
+}
+
+// mkdev encodes a device number as the platform's dev_t.
+func mkdev(major, minor int64) int {
+	if runtime.GOOS == "darwin" {
+		return int(major<<24 | minor&0xffffff)
+	}
+	return int((major&0xfffff000)<<32 | (major&0xfff)<<8 | (minor&0xffffff00)<<12 | minor&0xff)
+}
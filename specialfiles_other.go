@@ -0,0 +1,13 @@
+//go:build !linux && !darwin
+
+package getit
+
+import (
+	"archive/tar"
+	"errors"
+)
+
+// makeSpecial creates the FIFO or device node described by hdr at path, which is not supported on this platform.
+func makeSpecial(string, *tar.Header) error {
+	return errors.New("special files are not supported on this platform")
+}
@@ -0,0 +1,109 @@
+package getit_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/block/getit"
+)
+
+// fifoTar returns a tarball containing a regular file and a FIFO.
+func fifoTar(t *testing.T) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	assert.NoError(t, tw.WriteHeader(&tar.Header{Name: "file.txt", Mode: 0o644, Size: 6, Typeflag: tar.TypeReg}))
+	_, err := tw.Write([]byte("hello\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, tw.WriteHeader(&tar.Header{Name: "pipe", Mode: 0o600, Typeflag: tar.TypeFifo}))
+	assert.NoError(t, tw.Close())
+	return buf.Bytes()
+}
+
+func TestWithSpecialFilePolicy(t *testing.T) {
+	data := fifoTar(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+	ctx := context.Background()
+
+	t.Run("Skip", func(t *testing.T) {
+		dest := t.TempDir()
+		assert.NoError(t, getit.New([]getit.Resolver{getit.NewTAR()}, nil).Fetch(ctx, server.URL+"/archive.tar", dest))
+		_, err := os.Stat(filepath.Join(dest, "file.txt"))
+		assert.NoError(t, err)
+		_, err = os.Lstat(filepath.Join(dest, "pipe"))
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("Reject", func(t *testing.T) {
+		fetcher := getit.New([]getit.Resolver{getit.NewTAR()}, nil, getit.WithSpecialFilePolicy(getit.SpecialFilesReject))
+		err := fetcher.Fetch(ctx, server.URL+"/archive.tar", t.TempDir())
+		var specialErr *getit.SpecialFileError
+		assert.True(t, errors.As(err, &specialErr), "%v", err)
+		assert.Equal(t, "pipe", specialErr.Path)
+		assert.Equal(t, "FIFO", specialErr.Type)
+	})
+
+	t.Run("Create", func(t *testing.T) {
+		if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+			t.Skip("special files are not supported")
+		}
+		dest := t.TempDir()
+		fetcher := getit.New([]getit.Resolver{getit.NewTAR()}, nil, getit.WithSpecialFilePolicy(getit.SpecialFilesCreate))
+		assert.NoError(t, fetcher.Fetch(ctx, server.URL+"/archive.tar", dest))
+		info, err := os.Lstat(filepath.Join(dest, "pipe"))
+		assert.NoError(t, err)
+		assert.True(t, info.Mode()&os.ModeNamedPipe != 0, "%s", info.Mode())
+	})
+}
+
+func TestSpecialFilesManifestAndChecksums(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		t.Skip("special files are not supported")
+	}
+	data := fifoTar(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+	// Hashing the FIFO would open it, which blocks until it has a writer.
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "getit.sum")
+	fetcher := getit.New([]getit.Resolver{getit.NewTAR()}, nil,
+		getit.WithSpecialFilePolicy(getit.SpecialFilesCreate), getit.WithChecksumDB(dbPath))
+	dest := filepath.Join(dir, "dest")
+	manifestPath := filepath.Join(dir, "manifest.json")
+	_, err := fetcher.FetchWithOptions(ctx, server.URL+"/archive.tar", dest, getit.FetchOptions{ManifestPath: manifestPath})
+	assert.NoError(t, err)
+
+	manifest, err := getit.BuildManifest(ctx, dest)
+	assert.NoError(t, err)
+	assert.Equal(t, []getit.ManifestEntry{
+		{Path: "file.txt", Type: "file", Mode: 0o644, Size: 6, SHA256: "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"},
+		{Path: "pipe", Type: "fifo", Mode: 0o600},
+	}, manifest.Entries)
+	written, err := os.ReadFile(manifestPath)
+	assert.NoError(t, err)
+	assert.Contains(t, string(written), `"type": "fifo"`)
+
+	// A second fetch is verified against the recorded digest.
+	_, err = fetcher.FetchWithOptions(ctx, server.URL+"/archive.tar", filepath.Join(dir, "again"), getit.FetchOptions{})
+	assert.NoError(t, err)
+	db, err := os.ReadFile(dbPath)
+	assert.NoError(t, err)
+	assert.Contains(t, string(db), manifest.TreeHash)
+}
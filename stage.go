@@ -0,0 +1,118 @@
+package getit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// PostFetchCommand returns a [FetchOptions.PostFetch] hook that runs a command in the fetched tree.
+//
+// The command is not run via a shell; use eg. PostFetchCommand("sh", "-c", "chmod +x bin/*") for shell features.
+func PostFetchCommand(name string, args ...string) func(ctx context.Context, dir string) error {
+	return func(ctx context.Context, dir string) error {
+		stderr := &bytes.Buffer{}
+		cmd := command(ctx, name, args...)
+		cmd.Dir = dir
+		cmd.Stdout = stderr
+		cmd.Stderr = stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s failed: %w: %s", name, err, stderr)
+		}
+		return nil
+	}
+}
+
+// newStaging creates a staging directory alongside dest, so that it can later be promoted with a rename.
+func newStaging(dest string) (string, error) {
+	parent := filepath.Dir(dest)
+	if err := os.MkdirAll(parent, 0750); err != nil {
+		return "", fmt.Errorf("creating %s: %w", parent, err)
+	}
+	staging, err := os.MkdirTemp(parent, "."+filepath.Base(dest)+".getit-*")
+	if err != nil {
+		return "", fmt.Errorf("creating staging directory: %w", err)
+	}
+	return staging, nil
+}
+
+// promote moves the contents of staging into dest.
+//
+// If dest is missing or empty, staging is renamed to dest. Otherwise staging is merged into dest as an unstaged fetch
+// would have written it: directories are merged, and other entries replace those in dest.
+func promote(ctx context.Context, staging, dest string) error {
+	if isEmptyOrMissing(dest) {
+		if err := os.Remove(dest); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("promoting to %s: %w", dest, err)
+		}
+		if err := os.Rename(staging, dest); err != nil {
+			return fmt.Errorf("promoting to %s: %w", dest, err)
+		}
+		return nil
+	}
+	if err := mergeTree(ctx, staging, dest, mergeOptions{}); err != nil {
+		return fmt.Errorf("promoting to %s: %w", dest, err)
+	}
+	return nil
+}
+
+// promoteDelete promotes staging to dest, then removes any entries of dest that staging didn't have, so that dest
+// holds exactly the tree in staging.
+func promoteDelete(ctx context.Context, staging, dest string) error {
+	seen := map[string]bool{}
+	if err := markSeen(staging, ".", seen); err != nil {
+		return fmt.Errorf("promoting to %s: %w", dest, err)
+	}
+	if err := promote(ctx, staging, dest); err != nil {
+		return err
+	}
+	if _, err := pruneTree(dest, seen); err != nil {
+		return fmt.Errorf("removing stale entries from %s: %w", dest, err)
+	}
+	return nil
+}
+
+// replace swaps staging into place as dest, removing any existing dest. dest is only briefly missing, between two
+// renames.
+func replace(staging, dest string) error {
+	old := staging + ".old"
+	if err := os.Rename(dest, old); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("replacing %s: %w", dest, err)
+	}
+	if err := os.Rename(staging, dest); err != nil {
+		_ = os.Rename(old, dest)
+		return fmt.Errorf("replacing %s: %w", dest, err)
+	}
+	if err := os.RemoveAll(old); err != nil {
+		return fmt.Errorf("removing previous %s: %w", dest, err)
+	}
+	return nil
+}
+
+// writeFileAtomic writes the contents of r to path via a temporary file alongside it, so that path is never left
+// partially written.
+func writeFileAtomic(path string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return fmt.Errorf("creating %s: %w", filepath.Dir(path), err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".getit-*")
+	if err != nil {
+		return fmt.Errorf("creating temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	if _, err := io.Copy(tmp, r); err != nil {
+		return fmt.Errorf("writing %s: %w", tmp.Name(), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing %s: %w", tmp.Name(), err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package getit_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/block/getit"
+)
+
+func TestFetchWithOptionsPostFetch(t *testing.T) {
+	srcDir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(srcDir, "bin"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(srcDir, "bin", "tool"), []byte("#!/bin/sh\n"), 0o644))
+
+	fetcher := getit.New([]getit.Resolver{getit.NewFile()}, nil)
+	dest := filepath.Join(t.TempDir(), "dest")
+	_, err := fetcher.FetchWithOptions(context.Background(), "file://"+srcDir, dest, getit.FetchOptions{
+		PostFetch: getit.PostFetchCommand("sh", "-c", "chmod +x bin/*"),
+	})
+	assert.NoError(t, err)
+
+	info, err := os.Stat(filepath.Join(dest, "bin", "tool"))
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o111), info.Mode().Perm()&0o111)
+	staging, err := filepath.Glob(filepath.Join(filepath.Dir(dest), ".*.getit-*"))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(staging), "staging directory should be removed")
+}
+
+func TestFetchWithOptionsPostFetchRollback(t *testing.T) {
+	srcDir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(srcDir, "file.txt"), []byte("new\n"), 0o644))
+
+	dest := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dest, "file.txt"), []byte("old\n"), 0o644))
+
+	fetcher := getit.New([]getit.Resolver{getit.NewFile()}, nil)
+	_, err := fetcher.FetchWithOptions(context.Background(), "file://"+srcDir, dest, getit.FetchOptions{
+		PostFetch: getit.PostFetchCommand("false"),
+	})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "post-fetch hook")
+
+	content, err := os.ReadFile(filepath.Join(dest, "file.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "old\n", string(content))
+	staging, err := filepath.Glob(filepath.Join(filepath.Dir(dest), ".*.getit-*"))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(staging), "staging directory should be removed")
+}
+
+func TestFetchWithOptionsPostFetchMergesDest(t *testing.T) {
+	srcDir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(srcDir, "sub"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(srcDir, "sub", "new.txt"), []byte("new\n"), 0o644))
+
+	dest := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(dest, "sub"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dest, "sub", "old.txt"), []byte("old\n"), 0o644))
+
+	fetcher := getit.New([]getit.Resolver{getit.NewFile()}, nil)
+	_, err := fetcher.FetchWithOptions(context.Background(), "file://"+srcDir, dest, getit.FetchOptions{
+		PostFetch: func(context.Context, string) error { return nil },
+	})
+	assert.NoError(t, err)
+
+	for name, content := range map[string]string{"sub/old.txt": "old\n", "sub/new.txt": "new\n"} {
+		data, err := os.ReadFile(filepath.Join(dest, name))
+		assert.NoError(t, err, name)
+		assert.Equal(t, content, string(data), name)
+	}
+}
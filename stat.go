@@ -0,0 +1,41 @@
+package getit
+
+import (
+	"context"
+	"time"
+)
+
+// Stater is an optional interface implemented by a [Resolver] that can describe a source without fetching it.
+type Stater interface {
+	// Stat returns metadata about a source without writing anything locally.
+	Stat(ctx context.Context, source Source) (SourceInfo, error)
+}
+
+// SourceInfo describes a source, as returned by [Stater].
+type SourceInfo struct {
+	// Size in bytes of the source, or -1 if unknown.
+	Size int64
+	// Revision identifies the content of the source if known, eg. a git commit or an HTTP ETag.
+	Revision string
+	// Modified is the last modification time of the source if known.
+	Modified time.Time
+}
+
+// FetchResult reports the outcome of a fetch.
+type FetchResult struct {
+	// URL of the resolved source, with credentials redacted.
+	URL string
+	// Resolver that handled the source, eg. "TAR".
+	Resolver string
+	// Dest is the destination directory.
+	Dest string
+	// DryRun is true if nothing was written.
+	DryRun bool
+	// Info about the source, populated for dry runs by resolvers implementing [Stater].
+	Info SourceInfo
+	// Version is the concrete version selected for a version constraint, eg. the tag "v1.4.2" for a git source
+	// with ?ref=semver:^1.2.
+	Version string
+	// Usage is the disk space written to the destination.
+	Usage DiskUsage
+}
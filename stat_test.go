@@ -0,0 +1,63 @@
+package getit_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/block/getit"
+)
+
+func TestFetchWithOptionsDryRun(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "archive.tar.gz"))
+	assert.NoError(t, err)
+	var methods []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		if r.URL.Path == "/missing.tar.gz" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+
+	fetcher := getit.New([]getit.Resolver{getit.NewTAR()}, nil)
+	dest := filepath.Join(t.TempDir(), "dest")
+	result, err := fetcher.FetchWithOptions(context.Background(), server.URL+"/archive.tar.gz", dest, getit.FetchOptions{DryRun: true})
+	assert.NoError(t, err)
+	assert.Equal(t, &getit.FetchResult{
+		URL:      server.URL + "/archive.tar.gz",
+		Resolver: "TAR",
+		Dest:     dest,
+		DryRun:   true,
+		Info:     getit.SourceInfo{Size: int64(len(data)), Revision: `"v1"`},
+	}, result)
+	assert.Equal(t, []string{http.MethodHead}, methods)
+	_, err = os.Stat(dest)
+	assert.True(t, os.IsNotExist(err))
+
+	_, err = fetcher.FetchWithOptions(context.Background(), server.URL+"/missing.tar.gz", dest, getit.FetchOptions{DryRun: true})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "404")
+}
+
+func TestFileStat(t *testing.T) {
+	srcDir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(srcDir, "sub"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(srcDir, "a.txt"), []byte("12345"), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(srcDir, "sub", "b.txt"), []byte("123"), 0o644))
+
+	_, source, err := getit.New([]getit.Resolver{getit.NewFile()}, nil).Resolve("file://" + srcDir)
+	assert.NoError(t, err)
+	info, err := getit.NewFile().Stat(context.Background(), source)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(8), info.Size)
+}
@@ -0,0 +1,200 @@
+package getit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+)
+
+// WithStore deduplicates fetched files in a content-addressed object store rooted at dir.
+//
+// After each fetch, every regular file in the fetched tree is stored under the digest of its contents and replaced
+// by a hardlink to the stored object, so identical files across any number of destinations consume disk space
+// only once. The source is fetched into a staging directory alongside the destination, so files already in the
+// destination are never moved into the store. Files are keyed by their permission bits as well as their contents,
+// and by their modification time when [FetchOptions.PreserveTimes] is set, as linked files share all three.
+//
+// The store must be on the same filesystem as the destinations, otherwise files are left as independent copies.
+// As materialized files share storage, they are made read-only and must be replaced rather than modified in place.
+// An object that has been modified regardless is detected and replaced the next time its contents are fetched.
+// Objects are never removed by fetches; use [Store.GC] to bound the space taken by those no longer in use.
+//
+// A store may be shared by any number of processes, eg. CI jobs on one runner. Objects are only ever created and
+// replaced with atomic links and renames, so no process sees a partially written object, and temporary files left
+// behind by processes that were interrupted are removed by [Store.GC].
+func WithStore(dir string) Option {
+	return func(f *Fetcher) { f.config.store = dir }
+}
+
+// storeTree moves the regular files under dir, fetched from host, into the object store, replacing each with a
+// hardlink to its object. A .git directory at the root of dir is skipped, as git rewrites some of its files in place.
+func storeTree(ctx context.Context, store, dir, host string) error {
+	cfg := configFromContext(ctx)
+	objects := filepath.Join(store, "objects")
+	if err := os.MkdirAll(objects, 0750); err != nil {
+		return fmt.Errorf("creating store: %w", err)
+	}
+	var stored, linked int
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := contextError(ctx); err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" && filepath.Dir(path) == filepath.Clean(dir) {
+			return filepath.SkipDir
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err //nolint:wrapcheck // wrapped below
+		}
+		object, err := objectPath(objects, path, info, cfg.options.PreserveTimes)
+		if err != nil {
+			return err
+		}
+		created, err := storeFile(path, object)
+		if errors.Is(err, errStoreUnlinkable) {
+			cfg.logger.DebugContext(ctx, "store unavailable, leaving files unshared", "store", store, "error", err)
+			return filepath.SkipAll
+		} else if err != nil {
+			return err
+		}
+		if created {
+			stored++
+		} else {
+			linked++
+		}
+		cfg.cacheLookup(host, "store", !created)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("storing %s: %w", dir, err)
+	}
+	cfg.logger.DebugContext(ctx, "stored", "dir", dir, "store", store, "new", stored, "shared", linked)
+	return nil
+}
+
+// objectPath returns the path of the object for a file, keyed by its digest, mode and, if withTime is set, its
+// modification time. Objects are spread over 256 subdirectories to keep directory sizes manageable.
+func objectPath(objects, path string, info fs.FileInfo, withTime bool) (string, error) {
+	digest, err := hashFile(path)
+	if err != nil {
+		return "", err
+	}
+	name := digest + "-" + strconv.FormatUint(uint64(info.Mode().Perm()), 8)
+	if withTime {
+		name += "-" + strconv.FormatInt(info.ModTime().UnixNano(), 10)
+	}
+	return filepath.Join(objects, digest[:2], name), nil
+}
+
+// errStoreUnlinkable is returned by storeFile when files can't be linked into the store at all, eg. because it is on
+// a different filesystem.
+var errStoreUnlinkable = errors.New("cannot link into store")
+
+// storeFile links path into the store as object, or if object already exists replaces path with a link to it. It
+// reports whether a new object was created.
+//
+// Objects are made read-only so that materialized files aren't easily modified in place, and an existing object is
+// only reused if its contents still match path, so that a corrupted object is replaced rather than spread further.
+func storeFile(path, object string) (bool, error) {
+	if err := os.MkdirAll(filepath.Dir(object), 0750); err != nil {
+		return false, fmt.Errorf("creating store: %w", err)
+	}
+	for attempt := 1; ; attempt++ {
+		err := os.Link(path, object)
+		if err == nil {
+			return true, makeReadOnly(object)
+		} else if !errors.Is(err, fs.ErrExist) {
+			return false, fmt.Errorf("%w: %w", errStoreUnlinkable, err)
+		}
+		if sameFile(path, object) {
+			return false, nil
+		}
+		// Link the object alongside path, then rename it into place, so that path is never missing. The link rather
+		// than the object is checked, so that what is checked is what ends up at path.
+		tmp := path + ".getit-store"
+		_ = os.Remove(tmp)
+		if err := os.Link(object, tmp); errors.Is(err, fs.ErrNotExist) && attempt < 3 {
+			// The object was removed by Store.GC, possibly in another process, since it was found.
+			continue
+		} else if err != nil {
+			// Eg. the object has reached the filesystem's link limit, so leave path as an independent copy.
+			return false, nil //nolint:nilerr // not fatal
+		}
+		same, err := sameContents(path, tmp)
+		if err != nil || !same {
+			_ = os.Remove(tmp)
+			if err != nil {
+				return false, err
+			}
+			return true, replaceObject(path, object)
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			_ = os.Remove(tmp)
+			return false, fmt.Errorf("link %s: %w", path, err)
+		}
+		return false, nil
+	}
+}
+
+// storeTemps numbers the temporary names used within the store by this process.
+var storeTemps atomic.Uint64
+
+// replaceObject replaces an object whose contents no longer match its key with path. The replacement is linked under
+// a name unique to this process and then renamed over the object, so that processes replacing an object at the same
+// time don't disturb one another.
+func replaceObject(path, object string) error {
+	tmp := fmt.Sprintf("%s.getit-%d-%d", object, os.Getpid(), storeTemps.Add(1))
+	if err := os.Link(path, tmp); err != nil {
+		return fmt.Errorf("%w: %w", errStoreUnlinkable, err)
+	}
+	if err := os.Rename(tmp, object); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("replacing %s: %w", object, err)
+	}
+	return makeReadOnly(object)
+}
+
+// sameContents reports whether the files at a and b have the same contents.
+func sameContents(a, b string) (bool, error) {
+	want, err := hashFile(a)
+	if err != nil {
+		return false, err
+	}
+	got, err := hashFile(b)
+	if err != nil {
+		return false, err
+	}
+	return want == got, nil
+}
+
+// makeReadOnly clears the write permission bits of path.
+func makeReadOnly(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("stat %s: %w", path, err)
+	}
+	if err := os.Chmod(path, info.Mode().Perm()&^0o222); err != nil {
+		return fmt.Errorf("chmod %s: %w", path, err)
+	}
+	return nil
+}
+
+func sameFile(a, b string) bool {
+	ai, err := os.Lstat(a)
+	if err != nil {
+		return false
+	}
+	bi, err := os.Lstat(b)
+	return err == nil && os.SameFile(ai, bi)
+}
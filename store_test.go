@@ -0,0 +1,217 @@
+package getit_test
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/block/getit"
+)
+
+func TestFetchWithStore(t *testing.T) {
+	root := t.TempDir()
+	src := filepath.Join(root, "src")
+	files := map[string]os.FileMode{
+		"file.txt":       0o644,
+		"copy.txt":       0o644,
+		"script.sh":      0o755,
+		"sub/nested.txt": 0o644,
+	}
+	for name, mode := range files {
+		assert.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(src, name)), 0o755))
+		assert.NoError(t, os.WriteFile(filepath.Join(src, name), []byte("shared\n"), mode))
+		assert.NoError(t, os.Chmod(filepath.Join(src, name), mode))
+	}
+
+	store := filepath.Join(root, "store")
+	fetcher := getit.New([]getit.Resolver{getit.NewFile()}, nil, getit.WithStore(store))
+	first := filepath.Join(root, "first")
+	second := filepath.Join(root, "second")
+	for _, dest := range []string{first, second, first} {
+		err := fetcher.Fetch(context.Background(), "file://"+src, dest)
+		assert.NoError(t, err)
+	}
+
+	same := func(a, b string) bool {
+		ai, err := os.Stat(a)
+		assert.NoError(t, err)
+		bi, err := os.Stat(b)
+		assert.NoError(t, err)
+		return os.SameFile(ai, bi)
+	}
+	for name, mode := range files {
+		assert.True(t, same(filepath.Join(first, name), filepath.Join(second, name)), "%s not shared", name)
+		if runtime.GOOS != "windows" {
+			info, err := os.Stat(filepath.Join(second, name))
+			assert.NoError(t, err)
+			assert.Equal(t, mode&^0o222, info.Mode().Perm(), "stored files should be read-only")
+		}
+		content, err := os.ReadFile(filepath.Join(second, name))
+		assert.NoError(t, err)
+		assert.Equal(t, "shared\n", string(content))
+	}
+	assert.True(t, same(filepath.Join(first, "file.txt"), filepath.Join(first, "sub/nested.txt")))
+	if runtime.GOOS != "windows" {
+		assert.False(t, same(filepath.Join(first, "file.txt"), filepath.Join(first, "script.sh")))
+	}
+	leftovers, err := filepath.Glob(filepath.Join(first, "*.getit-store"))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(leftovers))
+}
+
+func TestFetchWithStoreLeavesExistingFiles(t *testing.T) {
+	root := t.TempDir()
+	src := filepath.Join(root, "src")
+	assert.NoError(t, os.MkdirAll(src, 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(src, "file.txt"), []byte("fetched\n"), 0o644))
+	dest := filepath.Join(root, "dest")
+	assert.NoError(t, os.MkdirAll(dest, 0o755))
+	mine := filepath.Join(dest, "mine.txt")
+	assert.NoError(t, os.WriteFile(mine, []byte("mine\n"), 0o644))
+
+	store := filepath.Join(root, "store")
+	fetcher := getit.New([]getit.Resolver{getit.NewFile()}, nil, getit.WithStore(store))
+	assert.NoError(t, fetcher.Fetch(context.Background(), "file://"+src, dest))
+
+	mineInfo, err := os.Stat(mine)
+	assert.NoError(t, err)
+	fetchedInfo, err := os.Stat(filepath.Join(dest, "file.txt"))
+	assert.NoError(t, err)
+	var mineStored, fetchedStored bool
+	err = filepath.WalkDir(store, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		mineStored = mineStored || os.SameFile(info, mineInfo)
+		fetchedStored = fetchedStored || os.SameFile(info, fetchedInfo)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.False(t, mineStored, "existing file linked into the store")
+	assert.True(t, fetchedStored, "fetched file not linked into the store")
+}
+
+func TestFetchWithStoreReplacesModifiedObject(t *testing.T) {
+	root := t.TempDir()
+	src := filepath.Join(root, "src")
+	assert.NoError(t, os.MkdirAll(src, 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(src, "f.txt"), []byte("good\n"), 0o644))
+
+	fetcher := getit.New([]getit.Resolver{getit.NewFile()}, nil,
+		getit.WithStore(filepath.Join(root, "store")), getit.WithChecksumDB(filepath.Join(root, "getit.sum")))
+	a := filepath.Join(root, "a")
+	assert.NoError(t, fetcher.Fetch(context.Background(), "file://"+src, a))
+
+	// Materialized files are read-only, but can still be modified in place by a determined user.
+	tampered := filepath.Join(a, "f.txt")
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(tampered)
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0), info.Mode().Perm()&0o222)
+	}
+	assert.NoError(t, os.Chmod(tampered, 0o644))
+	assert.NoError(t, os.WriteFile(tampered, []byte("EVIL\n"), 0o644))
+
+	b := filepath.Join(root, "b")
+	assert.NoError(t, fetcher.Fetch(context.Background(), "file://"+src, b))
+	content, err := os.ReadFile(filepath.Join(b, "f.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "good\n", string(content))
+
+	// The corrupted object was replaced, so later fetches share the good copy.
+	c := filepath.Join(root, "c")
+	assert.NoError(t, fetcher.Fetch(context.Background(), "file://"+src, c))
+	bi, err := os.Stat(filepath.Join(b, "f.txt"))
+	assert.NoError(t, err)
+	ci, err := os.Stat(filepath.Join(c, "f.txt"))
+	assert.NoError(t, err)
+	assert.True(t, os.SameFile(bi, ci))
+}
+
+func TestStoreGC(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("link counts of open files are not reliable on Windows")
+	}
+	root := t.TempDir()
+	store := filepath.Join(root, "store")
+	fetcher := getit.New([]getit.Resolver{getit.NewFile()}, nil, getit.WithStore(store))
+	var dests []string
+	for i, content := range []string{"first\n", "second\n", "third\n"} {
+		src := filepath.Join(root, "src", strconv.Itoa(i))
+		assert.NoError(t, os.MkdirAll(src, 0o755))
+		assert.NoError(t, os.WriteFile(filepath.Join(src, "file.txt"), []byte(content), 0o644))
+		dest := filepath.Join(root, "dest", strconv.Itoa(i))
+		assert.NoError(t, fetcher.Fetch(context.Background(), "file://"+src, dest))
+		dests = append(dests, dest)
+	}
+
+	stats, err := getit.Store{Dir: store}.Stats(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, getit.StoreStats{Objects: 3, Size: 19}, stats)
+
+	// Objects of removed destinations are unused, and collected least recently used first.
+	assert.NoError(t, os.RemoveAll(dests[0]))
+	time.Sleep(10 * time.Millisecond)
+	assert.NoError(t, os.RemoveAll(dests[1]))
+	stats, err = getit.Store{Dir: store}.GC(context.Background(), 7)
+	assert.NoError(t, err)
+	assert.Equal(t, getit.StoreStats{Objects: 2, Size: 13, Unused: 1, UnusedSize: 7, Removed: 1, RemovedSize: 6}, stats)
+
+	stats, err = getit.Store{Dir: store}.GC(context.Background(), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, getit.StoreStats{Objects: 1, Size: 6, Removed: 1, RemovedSize: 7}, stats)
+	content, err := os.ReadFile(filepath.Join(dests[2], "file.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "third\n", string(content))
+}
+
+func TestFetchWithStoreConcurrent(t *testing.T) {
+	root := t.TempDir()
+	src := filepath.Join(root, "src")
+	assert.NoError(t, os.MkdirAll(src, 0o755))
+	for i := range 20 {
+		assert.NoError(t, os.WriteFile(filepath.Join(src, strconv.Itoa(i)+".txt"), []byte("good\n"), 0o644))
+	}
+	store := filepath.Join(root, "store")
+	fetcher := getit.New([]getit.Resolver{getit.NewFile()}, nil, getit.WithStore(store))
+	first := filepath.Join(root, "first")
+	assert.NoError(t, fetcher.Fetch(context.Background(), "file://"+src, first))
+	// Corrupt the shared object, so that concurrent fetches all replace it.
+	corrupted := filepath.Join(first, "0.txt")
+	assert.NoError(t, os.Chmod(corrupted, 0o644))
+	assert.NoError(t, os.WriteFile(corrupted, []byte("EVIL\n"), 0o644))
+
+	// Fetchers stand in for separate processes sharing the store.
+	var wg sync.WaitGroup
+	errs := make([]error, 8)
+	for i := range errs {
+		wg.Go(func() {
+			fetcher := getit.New([]getit.Resolver{getit.NewFile()}, nil, getit.WithStore(store))
+			errs[i] = fetcher.Fetch(context.Background(), "file://"+src, filepath.Join(root, "dest", strconv.Itoa(i)))
+		})
+	}
+	wg.Wait()
+	for i, err := range errs {
+		assert.NoError(t, err)
+		for j := range 20 {
+			content, err := os.ReadFile(filepath.Join(root, "dest", strconv.Itoa(i), strconv.Itoa(j)+".txt"))
+			assert.NoError(t, err)
+			assert.Equal(t, "good\n", string(content))
+		}
+	}
+	leftovers, err := filepath.Glob(filepath.Join(store, "objects", "*", "*.getit-*"))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(leftovers))
+}
@@ -0,0 +1,161 @@
+package getit
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Store manages the object store of [WithStore] rooted at Dir, so that long-running hosts can bound its size:
+//
+//	stats, err := getit.Store{Dir: dir}.GC(ctx, 10<<30)
+//
+// Objects still linked from a destination take up no space of their own, as removing them from the store would not
+// free any. Only unused objects, whose destinations have all been removed, are collected.
+type Store struct {
+	// Dir is the directory passed to [WithStore].
+	Dir string
+}
+
+// StoreStats describe the objects in a [Store].
+type StoreStats struct {
+	// Objects is the number of objects in the store, and Size their total size in bytes.
+	Objects int   `json:"objects"`
+	Size    int64 `json:"size"`
+	// Unused is the number of objects no longer linked from any destination, and UnusedSize their total size in
+	// bytes. Objects whose links can't be counted on the current platform are never reported as unused.
+	Unused     int   `json:"unused"`
+	UnusedSize int64 `json:"unusedSize"`
+	// Removed is the number of objects removed by [Store.GC], and RemovedSize their total size in bytes.
+	Removed     int   `json:"removed,omitempty"`
+	RemovedSize int64 `json:"removedSize,omitempty"`
+}
+
+// storeObject is an object found in a store.
+type storeObject struct {
+	path   string
+	size   int64
+	unused bool
+	// used is when the object was last linked or unlinked, where the platform records it, and otherwise when it was
+	// last modified.
+	used time.Time
+	// temp is set for temporary files, which are not objects.
+	temp bool
+}
+
+// Stats returns statistics of the objects in the store.
+func (s Store) Stats(ctx context.Context) (StoreStats, error) {
+	_, stats, err := s.scan(ctx)
+	return stats, err
+}
+
+// staleStoreTemp is the age after which a temporary file in the store is assumed to have been left behind by an
+// interrupted process, rather than being in use.
+var staleStoreTemp = time.Hour
+
+// GC removes unused objects from the store, least recently used first, until their total size is at most
+// maxUnusedSize bytes. A maxUnusedSize of zero removes every unused object. Stale temporary files left behind by
+// interrupted processes are also removed. It returns the statistics of the store after collection.
+//
+// GC is safe to run while other processes fetch using the store: an object linked again since it was found unused is
+// kept, and a fetch that finds its object removed stores its own file in its place.
+func (s Store) GC(ctx context.Context, maxUnusedSize int64) (StoreStats, error) {
+	objects, stats, err := s.scan(ctx)
+	if err != nil {
+		return stats, err
+	}
+	for _, object := range objects {
+		if object.temp && time.Since(object.used) > staleStoreTemp {
+			if err := os.Remove(object.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return stats, fmt.Errorf("collecting %s: %w", s.Dir, err)
+			}
+		}
+	}
+	objects = slices.DeleteFunc(objects, func(o storeObject) bool { return o.temp || !o.unused })
+	slices.SortFunc(objects, func(a, b storeObject) int { return cmp.Compare(a.used.UnixNano(), b.used.UnixNano()) })
+	for _, object := range objects {
+		if stats.UnusedSize <= maxUnusedSize {
+			break
+		}
+		if err := contextError(ctx); err != nil {
+			return stats, err
+		}
+		// The object may have been linked again since the scan.
+		info, err := os.Lstat(object.path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return stats, fmt.Errorf("collecting %s: %w", s.Dir, err)
+		}
+		if links, _, ok := objectUse(object.path, info); !ok || links > 1 {
+			continue
+		}
+		if err := os.Remove(object.path); errors.Is(err, os.ErrNotExist) {
+			// Removed by another process.
+			continue
+		} else if err != nil {
+			return stats, fmt.Errorf("collecting %s: %w", s.Dir, err)
+		}
+		stats.Objects--
+		stats.Size -= object.size
+		stats.Unused--
+		stats.UnusedSize -= object.size
+		stats.Removed++
+		stats.RemovedSize += object.size
+	}
+	return stats, nil
+}
+
+// scan lists the objects and temporary files in the store.
+func (s Store) scan(ctx context.Context) ([]storeObject, StoreStats, error) {
+	var objects []storeObject
+	stats := StoreStats{}
+	err := filepath.WalkDir(filepath.Join(s.Dir, "objects"), func(path string, d fs.DirEntry, err error) error {
+		if errors.Is(err, os.ErrNotExist) && path == filepath.Join(s.Dir, "objects") {
+			// Nothing has been stored yet.
+			return filepath.SkipAll
+		} else if err != nil {
+			return err
+		}
+		if err := contextError(ctx); err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		} else if err != nil {
+			return err //nolint:wrapcheck // wrapped below
+		}
+		links, used, ok := objectUse(path, info)
+		if !ok {
+			used = info.ModTime()
+		}
+		if strings.Contains(d.Name(), ".getit-") {
+			objects = append(objects, storeObject{path: path, used: used, temp: true})
+			return nil
+		}
+		object := storeObject{path: path, size: info.Size(), unused: ok && links == 1, used: used}
+		objects = append(objects, object)
+		stats.Objects++
+		stats.Size += object.size
+		if object.unused {
+			stats.Unused++
+			stats.UnusedSize += object.size
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, stats, fmt.Errorf("scanning %s: %w", s.Dir, err)
+	}
+	return objects, stats, nil
+}
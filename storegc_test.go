@@ -0,0 +1,37 @@
+package getit //nolint:testpackage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestStoreGCStaleTemps(t *testing.T) {
+	store := t.TempDir()
+	shard := filepath.Join(store, "objects", "ab")
+	assert.NoError(t, os.MkdirAll(shard, 0o750))
+	object := filepath.Join(shard, "abcd-644")
+	assert.NoError(t, os.WriteFile(object, []byte("object\n"), 0o444))
+	temp := object + ".getit-1234-1"
+	assert.NoError(t, os.WriteFile(temp, []byte("partial"), 0o444))
+
+	// Temporary files may be in use by another process until they are stale.
+	stats, err := Store{Dir: store}.GC(context.Background(), 1<<20)
+	assert.NoError(t, err)
+	assert.Equal(t, StoreStats{Objects: 1, Size: 7, Unused: 1, UnusedSize: 7}, stats)
+	_, err = os.Stat(temp)
+	assert.NoError(t, err)
+
+	staleStoreTemp = 0
+	t.Cleanup(func() { staleStoreTemp = time.Hour })
+	_, err = Store{Dir: store}.GC(context.Background(), 1<<20)
+	assert.NoError(t, err)
+	_, err = os.Stat(temp)
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(object)
+	assert.NoError(t, err)
+}
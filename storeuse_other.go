@@ -0,0 +1,13 @@
+//go:build (!unix && !windows) || aix
+
+package getit
+
+import (
+	"io/fs"
+	"time"
+)
+
+// objectUse reports that the links to store objects can't be counted on this platform.
+func objectUse(string, fs.FileInfo) (links uint64, used time.Time, ok bool) {
+	return 0, time.Time{}, false
+}
@@ -0,0 +1,19 @@
+//go:build unix && !darwin && !freebsd && !netbsd && !aix
+
+package getit
+
+import (
+	"io/fs"
+	"syscall"
+	"time"
+)
+
+// objectUse returns the number of links to the store object described by info, and when it was last linked or
+// unlinked, which is its inode change time.
+func objectUse(_ string, info fs.FileInfo) (links uint64, used time.Time, ok bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, time.Time{}, false
+	}
+	return uint64(stat.Nlink), time.Unix(stat.Ctim.Unix()), true //nolint:unconvert // Nlink varies by platform
+}
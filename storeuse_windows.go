@@ -0,0 +1,23 @@
+package getit
+
+import (
+	"io/fs"
+	"os"
+	"syscall"
+	"time"
+)
+
+// objectUse returns the number of links to the store object at path. Windows doesn't record when links were last
+// changed, so the object's modification time stands in for when it was last used.
+func objectUse(path string, info fs.FileInfo) (links uint64, used time.Time, ok bool) {
+	f, err := os.Open(path) // #nosec G304
+	if err != nil {
+		return 0, time.Time{}, false
+	}
+	defer f.Close()
+	var data syscall.ByHandleFileInformation
+	if err := syscall.GetFileInformationByHandle(syscall.Handle(f.Fd()), &data); err != nil {
+		return 0, time.Time{}, false
+	}
+	return uint64(data.NumberOfLinks), info.ModTime(), true
+}
@@ -1,39 +1,299 @@
 package getit
 
 import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/url"
 	"os"
+	"os/exec"
+	"path"
 	"regexp"
 	"strings"
 )
 
 // The TAR [Resolver] knows how to unpack tarballs.
-type TAR struct{}
+//
+// Uncompressed, gzip, bzip2 and lz4 tarballs are unpacked natively. Other compression formats are decompressed by
+// piping through the corresponding external tool (xz, zstd, lzip or brotli). compress(1) tarballs are decompressed
+// with gzip where it is installed, and natively otherwise.
+//
+// Hardlinks are recreated as links, or copies where the filesystem can't link them. Sparse files, in either the old
+// GNU or PAX format, are written with holes on filesystems that support them.
+type TAR struct {
+	// PreserveXattrs applies the extended attributes and POSIX ACLs recorded in PAX headers, as written by GNU tar
+	// and bsdtar with --xattrs and --acls, eg. file capabilities in security.capability. It is only supported on
+	// Linux, and is ignored elsewhere or if the filesystem doesn't support extended attributes. Privileged attributes
+	// are skipped unless running as root.
+	PreserveXattrs bool
+}
 
-var _ Resolver = (*TAR)(nil)
+var (
+	_ Resolver           = (*TAR)(nil)
+	_ Stater             = (*TAR)(nil)
+	_ Pusher             = (*TAR)(nil)
+	_ CapabilityReporter = (*TAR)(nil)
+)
 
 func NewTAR() *TAR { return &TAR{} }
 
-var tarRe = regexp.MustCompile(`(\.tar(\.[a-z]+)?)|(\.tbz|\.tbz2|\.txz|\.tzstd|\.tlz|\.tZ|\.tgz)`)
+var tarRe = regexp.MustCompile(`(?i)\.(tar(\.[a-z0-9]+)?|tbz|tbz2|txz|tzst|tzstd|tlz|tlz4|tz|tgz)$`)
 
+// Match returns true for paths with a tarball extension, ignoring case and any query or fragment.
 func (t *TAR) Match(source *url.URL) bool {
-	return tarRe.MatchString(source.Path)
+	return tarRe.MatchString(archivePath(source))
+}
+
+func (t *TAR) Capabilities() Capabilities {
+	return Capabilities{SubDir: true, Binaries: []string{"xz", "zstd", "lzip", "brotli", "gzip"}}
+}
+
+func (t *TAR) Stat(ctx context.Context, source Source) (SourceInfo, error) {
+	return statArchive(ctx, source.URL)
 }
 
+// Push writes a .tar, .tar.gz or .tgz archive of srcDir to a local file:// path, or uploads it with a PUT request.
+func (t *TAR) Push(ctx context.Context, source Source, srcDir string) error {
+	return pushArchive(ctx, source, srcDir)
+}
+
+// Fetch extracts a tarball, either remote or a local file:// path. If the source has a subdirectory, only the
+// entries beneath it are extracted, relative to it.
+//
+// Extracting a subdirectory of a zstd tarball in the seekable format, either local or from a server supporting range
+// requests, skips the frames holding entries outside the subdirectory rather than decompressing the whole archive.
+// See [seekable zstd].
+//
+// [seekable zstd]: https://github.com/facebook/zstd/blob/dev/contrib/seekable_format/zstd_seekable_compression_format.md
 func (t *TAR) Fetch(ctx context.Context, source Source, dest string) error {
 	if err := os.MkdirAll(dest, 0750); err != nil {
 		return fmt.Errorf("creating destination directory: %w", err)
 	}
-	args := []string{"-x", "-C", dest}
-	args = append(args, compressionFlag(source.URL.Path))
-	return FetchIntoPipe(ctx, source.URL, "tar", args...)
+	path := archivePath(source.URL)
+	if source.Archive != "" {
+		path = "archive." + source.Archive
+	}
+	if source.SubDir != "" && compressionFlag(path) == "--zstd" {
+		if done, err := t.fetchSeekable(ctx, source, dest); done || err != nil {
+			return err
+		}
+	}
+	body, err := openArchive(ctx, source.URL)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	return extractTarBody(ctx, source.URL, body, path, dest, source.SubDir, t.PreserveXattrs)
+}
+
+// extractTarBody unpacks a tarball downloaded from u as it is streamed from body. The compression format is
+// detected from the extension of name, falling back to the magic bytes of the stream. If subdir is set, only the
+// entries beneath it are extracted. If xattrs is set, extended attributes recorded in the tarball are applied.
+func extractTarBody(ctx context.Context, u *url.URL, body io.Reader, name, dest, subdir string, xattrs bool) (err error) {
+	cfg := configFromContext(ctx)
+	cfg.logger.DebugContext(ctx, "extract", "url", RedactURL(u), "dest", dest)
+	ctx, span := startSpan(ctx, "getit.extract", map[string]string{"url": RedactURL(u)})
+	defer func() { span.End(err) }()
+	ctx, cancel := withPhaseTimeout(ctx, "extract", cfg.timeouts.Extract)
+	defer cancel()
+	compressed := &byteCounter{r: newCountingReader(ctx, body, u.Host)}
+	r, err := decompress(ctx, compressed, compressionFlag(name))
+	if err != nil {
+		return err
+	}
+	if err := extractTar(ctx, r, dest, subdir, newLimiter(cfg.limits, compressed.count), xattrs); err != nil {
+		_ = r.Close()
+		return err
+	}
+	return r.Close()
+}
+
+// extractTar unpacks a tar stream into dest. If subdir is set, only the entries beneath it are extracted, relative to
+// it. If r is also an [io.Seeker], the contents of entries outside subdir are skipped by seeking past them, and if it
+// is a tarIndexer, every entry read is indexed.
+func extractTar(ctx context.Context, r io.Reader, dest, subdir string, limits *limiter, xattrs bool) error {
+	cfg := configFromContext(ctx)
+	perms := cfg.permissions
+	times := newTimestamper(cfg.options)
+	cases := newCaseTracker(cfg.caseCollisions)
+	var cr io.Reader = &contextReader{ctx: ctx, r: r}
+	if seeker, ok := r.(io.Seeker); ok {
+		cr = &contextReadSeeker{contextReader: contextReader{ctx: ctx, r: r}, seeker: seeker}
+	}
+	tr := tar.NewReader(cr)
+	indexer, _ := r.(*tarIndexer)
+	found := subdir == ""
+	// Links are created after all other entries, so that no entry is written through a symlink in the archive, and
+	// symlinks are created before hardlinks, which may link to them.
+	var symlinks, hardlinks []*tar.Header
+	finish := func(path, name string, hdr *tar.Header, size int64) error {
+		if err := perms.chown(path, hdr.Uid, hdr.Gid); err != nil {
+			return err
+		}
+		// Attributes are applied after ownership, as changing the owner clears file capabilities.
+		if xattrs && hdr.Typeflag != tar.TypeSymlink {
+			if err := applyTarXattrs(path, hdr); err != nil {
+				return err
+			}
+		}
+		if err := times.record(path, hdr.FileInfo().Mode(), hdr.ModTime); err != nil {
+			return err
+		}
+		cfg.fileExtracted(name, size)
+		return nil
+	}
+	for {
+		if err := contextError(ctx); err != nil {
+			return err
+		}
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			if !found {
+				return fmt.Errorf("subdirectory %s not found in archive", subdir)
+			}
+			if err := extractTarLinks(dest, cases, append(symlinks, hardlinks...), finish); err != nil {
+				return err
+			}
+			return times.finish()
+		} else if err != nil {
+			return fmt.Errorf("reading tar: %w", err)
+		}
+		if indexer != nil {
+			indexer.add(hdr)
+		}
+		if subdir != "" {
+			rel, ok := withinSubDir(hdr.Name, subdir)
+			if !ok {
+				continue
+			}
+			found = true
+			if rel == "" {
+				continue
+			}
+			if hdr.Typeflag == tar.TypeLink {
+				if hdr.Linkname, ok = withinSubDir(hdr.Linkname, subdir); !ok || hdr.Linkname == "" {
+					return fmt.Errorf("hardlink %s: target is outside subdirectory %s", hdr.Name, subdir)
+				}
+			}
+			hdr.Name = rel
+		}
+		name, err := cases.resolve(hdr.Name)
+		if err != nil {
+			return err
+		}
+		path, err := securePath(dest, name)
+		if err != nil {
+			return err
+		}
+		if err := cfg.pathRules.check(name); err != nil {
+			return err
+		}
+		if err := limits.entry(hdr.Name); err != nil {
+			return err
+		}
+		var size int64
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = writeDir(path, hdr.FileInfo().Mode(), perms)
+		case tar.TypeReg, tar.TypeGNUSparse:
+			write := writeFile
+			if isSparse(hdr) {
+				write = writeSparseFile
+			}
+			size, err = write(path, limits.reader(hdr.Name, tr), hdr.FileInfo().Mode(), perms)
+		case tar.TypeSymlink:
+			hdr.Name = name
+			symlinks = append(symlinks, hdr)
+			continue
+		case tar.TypeLink:
+			hdr.Name = name
+			hardlinks = append(hardlinks, hdr)
+			continue
+		default:
+			kind, special := specialFileType(hdr.Typeflag)
+			if !special {
+				cfg.logger.DebugContext(ctx, "skipping unsupported tar entry", "name", hdr.Name, "type", hdr.Typeflag)
+				continue
+			}
+			var created bool
+			if created, err = cfg.specialFiles.extractSpecial(ctx, path, hdr, kind); err == nil && !created {
+				continue
+			}
+		}
+		if err != nil {
+			return err
+		}
+		if err := finish(path, name, hdr, size); err != nil {
+			return err
+		}
+	}
+}
+
+// extractTarLinks creates the symlinks and hardlinks of a tarball, named relative to dest, once its other entries have
+// been extracted. Paths are checked again as each link is created, as an earlier symlink may now lie in their path.
+func extractTarLinks(dest string, cases *caseTracker, links []*tar.Header, finish func(path, name string, hdr *tar.Header, size int64) error) error {
+	for _, hdr := range links {
+		path, err := securePath(dest, hdr.Name)
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag == tar.TypeSymlink {
+			err = writeSymlink(path, hdr.Linkname)
+		} else {
+			var target string
+			if target, err = securePath(dest, cases.lookup(hdr.Linkname)); err == nil {
+				err = writeArchiveHardlink(path, target)
+			}
+		}
+		if err != nil {
+			return err
+		}
+		if err := finish(path, hdr.Name, hdr, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// withinSubDir returns the path of an archive entry relative to subdir, "" for subdir itself, and false if the entry
+// is outside subdir.
+func withinSubDir(name, subdir string) (string, bool) {
+	name = path.Clean("/" + name)[1:]
+	subdir = path.Clean("/" + subdir)[1:]
+	if name == subdir {
+		return "", true
+	}
+	return strings.CutPrefix(name, subdir+"/")
+}
+
+// isSparse reports whether a tar entry is a sparse file, in either the old GNU or the PAX format. The holes are
+// filled with zeros by archive/tar as the entry is read.
+func isSparse(hdr *tar.Header) bool {
+	if hdr.Typeflag == tar.TypeGNUSparse {
+		return true
+	}
+	for key := range hdr.PAXRecords {
+		if strings.HasPrefix(key, "GNU.sparse.") {
+			return true
+		}
+	}
+	return false
 }
 
+// compressionFlag returns the tar flag for the compression of a tarball, from its path.
+//
+// Extensions are matched case-insensitively, except for compress(1)'s .Z, which is distinct from .z.
 func compressionFlag(path string) string {
 	lower := strings.ToLower(path)
 	switch {
+	case strings.HasSuffix(path, ".tar.Z"), strings.HasSuffix(path, ".tZ"):
+		return "-Z"
 	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
 		return "-z"
 	case strings.HasSuffix(lower, ".tar.bz2"), strings.HasSuffix(lower, ".tbz"), strings.HasSuffix(lower, ".tbz2"):
@@ -44,9 +304,105 @@ func compressionFlag(path string) string {
 		return "--zstd"
 	case strings.HasSuffix(lower, ".tar.lz"), strings.HasSuffix(lower, ".tlz"):
 		return "--lzip"
-	case strings.HasSuffix(lower, ".tar.Z"), strings.HasSuffix(lower, ".tZ"):
-		return "-Z"
+	case strings.HasSuffix(lower, ".tar.lz4"), strings.HasSuffix(lower, ".tlz4"):
+		return "--lz4"
+	case strings.HasSuffix(lower, ".tar.br"):
+		return "--brotli"
 	default:
 		return "-a"
 	}
 }
+
+// Magic numbers used to detect the compression of a tarball when its name is not conclusive.
+var compressionMagic = []struct {
+	magic []byte
+	flag  string
+}{
+	{[]byte{0x1f, 0x8b}, "-z"},
+	{[]byte("BZh"), "-j"},
+	{[]byte{0xfd, '7', 'z', 'X', 'Z', 0x00}, "-J"},
+	{[]byte{0x28, 0xb5, 0x2f, 0xfd}, "--zstd"},
+	{[]byte("LZIP"), "--lzip"},
+	{[]byte{0x1f, 0x9d}, "-Z"},
+	{[]byte{0x04, 0x22, 0x4d, 0x18}, "--lz4"},
+}
+
+// decompress wraps r in a decompressor selected by a tar compression flag as returned by compressionFlag. For
+// "-a" the compression is detected from the stream contents.
+//
+// The returned ReadCloser must be closed, which reports any failure of an external decompressor.
+func decompress(ctx context.Context, r io.Reader, flag string) (io.ReadCloser, error) {
+	if flag == "-a" {
+		br := bufio.NewReader(r)
+		flag = ""
+		for _, m := range compressionMagic {
+			if peek, _ := br.Peek(len(m.magic)); bytes.Equal(peek, m.magic) { //nolint:errcheck // short reads simply don't match
+				flag = m.flag
+				break
+			}
+		}
+		r = br
+	}
+	switch flag {
+	case "-z":
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("gzip: %w", err)
+		}
+		return gz, nil
+	case "-j":
+		return io.NopCloser(bzip2.NewReader(r)), nil
+	case "-J":
+		return decompressCommand(ctx, r, "xz", "-dc")
+	case "--zstd":
+		return decompressCommand(ctx, r, "zstd", "-dc")
+	case "--lzip":
+		return decompressCommand(ctx, r, "lzip", "-dc")
+	case "--lz4":
+		return io.NopCloser(newLZ4Reader(r)), nil
+	case "--brotli":
+		return decompressCommand(ctx, r, "brotli", "-dc")
+	case "-Z":
+		if _, err := exec.LookPath("gzip"); err == nil {
+			return decompressCommand(ctx, r, "gzip", "-dc")
+		}
+		lzw, err := newLZWReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(lzw), nil
+	default:
+		return io.NopCloser(r), nil
+	}
+}
+
+// decompressCommand pipes r through an external decompressor.
+func decompressCommand(ctx context.Context, r io.Reader, name string, args ...string) (io.ReadCloser, error) {
+	stderr := &bytes.Buffer{}
+	cmd := command(ctx, name, args...)
+	cmd.Stdin = r
+	cmd.Stderr = stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("%s failed: %w", name, err)
+	}
+	return &commandReader{ReadCloser: stdout, cmd: cmd, stderr: stderr}, nil
+}
+
+type commandReader struct {
+	io.ReadCloser
+	cmd    *exec.Cmd
+	stderr *bytes.Buffer
+}
+
+func (c *commandReader) Close() error {
+	// Drain any trailing output so the command doesn't fail writing to a closed pipe.
+	_, _ = io.Copy(io.Discard, c.ReadCloser)
+	if err := c.cmd.Wait(); err != nil {
+		return fmt.Errorf("%s failed: %w: %s", c.cmd.Path, err, c.stderr)
+	}
+	return nil
+}
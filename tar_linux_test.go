@@ -0,0 +1,39 @@
+package getit //nolint:testpackage
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestExtractTarSparseHoles(t *testing.T) {
+	for _, format := range []string{"gnu", "pax"} {
+		t.Run(format, func(t *testing.T) {
+			dest := fetchSparseTar(t, format, false)
+			info, err := os.Stat(filepath.Join(dest, "disk.img"))
+			assert.NoError(t, err)
+			stat, ok := info.Sys().(*syscall.Stat_t)
+			assert.True(t, ok)
+			if !supportsHoles(t, dest) {
+				t.Skip("filesystem does not support sparse files")
+			}
+			assert.True(t, stat.Blocks*512 < 64<<10, "expected holes, got %d blocks", stat.Blocks)
+		})
+	}
+}
+
+// supportsHoles reports whether the filesystem holding dir stores files with holes sparsely.
+func supportsHoles(t *testing.T, dir string) bool {
+	t.Helper()
+	path := filepath.Join(dir, "probe")
+	assert.NoError(t, os.WriteFile(path, nil, 0o600))
+	defer os.Remove(path)
+	assert.NoError(t, os.Truncate(path, 1<<20))
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	return ok && stat.Blocks*512 < info.Size()
+}
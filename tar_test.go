@@ -1,12 +1,18 @@
 package getit //nolint:testpackage
 
 import (
+	"archive/tar"
+	"bytes"
 	"context"
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/alecthomas/assert/v2"
@@ -34,6 +40,15 @@ func TestTARMatch(t *testing.T) {
 		{name: "TZ", path: "/archive.tZ", expected: true},
 		{name: "NestedPath", path: "/some/deep/path/archive.tar.gz", expected: true},
 		{name: "WithQueryParams", path: "/archive.tar.gz?token=abc", expected: true},
+		{name: "WithFragment", path: "/archive.tar.gz#section", expected: true},
+		{name: "WithSubdir", path: "/archive.tar.gz//subdir", expected: true},
+		{name: "Uppercase", path: "/ARCHIVE.TAR.GZ", expected: true},
+		{name: "UppercaseTgz", path: "/archive.TGZ", expected: true},
+		{name: "TarLz4", path: "/archive.tar.lz4", expected: true},
+		{name: "Tlz4", path: "/archive.tlz4", expected: true},
+		{name: "TarSignature", path: "/archive.tar.gz.sig", expected: false},
+		{name: "TarInDirectory", path: "/releases.tar/archive.zip", expected: false},
+		{name: "TarballZip", path: "/my.tarball.zip", expected: false},
 		{name: "ZipFile", path: "/archive.zip", expected: false},
 		{name: "PlainFile", path: "/file.txt", expected: false},
 		{name: "TarInName", path: "/tarball.zip", expected: false},
@@ -69,10 +84,12 @@ func TestCompressionFlag(t *testing.T) {
 		{name: "Tzstd", path: "/archive.tzstd", expected: "--zstd"},
 		{name: "TarLz", path: "/archive.tar.lz", expected: "--lzip"},
 		{name: "Tlz", path: "/archive.tlz", expected: "--lzip"},
-		// NOTE: .tar.Z and .tZ don't work because compressionFlag() lowercases the path,
-		// causing the uppercase Z check to never match. This appears to be a bug.
-		{name: "TarZ", path: "/archive.tar.Z", expected: "-a"},
-		{name: "TZ", path: "/archive.tZ", expected: "-a"},
+		{name: "TarLz4", path: "/archive.tar.lz4", expected: "--lz4"},
+		{name: "Tlz4", path: "/archive.tlz4", expected: "--lz4"},
+		{name: "TarBr", path: "/archive.tar.br", expected: "--brotli"},
+		{name: "TarZ", path: "/archive.tar.Z", expected: "-Z"},
+		{name: "TZ", path: "/archive.tZ", expected: "-Z"},
+		{name: "TarLowercaseZ", path: "/archive.tar.z", expected: "-a"},
 		{name: "PlainTar", path: "/archive.tar", expected: "-a"},
 		{name: "Unknown", path: "/archive.tar.unknown", expected: "-a"},
 	}
@@ -93,6 +110,8 @@ func TestTARFetch(t *testing.T) {
 		{name: "TarGz", filename: "archive.tar.gz"},
 		{name: "TarBz2", filename: "archive.tar.bz2"},
 		{name: "PlainTar", filename: "archive.tar"},
+		{name: "TarZ", filename: "archive.tar.Z"},
+		{name: "TarLz4", filename: "archive.tar.lz4"},
 	}
 
 	for _, tt := range tests {
@@ -125,6 +144,51 @@ func TestTARFetch(t *testing.T) {
 	}
 }
 
+func TestTARFetchSubDir(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "archive.tar")
+	writeTar := func(headers ...*tar.Header) {
+		buf := &bytes.Buffer{}
+		tw := tar.NewWriter(buf)
+		for _, hdr := range headers {
+			assert.NoError(t, tw.WriteHeader(hdr))
+			_, err := tw.Write(bytes.Repeat([]byte("x"), int(hdr.Size)))
+			assert.NoError(t, err)
+		}
+		assert.NoError(t, tw.Close())
+		assert.NoError(t, os.WriteFile(archive, buf.Bytes(), 0o600))
+	}
+	u := &url.URL{Scheme: "file", Path: "/" + strings.TrimPrefix(filepath.ToSlash(archive), "/")}
+
+	writeTar(
+		&tar.Header{Name: "./", Typeflag: tar.TypeDir, Mode: 0o755},
+		&tar.Header{Name: "./root.txt", Typeflag: tar.TypeReg, Mode: 0o644, Size: 4},
+		&tar.Header{Name: "./sub/", Typeflag: tar.TypeDir, Mode: 0o755},
+		&tar.Header{Name: "./sub/file.txt", Typeflag: tar.TypeReg, Mode: 0o644, Size: 4},
+		&tar.Header{Name: "./sub/link.txt", Typeflag: tar.TypeLink, Linkname: "./sub/file.txt"},
+		&tar.Header{Name: "./subdir/other.txt", Typeflag: tar.TypeReg, Mode: 0o644, Size: 4},
+	)
+	dest := t.TempDir()
+	assert.NoError(t, NewTAR().Fetch(context.Background(), Source{URL: u, SubDir: "sub/"}, dest))
+	entries, err := os.ReadDir(dest)
+	assert.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	assert.Equal(t, []string{"file.txt", "link.txt"}, names)
+
+	err = NewTAR().Fetch(context.Background(), Source{URL: u, SubDir: "missing"}, t.TempDir())
+	assert.EqualError(t, err, "subdirectory missing not found in archive")
+
+	// Hardlinks to entries outside the subdirectory can't be extracted.
+	writeTar(
+		&tar.Header{Name: "root.txt", Typeflag: tar.TypeReg, Mode: 0o644, Size: 4},
+		&tar.Header{Name: "sub/root.txt", Typeflag: tar.TypeLink, Linkname: "root.txt"},
+	)
+	err = NewTAR().Fetch(context.Background(), Source{URL: u, SubDir: "sub"}, t.TempDir())
+	assert.EqualError(t, err, "hardlink sub/root.txt: target is outside subdirectory sub")
+}
+
 func TestTARFetchHTTPError(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusNotFound)
@@ -176,3 +240,221 @@ func TestTARFetchCancelledContext(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "context canceled")
 }
+
+func TestDecompressDetectsCompression(t *testing.T) {
+	for _, filename := range []string{"archive.tar", "archive.tar.gz", "archive.tar.bz2", "archive.tar.Z", "archive.tar.lz4"} {
+		t.Run(filename, func(t *testing.T) {
+			f, err := os.Open(filepath.Join("testdata", filename))
+			assert.NoError(t, err)
+			defer f.Close()
+
+			r, err := decompress(context.Background(), f, "-a")
+			assert.NoError(t, err)
+			dest := t.TempDir()
+			err = extractTar(context.Background(), r, dest, "", newLimiter(Limits{}, nil), false)
+			assert.NoError(t, err)
+			assert.NoError(t, r.Close())
+
+			content, err := os.ReadFile(filepath.Join(dest, "file.txt"))
+			assert.NoError(t, err)
+			assert.Equal(t, "hello from test\n", string(content))
+		})
+	}
+}
+
+func TestDecompressExternal(t *testing.T) {
+	tests := []struct {
+		tool string
+		ext  string
+	}{
+		{tool: "xz", ext: ".tar.xz"},
+		{tool: "zstd", ext: ".tar.zst"},
+		{tool: "brotli", ext: ".tar.br"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tool, func(t *testing.T) {
+			if _, err := exec.LookPath(tt.tool); err != nil {
+				t.Skip(tt.tool + " not installed")
+			}
+			raw, err := os.Open(filepath.Join("testdata", "archive.tar"))
+			assert.NoError(t, err)
+			defer raw.Close()
+			cmd := exec.Command(tt.tool, "-c")
+			cmd.Stdin = raw
+			compressed, err := cmd.Output()
+			assert.NoError(t, err)
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write(compressed)
+			}))
+			defer server.Close()
+
+			u, err := url.Parse(server.URL + "/archive" + tt.ext)
+			assert.NoError(t, err)
+			dest := t.TempDir()
+			err = NewTAR().Fetch(context.Background(), Source{URL: u}, dest)
+			assert.NoError(t, err)
+
+			content, err := os.ReadFile(filepath.Join(dest, "nested.txt"))
+			assert.NoError(t, err)
+			assert.Equal(t, "nested content\n", string(content))
+		})
+	}
+}
+
+func TestDecompressExternalFailure(t *testing.T) {
+	if _, err := exec.LookPath("xz"); err != nil {
+		t.Skip("xz not installed")
+	}
+	r, err := decompress(context.Background(), strings.NewReader("not xz"), "-J")
+	assert.NoError(t, err)
+	_, _ = io.Copy(io.Discard, r)
+	err = r.Close()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "xz")
+}
+
+func TestExtractTarLimits(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "archive.tar"))
+	assert.NoError(t, err)
+	defer f.Close()
+
+	err = extractTar(context.Background(), f, t.TempDir(), "", newLimiter(Limits{MaxFileSize: 10}, nil), false)
+	var limitErr *LimitError
+	assert.True(t, errors.As(err, &limitErr), "expected a LimitError, got %v", err)
+	assert.Equal(t, "MaxFileSize", limitErr.Limit)
+}
+
+// fetchSparseTar fetches a GNU tar fixture containing a sparse disk image and a hardlink, compressed with xz if
+// compress is set, returning the destination.
+func fetchSparseTar(t *testing.T, format string, compress bool) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "sparse-"+format+".tar"))
+	assert.NoError(t, err)
+	filename := "sparse.tar"
+	if compress {
+		if _, err := exec.LookPath("xz"); err != nil {
+			t.Skip("xz not installed")
+		}
+		cmd := exec.Command("xz", "-c")
+		cmd.Stdin = bytes.NewReader(data)
+		data, err = cmd.Output()
+		assert.NoError(t, err)
+		filename += ".xz"
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(data)
+	}))
+	t.Cleanup(server.Close)
+
+	u, err := url.Parse(server.URL + "/" + filename)
+	assert.NoError(t, err)
+	dest := t.TempDir()
+	err = NewTAR().Fetch(context.Background(), Source{URL: u}, dest)
+	assert.NoError(t, err)
+	return dest
+}
+
+func TestExtractTarSparseAndHardlinks(t *testing.T) {
+	expected := make([]byte, 2<<20)
+	copy(expected[512<<10:], "data")
+	for _, format := range []string{"gnu", "pax"} {
+		for _, compress := range []bool{false, true} {
+			name := format
+			if compress {
+				name += "/xz"
+			}
+			t.Run(name, func(t *testing.T) {
+				dest := fetchSparseTar(t, format, compress)
+				content, err := os.ReadFile(filepath.Join(dest, "disk.img"))
+				assert.NoError(t, err)
+				assert.True(t, bytes.Equal(expected, content), "sparse file content mismatch")
+
+				file, err := os.Stat(filepath.Join(dest, "file.txt"))
+				assert.NoError(t, err)
+				link, err := os.Stat(filepath.Join(dest, "link.txt"))
+				assert.NoError(t, err)
+				assert.True(t, os.SameFile(file, link), "expected link.txt to be a hardlink to file.txt")
+			})
+		}
+	}
+}
+
+func TestExtractTarRejectsSymlinkTraversal(t *testing.T) {
+	outside := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(outside, "secret"), []byte("secret"), 0o600))
+	link := &tar.Header{Name: "link", Typeflag: tar.TypeSymlink, Linkname: outside}
+	tests := []struct {
+		name    string
+		headers []*tar.Header
+		// escapes is set if the error is reported as an escape, rather than as the symlink colliding with a
+		// directory extracted before it.
+		escapes bool
+	}{
+		{name: "File", headers: []*tar.Header{link, {Name: "link/pwned", Typeflag: tar.TypeReg, Mode: 0o644, Size: 5}}},
+		{name: "Dir", headers: []*tar.Header{link, {Name: "link/pwned", Typeflag: tar.TypeDir, Mode: 0o755}}},
+		{name: "Symlink", escapes: true, headers: []*tar.Header{link, {Name: "link/pwned", Typeflag: tar.TypeSymlink, Linkname: "target"}}},
+		{name: "Hardlink", escapes: true, headers: []*tar.Header{
+			{Name: "file", Typeflag: tar.TypeReg, Mode: 0o644, Size: 5},
+			link,
+			{Name: "link/pwned", Typeflag: tar.TypeLink, Linkname: "file"},
+		}},
+		{name: "HardlinkTarget", escapes: true, headers: []*tar.Header{link, {Name: "stolen", Typeflag: tar.TypeLink, Linkname: "link/secret"}}},
+		{name: "NestedSymlinks", escapes: true, headers: []*tar.Header{
+			{Name: "a", Typeflag: tar.TypeSymlink, Linkname: "b"},
+			{Name: "b", Typeflag: tar.TypeSymlink, Linkname: outside},
+			{Name: "a/pwned", Typeflag: tar.TypeSymlink, Linkname: "target"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			tw := tar.NewWriter(buf)
+			for _, hdr := range tt.headers {
+				assert.NoError(t, tw.WriteHeader(hdr))
+				if hdr.Size > 0 {
+					_, err := tw.Write([]byte("pwned"))
+					assert.NoError(t, err)
+				}
+			}
+			assert.NoError(t, tw.Close())
+
+			dest := t.TempDir()
+			err := extractTar(context.Background(), buf, dest, "", newLimiter(Limits{}, nil), false)
+			assert.Error(t, err)
+			if tt.escapes {
+				assert.Contains(t, err.Error(), "escapes destination")
+			}
+			_, err = os.Lstat(filepath.Join(outside, "pwned"))
+			assert.True(t, errors.Is(err, os.ErrNotExist), "entry written outside the destination")
+			_, err = os.Lstat(filepath.Join(dest, "stolen"))
+			assert.True(t, errors.Is(err, os.ErrNotExist), "hardlink created to a file outside the destination")
+		})
+	}
+}
+
+func TestExtractTarSymlinksAfterTargets(t *testing.T) {
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	for _, hdr := range []*tar.Header{
+		{Name: "hardlink", Typeflag: tar.TypeLink, Linkname: "symlink"},
+		{Name: "symlink", Typeflag: tar.TypeSymlink, Linkname: "dir/file"},
+		{Name: "dir/file", Typeflag: tar.TypeReg, Mode: 0o644, Size: 4},
+	} {
+		assert.NoError(t, tw.WriteHeader(hdr))
+		if hdr.Size > 0 {
+			_, err := tw.Write([]byte("data"))
+			assert.NoError(t, err)
+		}
+	}
+	assert.NoError(t, tw.Close())
+
+	dest := t.TempDir()
+	assert.NoError(t, extractTar(context.Background(), buf, dest, "", newLimiter(Limits{}, nil), false))
+	for _, name := range []string{"symlink", "hardlink"} {
+		content, err := os.ReadFile(filepath.Join(dest, name))
+		assert.NoError(t, err, name)
+		assert.Equal(t, "data", string(content), name)
+	}
+}
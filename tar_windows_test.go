@@ -0,0 +1,29 @@
+package getit_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/block/getit"
+)
+
+func TestExtractTarRejectsWindowsNames(t *testing.T) {
+	for _, name := range []string{"C:/evil.txt", "C:evil.txt", "file.txt:stream", "NUL", "sub/COM1"} {
+		t.Run(name, func(t *testing.T) {
+			body := tarball(t, name, "evil\n")
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = w.Write(body)
+			}))
+			defer server.Close()
+
+			fetcher := getit.New([]getit.Resolver{getit.NewTAR()}, nil)
+			err := fetcher.Fetch(context.Background(), server.URL+"/archive.tar.gz", t.TempDir())
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), "path escapes destination")
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package getit
+
+import (
+	"archive/tar"
+	"cmp"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"maps"
+	"os/user"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// POSIX ACLs are stored by Linux in the system.posix_acl_access and system.posix_acl_default extended attributes.
+const (
+	aclAccessXattr  = "system.posix_acl_access"
+	aclDefaultXattr = "system.posix_acl_default"
+)
+
+// Tags and version of the binary form of a POSIX ACL, as in linux/posix_acl_xattr.h.
+const (
+	aclXattrVersion = 2
+	aclUserObj      = 0x01
+	aclUser         = 0x02
+	aclGroupObj     = 0x04
+	aclGroup        = 0x08
+	aclMask         = 0x10
+	aclOther        = 0x20
+	aclUndefinedID  = 0xffffffff
+)
+
+// applyTarXattrs sets the extended attributes and POSIX ACLs recorded in the PAX records of a tar entry on path, as
+// written by GNU tar and bsdtar with --xattrs and --acls.
+func applyTarXattrs(path string, hdr *tar.Header) error {
+	for _, key := range slices.Sorted(maps.Keys(hdr.PAXRecords)) {
+		value := hdr.PAXRecords[key]
+		var name string
+		var data []byte
+		switch {
+		case strings.HasPrefix(key, "SCHILY.xattr."):
+			name, data = strings.TrimPrefix(key, "SCHILY.xattr."), []byte(value)
+		case key == "SCHILY.acl.access" || key == "SCHILY.acl.default":
+			if key == "SCHILY.acl.default" && hdr.Typeflag != tar.TypeDir {
+				continue
+			}
+			acl, err := parseACL(value)
+			if err != nil {
+				return fmt.Errorf("%s: %s: %w", hdr.Name, key, err)
+			}
+			name, data = aclAccessXattr, acl
+			if key == "SCHILY.acl.default" {
+				name = aclDefaultXattr
+			}
+		default:
+			continue
+		}
+		if supported, err := setXattr(path, name, data); err != nil || !supported {
+			return err
+		}
+	}
+	return nil
+}
+
+// aclEntry is an entry of a POSIX ACL.
+type aclEntry struct {
+	tag  uint16
+	perm uint16
+	id   uint32
+}
+
+// parseACL converts a POSIX ACL in its text form, eg. "user::rw-,user:1000:r--,group::r--,mask::r--,other::---",
+// to the binary form stored in extended attributes. Entries may be separated by commas or newlines, qualifiers may
+// be names or numeric IDs, and a trailing numeric ID as written by star takes precedence over a name.
+func parseACL(text string) ([]byte, error) {
+	var entries []aclEntry
+	for field := range strings.FieldsFuncSeq(text, func(r rune) bool { return r == ',' || r == '\n' }) {
+		field, _, _ = strings.Cut(field, "#")
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		parts := strings.Split(field, ":")
+		if len(parts) < 3 || len(parts) > 4 {
+			return nil, fmt.Errorf("invalid ACL entry %q", field)
+		}
+		entry := aclEntry{id: aclUndefinedID}
+		qualified := parts[1] != ""
+		switch parts[0] {
+		case "user", "u":
+			entry.tag = aclUserObj
+			if qualified {
+				entry.tag = aclUser
+			}
+		case "group", "g":
+			entry.tag = aclGroupObj
+			if qualified {
+				entry.tag = aclGroup
+			}
+		case "mask", "m":
+			entry.tag = aclMask
+		case "other", "o":
+			entry.tag = aclOther
+		default:
+			return nil, fmt.Errorf("invalid ACL entry %q", field)
+		}
+		if qualified {
+			qualifier := parts[1]
+			if len(parts) == 4 {
+				qualifier = parts[3]
+			}
+			id, err := aclID(qualifier, entry.tag == aclUser)
+			if err != nil {
+				return nil, fmt.Errorf("invalid ACL entry %q: %w", field, err)
+			}
+			entry.id = id
+		}
+		for _, c := range parts[2] {
+			switch c {
+			case 'r':
+				entry.perm |= 4
+			case 'w':
+				entry.perm |= 2
+			case 'x':
+				entry.perm |= 1
+			case '-':
+			default:
+				return nil, fmt.Errorf("invalid ACL permissions %q", parts[2])
+			}
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) == 0 {
+		return nil, errors.New("empty ACL")
+	}
+	// The kernel requires entries to be ordered by tag, then ID.
+	slices.SortFunc(entries, func(a, b aclEntry) int {
+		return cmp.Or(cmp.Compare(a.tag, b.tag), cmp.Compare(a.id, b.id))
+	})
+	data := binary.LittleEndian.AppendUint32(nil, aclXattrVersion)
+	for _, entry := range entries {
+		data = binary.LittleEndian.AppendUint16(data, entry.tag)
+		data = binary.LittleEndian.AppendUint16(data, entry.perm)
+		data = binary.LittleEndian.AppendUint32(data, entry.id)
+	}
+	return data, nil
+}
+
+// aclID resolves the qualifier of an ACL entry to a uid or gid.
+func aclID(qualifier string, isUser bool) (uint32, error) {
+	if id, err := strconv.ParseUint(qualifier, 10, 32); err == nil {
+		return uint32(id), nil
+	}
+	var id string
+	if isUser {
+		u, err := user.Lookup(qualifier)
+		if err != nil {
+			return 0, err //nolint:wrapcheck // wrapped by the caller
+		}
+		id = u.Uid
+	} else {
+		g, err := user.LookupGroup(qualifier)
+		if err != nil {
+			return 0, err //nolint:wrapcheck // wrapped by the caller
+		}
+		id = g.Gid
+	}
+	parsed, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("unsupported ID %q for %s", id, qualifier)
+	}
+	return uint32(parsed), nil
+}
@@ -0,0 +1,50 @@
+package getit
+
+import (
+	"fmt"
+	"regexp"
+	"runtime"
+)
+
+// Platform a source template is expanded for, named as by GOOS and GOARCH, eg. "linux" and "amd64".
+type Platform struct {
+	OS   string
+	Arch string
+}
+
+// CurrentPlatform returns the platform getit is running on.
+func CurrentPlatform() Platform { return Platform{OS: runtime.GOOS, Arch: runtime.GOARCH} }
+
+func (p Platform) String() string { return p.OS + "/" + p.Arch }
+
+var templateVarRe = regexp.MustCompile(`\{([a-z]+)\}`)
+
+// ExpandTemplate expands the variables {os}, {arch} and {version} in source for platform, so that a single
+// [BatchFetch] or [LockEntry] covers the release assets of every platform:
+//
+//	https://example.com/releases/v{version}/tool-{os}-{arch}.tar.gz
+//
+// It fails if source references any other variable, or {version} when version is empty.
+func ExpandTemplate(source string, platform Platform, version string) (string, error) {
+	var err error
+	expanded := templateVarRe.ReplaceAllStringFunc(source, func(ref string) string {
+		switch name := ref[1 : len(ref)-1]; name {
+		case "os":
+			return platform.OS
+		case "arch":
+			return platform.Arch
+		case "version":
+			if version == "" {
+				err = fmt.Errorf("template variable %q requires a version", name)
+			}
+			return version
+		default:
+			err = fmt.Errorf("unknown template variable %q", name)
+			return ref
+		}
+	})
+	if err != nil {
+		return "", fmt.Errorf("expanding %s: %w", redactSource(source), err)
+	}
+	return expanded, nil
+}
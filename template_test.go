@@ -0,0 +1,68 @@
+package getit_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/block/getit"
+)
+
+func TestExpandTemplate(t *testing.T) {
+	linux := getit.Platform{OS: "linux", Arch: "arm64"}
+	tests := []struct {
+		name     string
+		source   string
+		version  string
+		expected string
+		err      string
+	}{
+		{name: "Platform", source: "https://example.com/tool-{os}-{arch}.tar.gz", expected: "https://example.com/tool-linux-arm64.tar.gz"},
+		{name: "Version", source: "https://example.com/v{version}/tool-{os}.zip", version: "1.2.0", expected: "https://example.com/v1.2.0/tool-linux.zip"},
+		{name: "NoVariables", source: "github.com/org/repo?ref=main", version: "1.2.0", expected: "github.com/org/repo?ref=main"},
+		{name: "MissingVersion", source: "https://example.com/v{version}/tool.zip", err: `template variable "version" requires a version`},
+		{name: "UnknownVariable", source: "https://example.com/tool-{platform}.zip", err: `unknown template variable "platform"`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expanded, err := getit.ExpandTemplate(test.source, linux, test.version)
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), test.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, expanded)
+		})
+	}
+}
+
+func TestFetchAllTemplate(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "archive.tar.gz"))
+	assert.NoError(t, err)
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+
+	fetcher := getit.New([]getit.Resolver{getit.NewTAR()}, nil)
+	fetches := []getit.BatchFetch{
+		{Source: server.URL + "/v{version}/tool-{os}-{arch}.tar.gz", Dest: t.TempDir(), Version: "1.2.0"},
+		{Source: server.URL + "/v{version}/tool.tar.gz", Dest: t.TempDir()},
+	}
+	report, err := fetcher.FetchAll(context.Background(), fetches, getit.BatchOptions{
+		Concurrency: 1,
+		Platform:    getit.Platform{OS: "darwin", Arch: "arm64"},
+	})
+	assert.Error(t, err)
+	assert.Contains(t, report.Results[1].Err.Error(), `template variable "version" requires a version`)
+	assert.NoError(t, report.Results[0].Err)
+	assert.Equal(t, []string{"/v1.2.0/tool-darwin-arm64.tar.gz"}, paths)
+}
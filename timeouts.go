@@ -0,0 +1,89 @@
+package getit
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Timeouts bounds individual phases of a fetch, in addition to any deadline on the overall context.
+//
+// A zero value for any field disables that timeout.
+type Timeouts struct {
+	// Connect bounds establishing a connection, including the TLS handshake and waiting for response headers.
+	Connect time.Duration
+	// Download bounds transferring a remote source, including git clones.
+	Download time.Duration
+	// Extract bounds unpacking or copying into the destination.
+	Extract time.Duration
+}
+
+// WithTimeouts sets per-phase timeouts on a [Fetcher].
+func WithTimeouts(timeouts Timeouts) Option {
+	return func(f *Fetcher) { f.config.timeouts = timeouts }
+}
+
+// TimeoutError is returned when a phase of a fetch exceeds its configured timeout.
+type TimeoutError struct {
+	Phase   string
+	Timeout time.Duration
+}
+
+func (t *TimeoutError) Error() string {
+	return fmt.Sprintf("%s timed out after %s", t.Phase, t.Timeout)
+}
+
+// withPhaseTimeout returns a context that is cancelled with a *TimeoutError once timeout elapses.
+func withPhaseTimeout(ctx context.Context, phase string, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeoutCause(ctx, timeout, &TimeoutError{Phase: phase, Timeout: timeout})
+}
+
+// contextError returns the cause of a done context, so phase timeouts are reported as a *TimeoutError.
+func contextError(ctx context.Context) error {
+	if ctx.Err() == nil {
+		return nil
+	}
+	return fmt.Errorf("context: %w", context.Cause(ctx))
+}
+
+// contextReader fails reads once its context is done, so that long copies observe cancellation.
+type contextReader struct {
+	ctx context.Context //nolint:containedctx // the reader is scoped to a single operation
+	r   io.Reader
+}
+
+func (c *contextReader) Read(p []byte) (int, error) {
+	if err := contextError(c.ctx); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p) //nolint:wrapcheck // io.Reader errors such as io.EOF must not be wrapped
+}
+
+// contextReadSeeker is a contextReader that can also seek, eg. so that archive/tar skips entries by seeking.
+type contextReadSeeker struct {
+	contextReader
+	seeker io.Seeker
+}
+
+func (c *contextReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	if err := contextError(c.ctx); err != nil {
+		return 0, err
+	}
+	return c.seeker.Seek(offset, whence) //nolint:wrapcheck // passed through to archive/tar
+}
+
+// timeoutBody wraps a response body so that reads observe phase timeouts and closing releases them.
+type timeoutBody struct {
+	contextReader
+	body   io.Closer
+	cancel context.CancelFunc
+}
+
+func (t *timeoutBody) Close() error {
+	defer t.cancel()
+	return t.body.Close() //nolint:wrapcheck // passthrough of the underlying body
+}
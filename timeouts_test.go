@@ -0,0 +1,36 @@
+package getit_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/block/getit"
+)
+
+func TestWithTimeoutsDownload(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	fetcher := getit.New([]getit.Resolver{getit.NewTAR()}, nil, getit.WithTimeouts(getit.Timeouts{
+		Download: 100 * time.Millisecond,
+	}))
+	err := fetcher.Fetch(context.Background(), server.URL+"/archive.tar.gz", t.TempDir())
+	var timeout *getit.TimeoutError
+	assert.True(t, errors.As(err, &timeout), "expected a TimeoutError, got %v", err)
+	assert.Equal(t, "download", timeout.Phase)
+}
@@ -0,0 +1,106 @@
+package getit
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// deterministicTime is the modification time applied to all files when [FetchOptions.Deterministic] is set. It is
+// the earliest time representable in a zip archive.
+var deterministicTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// timestamper applies modification times to written entries according to FetchOptions.
+//
+// Directory times are applied by finish, as writing their contents would otherwise update them.
+type timestamper struct {
+	options FetchOptions
+	dirs    []string
+	times   []time.Time
+}
+
+func newTimestamper(options FetchOptions) *timestamper {
+	return &timestamper{options: options}
+}
+
+// record the source modification time of an entry that has been written to path.
+func (t *timestamper) record(path string, mode fs.FileMode, mtime time.Time) error {
+	switch {
+	case t.options.Deterministic:
+		mtime = deterministicTime
+	case !t.options.PreserveTimes || mtime.IsZero():
+		return nil
+	}
+	switch {
+	case mode&fs.ModeSymlink != 0:
+		// Setting the time of a link rather than its target is not portable.
+		return nil
+	case mode.IsDir():
+		t.dirs = append(t.dirs, path)
+		t.times = append(t.times, mtime)
+		return nil
+	default:
+		return chtime(path, mtime)
+	}
+}
+
+// finish applies deferred directory modification times, deepest first.
+func (t *timestamper) finish() error {
+	for i, dir := range slices.Backward(t.dirs) {
+		if err := chtime(dir, t.times[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stampTree sets the modification time of everything under dir, for resolvers that can't preserve source times.
+func stampTree(ctx context.Context, dir string, mtime time.Time) error {
+	ts := newTimestamper(FetchOptions{PreserveTimes: true})
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := contextError(ctx); err != nil {
+			return err
+		}
+		return ts.record(path, d.Type(), mtime)
+	})
+	if err != nil {
+		return fmt.Errorf("setting modification times in %s: %w", dir, err)
+	}
+	return ts.finish()
+}
+
+// normalizeDirs applies the permissions and modification time of [FetchOptions.Deterministic] to every directory under
+// dir, including any that resolvers created implicitly as the parents of other entries.
+func normalizeDirs(ctx context.Context, dir string) error {
+	ts := newTimestamper(FetchOptions{Deterministic: true})
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return err
+		}
+		if err := contextError(ctx); err != nil {
+			return err
+		}
+		if err := os.Chmod(path, Permissions{Normalize: true}.mode(fs.ModeDir)); err != nil {
+			return err //nolint:wrapcheck // wrapped below
+		}
+		return ts.record(path, fs.ModeDir, deterministicTime)
+	})
+	if err != nil {
+		return fmt.Errorf("normalizing directories in %s: %w", dir, err)
+	}
+	return ts.finish()
+}
+
+func chtime(path string, mtime time.Time) error {
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		return fmt.Errorf("chtimes %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package getit_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/block/getit"
+)
+
+func TestFetchWithOptionsTimes(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "archive.tar"))
+	assert.NoError(t, err)
+	f, err := os.Open(filepath.Join("testdata", "archive.tar"))
+	assert.NoError(t, err)
+	defer f.Close()
+	var archived time.Time
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		assert.NoError(t, err)
+		if hdr.Name == "./file.txt" {
+			archived = hdr.ModTime
+			break
+		}
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		options  getit.FetchOptions
+		expected time.Time
+	}{
+		{name: "PreserveTimes", options: getit.FetchOptions{PreserveTimes: true}, expected: archived},
+		{name: "Deterministic", options: getit.FetchOptions{PreserveTimes: true, Deterministic: true}, expected: time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	fetcher := getit.New([]getit.Resolver{getit.NewTAR()}, nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := t.TempDir()
+			_, err := fetcher.FetchWithOptions(context.Background(), server.URL+"/archive.tar", dest, tt.options)
+			assert.NoError(t, err)
+
+			info, err := os.Stat(filepath.Join(dest, "file.txt"))
+			assert.NoError(t, err)
+			assert.True(t, tt.expected.Equal(info.ModTime()), "expected %s, got %s", tt.expected, info.ModTime())
+		})
+	}
+}
+
+func TestFetchWithOptionsPreserveTimesFile(t *testing.T) {
+	srcDir := t.TempDir()
+	mtime := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	err := os.MkdirAll(filepath.Join(srcDir, "subdir"), 0o755)
+	assert.NoError(t, err)
+	err = os.WriteFile(filepath.Join(srcDir, "subdir", "file.txt"), []byte("hello\n"), 0o644)
+	assert.NoError(t, err)
+	assert.NoError(t, os.Chtimes(filepath.Join(srcDir, "subdir", "file.txt"), mtime, mtime))
+	assert.NoError(t, os.Chtimes(filepath.Join(srcDir, "subdir"), mtime, mtime))
+
+	fetcher := getit.New([]getit.Resolver{getit.NewFile()}, nil)
+	dest := t.TempDir()
+	_, err = fetcher.FetchWithOptions(context.Background(), "file://"+srcDir, dest, getit.FetchOptions{PreserveTimes: true})
+	assert.NoError(t, err)
+
+	for _, path := range []string{"subdir", filepath.Join("subdir", "file.txt")} {
+		info, err := os.Stat(filepath.Join(dest, path))
+		assert.NoError(t, err)
+		assert.True(t, mtime.Equal(info.ModTime()), "%s: expected %s, got %s", path, mtime, info.ModTime())
+	}
+}
+
+func TestFetchWithOptionsDeterministicImplicitDirs(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	assert.NoError(t, tw.WriteHeader(&tar.Header{Name: "a/b/file.txt", Mode: 0o644, Size: 6, ModTime: time.Now()}))
+	_, err := tw.Write([]byte("hello\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, tw.Close())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(buf.Bytes())
+	}))
+	defer server.Close()
+
+	fetcher := getit.New([]getit.Resolver{getit.NewTAR()}, nil)
+	dest := filepath.Join(t.TempDir(), "dest")
+	_, err = fetcher.FetchWithOptions(context.Background(), server.URL+"/archive.tar", dest, getit.FetchOptions{Deterministic: true})
+	assert.NoError(t, err)
+
+	epoch := time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, path := range []string{"a", filepath.Join("a", "b"), filepath.Join("a", "b", "file.txt")} {
+		info, err := os.Stat(filepath.Join(dest, path))
+		assert.NoError(t, err)
+		assert.True(t, epoch.Equal(info.ModTime()), "%s: expected %s, got %s", path, epoch, info.ModTime())
+		if info.IsDir() && runtime.GOOS != "windows" {
+			assert.Equal(t, os.FileMode(0o755), info.Mode().Perm(), path)
+		}
+	}
+}
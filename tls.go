@@ -0,0 +1,93 @@
+package getit
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ErrCertificatePin is returned when a server's certificate chain matches none of the pins configured for its host.
+var ErrCertificatePin = errors.New("certificate does not match pinned public keys")
+
+// TLS configures the TLS connections made by the HTTP-based resolvers, and requests to forge APIs. Git uses its own
+// TLS configuration, so git+https sources are not affected.
+type TLS struct {
+	// RootCAs verify server certificates in place of the system roots. To trust a private CA in addition to the
+	// system roots, add it to the pool returned by [x509.SystemCertPool].
+	RootCAs *x509.CertPool
+	// Certificates are presented to servers that request client authentication (mTLS).
+	Certificates []tls.Certificate
+	// MinVersion is the minimum TLS version accepted, eg. [tls.VersionTLS13]. Defaults to TLS 1.2.
+	MinVersion uint16
+	// Pins maps hosts to the base64-encoded SHA-256 digests of the SubjectPublicKeyInfo of certificates they may
+	// present, as for the "pin-sha256" directive of HTTP Public Key Pinning. Connections to a pinned host fail with
+	// [ErrCertificatePin] unless a certificate in the verified chain matches one of its pins.
+	//
+	// The pin of a certificate can be found with:
+	//
+	//	openssl x509 -in cert.pem -pubkey -noout | openssl pkey -pubin -outform der | openssl dgst -sha256 -binary | base64
+	Pins map[string][]string
+}
+
+// WithTLS configures TLS for HTTP requests made by a [Fetcher].
+func WithTLS(config TLS) Option {
+	return func(f *Fetcher) { f.config.tls = &config }
+}
+
+// clientConfig returns the crypto/tls configuration for t.
+func (t *TLS) clientConfig() *tls.Config {
+	config := &tls.Config{
+		RootCAs:      t.RootCAs,
+		Certificates: t.Certificates,
+		MinVersion:   t.MinVersion,
+	}
+	if config.MinVersion == 0 {
+		config.MinVersion = tls.VersionTLS12
+	}
+	if len(t.Pins) > 0 {
+		config.VerifyConnection = t.verifyPins
+	}
+	return config
+}
+
+// verifyPins checks the verified chain of a connection against the pins for its host.
+func (t *TLS) verifyPins(state tls.ConnectionState) error {
+	hosts := []string{state.ServerName}
+	if state.ServerName == "" && len(state.PeerCertificates) > 0 {
+		// The server name is not recorded for IP addresses, but the certificate was verified for the address
+		// connected to, so it is one of those the certificate names.
+		hosts = hosts[:0]
+		for _, ip := range state.PeerCertificates[0].IPAddresses {
+			hosts = append(hosts, ip.String())
+		}
+	}
+	for _, host := range hosts {
+		pins, ok := t.Pins[host]
+		if ok && !chainMatchesPins(state.VerifiedChains, pins) {
+			return fmt.Errorf("%s: %w", host, ErrCertificatePin)
+		}
+	}
+	return nil
+}
+
+func chainMatchesPins(chains [][]*x509.Certificate, pins []string) bool {
+	for _, chain := range chains {
+		for _, cert := range chain {
+			if slices.Contains(pins, SPKIPin(cert)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SPKIPin returns the pin of a certificate for [TLS.Pins]: the base64-encoded SHA-256 digest of its
+// SubjectPublicKeyInfo.
+func SPKIPin(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
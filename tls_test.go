@@ -0,0 +1,68 @@
+package getit_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/block/getit"
+)
+
+func TestTLS(t *testing.T) {
+	body := tarball(t, "file.txt", "hello\n")
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/mtls.tar.gz" && len(r.TLS.PeerCertificates) == 0 {
+			http.Error(w, "client certificate required", http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write(body)
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequestClientCert, MinVersion: tls.VersionTLS12, MaxVersion: tls.VersionTLS12}
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	defer server.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	pin := getit.SPKIPin(server.Certificate())
+	host := server.Listener.Addr().(*net.TCPAddr).IP.String() //nolint:forcetypeassert // always TCP
+
+	tests := []struct {
+		name   string
+		config *getit.TLS
+		path   string
+		error  string
+	}{
+		{name: "UnknownCA", path: "/archive.tar.gz", error: "certificate signed by unknown authority"},
+		{name: "RootCAs", config: &getit.TLS{RootCAs: roots}, path: "/archive.tar.gz"},
+		{name: "MinVersion", config: &getit.TLS{RootCAs: roots, MinVersion: tls.VersionTLS13}, path: "/archive.tar.gz", error: "protocol version"},
+		{name: "Pinned", config: &getit.TLS{RootCAs: roots, Pins: map[string][]string{host: {"bogus", pin}}}, path: "/archive.tar.gz"},
+		{name: "PinMismatch", config: &getit.TLS{RootCAs: roots, Pins: map[string][]string{host: {"bogus"}}}, path: "/archive.tar.gz", error: "certificate does not match pinned public keys"},
+		{name: "OtherHostPinned", config: &getit.TLS{RootCAs: roots, Pins: map[string][]string{"other.example.com": {"bogus"}}}, path: "/archive.tar.gz"},
+		{name: "NoClientCertificate", config: &getit.TLS{RootCAs: roots}, path: "/mtls.tar.gz", error: "403 Forbidden"},
+		{name: "ClientCertificate", config: &getit.TLS{RootCAs: roots, Certificates: server.TLS.Certificates}, path: "/mtls.tar.gz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var options []getit.Option
+			if tt.config != nil {
+				options = append(options, getit.WithTLS(*tt.config))
+			}
+			fetcher := getit.New([]getit.Resolver{getit.NewTAR()}, nil, options...)
+			err := fetcher.Fetch(context.Background(), server.URL+tt.path, t.TempDir())
+			if tt.error != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.error)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package getit
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// TokenProvider supplies bearer tokens for a host, eg. short-lived GitHub App installation tokens or workload identity
+// tokens. An oauth2.TokenSource can be adapted with:
+//
+//	getit.TokenProviderFunc(func(context.Context) (string, error) {
+//		token, err := source.Token()
+//		if err != nil {
+//			return "", err
+//		}
+//		return token.AccessToken, nil
+//	})
+type TokenProvider interface {
+	// Token returns a currently valid token. It is called for every request, so should return a cached token until
+	// shortly before it expires, as [CachedToken] does.
+	Token(ctx context.Context) (string, error)
+}
+
+// TokenProviderFunc adapts a function to a [TokenProvider].
+type TokenProviderFunc func(ctx context.Context) (string, error)
+
+// Token calls f(ctx).
+func (f TokenProviderFunc) Token(ctx context.Context) (string, error) { return f(ctx) }
+
+// WithTokenProvider authenticates HTTP requests, and git operations with https:// remotes, to host with bearer tokens
+// from provider. host is a host name, eg. "api.github.com", or host:port for a single port.
+//
+// Tokens take precedence over credentials from a [CredentialPrompter], but not over an Authorization header set by
+// [WithHeaders].
+func WithTokenProvider(host string, provider TokenProvider) Option {
+	return func(f *Fetcher) { f.config.ensureCredentials().tokens[host] = provider }
+}
+
+// tokenProvider returns the provider for the host of u, if any.
+func (c *credentials) tokenProvider(u *url.URL) (TokenProvider, bool) {
+	if provider, ok := c.tokens[u.Host]; ok {
+		return provider, true
+	}
+	provider, ok := c.tokens[u.Hostname()]
+	return provider, ok
+}
+
+// tokenRefreshLeeway is how long before it expires that [CachedToken] refreshes a token, so that it does not expire
+// in flight.
+const tokenRefreshLeeway = time.Minute
+
+// CachedToken returns a [TokenProvider] calling refresh for a new token when the last expires. Tokens are refreshed a
+// minute before their expiry, and a zero expiry is cached forever. Concurrent requests for an expired token share a
+// single refresh.
+func CachedToken(refresh func(ctx context.Context) (token string, expiry time.Time, err error)) TokenProvider {
+	return &cachedToken{refresh: refresh}
+}
+
+type cachedToken struct {
+	refresh func(ctx context.Context) (string, time.Time, error)
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+	valid  bool
+}
+
+func (c *cachedToken) Token(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.valid && (c.expiry.IsZero() || time.Until(c.expiry) > tokenRefreshLeeway) {
+		return c.token, nil
+	}
+	token, expiry, err := c.refresh(ctx)
+	if err != nil {
+		return "", fmt.Errorf("refreshing token: %w", err)
+	}
+	c.token, c.expiry, c.valid = token, expiry, true
+	return token, nil
+}
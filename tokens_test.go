@@ -0,0 +1,91 @@
+package getit //nolint:testpackage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestTokenProvider(t *testing.T) {
+	server := authServer(t, "Bearer token-2")
+	host := strings.TrimPrefix(server.URL, "http://")
+	ctx := context.Background()
+
+	var refreshes atomic.Int32
+	provider := CachedToken(func(context.Context) (string, time.Time, error) {
+		n := refreshes.Add(1)
+		// The first token expires within the refresh leeway, so is refreshed on its next use.
+		expiry := time.Now().Add(time.Hour)
+		if n == 1 {
+			expiry = time.Now().Add(time.Second)
+		}
+		return fmt.Sprintf("token-%d", n), expiry, nil
+	})
+	p := &prompter{}
+	fetcher := New([]Resolver{NewTAR()}, nil, WithTokenProvider("127.0.0.1", provider), WithCredentialPrompter(p))
+	token, err := provider.Token(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "token-1", token)
+	assert.NoError(t, fetcher.Fetch(ctx, server.URL+"/archive.tar.gz", t.TempDir()))
+	assert.NoError(t, fetcher.Fetch(ctx, server.URL+"/archive.tar.gz", t.TempDir()))
+	assert.Equal(t, int32(2), refreshes.Load())
+	assert.Equal(t, 0, len(p.requests))
+
+	// A rejected token is not retried.
+	fetcher = New([]Resolver{NewTAR()}, nil, WithTokenProvider(host, TokenProviderFunc(func(context.Context) (string, error) {
+		return "wrong", nil
+	})))
+	err = fetcher.Fetch(ctx, server.URL+"/archive.tar.gz", t.TempDir())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "401 Unauthorized")
+
+	// Other hosts are not sent the token.
+	fetcher = New([]Resolver{NewTAR()}, nil, WithTokenProvider("example.com", TokenProviderFunc(func(context.Context) (string, error) {
+		return "token-2", nil
+	})))
+	assert.Error(t, fetcher.Fetch(ctx, server.URL+"/archive.tar.gz", t.TempDir()))
+
+	fetcher = New([]Resolver{NewTAR()}, nil, WithTokenProvider(host, CachedToken(func(context.Context) (string, time.Time, error) {
+		return "", time.Time{}, errors.New("expired credentials")
+	})))
+	err = fetcher.Fetch(ctx, server.URL+"/archive.tar.gz", t.TempDir())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "token for "+host+": refreshing token: expired credentials")
+}
+
+func TestTokenProviderGit(t *testing.T) {
+	repoDir, _ := createTestRepo(t)
+	server := gitHTTPServer(t, repoDir, "Bearer token")
+	var calls atomic.Int32
+	fetcher := New([]Resolver{NewGit()}, nil, WithTokenProvider("127.0.0.1", TokenProviderFunc(func(context.Context) (string, error) {
+		calls.Add(1)
+		return "token", nil
+	})))
+	assert.NoError(t, fetcher.Fetch(context.Background(), "git+"+server.URL+"/repo", t.TempDir()))
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestTokenProviderContextVersions(t *testing.T) {
+	repoDir, _ := createTestRepo(t)
+	server := gitHTTPServer(t, repoDir, "Bearer token")
+	fetcher := New([]Resolver{NewGit()}, nil)
+	source := "git+" + server.URL + "/repo"
+
+	_, err := fetcher.Versions(context.Background(), source)
+	assert.Error(t, err)
+
+	var calls atomic.Int32
+	ctx := WithContextOptions(context.Background(), WithTokenProvider("127.0.0.1", TokenProviderFunc(func(context.Context) (string, error) {
+		calls.Add(1)
+		return "token", nil
+	})))
+	_, err = fetcher.Versions(ctx, source)
+	assert.NoError(t, err)
+	assert.True(t, calls.Load() > 0, "context token provider not used")
+}
@@ -0,0 +1,45 @@
+package getit
+
+import "context"
+
+// Tracer starts spans around the phases of a fetch (resolve, request, extract, clone, copy).
+//
+// It is deliberately minimal so that getit does not depend on OpenTelemetry directly; an adapter wrapping an
+// OpenTelemetry trace.Tracer is a few lines:
+//
+//	func (o otelTracer) Start(ctx context.Context, name string, attrs map[string]string) (context.Context, getit.Span) {
+//		ctx, span := o.tracer.Start(ctx, name)
+//		for k, v := range attrs {
+//			span.SetAttributes(attribute.String(k, v))
+//		}
+//		return ctx, otelSpan{span}
+//	}
+type Tracer interface {
+	Start(ctx context.Context, name string, attrs map[string]string) (context.Context, Span)
+}
+
+// Span is a single traced phase started by a [Tracer].
+type Span interface {
+	// End the span, recording err if it is non-nil.
+	End(err error)
+}
+
+// WithTracer instruments fetches with spans from the given [Tracer].
+func WithTracer(tracer Tracer) Option {
+	return func(f *Fetcher) { f.config.tracer = tracer }
+}
+
+type noopTracer struct{}
+
+func (noopTracer) Start(ctx context.Context, _ string, _ map[string]string) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) End(error) {}
+
+// startSpan starts a span using the Tracer of the Fetcher driving the current fetch.
+func startSpan(ctx context.Context, name string, attrs map[string]string) (context.Context, Span) {
+	return configFromContext(ctx).tracer.Start(ctx, name, attrs)
+}
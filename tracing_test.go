@@ -0,0 +1,63 @@
+package getit_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/block/getit"
+)
+
+type recordingTracer struct {
+	lock  sync.Mutex
+	spans []string
+	errs  []error
+}
+
+func (r *recordingTracer) Start(ctx context.Context, name string, _ map[string]string) (context.Context, getit.Span) {
+	return ctx, &recordingSpan{tracer: r, name: name}
+}
+
+type recordingSpan struct {
+	tracer *recordingTracer
+	name   string
+}
+
+func (s *recordingSpan) End(err error) {
+	s.tracer.lock.Lock()
+	defer s.tracer.lock.Unlock()
+	s.tracer.spans = append(s.tracer.spans, s.name)
+	s.tracer.errs = append(s.tracer.errs, err)
+}
+
+func TestWithTracer(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "archive.tar.gz"))
+	assert.NoError(t, err)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+
+	tracer := &recordingTracer{}
+	fetcher := getit.New([]getit.Resolver{getit.NewTAR()}, nil, getit.WithTracer(tracer))
+	err = fetcher.Fetch(context.Background(), server.URL+"/archive.tar.gz", t.TempDir())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"getit.Resolve", "getit.request", "getit.extract", "getit.Fetch"}, tracer.spans)
+}
+
+func TestWithTracerRecordsError(t *testing.T) {
+	tracer := &recordingTracer{}
+	fetcher := getit.New(nil, nil, getit.WithTracer(tracer))
+	err := fetcher.Fetch(context.Background(), "unsupported://source", t.TempDir())
+	assert.Error(t, err)
+	assert.Equal(t, []string{"getit.Resolve", "getit.Fetch"}, tracer.spans)
+	assert.Error(t, tracer.errs[0])
+	assert.Error(t, tracer.errs[1])
+}
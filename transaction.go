@@ -0,0 +1,172 @@
+package getit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ErrTransactionDone is returned when using a [Transaction] that has already been committed or rolled back.
+var ErrTransactionDone = errors.New("transaction already committed or rolled back")
+
+// Transaction groups fetches into several destinations, eg. the sibling directories of a workspace, so that either
+// all of them are updated or none are. Fetches are staged alongside their destinations, which are left untouched
+// until [Transaction.Commit] swaps every staged tree into place.
+//
+//	tx := fetcher.Begin()
+//	defer tx.Rollback()
+//	if err := tx.Fetch(ctx, "https://example.com/tools.tar.gz", "workspace/tools"); err != nil {
+//		return err
+//	}
+//	if err := tx.Fetch(ctx, "git+https://github.com/example/app", "workspace/app"); err != nil {
+//		return err
+//	}
+//	return tx.Commit()
+//
+// Unlike [Fetcher.Fetch], a committed fetch replaces its destination entirely rather than merging into it.
+// Transactions are safe for concurrent use, so fetches may run in parallel.
+type Transaction struct {
+	fetcher *Fetcher
+
+	mu      sync.Mutex
+	done    bool
+	pending int
+	// staged maps each destination to the staging directory holding its fetched tree, or "" while it is fetched.
+	staged map[string]string
+	order  []string
+}
+
+// Begin starts a [Transaction] fetching with f.
+func (f *Fetcher) Begin() *Transaction {
+	return &Transaction{fetcher: f, staged: map[string]string{}}
+}
+
+// Fetch fetches source into a staging directory for dest, which is replaced when the transaction is committed.
+func (t *Transaction) Fetch(ctx context.Context, source, dest string) error {
+	_, err := t.FetchWithOptions(ctx, source, dest, FetchOptions{})
+	return err
+}
+
+// FetchWithOptions fetches source into a staging directory for dest, controlled by options, which is replaced when
+// the transaction is committed. Each destination may only be fetched once per transaction.
+//
+// If the fetch fails the transaction remains open, so that the caller can decide whether to roll it back.
+func (t *Transaction) FetchWithOptions(ctx context.Context, source, dest string, options FetchOptions) (*FetchResult, error) {
+	dest = filepath.Clean(dest)
+	t.mu.Lock()
+	if t.done {
+		t.mu.Unlock()
+		return nil, ErrTransactionDone
+	}
+	if _, ok := t.staged[dest]; ok {
+		t.mu.Unlock()
+		return nil, fmt.Errorf("%s is already fetched in this transaction", dest)
+	}
+	t.staged[dest] = ""
+	t.pending++
+	t.mu.Unlock()
+
+	staging, err := newStaging(dest)
+	var result *FetchResult
+	if err == nil {
+		result, err = t.fetcher.FetchWithOptions(ctx, source, staging, options)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pending--
+	if err != nil || t.done {
+		if staging != "" {
+			_ = os.RemoveAll(staging)
+		}
+		delete(t.staged, dest)
+		if err == nil {
+			err = ErrTransactionDone
+		}
+		return nil, err
+	}
+	t.staged[dest] = staging
+	t.order = append(t.order, dest)
+	result.Dest = dest
+	return result, nil
+}
+
+// Commit replaces each destination with its staged tree. If any destination can't be replaced, those already
+// replaced are restored and the error is returned, leaving every destination as it was before the transaction.
+func (t *Transaction) Commit() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.done {
+		return ErrTransactionDone
+	}
+	if t.pending > 0 {
+		return errors.New("cannot commit a transaction with fetches in progress")
+	}
+	t.done = true
+	defer t.cleanup()
+	// backups maps each replaced destination to where its previous contents were moved, or "" if it didn't exist.
+	backups := map[string]string{}
+	for _, dest := range t.order {
+		staging := t.staged[dest]
+		backup := staging + ".old"
+		if err := os.Rename(dest, backup); errors.Is(err, os.ErrNotExist) {
+			backup = ""
+		} else if err != nil {
+			return errors.Join(fmt.Errorf("committing %s: %w", dest, err), restore(backups))
+		}
+		if err := os.Rename(staging, dest); err != nil {
+			backups[dest] = backup
+			return errors.Join(fmt.Errorf("committing %s: %w", dest, err), restore(backups))
+		}
+		backups[dest] = backup
+	}
+	for _, backup := range backups {
+		if backup != "" {
+			_ = os.RemoveAll(backup)
+		}
+	}
+	return nil
+}
+
+// Rollback discards the staged trees, leaving every destination untouched. Rolling back a transaction that has
+// already been committed or rolled back does nothing, so Rollback may be deferred.
+func (t *Transaction) Rollback() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.done {
+		return nil
+	}
+	t.done = true
+	t.cleanup()
+	return nil
+}
+
+// cleanup removes any staging directories that have not been committed.
+func (t *Transaction) cleanup() {
+	for _, staging := range t.staged {
+		if staging != "" {
+			_ = os.RemoveAll(staging)
+		}
+	}
+}
+
+// restore moves replaced destinations back from their backups.
+func restore(backups map[string]string) error {
+	var errs []error
+	for dest, backup := range backups {
+		if err := os.RemoveAll(dest); err != nil {
+			errs = append(errs, fmt.Errorf("rolling back %s: %w", dest, err))
+			continue
+		}
+		if backup == "" {
+			continue
+		}
+		if err := os.Rename(backup, dest); err != nil {
+			errs = append(errs, fmt.Errorf("rolling back %s: %w", dest, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,82 @@
+package getit_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/block/getit"
+)
+
+func TestTransaction(t *testing.T) {
+	sources := map[string][]byte{
+		"/tools.tar": layerTar(t, [2]string{"lint", "lint v2"}),
+		"/app.tar":   layerTar(t, [2]string{"main.go", "package main"}),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := sources[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+	fetcher := getit.New([]getit.Resolver{getit.NewTAR()}, nil)
+	ctx := context.Background()
+
+	// setup returns a workspace with an existing tools directory.
+	setup := func(t *testing.T) string {
+		t.Helper()
+		workspace := t.TempDir()
+		assert.NoError(t, os.MkdirAll(filepath.Join(workspace, "tools"), 0o750))
+		assert.NoError(t, os.WriteFile(filepath.Join(workspace, "tools", "lint"), []byte("lint v1"), 0o600))
+		assert.NoError(t, os.WriteFile(filepath.Join(workspace, "tools", "stale"), []byte("stale"), 0o600))
+		return workspace
+	}
+
+	t.Run("Commit", func(t *testing.T) {
+		workspace := setup(t)
+		tx := fetcher.Begin()
+		defer tx.Rollback() //nolint:errcheck
+		assert.NoError(t, tx.Fetch(ctx, server.URL+"/tools.tar", filepath.Join(workspace, "tools")))
+		result, err := tx.FetchWithOptions(ctx, server.URL+"/app.tar", filepath.Join(workspace, "app"), getit.FetchOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, filepath.Join(workspace, "app"), result.Dest)
+
+		// Nothing is visible until the transaction is committed.
+		assert.Equal(t, []string{"lint=lint v1", "stale=stale"}, listTree(t, filepath.Join(workspace, "tools")))
+		_, err = os.Stat(filepath.Join(workspace, "app"))
+		assert.True(t, os.IsNotExist(err))
+		assert.NoError(t, tx.Commit())
+		assert.Equal(t, []string{"app/", "app/main.go=package main", "tools/", "tools/lint=lint v2"}, listTree(t, workspace))
+		assert.True(t, errors.Is(tx.Commit(), getit.ErrTransactionDone))
+		assert.NoError(t, tx.Rollback())
+	})
+
+	t.Run("Rollback", func(t *testing.T) {
+		workspace := setup(t)
+		tx := fetcher.Begin()
+		assert.NoError(t, tx.Fetch(ctx, server.URL+"/tools.tar", filepath.Join(workspace, "tools")))
+		err := tx.Fetch(ctx, server.URL+"/missing.tar", filepath.Join(workspace, "app"))
+		assert.Error(t, err)
+		assert.NoError(t, tx.Rollback())
+		assert.Equal(t, []string{"tools/", "tools/lint=lint v1", "tools/stale=stale"}, listTree(t, workspace))
+		assert.True(t, errors.Is(tx.Fetch(ctx, server.URL+"/app.tar", filepath.Join(workspace, "app")), getit.ErrTransactionDone))
+	})
+
+	t.Run("DuplicateDest", func(t *testing.T) {
+		workspace := setup(t)
+		tx := fetcher.Begin()
+		defer tx.Rollback() //nolint:errcheck
+		assert.NoError(t, tx.Fetch(ctx, server.URL+"/tools.tar", filepath.Join(workspace, "tools")))
+		err := tx.Fetch(ctx, server.URL+"/app.tar", filepath.Join(workspace, "tools")+"/")
+		assert.EqualError(t, err, filepath.Join(workspace, "tools")+" is already fetched in this transaction")
+	})
+}
@@ -0,0 +1,158 @@
+package getit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Lockfile pins sources to the revisions and versions they were fetched at. See [Fetcher.CheckForUpdates].
+type Lockfile struct {
+	Entries []LockEntry `json:"entries"`
+}
+
+// LockEntry pins a single source.
+type LockEntry struct {
+	// Source as passed to [Fetcher.Fetch], eg. "github.com/user/repo?ref=main". It may reference the template
+	// variables {os} and {arch}, expanded for [CurrentPlatform], and {version}, expanded to Version. See
+	// [ExpandTemplate].
+	Source string `json:"source"`
+	// Revision the source was fetched at, as reported in [SourceInfo.Revision], eg. a git commit or an HTTP ETag.
+	Revision string `json:"revision,omitempty"`
+	// Version the source was fetched at, eg. the git tag "v1.2.0".
+	Version string `json:"version,omitempty"`
+}
+
+// ReadLockfile reads a JSON [Lockfile] from path.
+func ReadLockfile(path string) (*Lockfile, error) {
+	data, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		return nil, fmt.Errorf("reading lockfile: %w", err)
+	}
+	lockfile := &Lockfile{}
+	if err := json.Unmarshal(data, lockfile); err != nil {
+		return nil, fmt.Errorf("decoding lockfile %s: %w", path, err)
+	}
+	return lockfile, nil
+}
+
+// WriteFile writes the lockfile as JSON to path.
+func (l *Lockfile) WriteFile(path string) error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding lockfile: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("writing lockfile: %w", err)
+	}
+	return nil
+}
+
+// UpdateReport is returned by [Fetcher.CheckForUpdates].
+type UpdateReport struct {
+	// Entries correspond to the entries of the lockfile, in order.
+	Entries []UpdateStatus `json:"entries"`
+}
+
+// Outdated returns the entries with an update available.
+func (r *UpdateReport) Outdated() []UpdateStatus {
+	var outdated []UpdateStatus
+	for _, entry := range r.Entries {
+		if entry.Outdated {
+			outdated = append(outdated, entry)
+		}
+	}
+	return outdated
+}
+
+// UpdateStatus compares a pinned source against its upstream.
+type UpdateStatus struct {
+	// Source with credentials redacted.
+	Source string `json:"source"`
+	// Revision pinned by the lockfile.
+	Revision string `json:"revision,omitempty"`
+	// LatestRevision is the current upstream revision of the source, if the lockfile pins a revision.
+	LatestRevision string `json:"latestRevision,omitempty"`
+	// Version pinned by the lockfile.
+	Version string `json:"version,omitempty"`
+	// LatestVersion is the highest stable upstream version, if the lockfile pins a version.
+	LatestVersion string `json:"latestVersion,omitempty"`
+	// Outdated is true if the upstream revision differs from the pinned revision, or a newer version is available.
+	Outdated bool `json:"outdated"`
+	// Error checking the source, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// CheckForUpdates compares each source pinned by lockfile against its upstream, without fetching anything.
+//
+// Pinned revisions are compared against the current revision of the source as described by a [Stater], so a git
+// source with ?ref=main is outdated once main has moved on. Pinned versions are compared against the highest stable
+// version listed by a [Versioner]. Failures to check individual sources are reported in [UpdateStatus.Error] rather
+// than failing the whole check.
+func (f *Fetcher) CheckForUpdates(ctx context.Context, lockfile *Lockfile) (*UpdateReport, error) {
+	fetcher := f.withContextOptions(ctx)
+	if fetcher != f {
+		defer fetcher.config.client.CloseIdleConnections()
+	}
+	report := &UpdateReport{Entries: make([]UpdateStatus, 0, len(lockfile.Entries))}
+	for _, entry := range lockfile.Entries {
+		if err := contextError(ctx); err != nil {
+			return nil, err
+		}
+		status := UpdateStatus{Source: redactSource(entry.Source), Revision: entry.Revision, Version: entry.Version}
+		if err := fetcher.checkForUpdate(ctx, entry, &status); err != nil {
+			status.Error = err.Error()
+		}
+		fetcher.config.logger.DebugContext(ctx, "update check", "source", status.Source, "outdated", status.Outdated, "error", status.Error)
+		report.Entries = append(report.Entries, status)
+	}
+	return report, nil
+}
+
+func (f *Fetcher) checkForUpdate(ctx context.Context, entry LockEntry, status *UpdateStatus) error {
+	cfg := f.config
+	ctx = contextWithConfig(ctx, &cfg)
+	expanded, err := ExpandTemplate(entry.Source, CurrentPlatform(), entry.Version)
+	if err != nil {
+		return err
+	}
+	resolver, source, err := f.Resolve(expanded)
+	if err != nil {
+		return err
+	}
+	if entry.Version != "" {
+		versioner, ok := resolver.(Versioner)
+		if !ok {
+			return fmt.Errorf("listing versions: not supported by the %s resolver", resolverName(resolver))
+		}
+		versions, err := versioner.Versions(ctx, source)
+		if err != nil {
+			return fmt.Errorf("listing versions: %w", err)
+		}
+		if latest, ok := highestVersion(versions, anyStableVersion); ok {
+			status.LatestVersion = latest
+			latestVersion, _ := parseSemver(latest)
+			if pinned, ok := parseSemver(entry.Version); ok {
+				status.Outdated = latestVersion.compare(pinned) > 0
+			} else {
+				status.Outdated = latest != entry.Version
+			}
+		}
+	}
+	if entry.Revision != "" {
+		stater, ok := resolver.(Stater)
+		if !ok {
+			return fmt.Errorf("stat: not supported by the %s resolver", resolverName(resolver))
+		}
+		info, err := stater.Stat(ctx, source)
+		if err != nil {
+			return fmt.Errorf("stat: %w", err)
+		}
+		status.LatestRevision = info.Revision
+		if info.Revision != "" && info.Revision != entry.Revision {
+			status.Outdated = true
+		}
+	}
+	return nil
+}
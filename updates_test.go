@@ -0,0 +1,61 @@
+package getit_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/block/getit"
+)
+
+type releasesResolver struct{ getit.ZIP }
+
+func (r *releasesResolver) Versions(context.Context, getit.Source) ([]string, error) {
+	return []string{"v2.0.0-rc.1", "v1.3.0", "v1.2.0", "nightly"}, nil
+}
+
+func TestCheckForUpdates(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing.tar.gz" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("ETag", `"v2"`)
+	}))
+	defer server.Close()
+
+	lockfile := &getit.Lockfile{Entries: []getit.LockEntry{
+		{Source: server.URL + "/current.tar.gz", Revision: `"v2"`},
+		{Source: server.URL + "/changed.tar.gz", Revision: `"v1"`},
+		{Source: server.URL + "/missing.tar.gz", Revision: `"v1"`},
+		{Source: "https://example.com/release.zip", Version: "v1.2.0"},
+		{Source: "https://example.com/release.zip", Version: "v1.3.0"},
+		{Source: "ftp://example.com/unsupported", Revision: "x"},
+	}}
+	path := filepath.Join(t.TempDir(), "getit.lock")
+	assert.NoError(t, lockfile.WriteFile(path))
+	lockfile, err := getit.ReadLockfile(path)
+	assert.NoError(t, err)
+
+	fetcher := getit.New([]getit.Resolver{getit.NewTAR(), &releasesResolver{}}, nil)
+	report, err := fetcher.CheckForUpdates(context.Background(), lockfile)
+	assert.NoError(t, err)
+	assert.Equal(t, 6, len(report.Entries))
+
+	assert.False(t, report.Entries[0].Outdated)
+	assert.Equal(t, `"v2"`, report.Entries[0].LatestRevision)
+	assert.True(t, report.Entries[1].Outdated)
+	assert.Equal(t, `"v2"`, report.Entries[1].LatestRevision)
+	assert.Contains(t, report.Entries[2].Error, "404")
+	assert.Equal(t, getit.UpdateStatus{Source: "https://example.com/release.zip", Version: "v1.2.0", LatestVersion: "v1.3.0", Outdated: true}, report.Entries[3])
+	assert.False(t, report.Entries[4].Outdated)
+	assert.Contains(t, report.Entries[5].Error, "unsupported source")
+
+	outdated := report.Outdated()
+	assert.Equal(t, 2, len(outdated))
+	assert.Equal(t, server.URL+"/changed.tar.gz", outdated[0].Source)
+}
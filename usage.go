@@ -0,0 +1,72 @@
+package getit
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sync/atomic"
+)
+
+// DiskUsage is the space written to the destination by a fetch, eg. to enforce per-job storage quotas.
+type DiskUsage struct {
+	// Files is the number of files, directories and links written.
+	Files int64
+	// Bytes is the total size of the regular files written. Files deduplicated by [WithStore] are counted in full.
+	Bytes int64
+}
+
+// usageCounter accumulates the disk usage of a fetch.
+type usageCounter struct {
+	files atomic.Int64
+	bytes atomic.Int64
+}
+
+// add records files and bytes written, returning the totals so far. A nil counter records nothing.
+func (u *usageCounter) add(files, bytes int64) DiskUsage {
+	if u == nil {
+		return DiskUsage{Files: files, Bytes: bytes}
+	}
+	return DiskUsage{Files: u.files.Add(files), Bytes: u.bytes.Add(bytes)}
+}
+
+func (u *usageCounter) usage() DiskUsage {
+	if u == nil {
+		return DiskUsage{}
+	}
+	return DiskUsage{Files: u.files.Load(), Bytes: u.bytes.Load()}
+}
+
+// fileExtracted records a file written to the destination, and reports it to the hooks.
+func (c *config) fileExtracted(path string, size int64) {
+	usage := c.usage.add(1, size)
+	c.hooks.fileExtracted(path, size)
+	c.hooks.progress(usage)
+}
+
+// treeWritten records everything under dir as written, for resolvers that don't report files as they write them.
+func (c *config) treeWritten(ctx context.Context, dir string) error {
+	var files, bytes int64
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || path == dir {
+			return err
+		}
+		if err := contextError(ctx); err != nil {
+			return err
+		}
+		files++
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err //nolint:wrapcheck // wrapped below
+			}
+			bytes += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("measuring %s: %w", dir, err)
+	}
+	c.hooks.progress(c.usage.add(files, bytes))
+	return nil
+}
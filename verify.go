@@ -0,0 +1,34 @@
+package getit
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// Verify re-fetches source and compares it against dest, returning the differences.
+//
+// The source is fetched into a temporary directory, see [WithTempDir], which is removed afterwards. Modification times are not
+// compared.
+func (f *Fetcher) Verify(ctx context.Context, source, dest string) (ManifestDiff, error) {
+	tmp, err := f.config.mkdirTemp("getit-verify-*")
+	if err != nil {
+		return ManifestDiff{}, err
+	}
+	defer os.RemoveAll(tmp)
+	if err := f.Fetch(ctx, source, tmp); err != nil {
+		return ManifestDiff{}, fmt.Errorf("verifying: %w", err)
+	}
+	expected, err := BuildManifest(ctx, tmp)
+	if err != nil {
+		return ManifestDiff{}, fmt.Errorf("verifying: %w", err)
+	}
+	actual, err := BuildManifest(ctx, dest)
+	if err != nil {
+		return ManifestDiff{}, fmt.Errorf("verifying: %w", err)
+	}
+	diff := expected.Diff(actual)
+	f.config.logger.InfoContext(ctx, "verify", "source", redactSource(source), "dest", dest, "match", diff.Empty(),
+		"added", len(diff.Added), "modified", len(diff.Modified), "deleted", len(diff.Deleted))
+	return diff, nil
+}
@@ -0,0 +1,40 @@
+package getit_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/block/getit"
+)
+
+func TestVerify(t *testing.T) {
+	srcDir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(srcDir, "keep.txt"), []byte("keep\n"), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(srcDir, "modify.txt"), []byte("original\n"), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(srcDir, "delete.txt"), []byte("delete\n"), 0o644))
+
+	fetcher := getit.New([]getit.Resolver{getit.NewFile()}, nil)
+	dest := t.TempDir()
+	err := fetcher.Fetch(context.Background(), "file://"+srcDir, dest)
+	assert.NoError(t, err)
+
+	diff, err := fetcher.Verify(context.Background(), "file://"+srcDir, dest)
+	assert.NoError(t, err)
+	assert.True(t, diff.Empty())
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dest, "modify.txt"), []byte("tampered\n"), 0o644))
+	assert.NoError(t, os.Remove(filepath.Join(dest, "delete.txt")))
+	assert.NoError(t, os.WriteFile(filepath.Join(dest, "added.txt"), []byte("added\n"), 0o644))
+
+	diff, err = fetcher.Verify(context.Background(), "file://"+srcDir, dest)
+	assert.NoError(t, err)
+	assert.Equal(t, getit.ManifestDiff{
+		Added:    []string{"added.txt"},
+		Modified: []string{"modify.txt"},
+		Deleted:  []string{"delete.txt"},
+	}, diff)
+}
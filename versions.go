@@ -0,0 +1,62 @@
+package getit
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Versioner is an optional interface implemented by a [Resolver] that can list the versions of a source.
+type Versioner interface {
+	// Versions returns the available versions of a source, newest first.
+	Versions(ctx context.Context, source Source) ([]string, error)
+}
+
+// Versions lists the available versions of a source, eg. the tags of a git repository, newest first.
+//
+// Any version listed may be fetched by passing it as the source's ref. An error is returned if the resolver for the
+// source does not implement [Versioner].
+func (f *Fetcher) Versions(ctx context.Context, source string) ([]string, error) {
+	fetcher := f.withContextOptions(ctx)
+	if fetcher != f {
+		defer fetcher.config.client.CloseIdleConnections()
+	}
+	cfg := fetcher.config
+	ctx = contextWithConfig(ctx, &cfg)
+	display := redactSource(source)
+	resolver, src, err := fetcher.Resolve(source)
+	if err != nil {
+		return nil, err
+	}
+	versioner, ok := resolver.(Versioner)
+	if !ok {
+		return nil, fmt.Errorf("listing versions of %s: not supported by the %s resolver", display, resolverName(resolver))
+	}
+	versions, err := versioner.Versions(ctx, src)
+	if err != nil {
+		return nil, fmt.Errorf("listing versions of %s: %w", display, err)
+	}
+	return versions, nil
+}
+
+// sortVersions sorts tags newest first. Tags that are semantic versions are ordered by precedence and precede any
+// other tags, which are sorted lexically.
+func sortVersions(tags []string) {
+	slices.SortStableFunc(tags, func(a, b string) int {
+		av, aok := parseSemver(a)
+		bv, bok := parseSemver(b)
+		switch {
+		case aok && bok:
+			if c := bv.compare(av); c != 0 {
+				return c
+			}
+			return strings.Compare(a, b)
+		case aok:
+			return -1
+		case bok:
+			return 1
+		}
+		return strings.Compare(a, b)
+	})
+}
@@ -0,0 +1,33 @@
+package getit_test
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/block/getit"
+)
+
+type versionedResolver struct{ getit.TAR }
+
+func (v *versionedResolver) Versions(_ context.Context, source getit.Source) ([]string, error) {
+	return []string{source.URL.Host + "-v2", source.URL.Host + "-v1"}, nil
+}
+
+func TestFetcherVersions(t *testing.T) {
+	fetcher := getit.New([]getit.Resolver{&versionedResolver{}}, nil)
+	versions, err := fetcher.Versions(context.Background(), "https://example.com/archive.tar.gz")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"example.com-v2", "example.com-v1"}, versions)
+
+	fetcher = getit.New([]getit.Resolver{getit.NewTAR()}, nil)
+	_, err = fetcher.Versions(context.Background(), "https://example.com/archive.tar.gz")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not supported by the TAR resolver")
+
+	_, err = fetcher.Versions(context.Background(), (&url.URL{Scheme: "ftp", Host: "example.com"}).String())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported source")
+}
@@ -0,0 +1,101 @@
+package getit
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Watch fetches source into dest, then polls it every interval and re-fetches it whenever it changes, until ctx is
+// done. It is intended for long-running services that hot-reload content from a repository or bucket.
+//
+// Changes are detected with the [Stater] of the source's resolver, by revision (eg. a git commit or an HTTP ETag)
+// where the source reports one, or otherwise by modification time and size. Each fetch is made into a staging
+// directory which then replaces dest, so dest never holds a partial tree.
+//
+// onChange, if not nil, is called after every fetch, with any error. Errors don't stop watching: the fetch is retried
+// at the next poll. Watch returns an error if the source can't be watched, or once ctx is done.
+func (f *Fetcher) Watch(ctx context.Context, source, dest string, interval time.Duration, onChange func(info SourceInfo, err error)) error {
+	fetcher := f.withContextOptions(ctx)
+	if fetcher != f {
+		defer fetcher.config.client.CloseIdleConnections()
+	}
+	display := redactSource(source)
+	resolver, src, err := fetcher.Resolve(source)
+	if err != nil {
+		return err
+	}
+	stater, ok := resolver.(Stater)
+	if !ok {
+		return fmt.Errorf("watching %s: not supported by the %s resolver", display, resolverName(resolver))
+	}
+	if interval <= 0 {
+		return fmt.Errorf("watching %s: invalid interval %s", display, interval)
+	}
+	if onChange == nil {
+		onChange = func(SourceInfo, error) {}
+	}
+	logger := fetcher.config.logger
+	var last string
+	poll := func() {
+		cfg := fetcher.config
+		info, err := stater.Stat(contextWithConfig(ctx, &cfg), src)
+		if err != nil {
+			if contextError(ctx) == nil {
+				logger.WarnContext(ctx, "watch", "source", display, "error", err)
+				onChange(info, fmt.Errorf("watching %s: %w", display, err))
+			}
+			return
+		}
+		signature := sourceSignature(info)
+		if signature != "" && signature == last {
+			return
+		}
+		logger.InfoContext(ctx, "source changed", "source", display, "revision", info.Revision)
+		if err := f.sync(ctx, source, dest); err != nil {
+			if contextError(ctx) == nil {
+				onChange(info, err)
+			}
+			return
+		}
+		last = signature
+		onChange(info, nil)
+	}
+	poll()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return contextError(ctx)
+		case <-ticker.C:
+			poll()
+		}
+	}
+}
+
+// sourceSignature identifies the content of a source for change detection, or returns "" if it can't be identified.
+func sourceSignature(info SourceInfo) string {
+	switch {
+	case info.Revision != "":
+		return info.Revision
+	case !info.Modified.IsZero():
+		return info.Modified.UTC().Format(time.RFC3339Nano) + " " + strconv.FormatInt(info.Size, 10)
+	}
+	return ""
+}
+
+// sync fetches source into a staging directory, then replaces dest with it.
+func (f *Fetcher) sync(ctx context.Context, source, dest string) error {
+	staging, err := newStaging(dest)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(staging)
+	if err := f.Fetch(ctx, source, staging); err != nil {
+		return err
+	}
+	return replace(staging, dest)
+}
@@ -0,0 +1,100 @@
+package getit_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/block/getit"
+)
+
+func tarball(t *testing.T, name, content string) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+	assert.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+	_, err := tw.Write([]byte(content))
+	assert.NoError(t, err)
+	assert.NoError(t, tw.Close())
+	assert.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func TestWatch(t *testing.T) {
+	var mu sync.Mutex
+	etag, body := `"v1"`, tarball(t, "v1.txt", "one\n")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("ETag", etag)
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	changes := make(chan getit.SourceInfo, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dest := filepath.Join(t.TempDir(), "dest")
+	done := make(chan error, 1)
+	go func() {
+		fetcher := getit.New([]getit.Resolver{getit.NewTAR()}, nil)
+		done <- fetcher.Watch(ctx, server.URL+"/config.tar.gz", dest, 5*time.Millisecond, func(info getit.SourceInfo, err error) {
+			assert.NoError(t, err)
+			changes <- info
+		})
+	}()
+
+	info := <-changes
+	assert.Equal(t, `"v1"`, info.Revision)
+	content, err := os.ReadFile(filepath.Join(dest, "v1.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "one\n", string(content))
+
+	// Unchanged sources are not re-fetched.
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, 0, len(changes))
+
+	mu.Lock()
+	etag, body = `"v2"`, tarball(t, "v2.txt", "two\n")
+	mu.Unlock()
+	info = <-changes
+	assert.Equal(t, `"v2"`, info.Revision)
+	content, err = os.ReadFile(filepath.Join(dest, "v2.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "two\n", string(content))
+	_, err = os.Stat(filepath.Join(dest, "v1.txt"))
+	assert.True(t, os.IsNotExist(err))
+
+	cancel()
+	err = <-done
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "context canceled")
+	entries, err := os.ReadDir(filepath.Dir(dest))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(entries), "staging directories left behind")
+}
+
+// unstatableResolver fetches from any URL, but can't describe sources.
+type unstatableResolver struct{}
+
+func (unstatableResolver) Match(*url.URL) bool                               { return true }
+func (unstatableResolver) Fetch(context.Context, getit.Source, string) error { return nil }
+
+func TestWatchUnsupported(t *testing.T) {
+	fetcher := getit.New([]getit.Resolver{unstatableResolver{}}, nil)
+	err := fetcher.Watch(context.Background(), "https://example.com/config", t.TempDir(), time.Second, nil)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not supported by the unstatableResolver resolver")
+}
@@ -0,0 +1,78 @@
+package getit
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"syscall"
+)
+
+// copyXattrs copies the extended attributes of src to dest.
+//
+// Filesystems without extended attribute support are ignored, as are privileged attributes that the current user
+// can't set.
+func copyXattrs(src, dest string) error {
+	names, err := listXattrs(src)
+	if errors.Is(err, syscall.ENOTSUP) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("list xattrs %s: %w", src, err)
+	}
+	for _, name := range names {
+		value, err := getXattr(src, name)
+		if err != nil {
+			return fmt.Errorf("get xattr %s on %s: %w", name, src, err)
+		}
+		if supported, err := setXattr(dest, name, value); err != nil || !supported {
+			return err
+		}
+	}
+	return nil
+}
+
+// setXattr sets an extended attribute on path, reporting false if the filesystem doesn't support extended
+// attributes. Privileged attributes that the current user can't set are ignored.
+func setXattr(path, name string, value []byte) (bool, error) {
+	err := syscall.Setxattr(path, name, value, 0)
+	switch {
+	case err == nil:
+	case errors.Is(err, syscall.ENOTSUP):
+		return false, nil
+	case errors.Is(err, syscall.EPERM) && !strings.HasPrefix(name, "user."):
+	default:
+		return true, fmt.Errorf("set xattr %s on %s: %w", name, path, err)
+	}
+	return true, nil
+}
+
+func listXattrs(path string) ([]string, error) {
+	size, err := syscall.Listxattr(path, nil)
+	if err != nil || size == 0 {
+		return nil, err //nolint:wrapcheck // wrapped by the caller
+	}
+	buf := make([]byte, size)
+	size, err = syscall.Listxattr(path, buf)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // wrapped by the caller
+	}
+	var names []string
+	for name := range strings.SplitSeq(string(buf[:size]), "\x00") {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+func getXattr(path, name string) ([]byte, error) {
+	size, err := syscall.Getxattr(path, name, nil)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // wrapped by the caller
+	}
+	buf := make([]byte, size)
+	size, err = syscall.Getxattr(path, name, buf)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // wrapped by the caller
+	}
+	return buf[:size], nil
+}
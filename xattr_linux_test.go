@@ -0,0 +1,127 @@
+package getit_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/block/getit"
+)
+
+func TestFileFetchPreserveXattrs(t *testing.T) {
+	srcDir := t.TempDir()
+	path := filepath.Join(srcDir, "file.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("hello\n"), 0o644))
+	err := syscall.Setxattr(path, "user.getit", []byte("value"), 0)
+	if errors.Is(err, syscall.ENOTSUP) {
+		t.Skip("filesystem does not support extended attributes")
+	}
+	assert.NoError(t, err)
+
+	u, err := url.Parse("file://" + srcDir)
+	assert.NoError(t, err)
+	dest := t.TempDir()
+	f := &getit.File{PreserveXattrs: true}
+	err = f.Fetch(context.Background(), getit.Source{URL: u}, dest)
+	assert.NoError(t, err)
+
+	buf := make([]byte, 64)
+	n, err := syscall.Getxattr(filepath.Join(dest, "file.txt"), "user.getit", buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "value", string(buf[:n]))
+}
+
+func TestTARPreserveXattrs(t *testing.T) {
+	// cap_net_bind_service, effective and permitted, in the VFS_CAP_REVISION_2 format.
+	capability := string([]byte{0x01, 0x00, 0x00, 0x02, 0x00, 0x04, 0x00, 0x00, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0})
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	assert.NoError(t, tw.WriteHeader(&tar.Header{
+		Name: "bin/", Mode: 0o755, Typeflag: tar.TypeDir, Format: tar.FormatPAX,
+		PAXRecords: map[string]string{"SCHILY.acl.default": "user::rwx,group::r-x,other::r-x"},
+	}))
+	assert.NoError(t, tw.WriteHeader(&tar.Header{
+		Name: "bin/server", Mode: 0o755, Size: 3, Uid: 1000, Gid: 1000, Typeflag: tar.TypeReg, Format: tar.FormatPAX,
+		PAXRecords: map[string]string{
+			"SCHILY.xattr.user.getit":          "value",
+			"SCHILY.xattr.security.capability": capability,
+			"SCHILY.acl.access":                "user::rwx\nuser:1234:r-x:1234\ngroup::r-x\nmask::r-x\nother::r-x",
+			"SCHILY.acl.default":               "ignored on files",
+			"LIBARCHIVE.creationtime":          "1700000000",
+		},
+	}))
+	_, err := tw.Write([]byte("elf"))
+	assert.NoError(t, err)
+	assert.NoError(t, tw.Close())
+	data := buf.Bytes()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+
+	fetch := func(t *testing.T, tarResolver *getit.TAR) string {
+		t.Helper()
+		fetcher := getit.New([]getit.Resolver{tarResolver}, nil, getit.WithPermissions(getit.Permissions{PreserveOwner: os.Getuid() == 0}))
+		dest := t.TempDir()
+		assert.NoError(t, fetcher.Fetch(context.Background(), server.URL+"/archive.tar", dest))
+		return dest
+	}
+	getxattr := func(path, name string) (string, error) {
+		value := make([]byte, 256)
+		n, err := syscall.Getxattr(path, name, value)
+		if err != nil {
+			return "", err
+		}
+		return string(value[:n]), nil
+	}
+
+	t.Run("Disabled", func(t *testing.T) {
+		dest := fetch(t, getit.NewTAR())
+		_, err := getxattr(filepath.Join(dest, "bin", "server"), "user.getit")
+		assert.True(t, errors.Is(err, syscall.ENODATA), "%v", err)
+	})
+
+	t.Run("Enabled", func(t *testing.T) {
+		dest := fetch(t, &getit.TAR{PreserveXattrs: true})
+		path := filepath.Join(dest, "bin", "server")
+		value, err := getxattr(path, "user.getit")
+		if errors.Is(err, syscall.ENOTSUP) || errors.Is(err, syscall.ENODATA) {
+			t.Skip("filesystem does not support extended attributes")
+		}
+		assert.NoError(t, err)
+		assert.Equal(t, "value", value)
+
+		acl, err := getxattr(path, "system.posix_acl_access")
+		if err == nil {
+			expected := []byte{
+				2, 0, 0, 0,
+				0x01, 0, 7, 0, 0xff, 0xff, 0xff, 0xff,
+				0x02, 0, 5, 0, 0xd2, 0x04, 0, 0,
+				0x04, 0, 5, 0, 0xff, 0xff, 0xff, 0xff,
+				0x10, 0, 5, 0, 0xff, 0xff, 0xff, 0xff,
+				0x20, 0, 5, 0, 0xff, 0xff, 0xff, 0xff,
+			}
+			assert.Equal(t, string(expected), acl)
+			_, err = getxattr(filepath.Join(dest, "bin"), "system.posix_acl_default")
+			assert.NoError(t, err)
+		} else if !errors.Is(err, syscall.ENOTSUP) {
+			assert.NoError(t, err)
+		}
+
+		if os.Getuid() == 0 {
+			value, err := getxattr(path, "security.capability")
+			assert.NoError(t, err)
+			assert.Equal(t, capability, value)
+		}
+	})
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package getit
+
+// copyXattrs is a no-op on platforms where extended attributes are not supported.
+func copyXattrs(_, _ string) error {
+	return nil
+}
+
+// setXattr reports that extended attributes are not supported on this platform.
+func setXattr(_, _ string, _ []byte) (bool, error) {
+	return false, nil
+}
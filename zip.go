@@ -1,66 +1,400 @@
 package getit
 
 import (
-	"bytes"
+	"archive/zip"
+	"bufio"
 	"context"
+	"encoding/binary"
 	"fmt"
+	"hash/crc32"
 	"io"
+	"io/fs"
 	"net/http"
 	"net/url"
 	"os"
-	"os/exec"
+	"slices"
 	"strings"
 )
 
-type ZIP struct{}
+// The ZIP [Resolver] knows how to unpack zip archives.
+//
+// Zip64 archives are supported, as are split archives, whose parts preceding the final .zip are fetched from
+// alongside it as .z01, .z02 and so on.
+type ZIP struct {
+	// Concurrency is the number of entries extracted concurrently. Concurrent extraction needs random access to the
+	// whole archive, so if greater than 1 the archive is first downloaded to a temporary file. By default archives
+	// are extracted sequentially without buffering them to disk. Split archives are always extracted sequentially.
+	Concurrency int
+}
 
 func NewZIP() *ZIP {
 	return &ZIP{}
 }
 
-var _ Resolver = (*ZIP)(nil)
+var (
+	_ Resolver           = (*ZIP)(nil)
+	_ Stater             = (*ZIP)(nil)
+	_ Pusher             = (*ZIP)(nil)
+	_ CapabilityReporter = (*ZIP)(nil)
+)
 
+// Match returns true for paths with a .zip extension, ignoring case and any query or fragment.
 func (z *ZIP) Match(source *url.URL) bool {
-	return strings.HasSuffix(source.Path, ".zip")
+	return strings.HasSuffix(strings.ToLower(archivePath(source)), ".zip")
 }
 
+func (z *ZIP) Capabilities() Capabilities {
+	return Capabilities{SubDir: true}
+}
+
+func (z *ZIP) Stat(ctx context.Context, source Source) (SourceInfo, error) {
+	return statArchive(ctx, source.URL)
+}
+
+// Push writes a zip archive of srcDir to a local file:// path, or uploads it with a PUT request.
+func (z *ZIP) Push(ctx context.Context, source Source, srcDir string) error {
+	return pushArchive(ctx, source, srcDir)
+}
+
+// Fetch extracts a zip archive, either remote or a local file:// path. If the source has a subdirectory, only the
+// entries beneath it are extracted, relative to it.
+//
+// Unless [ZIP.Concurrency] is set, remote archives are not buffered to disk: if the server supports ranged requests
+// the archive is read in blocks as it is extracted, otherwise entries are extracted as the archive is streamed.
 func (z *ZIP) Fetch(ctx context.Context, source Source, dest string) error {
 	if err := os.MkdirAll(dest, 0750); err != nil {
 		return fmt.Errorf("creating destination directory: %w", err)
 	}
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source.URL.String(), nil)
+	if source.URL.Scheme == "file" {
+		return z.fetchLocal(ctx, source, dest)
+	}
+	if z.Concurrency > 1 {
+		return z.fetchToTemp(ctx, source, dest)
+	}
+	resp, err := httpGetRange(ctx, source.URL, fmt.Sprintf("bytes=-%d", rangeTailSize))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	cfg := configFromContext(ctx)
+	ranged := resp.StatusCode == http.StatusPartialContent
+	cfg.logger.DebugContext(ctx, "extract", "url", RedactURL(source.URL), "dest", dest, "ranged", ranged)
+	downloadCtx, cancelDownload := withPhaseTimeout(ctx, "download", cfg.timeouts.Download)
+	defer cancelDownload()
+	ctx, span := startSpan(ctx, "getit.extract", map[string]string{"url": RedactURL(source.URL)})
+	ctx, cancel := withPhaseTimeout(ctx, "extract", cfg.timeouts.Extract)
+	defer cancel()
+	if ranged {
+		err = extractRemoteZip(ctx, downloadCtx, source.URL, resp, dest, source.SubDir)
+	} else {
+		err = extractZipBody(ctx, source.URL, newCountingReader(ctx, resp.Body, source.URL.Host), dest, source.SubDir)
+	}
+	span.End(err)
+	return err
+}
+
+// extractZipResponse unpacks a zip archive downloaded from u as it is streamed from body.
+func extractZipResponse(ctx context.Context, u *url.URL, body io.Reader, dest, subdir string) (err error) {
+	cfg := configFromContext(ctx)
+	cfg.logger.DebugContext(ctx, "extract", "url", RedactURL(u), "dest", dest, "ranged", false)
+	ctx, span := startSpan(ctx, "getit.extract", map[string]string{"url": RedactURL(u)})
+	defer func() { span.End(err) }()
+	ctx, cancel := withPhaseTimeout(ctx, "extract", cfg.timeouts.Extract)
+	defer cancel()
+	return extractZipBody(ctx, u, newCountingReader(ctx, body, u.Host), dest, subdir)
+}
+
+// extractRemoteZip unpacks a zip archive read with ranged requests, given the response to a request for its end.
+// Requests are made with downloadCtx. The central directory is read from, or added to, any archive index cache.
+func extractRemoteZip(ctx, downloadCtx context.Context, u *url.URL, resp *http.Response, dest, subdir string) error {
+	ra, err := newRangeReader(downloadCtx, u, resp)
+	if err != nil {
+		return err
+	}
+	if disks := zipDisks(ra.tail); disks > 1 {
+		return extractSplitZip(ctx, u, disks, io.NewSectionReader(ra, 0, ra.size), dest, subdir)
+	}
+	cfg := configFromContext(ctx)
+	cache := cfg.archiveIndexes
+	key := cache.key(u, ra)
+	index, ok := cache.get(key)
+	if key != "" {
+		cfg.cacheLookup(u.Host, "archive-index", ok)
+	}
+	if ok && index.tailStart < ra.tailStart {
+		ra.tail, ra.tailStart = index.tail, index.tailStart
+	}
+	recorder := &lowestReaderAt{r: ra, lowest: ra.size}
+	zr, err := zip.NewReader(recorder, ra.size)
+	if err != nil {
+		return fmt.Errorf("unzip %s: %w", RedactURL(u), err)
+	}
+	// Only central directories extending beyond the initial request for the end of the archive are worth caching.
+	if start := recorder.lowest; key != "" && start < ra.tailStart && ra.size-start <= archiveIndexMaxTail {
+		tail := make([]byte, ra.size-start)
+		if _, err := ra.ReadAt(tail, start); err == nil {
+			cache.put(key, &archiveIndex{tail: tail, tailStart: start})
+		}
+	}
+	return extractZipReader(ctx, zr, func() int64 { return ra.size }, dest, subdir, 1)
+}
+
+// extractZipBody unpacks a zip archive as it is streamed from r. If subdir is set, only the entries beneath it are
+// extracted.
+//
+// The final part of a split archive is recognisable by starting partway through an entry rather than with a zip
+// signature. Only its end records how many parts precede it, so it is downloaded to a temporary file first.
+func extractZipBody(ctx context.Context, u *url.URL, r io.Reader, dest, subdir string) error {
+	br := bufio.NewReader(r)
+	if peek, err := br.Peek(4); err == nil {
+		switch binary.LittleEndian.Uint32(peek) {
+		case zipLocalHeaderSig, zipEndSig, zipSplitSig, zipSingleSplitSig:
+		default:
+			tmp, err := downloadZip(ctx, br)
+			if err != nil {
+				return err
+			}
+			defer os.Remove(tmp.Name())
+			defer tmp.Close()
+			return extractZipFile(ctx, u, tmp, dest, subdir, 1)
+		}
+	}
+	return extractZipStream(ctx, br, dest, subdir)
+}
+
+// fetchLocal extracts a local zip archive in place.
+func (z *ZIP) fetchLocal(ctx context.Context, source Source, dest string) (err error) {
+	path := localPath(source.URL)
+	f, err := os.Open(path) // #nosec G304
 	if err != nil {
-		return fmt.Errorf("creating request: %w", err)
+		return fmt.Errorf("open %s: %w", path, err)
 	}
-	resp, err := http.DefaultClient.Do(req)
+	defer f.Close()
+
+	cfg := configFromContext(ctx)
+	cfg.logger.DebugContext(ctx, "extract", "url", RedactURL(source.URL), "dest", dest, "concurrency", z.Concurrency)
+	ctx, span := startSpan(ctx, "getit.extract", map[string]string{"url": RedactURL(source.URL)})
+	defer func() { span.End(err) }()
+	ctx, cancel := withPhaseTimeout(ctx, "extract", cfg.timeouts.Extract)
+	defer cancel()
+	return extractZipFile(ctx, source.URL, f, dest, source.SubDir, max(z.Concurrency, 1))
+}
+
+// fetchToTemp downloads the archive to a temporary file, then extracts it concurrently.
+func (z *ZIP) fetchToTemp(ctx context.Context, source Source, dest string) error {
+	resp, err := httpGet(ctx, source.URL)
 	if err != nil {
-		return fmt.Errorf("fetching %s: %w", source.URL, err)
+		return err
 	}
 	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("fetching %s: %s", source.URL, resp.Status)
+
+	tmp, err := downloadZip(ctx, newCountingReader(ctx, resp.Body, source.URL.Host))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	cfg := configFromContext(ctx)
+	cfg.logger.DebugContext(ctx, "extract", "url", RedactURL(source.URL), "dest", dest, "concurrency", z.Concurrency)
+	ctx, span := startSpan(ctx, "getit.extract", map[string]string{"url": RedactURL(source.URL)})
+	ctx, cancel := withPhaseTimeout(ctx, "extract", cfg.timeouts.Extract)
+	defer cancel()
+	err = extractZipFile(ctx, source.URL, tmp, dest, source.SubDir, z.Concurrency)
+	span.End(err)
+	return err
+}
+
+// downloadZip copies a zip archive from r to a temporary file, which the caller must close and remove.
+func downloadZip(ctx context.Context, r io.Reader) (*os.File, error) {
+	tmp, err := configFromContext(ctx).createTemp("zip-*.zip")
+	if err != nil {
+		return nil, err
+	}
+	if _, err = io.Copy(tmp, r); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return nil, fmt.Errorf("copying response body to temporary file: %w", err)
+	}
+	return tmp, nil
+}
+
+// extractZipFile unpacks the zip archive downloaded from u to tmp, extracting up to concurrency entries at once. If
+// tmp is the final part of a split archive, the other parts are fetched and the archive is streamed instead.
+func extractZipFile(ctx context.Context, u *url.URL, tmp *os.File, dest, subdir string, concurrency int) error {
+	info, err := tmp.Stat()
+	if err != nil {
+		return fmt.Errorf("stat %s: %w", tmp.Name(), err)
+	}
+	disks, err := zipFileDisks(tmp, info.Size())
+	if err != nil {
+		return err
+	}
+	if disks > 1 {
+		return extractSplitZip(ctx, u, disks, io.NewSectionReader(tmp, 0, info.Size()), dest, subdir)
 	}
+	return extractZip(ctx, tmp.Name(), dest, subdir, concurrency)
+}
 
-	// Write the zip to a temporary file
-	zip, err := os.CreateTemp("", "zip-*.zip")
+// extractZip unpacks the zip file at path into dest, extracting up to concurrency entries at once.
+func extractZip(ctx context.Context, path, dest, subdir string, concurrency int) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("stat %s: %w", path, err)
+	}
+	zr, err := zip.OpenReader(path)
 	if err != nil {
-		return fmt.Errorf("creating temporary file: %w", err)
+		return fmt.Errorf("unzip %s: %w", path, err)
+	}
+	defer zr.Close()
+	return extractZipReader(ctx, &zr.Reader, info.Size, dest, subdir, concurrency)
+}
+
+// extractZipReader unpacks a zip archive of the given compressed size into dest. If subdir is set, only the entries
+// beneath it are extracted, relative to it.
+//
+// Directories and files are extracted by up to concurrency workers, while symlinks are created once they are done so
+// that no entry is written through a link.
+func extractZipReader(ctx context.Context, zr *zip.Reader, compressed func() int64, dest, subdir string, concurrency int) error {
+	cfg := configFromContext(ctx)
+	limits := newLimiter(cfg.limits, compressed)
+	times := newTimestamper(cfg.options)
+	files := zr.File
+	if cfg.options.Deterministic {
+		files = slices.Clone(files)
+		slices.SortStableFunc(files, func(a, b *zip.File) int { return strings.Compare(a.Name, b.Name) })
+	}
+	cases := newCaseTracker(cfg.caseCollisions)
+	pool := newWorkerPool(ctx, max(concurrency, 1))
+	var links []*zip.File
+	var linkNames []string
+	found := subdir == ""
+	extract := func(ctx context.Context, f *zip.File, name string) error {
+		target, err := securePath(dest, name)
+		if err != nil {
+			return err
+		}
+		size, err := extractZipEntry(ctx, f, target, limits, cfg.permissions, cfg.options.ArchivePassword)
+		if err != nil {
+			return err
+		}
+		return pool.finish(func() error {
+			if err := times.record(target, f.Mode(), f.Modified); err != nil {
+				return err
+			}
+			cfg.fileExtracted(name, size)
+			return nil
+		})
+	}
+	for _, f := range files {
+		if err := contextError(pool.ctx); err != nil {
+			pool.fail(err)
+			break
+		}
+		name, err := zipEntryName(f.Name, f.Flags, f.Extra, cfg.zipNames)
+		if err == nil && subdir != "" {
+			rel, ok := withinSubDir(name, subdir)
+			if !ok {
+				continue
+			}
+			found = true
+			if rel == "" {
+				continue
+			}
+			name = rel
+		}
+		if err == nil {
+			name, err = cases.resolve(name)
+		}
+		if err == nil {
+			_, err = securePath(dest, name)
+		}
+		if err == nil {
+			err = cfg.pathRules.check(name)
+		}
+		if err == nil {
+			err = limits.entry(f.Name)
+		}
+		if err != nil {
+			pool.fail(err)
+			break
+		}
+		if f.Mode()&fs.ModeSymlink != 0 {
+			links = append(links, f)
+			linkNames = append(linkNames, name)
+			continue
+		}
+		pool.run(func() error { return extract(pool.ctx, f, name) })
+	}
+	if err := pool.wait(); err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("subdirectory %s not found in archive", subdir)
+	}
+	for i, f := range links {
+		if err := extract(ctx, f, linkNames[i]); err != nil {
+			return err
+		}
+	}
+	return times.finish()
+}
+
+func extractZipEntry(ctx context.Context, f *zip.File, target string, limits *limiter, perms Permissions, password string) (int64, error) {
+	mode := f.Mode()
+	if mode.IsDir() {
+		return 0, writeDir(target, mode, perms)
 	}
-	defer zip.Close()
-	defer os.Remove(zip.Name())
-	if _, err = io.Copy(zip, resp.Body); err != nil {
-		return fmt.Errorf("copying response body to temporary file: %w", err)
+	r, err := openZipFile(f, password)
+	if err != nil {
+		return 0, err
 	}
-	if err = zip.Close(); err != nil {
-		return fmt.Errorf("closing temporary file: %w", err)
+	defer r.Close()
+	if mode&fs.ModeSymlink != 0 {
+		link, err := io.ReadAll(r)
+		if err != nil {
+			return 0, fmt.Errorf("read %s: %w", f.Name, err)
+		}
+		return 0, writeSymlink(target, string(link))
 	}
+	return writeFile(target, limits.reader(f.Name, &contextReader{ctx: ctx, r: r}), mode, perms)
+}
 
-	// Unzip
-	stderr := &bytes.Buffer{}
-	cmd := exec.CommandContext(ctx, "unzip", "-d", dest, zip.Name()) // #nosec G204
-	cmd.Stderr = stderr
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("unzip %s: %w: %s", zip.Name(), err, stderr)
+// openZipFile opens an entry of a zip archive, decrypting it with password if it is encrypted.
+func openZipFile(f *zip.File, password string) (io.ReadCloser, error) {
+	if f.Flags&zipFlagEncrypted == 0 {
+		r, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("open %s: %w", f.Name, err)
+		}
+		return r, nil
+	}
+	raw, err := f.OpenRaw()
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", f.Name, err)
+	}
+	header := zipEntryHeader{
+		name:         f.Name,
+		flags:        f.Flags,
+		method:       f.Method,
+		extra:        f.Extra,
+		crc32:        f.CRC32,
+		modifiedTime: f.ModifiedTime,
+		size:         int64(f.CompressedSize64), //nolint:gosec // sizes beyond int64 fail to read
+	}
+	r, checkCRC, err := openZipData(raw, header, password)
+	if err != nil {
+		return nil, err
+	}
+	if !checkCRC {
+		return io.NopCloser(r), nil
 	}
-	return nil
+	crc := crc32.NewIEEE()
+	return io.NopCloser(&checkedReader{r: io.TeeReader(r, crc), checks: []func() error{func() error {
+		if crc.Sum32() != f.CRC32 {
+			return fmt.Errorf("%s: checksum mismatch", f.Name)
+		}
+		return nil
+	}}}), nil
 }
@@ -1,13 +1,22 @@
 package getit_test
 
 import (
+	"archive/zip"
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"math/rand/v2"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
 	"path/filepath"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/alecthomas/assert/v2"
 
@@ -22,8 +31,11 @@ func TestZIPMatch(t *testing.T) {
 	}{
 		{name: "ZipFile", path: "/archive.zip", expected: true},
 		{name: "NestedPath", path: "/some/deep/path/archive.zip", expected: true},
-		{name: "WithQueryParams", path: "/archive.zip?token=abc", expected: false},
-		{name: "UppercaseZip", path: "/archive.ZIP", expected: false},
+		{name: "WithQueryParams", path: "/archive.zip?token=abc", expected: true},
+		{name: "WithFragment", path: "/archive.zip#section", expected: true},
+		{name: "WithSubdir", path: "/archive.zip//subdir", expected: true},
+		{name: "UppercaseZip", path: "/archive.ZIP", expected: true},
+		{name: "ZipSignature", path: "/archive.zip.sig", expected: false},
 		{name: "TarGz", path: "/archive.tar.gz", expected: false},
 		{name: "PlainFile", path: "/file.txt", expected: false},
 		{name: "ZipInName", path: "/zipfile.tar", expected: false},
@@ -119,3 +131,361 @@ func TestZIPFetchCancelledContext(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "context canceled")
 }
+
+func TestZIPFetchRejectsPathEscape(t *testing.T) {
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	w, err := zw.Create("../escaped.txt")
+	assert.NoError(t, err)
+	_, err = w.Write([]byte("escaped"))
+	assert.NoError(t, err)
+	assert.NoError(t, zw.Close())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(buf.Bytes())
+	}))
+	defer server.Close()
+
+	u, err := url.Parse(server.URL + "/archive.zip")
+	assert.NoError(t, err)
+
+	root := t.TempDir()
+	dest := filepath.Join(root, "dest")
+	err = getit.NewZIP().Fetch(context.Background(), getit.Source{URL: u}, dest)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "escapes destination")
+	_, err = os.Stat(filepath.Join(root, "escaped.txt"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestZIPFetchRejectsSymlinkTraversal(t *testing.T) {
+	outside := t.TempDir()
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	for _, link := range []struct{ name, target string }{{"a", outside}, {"a/pwned", "target"}} {
+		header := &zip.FileHeader{Name: link.name}
+		header.SetMode(os.ModeSymlink | 0o777)
+		w, err := zw.CreateHeader(header)
+		assert.NoError(t, err)
+		_, err = w.Write([]byte(link.target))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, zw.Close())
+	data := buf.Bytes()
+
+	for _, ranges := range []bool{true, false} {
+		t.Run(map[bool]string{true: "Ranges", false: "Streaming"}[ranges], func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if ranges {
+					http.ServeContent(w, r, "archive.zip", time.Time{}, bytes.NewReader(data))
+					return
+				}
+				_, _ = w.Write(data)
+			}))
+			defer server.Close()
+
+			u, err := url.Parse(server.URL + "/archive.zip")
+			assert.NoError(t, err)
+			err = getit.NewZIP().Fetch(context.Background(), getit.Source{URL: u}, t.TempDir())
+			assert.Error(t, err)
+			_, err = os.Lstat(filepath.Join(outside, "pwned"))
+			assert.True(t, os.IsNotExist(err), "entry written outside the destination")
+		})
+	}
+}
+
+// testZip builds a zip exercising the entry types and encodings supported by the ZIP resolver.
+func testZip(t *testing.T, large int) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	add := func(header *zip.FileHeader, content string) {
+		w, err := zw.CreateHeader(header)
+		assert.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		assert.NoError(t, err)
+	}
+	add(&zip.FileHeader{Name: "dir/", Method: zip.Store}, "")
+	add(&zip.FileHeader{Name: "dir/deflated.txt", Method: zip.Deflate}, "deflated\n")
+	add(&zip.FileHeader{Name: "dir/stored.txt", Method: zip.Store}, "stored PK\x07\x08 content\n")
+	exe := &zip.FileHeader{Name: "bin/tool", Method: zip.Deflate}
+	exe.SetMode(0o755)
+	add(exe, "#!/bin/sh\n")
+	link := &zip.FileHeader{Name: "link.txt", Method: zip.Store}
+	link.SetMode(0o777 | os.ModeSymlink)
+	add(link, "dir/deflated.txt")
+	// Random content is incompressible, so the archive spans several ranged reads.
+	random := make([]byte, large)
+	_, _ = rand.NewChaCha8([32]byte{}).Read(random)
+	add(&zip.FileHeader{Name: "large.bin", Method: zip.Deflate}, string(random))
+
+	raw := &zip.FileHeader{Name: "raw.txt", Method: zip.Store, CRC32: crc32.ChecksumIEEE([]byte("raw\n")), CompressedSize64: 4, UncompressedSize64: 4}
+	w, err := zw.CreateRaw(raw)
+	assert.NoError(t, err)
+	_, err = w.Write([]byte("raw\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestZIPFetchModes(t *testing.T) {
+	const large = 3_000_000
+	data := testZip(t, large)
+	tests := []struct {
+		name        string
+		ranged      bool
+		concurrency int
+	}{
+		{name: "Streaming"},
+		{name: "Ranged", ranged: true},
+		{name: "Concurrent", ranged: true, concurrency: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int64
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests.Add(1)
+				if tt.ranged {
+					http.ServeContent(w, r, "archive.zip", time.Time{}, bytes.NewReader(data))
+					return
+				}
+				_, _ = w.Write(data)
+			}))
+			defer server.Close()
+
+			u, err := url.Parse(server.URL + "/archive.zip")
+			assert.NoError(t, err)
+			dest := t.TempDir()
+			z := &getit.ZIP{Concurrency: tt.concurrency}
+			err = z.Fetch(context.Background(), getit.Source{URL: u}, dest)
+			assert.NoError(t, err)
+
+			for path, expected := range map[string]string{
+				"dir/deflated.txt": "deflated\n",
+				"dir/stored.txt":   "stored PK\x07\x08 content\n",
+				"bin/tool":         "#!/bin/sh\n",
+				"link.txt":         "deflated\n",
+				"raw.txt":          "raw\n",
+			} {
+				content, err := os.ReadFile(filepath.Join(dest, path))
+				assert.NoError(t, err)
+				assert.Equal(t, expected, string(content), path)
+			}
+			info, err := os.Stat(filepath.Join(dest, "large.bin"))
+			assert.NoError(t, err)
+			assert.Equal(t, int64(large), info.Size())
+			info, err = os.Stat(filepath.Join(dest, "bin", "tool"))
+			assert.NoError(t, err)
+			assert.Equal(t, os.FileMode(0o111), info.Mode().Perm()&0o111)
+			target, err := os.Readlink(filepath.Join(dest, "link.txt"))
+			assert.NoError(t, err)
+			assert.Equal(t, "dir/deflated.txt", target)
+			if tt.ranged && tt.concurrency == 0 {
+				assert.True(t, requests.Load() > 1, "expected ranged requests")
+			} else {
+				assert.Equal(t, int64(1), requests.Load())
+			}
+		})
+	}
+}
+
+func TestZIPFetchSubDir(t *testing.T) {
+	const large = 3_000_000
+	data := testZip(t, large)
+	local := filepath.Join(t.TempDir(), "archive.zip")
+	assert.NoError(t, os.WriteFile(local, data, 0o600))
+	tests := []struct {
+		name        string
+		local       bool
+		ranged      bool
+		concurrency int
+	}{
+		{name: "Streaming"},
+		{name: "Ranged", ranged: true},
+		{name: "Concurrent", ranged: true, concurrency: 4},
+		{name: "Local", local: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.ranged {
+					http.ServeContent(w, r, "archive.zip", time.Time{}, bytes.NewReader(data))
+					return
+				}
+				_, _ = w.Write(data)
+			}))
+			defer server.Close()
+			source := server.URL + "/archive.zip"
+			if tt.local {
+				source = fileURLForTest(local)
+			}
+			u, err := url.Parse(source)
+			assert.NoError(t, err)
+			z := &getit.ZIP{Concurrency: tt.concurrency}
+
+			dest := t.TempDir()
+			assert.NoError(t, z.Fetch(context.Background(), getit.Source{URL: u, SubDir: "dir"}, dest))
+			entries, err := os.ReadDir(dest)
+			assert.NoError(t, err)
+			var names []string
+			for _, entry := range entries {
+				names = append(names, entry.Name())
+			}
+			assert.Equal(t, []string{"deflated.txt", "stored.txt"}, names)
+			content, err := os.ReadFile(filepath.Join(dest, "stored.txt"))
+			assert.NoError(t, err)
+			assert.Equal(t, "stored PK\x07\x08 content\n", string(content))
+
+			err = z.Fetch(context.Background(), getit.Source{URL: u, SubDir: "missing"}, t.TempDir())
+			assert.EqualError(t, err, "subdirectory missing not found in archive")
+		})
+	}
+}
+
+func TestZIPFetchStreamingCorrupt(t *testing.T) {
+	data := testZip(t, 100)
+	// Corrupt the content of the first file.
+	index := bytes.Index(data, []byte("dir/deflated.txt")) + len("dir/deflated.txt")
+	data[index+2] ^= 0xff
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+
+	u, err := url.Parse(server.URL + "/archive.zip")
+	assert.NoError(t, err)
+	err = getit.NewZIP().Fetch(context.Background(), getit.Source{URL: u}, t.TempDir())
+	assert.Error(t, err)
+}
+
+// zip64Zip creates an archive using zip64 sizes, offsets and end of central directory records, as written for
+// archives over 4GB, containing a single stored file.
+func zip64Zip(t *testing.T, name, content string) []byte {
+	t.Helper()
+	size := uint64(len(content))
+	crc := crc32.ChecksumIEEE([]byte(content))
+	buf := &bytes.Buffer{}
+	write := func(fields ...any) {
+		for _, field := range fields {
+			assert.NoError(t, binary.Write(buf, binary.LittleEndian, field))
+		}
+	}
+	write(uint32(0x04034b50), uint16(45), uint16(0), uint16(zip.Store), uint16(0), uint16(0x21), crc,
+		uint32(0xffffffff), uint32(0xffffffff), uint16(len(name)), uint16(20))
+	buf.WriteString(name)
+	write(uint16(1), uint16(16), size, size)
+	buf.WriteString(content)
+
+	central := uint64(buf.Len())
+	write(uint32(0x02014b50), uint16(3<<8|45), uint16(45), uint16(0), uint16(zip.Store), uint16(0), uint16(0x21), crc,
+		uint32(0xffffffff), uint32(0xffffffff), uint16(len(name)), uint16(28), uint16(0), uint16(0), uint16(0),
+		uint32(0o100644<<16), uint32(0xffffffff))
+	buf.WriteString(name)
+	write(uint16(1), uint16(24), size, size, uint64(0))
+
+	end := uint64(buf.Len())
+	write(uint32(0x06064b50), uint64(44), uint16(45), uint16(45), uint32(0), uint32(0), uint64(1), uint64(1),
+		end-central, central)
+	write(uint32(0x07064b50), uint32(0), end, uint32(1))
+	write(uint32(0x06054b50), uint16(0xffff), uint16(0xffff), uint16(0xffff), uint16(0xffff),
+		uint32(0xffffffff), uint32(0xffffffff), uint16(0))
+	return buf.Bytes()
+}
+
+func TestZIPFetchZip64AndSplit(t *testing.T) {
+	part, err := os.ReadFile(filepath.Join("testdata", "split.z01"))
+	assert.NoError(t, err)
+	last, err := os.ReadFile(filepath.Join("testdata", "split.zip"))
+	assert.NoError(t, err)
+	archives := []struct {
+		name     string
+		files    map[string][]byte
+		expected map[string]string
+	}{
+		{
+			name:     "Zip64",
+			files:    map[string][]byte{"/archive.zip": zip64Zip(t, "big.txt", "zip64 content\n")},
+			expected: map[string]string{"big.txt": "463781d6750ea97c17fc5ee90fe9eea06645c7cdbcebe422e8e8e304fbfb75da"},
+		},
+		{
+			name:  "Split",
+			files: map[string][]byte{"/archive.z01": part, "/archive.zip": last},
+			expected: map[string]string{
+				"small.txt": "4c47b3e816fbe7d40cef9f665ba8f0be1ae68b5e8e7ed70f5b6bab7f70528e8f",
+				"data.bin":  "2cade6447451a3de8574077c8d66d9da3a9e26574e13721e236ee6a051f6731d",
+			},
+		},
+	}
+	modes := []struct {
+		name        string
+		ranged      bool
+		concurrency int
+	}{
+		{name: "Streaming"},
+		{name: "Ranged", ranged: true},
+		{name: "Concurrent", concurrency: 4},
+	}
+	for _, archive := range archives {
+		for _, mode := range modes {
+			t.Run(archive.name+"/"+mode.name, func(t *testing.T) {
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					data, ok := archive.files[r.URL.Path]
+					if !ok {
+						http.NotFound(w, r)
+						return
+					}
+					if mode.ranged {
+						http.ServeContent(w, r, "archive.zip", time.Time{}, bytes.NewReader(data))
+						return
+					}
+					_, _ = w.Write(data)
+				}))
+				defer server.Close()
+
+				u, err := url.Parse(server.URL + "/archive.zip")
+				assert.NoError(t, err)
+				dest := t.TempDir()
+				z := &getit.ZIP{Concurrency: mode.concurrency}
+				err = z.Fetch(context.Background(), getit.Source{URL: u}, dest)
+				assert.NoError(t, err)
+				for path, expected := range archive.expected {
+					content, err := os.ReadFile(filepath.Join(dest, path))
+					assert.NoError(t, err)
+					assert.Equal(t, expected, fmt.Sprintf("%x", sha256.Sum256(content)), path)
+				}
+			})
+		}
+	}
+}
+
+func TestZIPFetchWithTempDir(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "archive.zip"))
+	assert.NoError(t, err)
+	tempDir := filepath.Join(t.TempDir(), "scratch")
+	var downloading []os.DirEntry
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		// Wait for the download to start, then record the temporary file it is written to.
+		for range 100 {
+			if downloading, _ = os.ReadDir(tempDir); len(downloading) > 0 {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+
+	fetcher := getit.New([]getit.Resolver{&getit.ZIP{Concurrency: 2}}, nil, getit.WithTempDir(tempDir))
+	dest := t.TempDir()
+	assert.NoError(t, fetcher.Fetch(context.Background(), server.URL+"/archive.zip", dest))
+	assert.Equal(t, 1, len(downloading))
+	remaining, err := os.ReadDir(tempDir)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(remaining))
+	content, err := os.ReadFile(filepath.Join(dest, "file.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "hello from test\n", string(content))
+}
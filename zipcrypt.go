@@ -0,0 +1,229 @@
+package getit
+
+import (
+	"archive/zip"
+	"compress/flate"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/sha1" //nolint:gosec // required by the WinZip AES format
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+const (
+	zipMethodAES   = 99
+	zipAESExtraID  = 0x9901
+	zipAESMACSize  = 10
+	zipCryptoSize  = 12
+	zipAESVersion2 = 2
+)
+
+// ErrArchivePassword is returned when an encrypted archive entry can't be decrypted with
+// [FetchOptions.ArchivePassword].
+var ErrArchivePassword = errors.New("incorrect or missing archive password")
+
+// zipEntryHeader holds the fields of a zip entry header needed to decrypt and decompress it.
+type zipEntryHeader struct {
+	name         string
+	flags        uint16
+	method       uint16
+	extra        []byte
+	crc32        uint32
+	modifiedTime uint16
+	size         int64 // Compressed size, including any encryption header and trailer.
+}
+
+// openZipData returns a reader of the decompressed contents of an entry from its raw data, decrypting it with
+// password if it is encrypted, and whether the entry's CRC should be checked. Any authentication code is verified
+// once the contents have been read, and a mismatch is reported instead of io.EOF.
+func openZipData(raw io.Reader, header zipEntryHeader, password string) (io.Reader, bool, error) {
+	data := io.LimitReader(raw, header.size)
+	method := header.method
+	var checks []func() error
+	checkCRC := true
+	if header.flags&zipFlagEncrypted != 0 {
+		if password == "" {
+			return nil, false, fmt.Errorf("%s: %w", header.name, ErrArchivePassword)
+		}
+		var err error
+		if method == zipMethodAES {
+			var check func() error
+			data, method, checkCRC, check, err = decryptZipAES(data, header, password)
+			checks = append(checks, check)
+		} else {
+			data, err = decryptZipCrypto(data, header, password)
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("%s: %w", header.name, err)
+		}
+	}
+	switch method {
+	case zip.Store:
+	case zip.Deflate:
+		data = flate.NewReader(data)
+	default:
+		return nil, false, fmt.Errorf("%s: unsupported compression method %d", header.name, method)
+	}
+	return &checkedReader{r: data, checks: checks}, checkCRC, nil
+}
+
+// checkedReader runs checks once r is exhausted, returning the first failure instead of io.EOF.
+type checkedReader struct {
+	r      io.Reader
+	checks []func() error
+}
+
+func (c *checkedReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if errors.Is(err, io.EOF) {
+		for _, check := range c.checks {
+			if err := check(); err != nil {
+				return n, err
+			}
+		}
+		c.checks = nil
+	}
+	return n, err //nolint:wrapcheck // io.Reader errors such as io.EOF must not be wrapped
+}
+
+// decryptZipCrypto decrypts an entry encrypted with traditional PKWARE encryption.
+func decryptZipCrypto(r io.Reader, header zipEntryHeader, password string) (io.Reader, error) {
+	d := &zipCryptoReader{r: r, keys: [3]uint32{0x12345678, 0x23456789, 0x34567890}}
+	for i := range len(password) {
+		d.update(password[i])
+	}
+	var encryption [zipCryptoSize]byte
+	if _, err := io.ReadFull(d, encryption[:]); err != nil {
+		return nil, fmt.Errorf("reading encryption header: %w", noEOF(err))
+	}
+	// The last byte of the header is checked against the CRC, or the time if the CRC follows the data.
+	check := byte(header.crc32 >> 24)
+	if header.flags&zipFlagDescriptor != 0 {
+		check = byte(header.modifiedTime >> 8)
+	}
+	if encryption[zipCryptoSize-1] != check {
+		return nil, ErrArchivePassword
+	}
+	return d, nil
+}
+
+// zipCryptoReader decrypts traditional PKWARE encryption.
+type zipCryptoReader struct {
+	r    io.Reader
+	keys [3]uint32
+}
+
+func (z *zipCryptoReader) update(b byte) {
+	z.keys[0] = crc32Update(z.keys[0], b)
+	z.keys[1] = (z.keys[1]+z.keys[0]&0xff)*134775813 + 1
+	z.keys[2] = crc32Update(z.keys[2], byte(z.keys[1]>>24))
+}
+
+func (z *zipCryptoReader) Read(p []byte) (int, error) {
+	n, err := z.r.Read(p)
+	for i := range p[:n] {
+		temp := z.keys[2] | 2
+		p[i] ^= byte((temp * (temp ^ 1)) >> 8)
+		z.update(p[i])
+	}
+	return n, err //nolint:wrapcheck // io.Reader errors such as io.EOF must not be wrapped
+}
+
+func crc32Update(crc uint32, b byte) uint32 {
+	return crc32.IEEETable[byte(crc)^b] ^ crc>>8
+}
+
+// decryptZipAES decrypts an entry encrypted with WinZip AES encryption, returning the decrypted data, the actual
+// compression method, whether the CRC should be checked, and a check of the authentication code.
+func decryptZipAES(r io.Reader, header zipEntryHeader, password string) (io.Reader, uint16, bool, func() error, error) {
+	var field []byte
+	for id, data := range zipExtraFields(header.extra) {
+		if id == zipAESExtraID {
+			field = data
+		}
+	}
+	if len(field) < 7 || field[4] < 1 || field[4] > 3 {
+		return nil, 0, false, nil, errors.New("invalid AES extra field")
+	}
+	version := binary.LittleEndian.Uint16(field)
+	strength := int(field[4])
+	method := binary.LittleEndian.Uint16(field[5:])
+	saltSize, keySize := 4+4*strength, 8+8*strength
+
+	salt := make([]byte, saltSize+2)
+	if _, err := io.ReadFull(r, salt); err != nil {
+		return nil, 0, false, nil, fmt.Errorf("reading encryption header: %w", noEOF(err))
+	}
+	keys, err := pbkdf2.Key(sha1.New, password, salt[:saltSize], 1000, 2*keySize+2)
+	if err != nil {
+		return nil, 0, false, nil, fmt.Errorf("deriving key: %w", err)
+	}
+	if !hmac.Equal(keys[2*keySize:], salt[saltSize:]) {
+		return nil, 0, false, nil, ErrArchivePassword
+	}
+	block, err := aes.NewCipher(keys[:keySize])
+	if err != nil {
+		return nil, 0, false, nil, fmt.Errorf("creating cipher: %w", err)
+	}
+	mac := hmac.New(sha1.New, keys[keySize:2*keySize])
+	size := header.size - int64(saltSize) - 2 - zipAESMACSize
+	if size < 0 {
+		return nil, 0, false, nil, errors.New("truncated AES entry")
+	}
+	data := &zipAESReader{r: io.TeeReader(io.LimitReader(r, size), mac), block: block}
+	check := func() error {
+		// Consume any data not read by the decompressor, so that the whole of it is authenticated.
+		if _, err := io.Copy(io.Discard, data); err != nil {
+			return fmt.Errorf("%s: %w", header.name, err)
+		}
+		code := make([]byte, zipAESMACSize)
+		if _, err := io.ReadFull(r, code); err != nil {
+			return fmt.Errorf("%s: reading authentication code: %w", header.name, noEOF(err))
+		}
+		if !hmac.Equal(code, mac.Sum(nil)[:zipAESMACSize]) {
+			return fmt.Errorf("%s: authentication failed", header.name)
+		}
+		return nil
+	}
+	// AE-2 omits the CRC, relying on the authentication code.
+	return data, method, version != zipAESVersion2, check, nil
+}
+
+// zipAESReader decrypts AES in CTR mode with the little-endian counter, starting at 1, used by WinZip.
+type zipAESReader struct {
+	r         io.Reader
+	block     cipher.Block
+	counter   [aes.BlockSize]byte
+	keystream [aes.BlockSize]byte
+	used      int
+	started   bool
+}
+
+func (z *zipAESReader) Read(p []byte) (int, error) {
+	n, err := z.r.Read(p)
+	for i := range p[:n] {
+		if !z.started || z.used == aes.BlockSize {
+			z.next()
+		}
+		p[i] ^= z.keystream[z.used]
+		z.used++
+	}
+	return n, err //nolint:wrapcheck // io.Reader errors such as io.EOF must not be wrapped
+}
+
+func (z *zipAESReader) next() {
+	z.started = true
+	for i := range z.counter {
+		z.counter[i]++
+		if z.counter[i] != 0 {
+			break
+		}
+	}
+	z.block.Encrypt(z.keystream[:], z.counter[:])
+	z.used = 0
+}
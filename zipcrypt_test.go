@@ -0,0 +1,139 @@
+package getit_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"context"
+	"crypto/aes"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/sha1" //nolint:gosec // required by the WinZip AES format
+	"encoding/binary"
+	"hash/crc32"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/block/getit"
+)
+
+// aesZip creates a zip archive containing a single deflated entry encrypted with 256-bit WinZip AES.
+func aesZip(t *testing.T, name, content, password string, version uint16) []byte {
+	t.Helper()
+	compressed := &bytes.Buffer{}
+	fw, err := flate.NewWriter(compressed, flate.DefaultCompression)
+	assert.NoError(t, err)
+	_, err = fw.Write([]byte(content))
+	assert.NoError(t, err)
+	assert.NoError(t, fw.Close())
+
+	salt := bytes.Repeat([]byte{0x5a}, 16)
+	keys, err := pbkdf2.Key(sha1.New, password, salt, 1000, 66)
+	assert.NoError(t, err)
+	block, err := aes.NewCipher(keys[:32])
+	assert.NoError(t, err)
+	ciphertext := compressed.Bytes()
+	var counter, keystream [aes.BlockSize]byte
+	for i := range ciphertext {
+		if i%aes.BlockSize == 0 {
+			binary.LittleEndian.PutUint64(counter[:], uint64(i/aes.BlockSize+1)) //nolint:gosec // test data is small
+			block.Encrypt(keystream[:], counter[:])
+		}
+		ciphertext[i] ^= keystream[i%aes.BlockSize]
+	}
+	mac := hmac.New(sha1.New, keys[32:64])
+	_, _ = mac.Write(ciphertext)
+
+	extra := binary.LittleEndian.AppendUint16(nil, 0x9901)
+	extra = binary.LittleEndian.AppendUint16(extra, 7)
+	extra = binary.LittleEndian.AppendUint16(extra, version)
+	extra = append(extra, 'A', 'E', 3)
+	extra = binary.LittleEndian.AppendUint16(extra, zip.Deflate)
+	header := &zip.FileHeader{Name: name, Method: 99, Flags: 0x1, Extra: extra, Modified: time.Now()}
+	payload := append(append(append(salt, keys[64:]...), ciphertext...), mac.Sum(nil)[:10]...)
+	header.CompressedSize64 = uint64(len(payload))
+	header.UncompressedSize64 = uint64(len(content))
+	if version == 1 {
+		header.CRC32 = crc32.ChecksumIEEE([]byte(content))
+	}
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	w, err := zw.CreateRaw(header)
+	assert.NoError(t, err)
+	_, err = w.Write(payload)
+	assert.NoError(t, err)
+	assert.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestFetchEncryptedZIP(t *testing.T) {
+	zipCrypto, err := os.ReadFile(filepath.Join("testdata", "encrypted.zip"))
+	assert.NoError(t, err)
+	archives := []struct {
+		name string
+		data []byte
+	}{
+		{name: "ZipCrypto", data: zipCrypto},
+		{name: "AE1", data: aesZip(t, "file.txt", "secret content\n", "secret", 1)},
+		{name: "AE2", data: aesZip(t, "file.txt", "secret content\n", "secret", 2)},
+	}
+	tests := []struct {
+		name     string
+		password string
+		ranged   bool
+		err      string
+	}{
+		{name: "Streaming", password: "secret"},
+		{name: "Ranged", password: "secret", ranged: true},
+		{name: "WrongPassword", password: "wrong", ranged: true, err: "incorrect or missing archive password"},
+		{name: "MissingPassword", ranged: true, err: "incorrect or missing archive password"},
+	}
+	for _, archive := range archives {
+		for _, tt := range tests {
+			t.Run(archive.name+"/"+tt.name, func(t *testing.T) {
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if tt.ranged {
+						http.ServeContent(w, r, "archive.zip", time.Time{}, bytes.NewReader(archive.data))
+						return
+					}
+					_, _ = w.Write(archive.data)
+				}))
+				defer server.Close()
+
+				dest := t.TempDir()
+				fetcher := getit.New([]getit.Resolver{getit.NewZIP()}, nil)
+				_, err := fetcher.FetchWithOptions(context.Background(), server.URL+"/archive.zip", dest, getit.FetchOptions{ArchivePassword: tt.password})
+				if tt.err != "" {
+					assert.IsError(t, err, getit.ErrArchivePassword)
+					assert.Contains(t, err.Error(), tt.err)
+					return
+				}
+				assert.NoError(t, err)
+				content, err := os.ReadFile(filepath.Join(dest, "file.txt"))
+				assert.NoError(t, err)
+				assert.Equal(t, "secret content\n", string(content))
+			})
+		}
+	}
+}
+
+func TestFetchEncryptedZIPTampered(t *testing.T) {
+	data := aesZip(t, "file.txt", "secret content\n", "secret", 2)
+	// Flip a bit in the ciphertext, which follows the 30 byte local header, name, extra, salt and verifier.
+	data[30+len("file.txt")+11+18] ^= 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "archive.zip", time.Time{}, bytes.NewReader(data))
+	}))
+	defer server.Close()
+
+	fetcher := getit.New([]getit.Resolver{getit.NewZIP()}, nil)
+	_, err := fetcher.FetchWithOptions(context.Background(), server.URL+"/archive.zip", t.TempDir(), getit.FetchOptions{ArchivePassword: "secret"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "authentication failed")
+}
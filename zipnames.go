@@ -0,0 +1,118 @@
+package getit
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"strings"
+	"unicode/utf8"
+)
+
+// ZipNamePolicy controls how zip entry names that are not UTF-8 are extracted.
+//
+// Zip entry names are CP437 unless flagged as UTF-8, although many tools write UTF-8 or the local code page without
+// setting the flag. A name is decoded from the Info-ZIP Unicode path extra field if present, as UTF-8 if flagged or
+// valid, and otherwise from CP437.
+type ZipNamePolicy int
+
+const (
+	// ZipNamesDecode decodes names as described by [ZipNamePolicy], replacing invalid bytes in names flagged as
+	// UTF-8 with U+FFFD. This is the default.
+	ZipNamesDecode ZipNamePolicy = iota
+	// ZipNamesTransliterate decodes names as ZipNamesDecode, then replaces accented Latin letters with their ASCII
+	// equivalents and any other non-ASCII characters with "_".
+	ZipNamesTransliterate
+	// ZipNamesReject fails extraction of entries whose names are neither valid UTF-8 nor recorded in a Unicode
+	// path extra field.
+	ZipNamesReject
+)
+
+// WithZipNamePolicy sets how a [Fetcher] extracts zip entries whose names are not UTF-8.
+func WithZipNamePolicy(policy ZipNamePolicy) Option {
+	return func(f *Fetcher) { f.config.zipNames = policy }
+}
+
+const (
+	zipFlagUTF8           = 0x800
+	zipUnicodePathExtraID = 0x7075
+)
+
+// zipEntryName decodes the raw name of a zip entry according to policy.
+func zipEntryName(raw string, flags uint16, extra []byte, policy ZipNamePolicy) (string, error) {
+	name, ok := zipUnicodePath(raw, extra)
+	switch {
+	case ok:
+	case utf8.ValidString(raw):
+		name = raw
+	case policy == ZipNamesReject:
+		return "", fmt.Errorf("%q: zip entry name is not valid UTF-8", raw)
+	case flags&zipFlagUTF8 != 0:
+		name = strings.ToValidUTF8(raw, "\uFFFD")
+	default:
+		name = decodeCP437(raw)
+	}
+	if policy == ZipNamesTransliterate {
+		name = transliterate(name)
+	}
+	return name, nil
+}
+
+// zipUnicodePath returns the name from an Info-ZIP Unicode path extra field, if present and it matches raw.
+func zipUnicodePath(raw string, extra []byte) (string, bool) {
+	for id, data := range zipExtraFields(extra) {
+		if id != zipUnicodePathExtraID || len(data) < 5 || data[0] != 1 {
+			continue
+		}
+		// The field is ignored if the name has been changed by a tool that doesn't update it.
+		if binary.LittleEndian.Uint32(data[1:5]) != crc32.ChecksumIEEE([]byte(raw)) || !utf8.Valid(data[5:]) {
+			return "", false
+		}
+		return string(data[5:]), true
+	}
+	return "", false
+}
+
+// cp437 maps the upper half of code page 437 to Unicode.
+var cp437 = []rune("" +
+	"ÇüéâäàåçêëèïîìÄÅÉæÆôöòûùÿÖÜ¢£¥₧ƒ" +
+	"áíóúñÑªº¿⌐¬½¼¡«»░▒▓│┤╡╢╖╕╣║╗╝╜╛┐" +
+	"└┴┬├─┼╞╟╚╔╩╦╠═╬╧╨╤╥╙╘╒╓╫╪┘┌█▄▌▐▀" +
+	"αßΓπΣσµτΦΘΩδ∞φε∩≡±≥≤⌠⌡÷≈°∙·√ⁿ²■\u00a0")
+
+func decodeCP437(s string) string {
+	var b strings.Builder
+	for i := range len(s) {
+		if c := s[i]; c < 0x80 {
+			b.WriteByte(c)
+		} else {
+			b.WriteRune(cp437[c-0x80])
+		}
+	}
+	return b.String()
+}
+
+// transliterations of non-ASCII letters to ASCII.
+var transliterations = func() map[rune]string {
+	from := []rune("ÀÁÂÃÄÅàáâãäåÇçÈÉÊËèéêëÌÍÎÏìíîïÑñÒÓÔÕÖØòóôõöøÙÚÛÜùúûüÝýÿ")
+	to := "AAAAAAaaaaaaCcEEEEeeeeIIIIiiiiNnOOOOOOooooooUUUUuuuuYyy"
+	m := map[rune]string{'ß': "ss", 'æ': "ae", 'Æ': "AE", 'œ': "oe", 'Œ': "OE"}
+	for i, r := range from {
+		m[r] = to[i : i+1]
+	}
+	return m
+}()
+
+func transliterate(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r < utf8.RuneSelf:
+			b.WriteRune(r)
+		case transliterations[r] != "":
+			b.WriteString(transliterations[r])
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,86 @@
+package getit_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/block/getit"
+)
+
+func legacyNamesZip(t *testing.T, name string) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: name, NonUTF8: true, Method: zip.Deflate})
+	assert.NoError(t, err)
+	_, err = w.Write([]byte("content"))
+	assert.NoError(t, err)
+
+	unicodeName := "ünï.txt"
+	extra := binary.LittleEndian.AppendUint16(nil, 0x7075)
+	extra = binary.LittleEndian.AppendUint16(extra, uint16(5+len(unicodeName)))
+	extra = append(extra, 1)
+	extra = binary.LittleEndian.AppendUint32(extra, crc32.ChecksumIEEE([]byte("legacy.txt")))
+	extra = append(extra, unicodeName...)
+	w, err = zw.CreateHeader(&zip.FileHeader{Name: "legacy.txt", NonUTF8: true, Extra: extra})
+	assert.NoError(t, err)
+	_, err = w.Write([]byte("unicode"))
+	assert.NoError(t, err)
+	assert.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestWithZipNamePolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   getit.ZipNamePolicy
+		entry    string
+		expected []string
+		err      string
+	}{
+		{name: "DecodeCP437", entry: "caf\x82 \x9b.txt", expected: []string{"café ¢.txt", "ünï.txt"}},
+		{name: "DecodeBoxDrawing", entry: "\xc9\xcd\xbb.txt", expected: []string{"╔═╗.txt", "ünï.txt"}},
+		{name: "Transliterate", policy: getit.ZipNamesTransliterate, entry: "caf\x82 \x9b.txt", expected: []string{"cafe _.txt", "uni.txt"}},
+		{name: "Reject", policy: getit.ZipNamesReject, entry: "caf\x82.txt", err: "not valid UTF-8"},
+	}
+	for _, tt := range tests {
+		data := legacyNamesZip(t, tt.entry)
+		for _, ranged := range []bool{false, true} {
+			t.Run(tt.name, func(t *testing.T) {
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if ranged {
+						http.ServeContent(w, r, "archive.zip", time.Time{}, bytes.NewReader(data))
+						return
+					}
+					_, _ = w.Write(data)
+				}))
+				defer server.Close()
+
+				fetcher := getit.New([]getit.Resolver{getit.NewZIP()}, nil, getit.WithZipNamePolicy(tt.policy))
+				dest := t.TempDir()
+				err := fetcher.Fetch(context.Background(), server.URL+"/archive.zip", dest)
+				if tt.err != "" {
+					assert.Error(t, err)
+					assert.Contains(t, err.Error(), tt.err)
+					return
+				}
+				assert.NoError(t, err)
+				for _, name := range tt.expected {
+					_, err := os.Stat(filepath.Join(dest, name))
+					assert.NoError(t, err)
+				}
+			})
+		}
+	}
+}
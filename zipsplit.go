@@ -0,0 +1,121 @@
+package getit
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+)
+
+const (
+	zip64LocatorSig = 0x07064b50
+	// zipSplitSig marks the start of a split archive, and zipSingleSplitSig an archive that was written to be split
+	// but fit in one part.
+	zipSplitSig       = zipDescriptorSig
+	zipSingleSplitSig = 0x30304b50
+)
+
+// zipDisks returns the number of parts of a zip archive given the end of its final part, or 0 if it has no end of
+// central directory record.
+func zipDisks(tail []byte) int {
+	i := bytes.LastIndex(tail, binary.LittleEndian.AppendUint32(nil, zipEndSig))
+	if i < 0 || len(tail)-i < 22 {
+		return 0
+	}
+	disk := binary.LittleEndian.Uint16(tail[i+4:])
+	if disk == 0xffff && i >= 20 && binary.LittleEndian.Uint32(tail[i-20:]) == zip64LocatorSig {
+		return int(binary.LittleEndian.Uint32(tail[i-4:]))
+	}
+	return int(disk) + 1
+}
+
+// zipFileDisks returns the number of parts of the zip archive whose final part is f, of the given size.
+func zipFileDisks(f *os.File, size int64) (int, error) {
+	start := max(size-rangeTailSize, 0)
+	tail := make([]byte, size-start)
+	if _, err := f.ReadAt(tail, start); err != nil {
+		return 0, fmt.Errorf("reading %s: %w", f.Name(), err)
+	}
+	return zipDisks(tail), nil
+}
+
+// zipPartURL returns the URL of a part of a split zip archive, replacing the .zip extension with .z01, .z02 etc. in
+// the same case.
+func zipPartURL(u *url.URL, part int) *url.URL {
+	pu := *u
+	base := u.Path[:len(u.Path)-len(".zip")]
+	pu.Path = fmt.Sprintf("%s.%c%02d", base, u.Path[len(base)+1], part)
+	pu.RawPath = ""
+	return &pu
+}
+
+// extractSplitZip unpacks a split zip archive of the given number of parts, whose final part is last. The preceding
+// parts are fetched from the URLs alongside u and streamed in order.
+func extractSplitZip(ctx context.Context, u *url.URL, disks int, last io.Reader, dest, subdir string) error {
+	configFromContext(ctx).logger.DebugContext(ctx, "extracting split zip", "url", RedactURL(u), "parts", disks)
+	r := &zipPartsReader{ctx: ctx, u: u, parts: disks - 1, last: last}
+	defer r.Close()
+	return extractZipStream(ctx, r, dest, subdir)
+}
+
+// zipPartsReader reads the parts of a split zip archive as a single stream, fetching each part preceding the last
+// as the previous one is exhausted. Parts of local file:// archives are read from alongside the last part.
+type zipPartsReader struct {
+	ctx   context.Context //nolint:containedctx // scoped to a single fetch
+	u     *url.URL
+	parts int
+	last  io.Reader
+	next  int
+	part  io.ReadCloser
+	body  io.Reader
+}
+
+func (z *zipPartsReader) Read(p []byte) (int, error) {
+	for z.part != nil || z.next < z.parts {
+		if z.part == nil {
+			z.next++
+			if err := z.open(zipPartURL(z.u, z.next)); err != nil {
+				return 0, fmt.Errorf("fetching part %d of split zip: %w", z.next, err)
+			}
+		}
+		n, err := z.body.Read(p)
+		if errors.Is(err, io.EOF) {
+			_ = z.part.Close()
+			z.part = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err //nolint:wrapcheck // io.Reader errors such as io.EOF must not be wrapped
+	}
+	return z.last.Read(p) //nolint:wrapcheck // io.Reader errors such as io.EOF must not be wrapped
+}
+
+func (z *zipPartsReader) open(u *url.URL) error {
+	if u.Scheme == "file" {
+		f, err := os.Open(localPath(u)) // #nosec G304
+		if err != nil {
+			return err //nolint:wrapcheck // wrapped by Read
+		}
+		z.part, z.body = f, f
+		return nil
+	}
+	resp, err := httpGet(z.ctx, u)
+	if err != nil {
+		return err
+	}
+	z.part, z.body = resp.Body, newCountingReader(z.ctx, resp.Body, u.Host)
+	return nil
+}
+
+func (z *zipPartsReader) Close() error {
+	if z.part == nil {
+		return nil
+	}
+	return z.part.Close() //nolint:wrapcheck // closing a part
+}
@@ -0,0 +1,464 @@
+package getit
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	zipLocalHeaderSig   = 0x04034b50
+	zipCentralHeaderSig = 0x02014b50
+	zipDescriptorSig    = 0x08074b50
+	zipEndSig           = 0x06054b50
+	zip64EndSig         = 0x06064b50
+	zip64ExtraID        = 0x0001
+	zipTimeExtraID      = 0x5455
+	zipFlagEncrypted    = 0x1
+	zipFlagDescriptor   = 0x8
+)
+
+// zipStreamEntry is an entry written by extractZipStream, whose mode is applied once the central directory is read.
+type zipStreamEntry struct {
+	// target is empty for entries outside the subdirectory being extracted, which are read past.
+	target   string
+	modified time.Time
+	// inSubDir is true if the entry is the subdirectory being extracted, or beneath it.
+	inSubDir bool
+}
+
+// extractZipStream unpacks a zip archive into dest as it is read, without buffering the archive. If subdir is set,
+// only the entries beneath it are extracted, relative to it, and the others are read past.
+//
+// Entries are extracted from their local headers, so only stored and deflated entries are supported. Modes and
+// symlinks are only recorded in the central directory at the end of the archive, so files are written as regular
+// files and then updated: symlinks are created, and executable and special bits are added.
+func extractZipStream(ctx context.Context, r io.Reader, dest, subdir string) error {
+	cfg := configFromContext(ctx)
+	counter := &byteCounter{r: r}
+	br := bufio.NewReaderSize(counter, 64<<10)
+	limits := newLimiter(cfg.limits, counter.count)
+	cases := newCaseTracker(cfg.caseCollisions)
+	entries := map[string]zipStreamEntry{}
+	var central []zip.FileHeader
+	split := false
+	found := subdir == ""
+	for first := true; ; first = false {
+		if err := contextError(ctx); err != nil {
+			return err
+		}
+		var sig uint32
+		if err := binary.Read(br, binary.LittleEndian, &sig); err != nil {
+			return fmt.Errorf("reading zip: %w", noEOF(err))
+		}
+		switch sig {
+		case zipLocalHeaderSig:
+			name, entry, err := extractZipStreamEntry(ctx, br, dest, subdir, limits, cases)
+			if err != nil {
+				return err
+			}
+			if entry.target != "" {
+				entries[name] = entry
+			}
+			found = found || entry.inSubDir
+
+		case zipCentralHeaderSig:
+			header, err := readZipCentralHeader(br)
+			if err != nil {
+				return err
+			}
+			central = append(central, header)
+
+		case zipSplitSig, zipSingleSplitSig:
+			if !first {
+				return fmt.Errorf("reading zip: unexpected signature %#08x", sig)
+			}
+			split = sig == zipSplitSig
+
+		case zipEndSig, zip64EndSig:
+			// The number of the disk holding the end record is a uint16 directly after the end signature, or a uint32
+			// after the record size and versions for zip64.
+			offset, size := 0, 2
+			if sig == zip64EndSig {
+				offset, size = 12, 4
+			}
+			if peek, err := br.Peek(offset + size); err == nil && !split && !bytes.Equal(peek[offset:], make([]byte, size)) {
+				return errors.New("reading zip: the archive is the final part of a split zip, which can only be extracted from a server supporting ranged requests or with ZIP.Concurrency")
+			}
+			if !found {
+				return fmt.Errorf("subdirectory %s not found in archive", subdir)
+			}
+			return finishZipStream(cfg, dest, entries, central)
+
+		default:
+			return fmt.Errorf("reading zip: unexpected signature %#08x", sig)
+		}
+	}
+}
+
+// finishZipStream applies modes from the central directory to streamed entries, which were extracted into dest.
+// Symlinks, streamed as files holding their targets, are created last, so that no other entry is changed through one.
+func finishZipStream(cfg *config, dest string, entries map[string]zipStreamEntry, central []zip.FileHeader) error {
+	perms := cfg.permissions
+	times := newTimestamper(cfg.options)
+	isLink := func(header zip.FileHeader) bool { return header.Mode()&fs.ModeSymlink != 0 }
+	ordered := slices.Concat(
+		slices.DeleteFunc(slices.Clone(central), isLink),
+		slices.DeleteFunc(slices.Clone(central), func(header zip.FileHeader) bool { return !isLink(header) }),
+	)
+	for _, header := range ordered {
+		entry, ok := entries[header.Name]
+		if !ok {
+			continue
+		}
+		mode := header.Mode()
+		switch {
+		case mode&fs.ModeSymlink != 0:
+			rel, err := filepath.Rel(dest, entry.target)
+			if err != nil {
+				return fmt.Errorf("%s: %w", header.Name, err)
+			}
+			if err := checkParents(dest, rel); err != nil {
+				return fmt.Errorf("%s: %w", header.Name, err)
+			}
+			link, err := os.ReadFile(entry.target)
+			if err != nil {
+				return fmt.Errorf("read %s: %w", header.Name, err)
+			}
+			if err := writeSymlink(entry.target, string(link)); err != nil {
+				return err
+			}
+		case mode.IsDir():
+			if err := writeDir(entry.target, mode, perms); err != nil {
+				return err
+			}
+		case perms.Normalize:
+			if err := os.Chmod(entry.target, perms.mode(mode)); err != nil {
+				return fmt.Errorf("chmod %s: %w", entry.target, err)
+			}
+		case mode&0o111 != 0 || (perms.PreserveSpecialBits && mode&specialBits != 0):
+			// Add executable bits alongside the read bits the file was created with, so the umask still applies.
+			info, err := os.Stat(entry.target)
+			if err != nil {
+				return fmt.Errorf("stat %s: %w", entry.target, err)
+			}
+			current := info.Mode().Perm()
+			updated := current | ((current&0o444)>>2)&mode.Perm()&^perms.Umask.Perm()
+			if perms.PreserveSpecialBits {
+				updated |= mode & specialBits
+			}
+			if err := os.Chmod(entry.target, updated); err != nil {
+				return fmt.Errorf("chmod %s: %w", entry.target, err)
+			}
+		}
+		if err := times.record(entry.target, mode, entry.modified); err != nil {
+			return err
+		}
+	}
+	return times.finish()
+}
+
+// zipLocalHeader is the fixed size part of a zip local file header, following the signature.
+type zipLocalHeader struct {
+	Version          uint16
+	Flags            uint16
+	Method           uint16
+	ModifiedTime     uint16
+	ModifiedDate     uint16
+	CRC32            uint32
+	CompressedSize   uint32
+	UncompressedSize uint32
+	NameLength       uint16
+	ExtraLength      uint16
+}
+
+// extractZipStreamEntry extracts the entry whose local header signature has just been read from r. Entries outside
+// subdir, if set, are read and verified but not written.
+func extractZipStreamEntry(ctx context.Context, r *bufio.Reader, dest, subdir string, limits *limiter, cases *caseTracker) (string, zipStreamEntry, error) {
+	cfg := configFromContext(ctx)
+	var header zipLocalHeader
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return "", zipStreamEntry{}, fmt.Errorf("reading zip: %w", noEOF(err))
+	}
+	name, extra, err := readZipNameAndExtra(r, int(header.NameLength), int(header.ExtraLength), 0)
+	if err != nil {
+		return "", zipStreamEntry{}, err
+	}
+	compressed, zip64 := int64(header.CompressedSize), false
+	modified := msDosTime(header.ModifiedDate, header.ModifiedTime)
+	for id, data := range zipExtraFields(extra) {
+		switch {
+		case id == zip64ExtraID:
+			zip64 = true
+			if header.UncompressedSize == 0xffffffff && len(data) >= 8 {
+				data = data[8:]
+			}
+			if header.CompressedSize == 0xffffffff && len(data) >= 8 {
+				compressed = int64(binary.LittleEndian.Uint64(data)) //nolint:gosec // sizes beyond int64 fail to read
+			}
+		case id == zipTimeExtraID && len(data) >= 5 && data[0]&1 != 0:
+			modified = time.Unix(int64(int32(binary.LittleEndian.Uint32(data[1:5]))), 0) //nolint:gosec // a signed 32-bit time
+		}
+	}
+
+	encrypted := header.Flags&zipFlagEncrypted != 0
+	if encrypted && compressed == 0 && header.Flags&zipFlagDescriptor != 0 {
+		// Encrypted data can't be scanned for its descriptor, but most writers record sizes in the local header anyway.
+		return "", zipStreamEntry{}, fmt.Errorf("%s: encrypted zip entries of unknown size can't be streamed, use ZIP.Concurrency or a server supporting ranged requests", name)
+	}
+	resolved, err := zipEntryName(name, header.Flags, extra, cfg.zipNames)
+	if err != nil {
+		return "", zipStreamEntry{}, err
+	}
+	inSubDir, skip := true, false
+	if subdir != "" {
+		resolved, inSubDir = withinSubDir(resolved, subdir)
+		skip = !inSubDir || resolved == ""
+	}
+	var target string
+	if !skip {
+		if resolved, err = cases.resolve(resolved); err != nil {
+			return "", zipStreamEntry{}, err
+		}
+		if target, err = securePath(dest, resolved); err != nil {
+			return "", zipStreamEntry{}, err
+		}
+		if err := cfg.pathRules.check(resolved); err != nil {
+			return "", zipStreamEntry{}, err
+		}
+		if err := limits.entry(name); err != nil {
+			return "", zipStreamEntry{}, err
+		}
+	}
+
+	descriptor := header.Flags&zipFlagDescriptor != 0
+	crc := crc32.NewIEEE()
+	var data io.Reader
+	switch {
+	case !descriptor, encrypted:
+		data = io.LimitReader(r, compressed)
+	case header.Method == zip.Store:
+		data = &storedDescriptorReader{r: r, crc: crc32.NewIEEE(), zip64: zip64}
+	default:
+		data = r
+	}
+	var content io.Reader
+	checkCRC := true
+	switch {
+	case encrypted:
+		content, checkCRC, err = openZipData(data, zipEntryHeader{
+			name:         name,
+			flags:        header.Flags,
+			method:       header.Method,
+			extra:        extra,
+			crc32:        header.CRC32,
+			modifiedTime: header.ModifiedTime,
+			size:         compressed,
+		}, cfg.options.ArchivePassword)
+		if err != nil {
+			return "", zipStreamEntry{}, err
+		}
+	case header.Method == zip.Store:
+		content = data
+	case header.Method == zip.Deflate:
+		fr := flate.NewReader(data)
+		defer fr.Close()
+		content = fr
+	default:
+		return "", zipStreamEntry{}, fmt.Errorf("%s: unsupported compression method %d", name, header.Method)
+	}
+
+	var size int64
+	switch {
+	case skip:
+		if _, err = io.Copy(io.Discard, &contextReader{ctx: ctx, r: io.TeeReader(content, crc)}); err != nil {
+			err = fmt.Errorf("reading %s: %w", name, err)
+		}
+	case strings.HasSuffix(name, "/"):
+		err = writeDir(target, 0o755|fs.ModeDir, cfg.permissions)
+	default:
+		size, err = writeFile(target, limits.reader(name, &contextReader{ctx: ctx, r: io.TeeReader(content, crc)}), 0o644, cfg.permissions)
+	}
+	if err != nil {
+		return "", zipStreamEntry{}, err
+	}
+	if !descriptor || encrypted {
+		// Skip any compressed data not consumed by the decompressor.
+		if _, err := io.Copy(io.Discard, data); err != nil {
+			return "", zipStreamEntry{}, fmt.Errorf("reading %s: %w", name, err)
+		}
+	}
+
+	expected := header.CRC32
+	if descriptor {
+		if expected, err = readZipDescriptor(r, zip64); err != nil {
+			return "", zipStreamEntry{}, fmt.Errorf("reading %s: %w", name, err)
+		}
+	}
+	if checkCRC && crc.Sum32() != expected {
+		return "", zipStreamEntry{}, fmt.Errorf("%s: checksum mismatch", name)
+	}
+	if !skip {
+		cfg.fileExtracted(resolved, size)
+	}
+	return name, zipStreamEntry{target: target, modified: modified, inSubDir: inSubDir}, nil
+}
+
+// readZipDescriptor reads the data descriptor following an entry, returning its checksum.
+func readZipDescriptor(r *bufio.Reader, zip64 bool) (uint32, error) {
+	if peek, err := r.Peek(4); err == nil && binary.LittleEndian.Uint32(peek) == zipDescriptorSig {
+		_, _ = r.Discard(4)
+	}
+	size := 12
+	if zip64 {
+		size = 20
+	}
+	buf := make([]byte, size)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return 0, fmt.Errorf("reading data descriptor: %w", noEOF(err))
+	}
+	return binary.LittleEndian.Uint32(buf), nil
+}
+
+// storedDescriptorReader reads a stored entry of unknown size, which ends at the first data descriptor whose
+// checksum and size match the data read so far.
+type storedDescriptorReader struct {
+	r     *bufio.Reader
+	crc   hash.Hash32
+	n     int64
+	zip64 bool
+	done  bool
+}
+
+func (s *storedDescriptorReader) Read(p []byte) (int, error) {
+	if s.done {
+		return 0, io.EOF
+	}
+	read := 0
+	for read < len(p) {
+		if s.atDescriptor() {
+			s.done = true
+			break
+		}
+		b, err := s.r.ReadByte()
+		if err != nil {
+			return read, fmt.Errorf("reading stored entry: %w", noEOF(err))
+		}
+		p[read] = b
+		read++
+		s.n++
+		_, _ = s.crc.Write([]byte{b})
+	}
+	if read == 0 && s.done {
+		return 0, io.EOF
+	}
+	return read, nil
+}
+
+// atDescriptor reports whether the reader is positioned at the data descriptor for the data read so far.
+func (s *storedDescriptorReader) atDescriptor() bool {
+	size := 16
+	if s.zip64 {
+		size = 24
+	}
+	peek, err := s.r.Peek(size)
+	if err != nil || binary.LittleEndian.Uint32(peek) != zipDescriptorSig {
+		return false
+	}
+	if binary.LittleEndian.Uint32(peek[4:]) != s.crc.Sum32() {
+		return false
+	}
+	if s.zip64 {
+		return binary.LittleEndian.Uint64(peek[8:]) == uint64(s.n) //nolint:gosec // n is never negative
+	}
+	return binary.LittleEndian.Uint32(peek[8:]) == uint32(s.n) //nolint:gosec // truncated as in the descriptor
+}
+
+// zipCentralHeader is the fixed size part of a zip central directory header, following the signature.
+type zipCentralHeader struct {
+	CreatorVersion   uint16
+	ReaderVersion    uint16
+	Flags            uint16
+	Method           uint16
+	ModifiedTime     uint16
+	ModifiedDate     uint16
+	CRC32            uint32
+	CompressedSize   uint32
+	UncompressedSize uint32
+	NameLength       uint16
+	ExtraLength      uint16
+	CommentLength    uint16
+	DiskNumber       uint16
+	InternalAttrs    uint16
+	ExternalAttrs    uint32
+	Offset           uint32
+}
+
+// readZipCentralHeader reads the central directory header whose signature has just been read from r.
+func readZipCentralHeader(r *bufio.Reader) (zip.FileHeader, error) {
+	var header zipCentralHeader
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return zip.FileHeader{}, fmt.Errorf("reading zip central directory: %w", noEOF(err))
+	}
+	name, _, err := readZipNameAndExtra(r, int(header.NameLength), int(header.ExtraLength), int(header.CommentLength))
+	if err != nil {
+		return zip.FileHeader{}, err
+	}
+	return zip.FileHeader{Name: name, CreatorVersion: header.CreatorVersion, ExternalAttrs: header.ExternalAttrs}, nil
+}
+
+// readZipNameAndExtra reads the variable length fields of a header, discarding any comment.
+func readZipNameAndExtra(r *bufio.Reader, nameLength, extraLength, commentLength int) (string, []byte, error) {
+	buf := make([]byte, nameLength+extraLength+commentLength)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return "", nil, fmt.Errorf("reading zip: %w", noEOF(err))
+	}
+	return string(buf[:nameLength]), buf[nameLength : nameLength+extraLength], nil
+}
+
+// zipExtraFields iterates over the fields of a zip extra block.
+func zipExtraFields(extra []byte) func(yield func(uint16, []byte) bool) {
+	return func(yield func(uint16, []byte) bool) {
+		for len(extra) >= 4 {
+			id := binary.LittleEndian.Uint16(extra)
+			size := int(binary.LittleEndian.Uint16(extra[2:]))
+			if len(extra) < 4+size {
+				return
+			}
+			if !yield(id, extra[4:4+size]) {
+				return
+			}
+			extra = extra[4+size:]
+		}
+	}
+}
+
+// msDosTime converts an MS-DOS date and time, as recorded in zip headers, to a time in UTC.
+func msDosTime(date, t uint16) time.Time {
+	return time.Date(
+		int(date>>9)+1980, time.Month(date>>5&0xf), int(date&0x1f),
+		int(t>>11), int(t>>5&0x3f), int(t&0x1f)*2, 0, time.UTC,
+	)
+}
+
+// noEOF converts io.EOF to io.ErrUnexpectedEOF, for reads that must not end the archive.
+func noEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,269 @@
+package getit
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"sync/atomic"
+)
+
+const (
+	zstdSkippableMagic = 0x184D2A5E
+	zstdSeekableMagic  = 0x8F92EAB1
+	// zstdSeekFooterSize is the size of the footer ending a seekable zstd archive.
+	zstdSeekFooterSize = 9
+	// zstdMaxSeekFrames and zstdMaxSeekFrameSize bound the seek tables that are used. Frames are decompressed into
+	// memory, so archives with larger frames are streamed instead.
+	zstdMaxSeekFrames    = 1 << 20
+	zstdMaxSeekFrameSize = 64 << 20
+)
+
+// zstdFrame locates a frame of a seekable zstd archive, in both the compressed and the decompressed stream.
+type zstdFrame struct {
+	compressedOffset int64
+	compressedSize   int64
+	offset           int64
+	size             int64
+}
+
+// fetchSeekable extracts the subdirectory of a source from a zstd tarball in the seekable format. It returns false
+// without extracting anything if the tarball is not seekable, or is remote and the server doesn't support range
+// requests. The entries of remote tarballs are read from, or added to, any archive index cache.
+func (t *TAR) fetchSeekable(ctx context.Context, source Source, dest string) (bool, error) {
+	cfg := configFromContext(ctx)
+	downloadCtx, cancelDownload := withPhaseTimeout(ctx, "download", cfg.timeouts.Download)
+	defer cancelDownload()
+	var compressed io.ReaderAt
+	var size int64
+	var key string
+	if source.URL.Scheme == "file" {
+		path := localPath(source.URL)
+		f, err := os.Open(path) // #nosec G304
+		if err != nil {
+			return false, fmt.Errorf("open %s: %w", path, err)
+		}
+		defer f.Close()
+		info, err := f.Stat()
+		if err != nil {
+			return false, fmt.Errorf("stat %s: %w", path, err)
+		}
+		compressed, size = f, info.Size()
+	} else {
+		resp, err := httpGetRange(downloadCtx, source.URL, fmt.Sprintf("bytes=-%d", rangeTailSize))
+		if err != nil {
+			return false, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusPartialContent {
+			return false, nil
+		}
+		ra, err := newRangeReader(downloadCtx, source.URL, resp)
+		if err != nil {
+			return false, err
+		}
+		compressed, size = ra, ra.size
+		key = cfg.archiveIndexes.key(source.URL, ra)
+	}
+	frames, ok, err := readZstdSeekTable(compressed, size)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	cfg.logger.DebugContext(ctx, "extract", "url", RedactURL(source.URL), "dest", dest, "seekable", true, "frames", len(frames))
+	ctx, span := startSpan(ctx, "getit.extract", map[string]string{"url": RedactURL(source.URL)})
+	ctx, cancel := withPhaseTimeout(ctx, "extract", cfg.timeouts.Extract)
+	defer cancel()
+	r := newSeekableZstd(ctx, compressed, frames)
+	limits := newLimiter(cfg.limits, r.compressed.Load)
+	index, ok := cfg.archiveIndexes.get(key)
+	ok = ok && index.entries != nil
+	if key != "" {
+		cfg.cacheLookup(source.URL.Host, "archive-index", ok)
+	}
+	if ok {
+		stream := tarIndexStream(index.entries, r, source.SubDir)
+		defer stream.Close()
+		err = extractTar(ctx, stream, dest, source.SubDir, limits, t.PreserveXattrs)
+	} else if key != "" {
+		indexer := newTarIndexer(r)
+		if err = extractTar(ctx, indexer, dest, source.SubDir, limits, t.PreserveXattrs); err == nil && indexer.complete {
+			cfg.archiveIndexes.put(key, &archiveIndex{entries: indexer.entries})
+		}
+	} else {
+		err = extractTar(ctx, r, dest, source.SubDir, limits, t.PreserveXattrs)
+	}
+	span.End(err)
+	return true, err
+}
+
+// readZstdSeekTable reads the seek table from the end of a zstd archive of the given size. It returns false if the
+// archive is not in the seekable format, or its seek table is outside the bounds getit uses.
+func readZstdSeekTable(r io.ReaderAt, size int64) ([]zstdFrame, bool, error) {
+	if size < 8+zstdSeekFooterSize {
+		return nil, false, nil
+	}
+	footer := make([]byte, zstdSeekFooterSize)
+	if _, err := r.ReadAt(footer, size-zstdSeekFooterSize); err != nil {
+		return nil, false, fmt.Errorf("reading zstd seek table: %w", err)
+	}
+	if binary.LittleEndian.Uint32(footer[5:]) != zstdSeekableMagic {
+		return nil, false, nil
+	}
+	count, descriptor := int64(binary.LittleEndian.Uint32(footer)), footer[4]
+	if descriptor&0x7c != 0 {
+		return nil, false, errors.New("invalid zstd seek table: reserved bits set")
+	}
+	if count > zstdMaxSeekFrames {
+		return nil, false, nil
+	}
+	entrySize := int64(8)
+	if descriptor&0x80 != 0 {
+		// Entries include a checksum, which is left to the decompressor.
+		entrySize = 12
+	}
+	tableSize := 8 + count*entrySize + zstdSeekFooterSize
+	if tableSize > size {
+		return nil, false, errors.New("invalid zstd seek table: larger than the archive")
+	}
+	table := make([]byte, tableSize-zstdSeekFooterSize)
+	if _, err := r.ReadAt(table, size-tableSize); err != nil {
+		return nil, false, fmt.Errorf("reading zstd seek table: %w", err)
+	}
+	if binary.LittleEndian.Uint32(table) != zstdSkippableMagic || int64(binary.LittleEndian.Uint32(table[4:])) != tableSize-8 {
+		return nil, false, errors.New("invalid zstd seek table: bad skippable frame header")
+	}
+	frames := make([]zstdFrame, count)
+	var compressedOffset, offset int64
+	for i := range frames {
+		entry := table[8+int64(i)*entrySize:]
+		frame := zstdFrame{
+			compressedOffset: compressedOffset,
+			compressedSize:   int64(binary.LittleEndian.Uint32(entry)),
+			offset:           offset,
+			size:             int64(binary.LittleEndian.Uint32(entry[4:])),
+		}
+		if frame.size > zstdMaxSeekFrameSize {
+			return nil, false, nil
+		}
+		frames[i] = frame
+		compressedOffset += frame.compressedSize
+		offset += frame.size
+	}
+	if compressedOffset != size-tableSize {
+		return nil, false, errors.New("invalid zstd seek table: frames don't cover the archive")
+	}
+	return frames, true, nil
+}
+
+// seekableZstd is an [io.ReadSeeker] over the decompressed content of a seekable zstd archive. Frames are
+// decompressed with the zstd tool as they are read, so seeking past frames skips decompressing, and for remote
+// archives downloading, them.
+type seekableZstd struct {
+	ctx        context.Context //nolint:containedctx // scoped to a single extraction
+	r          io.ReaderAt
+	frames     []zstdFrame
+	size       int64
+	off        int64
+	current    int          // Index of the frame held in data, or -1.
+	data       []byte       // Decompressed content of the current frame.
+	compressed atomic.Int64 // Compressed bytes read.
+}
+
+var (
+	_ io.ReadSeeker = (*seekableZstd)(nil)
+	_ io.ReaderAt   = (*seekableZstd)(nil)
+)
+
+func newSeekableZstd(ctx context.Context, r io.ReaderAt, frames []zstdFrame) *seekableZstd {
+	var size int64
+	if len(frames) > 0 {
+		last := frames[len(frames)-1]
+		size = last.offset + last.size
+	}
+	return &seekableZstd{ctx: ctx, r: r, frames: frames, size: size, current: -1}
+}
+
+func (s *seekableZstd) Read(p []byte) (int, error) {
+	if s.off >= s.size {
+		return 0, io.EOF
+	}
+	i := sort.Search(len(s.frames), func(i int) bool { return s.frames[i].offset+s.frames[i].size > s.off })
+	if i != s.current {
+		if err := s.decode(i); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, s.data[s.off-s.frames[i].offset:])
+	s.off += int64(n)
+	return n, nil
+}
+
+// ReadAt reads from the decompressed stream at off, without moving the offset used by Read. Unlike most ReaderAts it
+// must not be called concurrently, as it shares the frame cache with Read.
+func (s *seekableZstd) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+	read := 0
+	for read < len(p) {
+		if off >= s.size {
+			return read, io.EOF
+		}
+		i := sort.Search(len(s.frames), func(i int) bool { return s.frames[i].offset+s.frames[i].size > off })
+		if i != s.current {
+			if err := s.decode(i); err != nil {
+				return read, err
+			}
+		}
+		n := copy(p[read:], s.data[off-s.frames[i].offset:])
+		read += n
+		off += int64(n)
+	}
+	return read, nil
+}
+
+func (s *seekableZstd) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += s.off
+	case io.SeekEnd:
+		offset += s.size
+	default:
+		return 0, fmt.Errorf("invalid whence %d", whence)
+	}
+	if offset < 0 {
+		return 0, errors.New("negative offset")
+	}
+	s.off = offset
+	return offset, nil
+}
+
+// decode decompresses frame i into data.
+func (s *seekableZstd) decode(i int) error {
+	frame := s.frames[i]
+	s.current = -1
+	r, err := decompressCommand(s.ctx, io.NewSectionReader(s.r, frame.compressedOffset, frame.compressedSize), "zstd", "-dc")
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(io.LimitReader(r, frame.size+1))
+	if err != nil {
+		_ = r.Close()
+		return fmt.Errorf("decompressing zstd frame %d: %w", i, err)
+	}
+	if err := r.Close(); err != nil {
+		return err
+	}
+	if int64(len(data)) != frame.size {
+		return fmt.Errorf("decompressing zstd frame %d: got %d bytes, seek table records %d", i, len(data), frame.size)
+	}
+	s.compressed.Add(frame.compressedSize)
+	s.current, s.data = i, data
+	return nil
+}
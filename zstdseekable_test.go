@@ -0,0 +1,167 @@
+package getit //nolint:testpackage
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+// seekableZstdArchive compresses data into a seekable zstd archive of frames of frameSize decompressed bytes.
+func seekableZstdArchive(t *testing.T, data []byte, frameSize int) []byte {
+	t.Helper()
+	archive := &bytes.Buffer{}
+	table := &bytes.Buffer{}
+	frames := 0
+	for chunk := range slices.Chunk(data, frameSize) {
+		cmd := exec.Command("zstd", "-c", "-q")
+		cmd.Stdin = bytes.NewReader(chunk)
+		frame, err := cmd.Output()
+		assert.NoError(t, err)
+		archive.Write(frame)
+		_ = binary.Write(table, binary.LittleEndian, [2]uint32{uint32(len(frame)), uint32(len(chunk))}) //nolint:gosec // frames are small
+		frames++
+	}
+	_ = binary.Write(archive, binary.LittleEndian, [2]uint32{zstdSkippableMagic, uint32(table.Len() + zstdSeekFooterSize)}) //nolint:gosec // tables are small
+	archive.Write(table.Bytes())
+	_ = binary.Write(archive, binary.LittleEndian, uint32(frames)) //nolint:gosec // tables are small
+	archive.WriteByte(0)
+	_ = binary.Write(archive, binary.LittleEndian, uint32(zstdSeekableMagic))
+	return archive.Bytes()
+}
+
+// largeTar returns a tarball with a large incompressible file in big/ followed by a small file in small/.
+func largeTar(t *testing.T) []byte {
+	t.Helper()
+	big := make([]byte, 8<<20)
+	_, _ = rand.Read(big)
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	for _, file := range []struct{ name, content string }{
+		{"big/data.bin", string(big)},
+		{"small/file.txt", "hello\n"},
+	} {
+		assert.NoError(t, tw.WriteHeader(&tar.Header{Name: file.name, Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len(file.content))}))
+		_, err := tw.Write([]byte(file.content))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, tw.Close())
+	return buf.Bytes()
+}
+
+func TestTARFetchSeekableZstd(t *testing.T) {
+	if _, err := exec.LookPath("zstd"); err != nil {
+		t.Skip("zstd not installed")
+	}
+	tarball := largeTar(t)
+	archive := seekableZstdArchive(t, tarball, 256<<10)
+	var served atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		counter := &countingResponseWriter{ResponseWriter: w, n: &served}
+		if strings.Contains(r.URL.Path, "noranges") {
+			_, _ = counter.Write(archive)
+			return
+		}
+		http.ServeContent(counter, r, "archive.tar.zst", time.Time{}, bytes.NewReader(archive))
+	}))
+	defer server.Close()
+	local := filepath.Join(t.TempDir(), "archive.tar.zst")
+	assert.NoError(t, os.WriteFile(local, archive, 0o600))
+	plain := filepath.Join(t.TempDir(), "plain.tar.zst")
+	cmd := exec.Command("zstd", "-c", "-q")
+	cmd.Stdin = bytes.NewReader(tarball)
+	compressed, err := cmd.Output()
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(plain, compressed, 0o600))
+
+	tests := []struct {
+		name      string
+		source    string
+		maxServed int64
+	}{
+		{name: "Ranged", source: server.URL + "/archive.tar.zst//small", maxServed: int64(len(archive)) / 2},
+		{name: "NoRanges", source: server.URL + "/noranges/archive.tar.zst//small"},
+		{name: "Local", source: "file://" + filepath.ToSlash(local) + "//small"},
+		{name: "NotSeekable", source: "file://" + filepath.ToSlash(plain) + "//small"},
+	}
+	fetcher := New([]Resolver{NewTAR()}, nil)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			served.Store(0)
+			dest := t.TempDir()
+			assert.NoError(t, fetcher.Fetch(context.Background(), test.source, dest))
+			content, err := os.ReadFile(filepath.Join(dest, "file.txt"))
+			assert.NoError(t, err)
+			assert.Equal(t, "hello\n", string(content))
+			_, err = os.Stat(filepath.Join(dest, "data.bin"))
+			assert.True(t, errors.Is(err, os.ErrNotExist))
+			if test.maxServed > 0 {
+				assert.True(t, served.Load() < test.maxServed, "served %d of %d bytes", served.Load(), len(archive))
+			}
+		})
+	}
+
+	// Without a subdirectory the whole archive is extracted.
+	dest := t.TempDir()
+	assert.NoError(t, fetcher.Fetch(context.Background(), server.URL+"/archive.tar.zst", dest))
+	info, err := os.Stat(filepath.Join(dest, "big", "data.bin"))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(8<<20), info.Size())
+}
+
+type countingResponseWriter struct {
+	http.ResponseWriter
+	n *atomic.Int64
+}
+
+func (c *countingResponseWriter) Write(p []byte) (int, error) {
+	c.n.Add(int64(len(p)))
+	return c.ResponseWriter.Write(p)
+}
+
+func TestReadZstdSeekTable(t *testing.T) {
+	if _, err := exec.LookPath("zstd"); err != nil {
+		t.Skip("zstd not installed")
+	}
+	archive := seekableZstdArchive(t, bytes.Repeat([]byte("0123456789"), 100), 300)
+	frames, ok, err := readZstdSeekTable(bytes.NewReader(archive), int64(len(archive)))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 4, len(frames))
+	assert.Equal(t, int64(900), frames[3].offset)
+	assert.Equal(t, int64(100), frames[3].size)
+
+	r := newSeekableZstd(context.Background(), bytes.NewReader(archive), frames)
+	_, err = r.Seek(595, 0)
+	assert.NoError(t, err)
+	buf := make([]byte, 10)
+	n, err := r.Read(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "56789", string(buf[:n]), "reads stop at frame boundaries")
+	assert.Equal(t, frames[1].compressedSize, r.compressed.Load(), "only the frame read is decompressed")
+
+	// Archives that aren't seekable are reported as such.
+	_, ok, err = readZstdSeekTable(bytes.NewReader(archive[:100]), 100)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// Corrupt seek tables are rejected.
+	corrupt := bytes.Clone(archive)
+	corrupt[len(corrupt)-zstdSeekFooterSize]++
+	_, _, err = readZstdSeekTable(bytes.NewReader(corrupt), int64(len(corrupt)))
+	assert.Error(t, err)
+}